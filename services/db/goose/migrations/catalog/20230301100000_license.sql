@@ -0,0 +1,138 @@
+-- +goose Up
+-- License registry
+-- license_id is the internal identifier used within license expressions, e.g. _gpl-2.0 or CUSTOM[<identifier>]
+CREATE TABLE IF NOT EXISTS license (
+    license_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    spdx_id TEXT UNIQUE,
+    custom BOOLEAN NOT NULL DEFAULT FALSE,
+    record JSONB NOT NULL DEFAULT '{}',
+    text TEXT,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS license_alias (
+    alias TEXT PRIMARY KEY,
+    license_id TEXT NOT NULL REFERENCES license(license_id)
+);
+
+-- Commonly found licenses from the SPDX license list
+-- The rest of the list can be loaded from licenses.json with the importLicenseList mutation
+INSERT INTO license (license_id, name, spdx_id) VALUES
+    ('_0bsd', 'BSD Zero Clause License', '0BSD'),
+    ('_afl-3.0', 'Academic Free License v3.0', 'AFL-3.0'),
+    ('_agpl-3.0', 'GNU Affero General Public License v3.0 only', 'AGPL-3.0-only'),
+    ('_agpl-3.0+', 'GNU Affero General Public License v3.0 or later', 'AGPL-3.0-or-later'),
+    ('_apache-1.1', 'Apache License 1.1', 'Apache-1.1'),
+    ('_apache-2.0', 'Apache License 2.0', 'Apache-2.0'),
+    ('_apsl-2.0', 'Apple Public Source License 2.0', 'APSL-2.0'),
+    ('_artistic-1.0', 'Artistic License 1.0', 'Artistic-1.0'),
+    ('_artistic-2.0', 'Artistic License 2.0', 'Artistic-2.0'),
+    ('_bsd-1-clause', 'BSD 1-Clause License', 'BSD-1-Clause'),
+    ('_bsd-2-clause', 'BSD 2-Clause "Simplified" License', 'BSD-2-Clause'),
+    ('_bsd-2-clause-patent', 'BSD-2-Clause Plus Patent License', 'BSD-2-Clause-Patent'),
+    ('_bsd-3-clause', 'BSD 3-Clause "New" or "Revised" License', 'BSD-3-Clause'),
+    ('_bsd-3-clause-clear', 'BSD 3-Clause Clear License', 'BSD-3-Clause-Clear'),
+    ('_bsd-4-clause', 'BSD 4-Clause "Original" or "Old" License', 'BSD-4-Clause'),
+    ('_bsl-1.0', 'Boost Software License 1.0', 'BSL-1.0'),
+    ('_bzip2-1.0.6', 'bzip2 and libbzip2 License v1.0.6', 'bzip2-1.0.6'),
+    ('_cc-by-3.0', 'Creative Commons Attribution 3.0 Unported', 'CC-BY-3.0'),
+    ('_cc-by-4.0', 'Creative Commons Attribution 4.0 International', 'CC-BY-4.0'),
+    ('_cc-by-sa-3.0', 'Creative Commons Attribution Share Alike 3.0 Unported', 'CC-BY-SA-3.0'),
+    ('_cc-by-sa-4.0', 'Creative Commons Attribution Share Alike 4.0 International', 'CC-BY-SA-4.0'),
+    ('_cc0-1.0', 'Creative Commons Zero v1.0 Universal', 'CC0-1.0'),
+    ('_cddl-1.0', 'Common Development and Distribution License 1.0', 'CDDL-1.0'),
+    ('_cddl-1.1', 'Common Development and Distribution License 1.1', 'CDDL-1.1'),
+    ('_cpl-1.0', 'Common Public License 1.0', 'CPL-1.0'),
+    ('_curl', 'curl License', 'curl'),
+    ('_ecl-2.0', 'Educational Community License v2.0', 'ECL-2.0'),
+    ('_epl-1.0', 'Eclipse Public License 1.0', 'EPL-1.0'),
+    ('_epl-2.0', 'Eclipse Public License 2.0', 'EPL-2.0'),
+    ('_eupl-1.1', 'European Union Public License 1.1', 'EUPL-1.1'),
+    ('_eupl-1.2', 'European Union Public License 1.2', 'EUPL-1.2'),
+    ('_ftl', 'Freetype Project License', 'FTL'),
+    ('_gfdl-1.2', 'GNU Free Documentation License v1.2 only', 'GFDL-1.2-only'),
+    ('_gfdl-1.2+', 'GNU Free Documentation License v1.2 or later', 'GFDL-1.2-or-later'),
+    ('_gfdl-1.3', 'GNU Free Documentation License v1.3 only', 'GFDL-1.3-only'),
+    ('_gfdl-1.3+', 'GNU Free Documentation License v1.3 or later', 'GFDL-1.3-or-later'),
+    ('_gpl-1.0', 'GNU General Public License v1.0 only', 'GPL-1.0-only'),
+    ('_gpl-1.0+', 'GNU General Public License v1.0 or later', 'GPL-1.0-or-later'),
+    ('_gpl-2.0', 'GNU General Public License v2.0 only', 'GPL-2.0-only'),
+    ('_gpl-2.0+', 'GNU General Public License v2.0 or later', 'GPL-2.0-or-later'),
+    ('_gpl-3.0', 'GNU General Public License v3.0 only', 'GPL-3.0-only'),
+    ('_gpl-3.0+', 'GNU General Public License v3.0 or later', 'GPL-3.0-or-later'),
+    ('_hpnd', 'Historical Permission Notice and Disclaimer', 'HPND'),
+    ('_icu', 'ICU License', 'ICU'),
+    ('_ijg', 'Independent JPEG Group License', 'IJG'),
+    ('_isc', 'ISC License', 'ISC'),
+    ('_lgpl-2.0', 'GNU Library General Public License v2 only', 'LGPL-2.0-only'),
+    ('_lgpl-2.0+', 'GNU Library General Public License v2 or later', 'LGPL-2.0-or-later'),
+    ('_lgpl-2.1', 'GNU Lesser General Public License v2.1 only', 'LGPL-2.1-only'),
+    ('_lgpl-2.1+', 'GNU Lesser General Public License v2.1 or later', 'LGPL-2.1-or-later'),
+    ('_lgpl-3.0', 'GNU Lesser General Public License v3.0 only', 'LGPL-3.0-only'),
+    ('_lgpl-3.0+', 'GNU Lesser General Public License v3.0 or later', 'LGPL-3.0-or-later'),
+    ('_libpng', 'libpng License', 'Libpng'),
+    ('_libtiff', 'libtiff License', 'libtiff'),
+    ('_mit', 'MIT License', 'MIT'),
+    ('_mit-0', 'MIT No Attribution', 'MIT-0'),
+    ('_mpl-1.1', 'Mozilla Public License 1.1', 'MPL-1.1'),
+    ('_mpl-2.0', 'Mozilla Public License 2.0', 'MPL-2.0'),
+    ('_ms-pl', 'Microsoft Public License', 'MS-PL'),
+    ('_ms-rl', 'Microsoft Reciprocal License', 'MS-RL'),
+    ('_ncsa', 'University of Illinois/NCSA Open Source License', 'NCSA'),
+    ('_ofl-1.1', 'SIL Open Font License 1.1', 'OFL-1.1'),
+    ('_openssl', 'OpenSSL License', 'OpenSSL'),
+    ('_osl-3.0', 'Open Software License 3.0', 'OSL-3.0'),
+    ('_php-3.01', 'PHP License v3.01', 'PHP-3.01'),
+    ('_postgresql', 'PostgreSQL License', 'PostgreSQL'),
+    ('_psf-2.0', 'Python Software Foundation License 2.0', 'PSF-2.0'),
+    ('_python-2.0', 'Python License 2.0', 'Python-2.0'),
+    ('_ruby', 'Ruby License', 'Ruby'),
+    ('_sspl-1.0', 'Server Side Public License, v 1', 'SSPL-1.0'),
+    ('_unicode-dfs-2016', 'Unicode License Agreement - Data Files and Software (2016)', 'Unicode-DFS-2016'),
+    ('_unlicense', 'The Unlicense', 'Unlicense'),
+    ('_upl-1.0', 'Universal Permissive License v1.0', 'UPL-1.0'),
+    ('_vim', 'Vim License', 'Vim'),
+    ('_w3c', 'W3C Software Notice and License (2002-12-31)', 'W3C'),
+    ('_wtfpl', 'Do What The F*ck You Want To Public License', 'WTFPL'),
+    ('_x11', 'X11 License', 'X11'),
+    ('_zlib', 'zlib License', 'Zlib'),
+    ('_zpl-2.1', 'Zope Public License 2.1', 'ZPL-2.1')
+ON CONFLICT (license_id) DO NOTHING;
+
+INSERT INTO license_alias (alias, license_id) VALUES
+    ('AGPL-3.0', '_agpl-3.0'),
+    ('AGPLv3', '_agpl-3.0'),
+    ('GFDL-1.2', '_gfdl-1.2'),
+    ('GFDL-1.3', '_gfdl-1.3'),
+    ('GPL-1.0', '_gpl-1.0'),
+    ('GPL-1.0+', '_gpl-1.0+'),
+    ('GPL-2.0', '_gpl-2.0'),
+    ('GPL-2.0+', '_gpl-2.0+'),
+    ('GPLv2', '_gpl-2.0'),
+    ('GPLv2+', '_gpl-2.0+'),
+    ('GPL-3.0', '_gpl-3.0'),
+    ('GPL-3.0+', '_gpl-3.0+'),
+    ('GPLv3', '_gpl-3.0'),
+    ('GPLv3+', '_gpl-3.0+'),
+    ('LGPL-2.0', '_lgpl-2.0'),
+    ('LGPL-2.0+', '_lgpl-2.0+'),
+    ('LGPL-2.1', '_lgpl-2.1'),
+    ('LGPL-2.1+', '_lgpl-2.1+'),
+    ('LGPLv2.1', '_lgpl-2.1'),
+    ('LGPLv2.1+', '_lgpl-2.1+'),
+    ('LGPL-3.0', '_lgpl-3.0'),
+    ('LGPL-3.0+', '_lgpl-3.0+'),
+    ('LGPLv3', '_lgpl-3.0'),
+    ('LGPLv3+', '_lgpl-3.0+'),
+    ('Apache 2.0', '_apache-2.0'),
+    ('ASL 2.0', '_apache-2.0'),
+    ('Apache License 2.0', '_apache-2.0'),
+    ('Expat', '_mit'),
+    ('MPLv2.0', '_mpl-2.0'),
+    ('Public Domain CC0', '_cc0-1.0')
+ON CONFLICT (alias) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS license_alias;
+DROP TABLE IF EXISTS license;
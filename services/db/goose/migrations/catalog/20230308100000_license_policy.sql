@@ -0,0 +1,13 @@
+-- +goose Up
+-- License policies
+-- rules is a JSON array of rules, see the policy package for the accepted rule types
+CREATE TABLE IF NOT EXISTS license_policy (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    rules JSONB NOT NULL DEFAULT '[]',
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS license_policy;
@@ -0,0 +1,181 @@
+-- +goose Up
+-- Obligations a license places on anyone distributing a part under it
+-- attribution: copyright and license notices must be reproduced
+-- source_offer: source code must be provided or offered
+-- change_notice: modified files must carry notices stating they were changed
+-- patent: the license grants or terminates patent rights
+CREATE TABLE IF NOT EXISTS license_obligation (
+    license_id TEXT NOT NULL REFERENCES license(license_id) ON DELETE CASCADE,
+    obligation TEXT NOT NULL CHECK (obligation IN ('attribution', 'source_offer', 'change_notice', 'patent')),
+    description TEXT,
+    PRIMARY KEY(license_id, obligation)
+);
+
+-- Obligations of the licenses seeded by the license registry
+-- Only licenses that exist are given obligations, in case the registry was trimmed
+INSERT INTO license_obligation (license_id, obligation)
+SELECT v.license_id, v.obligation FROM (VALUES
+    ('_afl-3.0', 'attribution'),
+    ('_agpl-3.0', 'attribution'),
+    ('_agpl-3.0+', 'attribution'),
+    ('_apache-1.1', 'attribution'),
+    ('_apache-2.0', 'attribution'),
+    ('_apsl-2.0', 'attribution'),
+    ('_artistic-1.0', 'attribution'),
+    ('_artistic-2.0', 'attribution'),
+    ('_bsd-1-clause', 'attribution'),
+    ('_bsd-2-clause', 'attribution'),
+    ('_bsd-2-clause-patent', 'attribution'),
+    ('_bsd-3-clause', 'attribution'),
+    ('_bsd-3-clause-clear', 'attribution'),
+    ('_bsd-4-clause', 'attribution'),
+    ('_bsl-1.0', 'attribution'),
+    ('_bzip2-1.0.6', 'attribution'),
+    ('_cc-by-3.0', 'attribution'),
+    ('_cc-by-4.0', 'attribution'),
+    ('_cc-by-sa-3.0', 'attribution'),
+    ('_cc-by-sa-4.0', 'attribution'),
+    ('_cddl-1.0', 'attribution'),
+    ('_cddl-1.1', 'attribution'),
+    ('_cpl-1.0', 'attribution'),
+    ('_curl', 'attribution'),
+    ('_ecl-2.0', 'attribution'),
+    ('_epl-1.0', 'attribution'),
+    ('_epl-2.0', 'attribution'),
+    ('_eupl-1.1', 'attribution'),
+    ('_eupl-1.2', 'attribution'),
+    ('_ftl', 'attribution'),
+    ('_gfdl-1.2', 'attribution'),
+    ('_gfdl-1.2+', 'attribution'),
+    ('_gfdl-1.3', 'attribution'),
+    ('_gfdl-1.3+', 'attribution'),
+    ('_gpl-1.0', 'attribution'),
+    ('_gpl-1.0+', 'attribution'),
+    ('_gpl-2.0', 'attribution'),
+    ('_gpl-2.0+', 'attribution'),
+    ('_gpl-3.0', 'attribution'),
+    ('_gpl-3.0+', 'attribution'),
+    ('_hpnd', 'attribution'),
+    ('_icu', 'attribution'),
+    ('_ijg', 'attribution'),
+    ('_isc', 'attribution'),
+    ('_lgpl-2.0', 'attribution'),
+    ('_lgpl-2.0+', 'attribution'),
+    ('_lgpl-2.1', 'attribution'),
+    ('_lgpl-2.1+', 'attribution'),
+    ('_lgpl-3.0', 'attribution'),
+    ('_lgpl-3.0+', 'attribution'),
+    ('_libpng', 'attribution'),
+    ('_libtiff', 'attribution'),
+    ('_mit', 'attribution'),
+    ('_mpl-1.1', 'attribution'),
+    ('_mpl-2.0', 'attribution'),
+    ('_ms-pl', 'attribution'),
+    ('_ms-rl', 'attribution'),
+    ('_ncsa', 'attribution'),
+    ('_ofl-1.1', 'attribution'),
+    ('_openssl', 'attribution'),
+    ('_osl-3.0', 'attribution'),
+    ('_php-3.01', 'attribution'),
+    ('_postgresql', 'attribution'),
+    ('_psf-2.0', 'attribution'),
+    ('_python-2.0', 'attribution'),
+    ('_ruby', 'attribution'),
+    ('_sspl-1.0', 'attribution'),
+    ('_unicode-dfs-2016', 'attribution'),
+    ('_upl-1.0', 'attribution'),
+    ('_vim', 'attribution'),
+    ('_w3c', 'attribution'),
+    ('_x11', 'attribution'),
+    ('_zlib', 'attribution'),
+    ('_zpl-2.1', 'attribution'),
+    ('_agpl-3.0', 'source_offer'),
+    ('_agpl-3.0+', 'source_offer'),
+    ('_cddl-1.0', 'source_offer'),
+    ('_cddl-1.1', 'source_offer'),
+    ('_cpl-1.0', 'source_offer'),
+    ('_epl-1.0', 'source_offer'),
+    ('_epl-2.0', 'source_offer'),
+    ('_eupl-1.1', 'source_offer'),
+    ('_eupl-1.2', 'source_offer'),
+    ('_gpl-1.0', 'source_offer'),
+    ('_gpl-1.0+', 'source_offer'),
+    ('_gpl-2.0', 'source_offer'),
+    ('_gpl-2.0+', 'source_offer'),
+    ('_gpl-3.0', 'source_offer'),
+    ('_gpl-3.0+', 'source_offer'),
+    ('_lgpl-2.0', 'source_offer'),
+    ('_lgpl-2.0+', 'source_offer'),
+    ('_lgpl-2.1', 'source_offer'),
+    ('_lgpl-2.1+', 'source_offer'),
+    ('_lgpl-3.0', 'source_offer'),
+    ('_lgpl-3.0+', 'source_offer'),
+    ('_mpl-1.1', 'source_offer'),
+    ('_mpl-2.0', 'source_offer'),
+    ('_ms-rl', 'source_offer'),
+    ('_osl-3.0', 'source_offer'),
+    ('_sspl-1.0', 'source_offer'),
+    ('_vim', 'source_offer'),
+    ('_agpl-3.0', 'change_notice'),
+    ('_agpl-3.0+', 'change_notice'),
+    ('_apache-2.0', 'change_notice'),
+    ('_artistic-1.0', 'change_notice'),
+    ('_artistic-2.0', 'change_notice'),
+    ('_cc-by-4.0', 'change_notice'),
+    ('_cc-by-sa-4.0', 'change_notice'),
+    ('_cddl-1.0', 'change_notice'),
+    ('_cddl-1.1', 'change_notice'),
+    ('_ecl-2.0', 'change_notice'),
+    ('_eupl-1.1', 'change_notice'),
+    ('_eupl-1.2', 'change_notice'),
+    ('_gfdl-1.2', 'change_notice'),
+    ('_gfdl-1.2+', 'change_notice'),
+    ('_gfdl-1.3', 'change_notice'),
+    ('_gfdl-1.3+', 'change_notice'),
+    ('_gpl-1.0', 'change_notice'),
+    ('_gpl-1.0+', 'change_notice'),
+    ('_gpl-2.0', 'change_notice'),
+    ('_gpl-2.0+', 'change_notice'),
+    ('_gpl-3.0', 'change_notice'),
+    ('_gpl-3.0+', 'change_notice'),
+    ('_lgpl-2.0', 'change_notice'),
+    ('_lgpl-2.0+', 'change_notice'),
+    ('_lgpl-2.1', 'change_notice'),
+    ('_lgpl-2.1+', 'change_notice'),
+    ('_lgpl-3.0', 'change_notice'),
+    ('_lgpl-3.0+', 'change_notice'),
+    ('_mpl-1.1', 'change_notice'),
+    ('_osl-3.0', 'change_notice'),
+    ('_php-3.01', 'change_notice'),
+    ('_psf-2.0', 'change_notice'),
+    ('_python-2.0', 'change_notice'),
+    ('_sspl-1.0', 'change_notice'),
+    ('_zlib', 'change_notice'),
+    ('_agpl-3.0', 'patent'),
+    ('_agpl-3.0+', 'patent'),
+    ('_apache-2.0', 'patent'),
+    ('_bsd-2-clause-patent', 'patent'),
+    ('_cddl-1.0', 'patent'),
+    ('_cddl-1.1', 'patent'),
+    ('_cpl-1.0', 'patent'),
+    ('_ecl-2.0', 'patent'),
+    ('_epl-1.0', 'patent'),
+    ('_epl-2.0', 'patent'),
+    ('_eupl-1.2', 'patent'),
+    ('_gpl-3.0', 'patent'),
+    ('_gpl-3.0+', 'patent'),
+    ('_lgpl-3.0', 'patent'),
+    ('_lgpl-3.0+', 'patent'),
+    ('_mpl-1.1', 'patent'),
+    ('_mpl-2.0', 'patent'),
+    ('_ms-pl', 'patent'),
+    ('_ms-rl', 'patent'),
+    ('_osl-3.0', 'patent'),
+    ('_sspl-1.0', 'patent'),
+    ('_upl-1.0', 'patent')
+) AS v(license_id, obligation)
+INNER JOIN license ON license.license_id=v.license_id
+ON CONFLICT (license_id, obligation) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS license_obligation;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- Corresponding-source bundles built for a part list
+-- size is the number of bytes of the tarball that have been committed, and is where a resumed build continues from
+CREATE TABLE IF NOT EXISTS source_bundle (
+    id BIGSERIAL PRIMARY KEY,
+    partlist_id BIGINT REFERENCES partlist(id) ON DELETE SET NULL,
+    licenses JSONB NOT NULL DEFAULT '[]',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'complete', 'failed')),
+    storage_path TEXT,
+    size BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    update_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Every member written to a bundle's tarball, in order
+-- end_offset is the size of the tarball after the member and its padding were written
+CREATE TABLE IF NOT EXISTS source_bundle_entry (
+    bundle_id BIGINT NOT NULL REFERENCES source_bundle(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    part_id UUID,
+    source TEXT NOT NULL,
+    sha256 SHA256_BYTEA NOT NULL,
+    size BIGINT NOT NULL,
+    end_offset BIGINT NOT NULL,
+    PRIMARY KEY(bundle_id, path)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS source_bundle_entry;
+DROP TABLE IF EXISTS source_bundle;
@@ -0,0 +1,63 @@
+-- +goose Up
+-- Vulnerability records imported from OSV data
+-- record is the original OSV JSON
+CREATE TABLE IF NOT EXISTS vulnerability (
+    vulnerability_id TEXT PRIMARY KEY,
+    summary TEXT,
+    details TEXT,
+    severity JSONB NOT NULL DEFAULT '[]',
+    published TIMESTAMP,
+    modified TIMESTAMP,
+    withdrawn TIMESTAMP,
+    record JSONB NOT NULL,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Other identifiers of a vulnerability, such as CVE IDs
+CREATE TABLE IF NOT EXISTS vulnerability_alias (
+    vulnerability_id TEXT NOT NULL REFERENCES vulnerability(vulnerability_id) ON DELETE CASCADE,
+    alias TEXT NOT NULL,
+    PRIMARY KEY(vulnerability_id, alias)
+);
+CREATE INDEX IF NOT EXISTS vulnerability_alias_alias_idx ON vulnerability_alias(alias);
+
+-- Packages affected by a vulnerability
+-- match_name is the lower case last component of the package name, which is compared against part names
+-- affected is the OSV affected entry, with its ranges and versions
+CREATE TABLE IF NOT EXISTS vulnerability_package (
+    id BIGSERIAL PRIMARY KEY,
+    vulnerability_id TEXT NOT NULL REFERENCES vulnerability(vulnerability_id) ON DELETE CASCADE,
+    ecosystem TEXT,
+    name TEXT NOT NULL,
+    match_name TEXT NOT NULL,
+    purl TEXT,
+    affected JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS vulnerability_package_match_name_idx ON vulnerability_package(match_name);
+
+-- sha256 of affected archives or files, when an advisory lists them
+CREATE TABLE IF NOT EXISTS vulnerability_hash (
+    vulnerability_id TEXT NOT NULL REFERENCES vulnerability(vulnerability_id) ON DELETE CASCADE,
+    sha256 SHA256_BYTEA NOT NULL,
+    PRIMARY KEY(vulnerability_id, sha256)
+);
+CREATE INDEX IF NOT EXISTS vulnerability_hash_sha256_idx ON vulnerability_hash(sha256);
+
+-- Vulnerabilities matched to parts
+-- match is how the vulnerability was matched: package, archive or file
+CREATE TABLE IF NOT EXISTS part_has_vulnerability (
+    part_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    vulnerability_id TEXT NOT NULL REFERENCES vulnerability(vulnerability_id) ON DELETE CASCADE,
+    match TEXT NOT NULL CHECK (match IN ('package', 'archive', 'file')),
+    detail TEXT,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(part_id, vulnerability_id)
+);
+CREATE INDEX IF NOT EXISTS part_has_vulnerability_vulnerability_id_idx ON part_has_vulnerability(vulnerability_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_has_vulnerability;
+DROP TABLE IF EXISTS vulnerability_hash;
+DROP TABLE IF EXISTS vulnerability_package;
+DROP TABLE IF EXISTS vulnerability_alias;
+DROP TABLE IF EXISTS vulnerability;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- VEX statements on the exploitability of a vulnerability in a part, or in every part of a part list
+-- vulnerability_id is the id as given by the statement, which may be an alias of an imported vulnerability
+-- justification uses the OpenVEX vocabulary, and is only meaningful for not_affected
+CREATE TABLE IF NOT EXISTS vex_statement (
+    id BIGSERIAL PRIMARY KEY,
+    vulnerability_id TEXT NOT NULL,
+    part_id UUID REFERENCES part(part_id) ON DELETE CASCADE,
+    partlist_id BIGINT REFERENCES partlist(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('not_affected', 'affected', 'fixed', 'under_investigation')),
+    justification TEXT CHECK (justification IN ('component_not_present', 'vulnerable_code_not_present', 'vulnerable_code_not_in_execute_path', 'vulnerable_code_cannot_be_controlled_by_adversary', 'inline_mitigations_already_exist')),
+    impact_statement TEXT,
+    action_statement TEXT,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    update_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((part_id IS NULL) <> (partlist_id IS NULL))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS vex_statement_part_idx ON vex_statement(vulnerability_id, part_id) WHERE part_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS vex_statement_partlist_idx ON vex_statement(vulnerability_id, partlist_id) WHERE partlist_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS vex_statement;
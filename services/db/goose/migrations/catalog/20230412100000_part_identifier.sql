@@ -0,0 +1,17 @@
+-- +goose Up
+-- external identifiers of a part, purl, CPE 2.3 formatted string or SWID tag id, stored normalized
+-- name is the identifier without its version, so every version of a package can be found by it
+CREATE TABLE IF NOT EXISTS part_identifier (
+    part_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('purl', 'cpe', 'swid')),
+    value TEXT NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (part_id, type, value)
+);
+CREATE INDEX IF NOT EXISTS part_identifier_value_idx ON part_identifier(type, value);
+CREATE INDEX IF NOT EXISTS part_identifier_name_idx ON part_identifier(type, name);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_identifier;
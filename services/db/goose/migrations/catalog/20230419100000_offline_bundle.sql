@@ -0,0 +1,56 @@
+-- +goose Up
+-- offline bundles move parts and partlists between catalog instances that cannot reach each other
+-- an export is complete once its tarball was written in full, only complete exports can be the base of an incremental export
+CREATE TABLE IF NOT EXISTS offline_export (
+    id BIGSERIAL PRIMARY KEY,
+    since_id BIGINT REFERENCES offline_export(id) ON DELETE SET NULL,
+    part_ids JSONB NOT NULL DEFAULT '[]',
+    partlist_id BIGINT REFERENCES partlist(id) ON DELETE SET NULL,
+    blobs BOOLEAN NOT NULL DEFAULT TRUE,
+    complete BOOLEAN NOT NULL DEFAULT FALSE,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the state the receiving instance has after importing an export, digests of every part and partlist record, and every blob sent so far
+-- an incremental export leaves out records whose digest is unchanged, and blobs already sent, since its base
+CREATE TABLE IF NOT EXISTS offline_export_entry (
+    export_id BIGINT NOT NULL REFERENCES offline_export(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('part', 'partlist', 'blob')),
+    key TEXT NOT NULL,
+    digest BYTEA,
+    PRIMARY KEY (export_id, kind, key)
+);
+
+-- parts imported from other instances, so that later bundles update the same parts
+CREATE TABLE IF NOT EXISTS offline_part_origin (
+    instance TEXT NOT NULL,
+    source_part_id UUID NOT NULL,
+    part_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    PRIMARY KEY (instance, source_part_id)
+);
+
+-- partlists imported from other instances
+CREATE TABLE IF NOT EXISTS offline_partlist_origin (
+    instance TEXT NOT NULL,
+    source_partlist_id BIGINT NOT NULL,
+    partlist_id BIGINT NOT NULL REFERENCES partlist(id) ON DELETE CASCADE,
+    PRIMARY KEY (instance, source_partlist_id)
+);
+
+-- bundles imported into this instance, with the conflicts found on curated fields
+CREATE TABLE IF NOT EXISTS offline_import (
+    id BIGSERIAL PRIMARY KEY,
+    instance TEXT NOT NULL,
+    export_id BIGINT NOT NULL,
+    since_id BIGINT,
+    report JSONB NOT NULL,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS offline_import_export_idx ON offline_import(instance, export_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS offline_import;
+DROP TABLE IF EXISTS offline_partlist_origin;
+DROP TABLE IF EXISTS offline_part_origin;
+DROP TABLE IF EXISTS offline_export_entry;
+DROP TABLE IF EXISTS offline_export;
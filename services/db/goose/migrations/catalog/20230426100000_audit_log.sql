@@ -0,0 +1,32 @@
+-- +goose Up
+-- append-only history of changes to the curated data of parts and partlists, written in the same transaction as each change
+-- revisions count up per part or partlist, and changes are field-level diffs, [{"field", "key", "title", "old", "new"}]
+-- rows outlive the parts and partlists they are about, so there are no foreign keys
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    part_id UUID,
+    partlist_id BIGINT,
+    revision BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    changes JSONB NOT NULL DEFAULT '[]',
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((part_id IS NULL) <> (partlist_id IS NULL))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS audit_log_part_revision_idx ON audit_log(part_id, revision) WHERE part_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS audit_log_partlist_revision_idx ON audit_log(partlist_id, revision) WHERE partlist_id IS NOT NULL;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+    BEGIN
+        RAISE EXCEPTION 'audit_log is append-only';
+    END;
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only;
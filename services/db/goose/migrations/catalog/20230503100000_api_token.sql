@@ -0,0 +1,18 @@
+-- +goose Up
+-- static API tokens, only their sha256 is stored, tokens themselves are shown once when created
+-- roles is a JSON array of viewer, curator, uploader, or admin
+CREATE TABLE IF NOT EXISTS api_token (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    token_sha256 SHA256_BYTEA NOT NULL UNIQUE,
+    roles JSONB NOT NULL DEFAULT '[]',
+    expires TIMESTAMP,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used TIMESTAMP,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+-- a name can be reused once its token is revoked
+CREATE UNIQUE INDEX IF NOT EXISTS api_token_name_idx ON api_token(name) WHERE NOT revoked;
+
+-- +goose Down
+DROP TABLE IF EXISTS api_token;
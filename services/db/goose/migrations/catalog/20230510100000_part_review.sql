@@ -0,0 +1,29 @@
+-- +goose Up
+-- review state of parts, unreviewed -> in_review -> approved or rejected, parts without a row are unreviewed
+-- reviewer and review_date are who last approved or rejected the part and when, requested_by and request_date who last requested its review
+CREATE TABLE IF NOT EXISTS part_review (
+    part_id UUID PRIMARY KEY REFERENCES part(part_id) ON DELETE CASCADE,
+    state TEXT NOT NULL DEFAULT 'unreviewed' CHECK (state IN ('unreviewed', 'in_review', 'approved', 'rejected')),
+    requested_by TEXT,
+    request_date TIMESTAMP,
+    reviewer TEXT,
+    review_date TIMESTAMP,
+    comment TEXT,
+    update_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS part_review_state_idx ON part_review(state);
+
+-- every transition of the review state of a part, with who made it and their comment
+CREATE TABLE IF NOT EXISTS part_review_event (
+    id BIGSERIAL PRIMARY KEY,
+    part_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    state TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    comment TEXT,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS part_review_event_part_idx ON part_review_event(part_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_review_event;
+DROP TABLE IF EXISTS part_review;
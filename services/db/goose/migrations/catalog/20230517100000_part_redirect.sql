@@ -0,0 +1,12 @@
+-- +goose Up
+-- ids of parts merged into another part, so references to them keep resolving to the part they were merged into
+-- redirects to a part that is merged again are moved to the part it was merged into, so there are no chains
+CREATE TABLE IF NOT EXISTS part_redirect (
+    part_id UUID PRIMARY KEY,
+    target_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS part_redirect_target_idx ON part_redirect(target_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_redirect;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- MinHash signatures of the file sha256 sets of parts with their sub-parts, computed at ingestion, to find similar parts without comparing every pair
+-- signature is the 128 minimum hashes as big-endian 64 bit integers, and file_count the number of distinct files in the set, for estimating how much of one part another contains
+CREATE TABLE IF NOT EXISTS part_minhash (
+    part_id UUID PRIMARY KEY REFERENCES part(part_id) ON DELETE CASCADE,
+    signature BYTEA NOT NULL,
+    file_count BIGINT NOT NULL,
+    update_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- locality-sensitive hashing bands of signatures, parts sharing the hash of any band are candidates for similarity
+CREATE TABLE IF NOT EXISTS part_minhash_band (
+    part_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    band SMALLINT NOT NULL,
+    hash BIGINT NOT NULL,
+    PRIMARY KEY (part_id, band)
+);
+CREATE INDEX IF NOT EXISTS part_minhash_band_hash_idx ON part_minhash_band(band, hash);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_minhash_band;
+DROP TABLE IF EXISTS part_minhash;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- version families are the parts sharing a family_name, ordered by the version scheme of the family
+-- a family is only listed here once its scheme is set, otherwise the scheme is inferred from the purls of its parts
+CREATE TABLE IF NOT EXISTS version_family (
+    name TEXT PRIMARY KEY,
+    scheme TEXT NOT NULL CHECK (scheme IN ('generic', 'semver', 'deb', 'rpm', 'pypi')),
+    update_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS part_family_name_idx ON part(family_name);
+
+-- +goose Down
+DROP INDEX IF EXISTS part_family_name_idx;
+DROP TABLE IF EXISTS version_family;
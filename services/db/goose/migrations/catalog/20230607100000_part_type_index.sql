@@ -0,0 +1,6 @@
+-- +goose Up
+-- part types are queried by the types beneath them, type <@ 'part.file.binary'
+CREATE INDEX IF NOT EXISTS part_type_gist_idx ON part USING GIST (type);
+
+-- +goose Down
+DROP INDEX IF EXISTS part_type_gist_idx;
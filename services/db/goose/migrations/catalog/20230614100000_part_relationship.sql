@@ -0,0 +1,16 @@
+-- +goose Up
+-- typed relationships between parts, read as part_id type related_id, e.g. a DEPENDS_ON b, beyond the containment of part_has_part
+CREATE TABLE IF NOT EXISTS part_relationship (
+    part_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('DEPENDS_ON', 'BUILD_DEPENDENCY_OF', 'GENERATED_FROM', 'PATCH_FOR', 'VARIANT_OF', 'DYNAMIC_LINK', 'DESCENDANT_OF')),
+    related_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    comment TEXT,
+    metadata JSONB,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (part_id, type, related_id),
+    CHECK (part_id <> related_id)
+);
+CREATE INDEX IF NOT EXISTS part_relationship_related_idx ON part_relationship(related_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_relationship;
@@ -0,0 +1,122 @@
+-- +goose Up
+-- every ancestor and descendant of part_has_part, once per path of the descendant within the ancestor, the paths of the sub-parts in between joined by /
+-- depth is the number of sub-parts in between plus one, so parents are at depth 1
+-- kept up to date by the part_has_part_closure trigger, so the whole tree beneath or above a part is a single select
+CREATE TABLE IF NOT EXISTS part_closure (
+    ancestor_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    descendant_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    depth INTEGER NOT NULL CHECK (depth > 0),
+    path TEXT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id, depth, path)
+);
+CREATE INDEX IF NOT EXISTS part_closure_descendant_idx ON part_closure(descendant_id);
+
+-- +goose StatementBegin
+-- part_closure_insert adds the paths through a new part_has_part edge, from the parent and its ancestors to the child and its descendants
+CREATE OR REPLACE FUNCTION part_closure_insert(_parent UUID, _child UUID, _path TEXT) RETURNS VOID LANGUAGE SQL AS $$
+    INSERT INTO part_closure (ancestor_id, descendant_id, depth, path)
+    SELECT a.ancestor_id, d.descendant_id, a.depth + 1 + d.depth, CONCAT_WS('/', NULLIF(a.path, ''), NULLIF(_path, ''), NULLIF(d.path, ''))
+    FROM (SELECT ancestor_id, depth, path FROM part_closure WHERE descendant_id=_parent UNION ALL SELECT _parent, 0, '') a
+    CROSS JOIN (SELECT descendant_id, depth, path FROM part_closure WHERE ancestor_id=_child UNION ALL SELECT _child, 0, '') d
+    WHERE a.ancestor_id <> d.descendant_id
+    ON CONFLICT DO NOTHING;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- part_closure_delete removes the paths through a removed part_has_part edge
+CREATE OR REPLACE FUNCTION part_closure_delete(_parent UUID, _child UUID, _path TEXT) RETURNS VOID LANGUAGE SQL AS $$
+    DELETE FROM part_closure pc
+    USING (SELECT ancestor_id, depth, path FROM part_closure WHERE descendant_id=_parent UNION ALL SELECT _parent, 0, '') a,
+        (SELECT descendant_id, depth, path FROM part_closure WHERE ancestor_id=_child UNION ALL SELECT _child, 0, '') d
+    WHERE pc.ancestor_id=a.ancestor_id AND pc.descendant_id=d.descendant_id
+    AND pc.depth=a.depth + 1 + d.depth
+    AND pc.path=CONCAT_WS('/', NULLIF(a.path, ''), NULLIF(_path, ''), NULLIF(d.path, ''));
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- rebuild_part_closure recomputes part_closure from part_has_part, parts found again along a path are not followed further
+CREATE OR REPLACE FUNCTION rebuild_part_closure() RETURNS VOID LANGUAGE SQL AS $$
+    DELETE FROM part_closure;
+    INSERT INTO part_closure (ancestor_id, descendant_id, depth, path)
+    WITH RECURSIVE down(ancestor_id, descendant_id, depth, path, visited) AS (
+        SELECT parent_id, child_id, 1, path, ARRAY[parent_id, child_id] FROM part_has_part WHERE parent_id <> child_id
+        UNION ALL SELECT down.ancestor_id, php.child_id, down.depth + 1, CONCAT_WS('/', NULLIF(down.path, ''), NULLIF(php.path, '')), down.visited || php.child_id
+        FROM part_has_part php INNER JOIN down ON php.parent_id=down.descendant_id
+        WHERE php.child_id <> ALL(down.visited)
+    )
+    SELECT ancestor_id, descendant_id, depth, path FROM down
+    ON CONFLICT DO NOTHING;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION part_has_part_closure() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+    BEGIN
+        IF TG_OP IN ('DELETE', 'UPDATE') THEN
+            PERFORM part_closure_delete(OLD.parent_id, OLD.child_id, OLD.path);
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM part_closure_insert(NEW.parent_id, NEW.child_id, NEW.path);
+        END IF;
+
+        RETURN NULL;
+    END;
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER part_has_part_closure AFTER INSERT OR UPDATE OR DELETE ON part_has_part
+    FOR EACH ROW EXECUTE FUNCTION part_has_part_closure();
+
+SELECT rebuild_part_closure();
+
+-- +goose StatementBegin
+-- collect_part_files_sha256 reads the files of the sub-parts from part_closure rather than recursing per sub-part, with the same result
+CREATE OR REPLACE FUNCTION collect_part_files_sha256(_pid UUID) RETURNS SHA256_BYTEA[] LANGUAGE SQL AS $$
+    SELECT ARRAY(
+        SELECT f.sha256 FROM file f
+        INNER JOIN part_has_file phf ON phf.file_sha256=f.sha256
+        WHERE phf.part_id=_pid AND f.sha256 IS NOT NULL
+        UNION ALL SELECT f.sha256 FROM part_closure pc
+        INNER JOIN part_has_file phf ON phf.part_id=pc.descendant_id
+        INNER JOIN file f ON f.sha256=phf.file_sha256
+        WHERE pc.ancestor_id=_pid AND f.sha256 IS NOT NULL
+    )::SHA256_BYTEA[];
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION collect_part_files_sha256(_pid UUID) RETURNS SHA256_BYTEA[] LANGUAGE plpgsql AS $$
+    DECLARE
+        _file RECORD;
+        _sub_part UUID;
+        _shas SHA256_BYTEA[] := ARRAY[]::SHA256_BYTEA[];
+    BEGIN
+        FOR _file IN SELECT f.sha256 FROM file f
+            INNER JOIN part_has_file phf ON phf.file_sha256=f.sha256
+            INNER JOIN part p ON p.part_id=phf.part_id
+            WHERE p.part_id=_pid
+            AND f.sha256 IS NOT NULL
+        LOOP
+            _shas := _shas || _file.sha256;
+        END LOOP;
+
+        FOR _sub_part IN SELECT php.child_id FROM part_has_part php
+            WHERE php.parent_id=_pid
+        LOOP
+            SELECT array_cat(_shas, (SELECT collect_part_files_sha256(_sub_part))) INTO _shas;
+        END LOOP;
+
+        RETURN _shas;
+    END;
+$$;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS part_has_part_closure ON part_has_part;
+DROP FUNCTION IF EXISTS part_has_part_closure;
+DROP FUNCTION IF EXISTS rebuild_part_closure;
+DROP FUNCTION IF EXISTS part_closure_delete;
+DROP FUNCTION IF EXISTS part_closure_insert;
+DROP TABLE IF EXISTS part_closure;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- part_has_part_acyclic rejects edges that would make a part its own ancestor, by part_closure, whichever code writes them
+CREATE OR REPLACE FUNCTION part_has_part_acyclic() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+    BEGIN
+        IF NEW.parent_id=NEW.child_id OR EXISTS (SELECT 1 FROM part_closure WHERE ancestor_id=NEW.child_id AND descendant_id=NEW.parent_id) THEN
+            RAISE EXCEPTION 'part % would be its own ancestor as a sub-part of %', NEW.parent_id, NEW.child_id USING ERRCODE = 'check_violation';
+        END IF;
+
+        RETURN NEW;
+    END;
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER part_has_part_acyclic BEFORE INSERT OR UPDATE ON part_has_part
+    FOR EACH ROW EXECUTE FUNCTION part_has_part_acyclic();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS part_has_part_acyclic ON part_has_part;
+DROP FUNCTION IF EXISTS part_has_part_acyclic;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- the file verification code 2 computed for each part when it was last verified, parts whose stored code differs are mismatches
+CREATE TABLE IF NOT EXISTS part_verification (
+    part_id UUID PRIMARY KEY REFERENCES part(part_id) ON DELETE CASCADE,
+    computed BYTEA NOT NULL,
+    check_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_verification;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- file verification code 1, FVC1 followed by the sha1 of the sorted hex sha1s of the files of a part and its sub-parts,
+-- whose payload is the SPDX package verification code, kept alongside file_verification_code for parts with one
+ALTER TABLE part ADD COLUMN IF NOT EXISTS file_verification_code_one BYTEA;
+CREATE INDEX IF NOT EXISTS part_file_verification_code_one_idx ON part(file_verification_code_one);
+
+-- +goose StatementBegin
+-- calculate_part_verification_code_v1 calculates the file verification code 1 of a part, the files of a sub-part once per path it is found at,
+-- or NULL if the part has no files or a file has no sha1
+CREATE OR REPLACE FUNCTION calculate_part_verification_code_v1(_pid UUID) RETURNS BYTEA LANGUAGE SQL AS $$
+    SELECT CASE WHEN COUNT(*) > 0 AND COUNT(*) = COUNT(f.sha1)
+        THEN '\x4656433100'::BYTEA || DIGEST(STRING_AGG(ENCODE(f.sha1, 'hex'), '' ORDER BY f.sha1), 'sha1') END
+    FROM (
+        SELECT file_sha256 FROM part_has_file WHERE part_id=_pid
+        UNION ALL SELECT phf.file_sha256 FROM part_closure pc
+        INNER JOIN part_has_file phf ON phf.part_id=pc.descendant_id
+        WHERE pc.ancestor_id=_pid
+    ) files
+    INNER JOIN file f ON f.sha256=files.file_sha256;
+$$;
+-- +goose StatementEnd
+
+UPDATE part SET file_verification_code_one=calculate_part_verification_code_v1(part_id) WHERE file_verification_code IS NOT NULL;
+
+-- +goose Down
+DROP FUNCTION IF EXISTS calculate_part_verification_code_v1;
+DROP INDEX IF EXISTS part_file_verification_code_one_idx;
+ALTER TABLE part DROP COLUMN IF EXISTS file_verification_code_one;
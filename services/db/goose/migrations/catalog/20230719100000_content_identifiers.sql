@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE DOMAIN sha512_bytea AS BYTEA CHECK (OCTET_LENGTH(VALUE) = 64);
+
+-- sha512, and the git blob ids of the content in sha1 and sha256, the sha1 being the id of the swh:1:cnt SWHID
+-- and the sha256 the OmniBOR artifact id, NULL for files and archives cataloged before they were calculated
+ALTER TABLE file ADD COLUMN IF NOT EXISTS sha512 SHA512_BYTEA;
+ALTER TABLE file ADD COLUMN IF NOT EXISTS gitoid_sha1 SHA1_BYTEA;
+ALTER TABLE file ADD COLUMN IF NOT EXISTS gitoid_sha256 SHA256_BYTEA;
+CREATE INDEX IF NOT EXISTS file_sha1_idx ON file(sha1);
+CREATE INDEX IF NOT EXISTS file_sha512_idx ON file(sha512);
+CREATE INDEX IF NOT EXISTS file_gitoid_sha1_idx ON file(gitoid_sha1);
+CREATE INDEX IF NOT EXISTS file_gitoid_sha256_idx ON file(gitoid_sha256);
+
+ALTER TABLE archive ADD COLUMN IF NOT EXISTS sha512 SHA512_BYTEA;
+ALTER TABLE archive ADD COLUMN IF NOT EXISTS gitoid_sha1 SHA1_BYTEA;
+ALTER TABLE archive ADD COLUMN IF NOT EXISTS gitoid_sha256 SHA256_BYTEA;
+CREATE INDEX IF NOT EXISTS archive_sha512_idx ON archive(sha512);
+CREATE INDEX IF NOT EXISTS archive_gitoid_sha1_idx ON archive(gitoid_sha1);
+CREATE INDEX IF NOT EXISTS archive_gitoid_sha256_idx ON archive(gitoid_sha256);
+
+-- git tree id of the extracted archive of a part, the id of its swh:1:dir SWHID
+ALTER TABLE part ADD COLUMN IF NOT EXISTS git_tree_sha1 SHA1_BYTEA;
+CREATE INDEX IF NOT EXISTS part_git_tree_sha1_idx ON part(git_tree_sha1);
+
+-- +goose Down
+DROP INDEX IF EXISTS part_git_tree_sha1_idx;
+ALTER TABLE part DROP COLUMN IF EXISTS git_tree_sha1;
+
+DROP INDEX IF EXISTS archive_gitoid_sha256_idx;
+DROP INDEX IF EXISTS archive_gitoid_sha1_idx;
+DROP INDEX IF EXISTS archive_sha512_idx;
+ALTER TABLE archive DROP COLUMN IF EXISTS gitoid_sha256;
+ALTER TABLE archive DROP COLUMN IF EXISTS gitoid_sha1;
+ALTER TABLE archive DROP COLUMN IF EXISTS sha512;
+
+DROP INDEX IF EXISTS file_gitoid_sha256_idx;
+DROP INDEX IF EXISTS file_gitoid_sha1_idx;
+DROP INDEX IF EXISTS file_sha512_idx;
+DROP INDEX IF EXISTS file_sha1_idx;
+ALTER TABLE file DROP COLUMN IF EXISTS gitoid_sha256;
+ALTER TABLE file DROP COLUMN IF EXISTS gitoid_sha1;
+ALTER TABLE file DROP COLUMN IF EXISTS sha512;
+
+DROP DOMAIN IF EXISTS sha512_bytea;
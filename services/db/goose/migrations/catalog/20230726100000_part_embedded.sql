@@ -0,0 +1,15 @@
+-- +goose Up
+-- known parts found embedded as directories of uploaded archives, at path within the part of the archive,
+-- linked if the directory was added as a sub-part rather than as files
+CREATE TABLE IF NOT EXISTS part_embedded (
+    part_id UUID REFERENCES part(part_id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    embedded_id UUID NOT NULL REFERENCES part(part_id) ON DELETE CASCADE,
+    linked BOOLEAN NOT NULL DEFAULT FALSE,
+    insert_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(part_id, path)
+);
+CREATE INDEX IF NOT EXISTS part_embedded_embedded_id_idx ON part_embedded(embedded_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS part_embedded;
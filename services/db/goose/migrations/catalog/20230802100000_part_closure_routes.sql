@@ -0,0 +1,94 @@
+-- +goose Up
+-- routes counts the routes through part_has_part an ancestor reaches a descendant by at the same depth and path, such as both sides of a diamond,
+-- so removing one of them leaves the row until the last is removed
+ALTER TABLE part_closure ADD COLUMN IF NOT EXISTS routes INTEGER NOT NULL DEFAULT 1 CHECK (routes > 0);
+
+-- +goose StatementBegin
+-- part_closure_insert adds the routes through a new part_has_part edge, from the parent and its ancestors to the child and its descendants
+CREATE OR REPLACE FUNCTION part_closure_insert(_parent UUID, _child UUID, _path TEXT) RETURNS VOID LANGUAGE SQL AS $$
+    INSERT INTO part_closure (ancestor_id, descendant_id, depth, path, routes)
+    SELECT a.ancestor_id, d.descendant_id, a.depth + 1 + d.depth, CONCAT_WS('/', NULLIF(a.path, ''), NULLIF(_path, ''), NULLIF(d.path, '')), SUM(a.routes * d.routes)
+    FROM (SELECT ancestor_id, depth, path, routes FROM part_closure WHERE descendant_id=_parent UNION ALL SELECT _parent, 0, '', 1) a
+    CROSS JOIN (SELECT descendant_id, depth, path, routes FROM part_closure WHERE ancestor_id=_child UNION ALL SELECT _child, 0, '', 1) d
+    WHERE a.ancestor_id <> d.descendant_id
+    GROUP BY 1, 2, 3, 4
+    ON CONFLICT (ancestor_id, descendant_id, depth, path) DO UPDATE SET routes=part_closure.routes + EXCLUDED.routes;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- part_closure_delete removes the routes through a removed part_has_part edge, and the rows left without any
+CREATE OR REPLACE FUNCTION part_closure_delete(_parent UUID, _child UUID, _path TEXT) RETURNS VOID LANGUAGE SQL AS $$
+    WITH through AS (
+        SELECT a.ancestor_id, d.descendant_id, a.depth + 1 + d.depth AS depth,
+            CONCAT_WS('/', NULLIF(a.path, ''), NULLIF(_path, ''), NULLIF(d.path, '')) AS path, SUM(a.routes * d.routes) AS routes
+        FROM (SELECT ancestor_id, depth, path, routes FROM part_closure WHERE descendant_id=_parent UNION ALL SELECT _parent, 0, '', 1) a
+        CROSS JOIN (SELECT descendant_id, depth, path, routes FROM part_closure WHERE ancestor_id=_child UNION ALL SELECT _child, 0, '', 1) d
+        WHERE a.ancestor_id <> d.descendant_id
+        GROUP BY 1, 2, 3, 4
+    ), deleted AS (
+        DELETE FROM part_closure pc USING through t
+        WHERE pc.ancestor_id=t.ancestor_id AND pc.descendant_id=t.descendant_id AND pc.depth=t.depth AND pc.path=t.path AND pc.routes <= t.routes
+    )
+    UPDATE part_closure pc SET routes=pc.routes - t.routes FROM through t
+    WHERE pc.ancestor_id=t.ancestor_id AND pc.descendant_id=t.descendant_id AND pc.depth=t.depth AND pc.path=t.path AND pc.routes > t.routes;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- rebuild_part_closure recomputes part_closure from part_has_part, counting the routes of each row, parts found again along a route are not followed further
+CREATE OR REPLACE FUNCTION rebuild_part_closure() RETURNS VOID LANGUAGE SQL AS $$
+    DELETE FROM part_closure;
+    INSERT INTO part_closure (ancestor_id, descendant_id, depth, path, routes)
+    WITH RECURSIVE down(ancestor_id, descendant_id, depth, path, visited) AS (
+        SELECT parent_id, child_id, 1, path, ARRAY[parent_id, child_id] FROM part_has_part WHERE parent_id <> child_id
+        UNION ALL SELECT down.ancestor_id, php.child_id, down.depth + 1, CONCAT_WS('/', NULLIF(down.path, ''), NULLIF(php.path, '')), down.visited || php.child_id
+        FROM part_has_part php INNER JOIN down ON php.parent_id=down.descendant_id
+        WHERE php.child_id <> ALL(down.visited)
+    )
+    SELECT ancestor_id, descendant_id, depth, path, COUNT(*) FROM down
+    GROUP BY 1, 2, 3, 4;
+$$;
+-- +goose StatementEnd
+
+SELECT rebuild_part_closure();
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION part_closure_insert(_parent UUID, _child UUID, _path TEXT) RETURNS VOID LANGUAGE SQL AS $$
+    INSERT INTO part_closure (ancestor_id, descendant_id, depth, path)
+    SELECT a.ancestor_id, d.descendant_id, a.depth + 1 + d.depth, CONCAT_WS('/', NULLIF(a.path, ''), NULLIF(_path, ''), NULLIF(d.path, ''))
+    FROM (SELECT ancestor_id, depth, path FROM part_closure WHERE descendant_id=_parent UNION ALL SELECT _parent, 0, '') a
+    CROSS JOIN (SELECT descendant_id, depth, path FROM part_closure WHERE ancestor_id=_child UNION ALL SELECT _child, 0, '') d
+    WHERE a.ancestor_id <> d.descendant_id
+    ON CONFLICT DO NOTHING;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION part_closure_delete(_parent UUID, _child UUID, _path TEXT) RETURNS VOID LANGUAGE SQL AS $$
+    DELETE FROM part_closure pc
+    USING (SELECT ancestor_id, depth, path FROM part_closure WHERE descendant_id=_parent UNION ALL SELECT _parent, 0, '') a,
+        (SELECT descendant_id, depth, path FROM part_closure WHERE ancestor_id=_child UNION ALL SELECT _child, 0, '') d
+    WHERE pc.ancestor_id=a.ancestor_id AND pc.descendant_id=d.descendant_id
+    AND pc.depth=a.depth + 1 + d.depth
+    AND pc.path=CONCAT_WS('/', NULLIF(a.path, ''), NULLIF(_path, ''), NULLIF(d.path, ''));
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION rebuild_part_closure() RETURNS VOID LANGUAGE SQL AS $$
+    DELETE FROM part_closure;
+    INSERT INTO part_closure (ancestor_id, descendant_id, depth, path)
+    WITH RECURSIVE down(ancestor_id, descendant_id, depth, path, visited) AS (
+        SELECT parent_id, child_id, 1, path, ARRAY[parent_id, child_id] FROM part_has_part WHERE parent_id <> child_id
+        UNION ALL SELECT down.ancestor_id, php.child_id, down.depth + 1, CONCAT_WS('/', NULLIF(down.path, ''), NULLIF(php.path, '')), down.visited || php.child_id
+        FROM part_has_part php INNER JOIN down ON php.parent_id=down.descendant_id
+        WHERE php.child_id <> ALL(down.visited)
+    )
+    SELECT ancestor_id, descendant_id, depth, path FROM down
+    ON CONFLICT DO NOTHING;
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE part_closure DROP COLUMN IF EXISTS routes;
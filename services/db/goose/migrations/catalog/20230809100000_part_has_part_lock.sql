@@ -0,0 +1,61 @@
+-- +goose Up
+-- writes of part_has_part take the transaction lock of hashtext('part_has_part') before reading part_closure,
+-- so two transactions can't each add half of a cycle, or maintain part_closure from rows the other has not committed yet.
+-- Every statement of the triggers takes a new snapshot under read committed, so the rows committed while waiting are seen.
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION part_has_part_acyclic() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+    BEGIN
+        PERFORM pg_advisory_xact_lock(hashtext('part_has_part'));
+        IF NEW.parent_id=NEW.child_id OR EXISTS (SELECT 1 FROM part_closure WHERE ancestor_id=NEW.child_id AND descendant_id=NEW.parent_id) THEN
+            RAISE EXCEPTION 'part % would be its own ancestor as a sub-part of %', NEW.parent_id, NEW.child_id USING ERRCODE = 'check_violation';
+        END IF;
+
+        RETURN NEW;
+    END;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION part_has_part_closure() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+    BEGIN
+        PERFORM pg_advisory_xact_lock(hashtext('part_has_part'));
+        IF TG_OP IN ('DELETE', 'UPDATE') THEN
+            PERFORM part_closure_delete(OLD.parent_id, OLD.child_id, OLD.path);
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM part_closure_insert(NEW.parent_id, NEW.child_id, NEW.path);
+        END IF;
+
+        RETURN NULL;
+    END;
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION part_has_part_acyclic() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+    BEGIN
+        IF NEW.parent_id=NEW.child_id OR EXISTS (SELECT 1 FROM part_closure WHERE ancestor_id=NEW.child_id AND descendant_id=NEW.parent_id) THEN
+            RAISE EXCEPTION 'part % would be its own ancestor as a sub-part of %', NEW.parent_id, NEW.child_id USING ERRCODE = 'check_violation';
+        END IF;
+
+        RETURN NEW;
+    END;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION part_has_part_closure() RETURNS TRIGGER LANGUAGE plpgsql AS $$
+    BEGIN
+        IF TG_OP IN ('DELETE', 'UPDATE') THEN
+            PERFORM part_closure_delete(OLD.parent_id, OLD.child_id, OLD.path);
+        END IF;
+        IF TG_OP IN ('INSERT', 'UPDATE') THEN
+            PERFORM part_closure_insert(NEW.parent_id, NEW.child_id, NEW.path);
+        END IF;
+
+        RETURN NULL;
+    END;
+$$;
+-- +goose StatementEnd
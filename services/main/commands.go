@@ -0,0 +1,344 @@
+//go:build !script
+
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"wrs/tk/packages/core/audit"
+	"wrs/tk/packages/core/offline"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/policy"
+	"wrs/tk/packages/core/similarity"
+	"wrs/tk/packages/core/vulnerability"
+	"wrs/tk/packages/middleware"
+	"wrs/tk/packages/server"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// partIDsFlag collects part ids from repeated flags
+type partIDsFlag []part.ID
+
+func (ids *partIDsFlag) String() string {
+	s := make([]string, 0, len(*ids))
+	for _, id := range *ids {
+		s = append(s, id.String())
+	}
+
+	return strings.Join(s, ",")
+}
+
+func (ids *partIDsFlag) Set(value string) error {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	*ids = append(*ids, part.ID(id))
+	return nil
+}
+
+// runCommand runs the command named by the first argument instead of the server, returning false if there is no such command
+func runCommand(db *sqlx.DB, args []string) (bool, error) {
+	switch args[0] {
+	case "export":
+		return true, runExport(db, args[1:])
+	case "import":
+		return true, runImport(db, args[1:])
+	case "token":
+		return true, runToken(db, args[1:])
+	case "signatures":
+		return true, runSignatures(db, args[1:])
+	case "integrity":
+		return true, runIntegrity(db, args[1:])
+	case "check-policy":
+		return true, runCheckPolicy(db, args[1:])
+	default:
+		return false, nil
+	}
+}
+
+// runExport writes an offline bundle of parts and/or a partlist to a file, or stdout
+func runExport(db *sqlx.DB, args []string) error {
+	var partIDs partIDsFlag
+	var partlistID, sinceID int64
+	var blobs bool
+	var output string
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.Var(&partIDs, "part", "Part ID to export, may be repeated")
+	flags.Int64Var(&partlistID, "partlist", 0, "Partlist ID to export, with the partlists beneath it")
+	flags.Int64Var(&sinceID, "since", 0, "Previous export ID to export changes since")
+	flags.BoolVar(&blobs, "blobs", true, "Include file and archive contents")
+	flags.StringVar(&output, "o", "", "Output file, stdout if not given")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	options := offline.ExportOptions{PartIDs: partIDs, Blobs: blobs}
+	if partlistID > 0 {
+		options.PartListID = &partlistID
+	}
+	if sinceID > 0 {
+		options.SinceID = &sinceID
+	}
+
+	controller, err := offlineController(db)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return errors.Wrapf(err, "error creating %s", output)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	export, err := controller.Export(w, options)
+	if err != nil {
+		if output != "" {
+			os.Remove(output)
+		}
+
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "offline export %d complete\n", export.ID)
+	return nil
+}
+
+// runImport merges an offline bundle from a file, or stdin, matches the parts it reports to vulnerabilities, and prints the import report
+func runImport(db *sqlx.DB, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	controller, err := offlineController(db)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if flags.NArg() > 0 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return errors.Wrapf(err, "error opening %s", flags.Arg(0))
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	report, err := controller.Import(r, audit.System)
+	if err != nil {
+		return err
+	}
+	// matched before exiting, as a queue would be dropped with the process
+	if len(report.PartIDs) > 0 {
+		if err := vulnerability.NewVulnerabilityController(db).MatchTree(report.PartIDs...); err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// runToken creates an API token, printing it, or revokes one
+func runToken(db *sqlx.DB, args []string) error {
+	if len(args) == 0 || args[0] != "create" && args[0] != "revoke" {
+		return errors.New("token requires create or revoke")
+	}
+
+	var name, roles string
+	var expires time.Duration
+
+	flags := flag.NewFlagSet("token "+args[0], flag.ExitOnError)
+	flags.StringVar(&name, "name", "", "Name of the token, recorded as the actor of its changes")
+	if args[0] == "create" {
+		flags.StringVar(&roles, "roles", middleware.RoleViewer, "Comma separated roles of the token, "+strings.Join(middleware.Roles, ", "))
+		flags.DurationVar(&expires, "expires", 0, "Duration until the token expires, never if not given")
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("token requires a -name")
+	}
+
+	authenticator := middleware.TokenAuthenticator{DB: db}
+	if args[0] == "revoke" {
+		return authenticator.RevokeToken(name)
+	}
+
+	var expiry *time.Time
+	if expires > 0 {
+		t := time.Now().Add(expires)
+		expiry = &t
+	}
+
+	token, err := authenticator.CreateToken(name, strings.Split(roles, ","), expiry)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(token)
+	return nil
+}
+
+// runSignatures computes the similarity signatures of every part, such as those ingested before signatures were computed
+func runSignatures(db *sqlx.DB, args []string) error {
+	flags := flag.NewFlagSet("signatures", flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := similarity.SimilarityController{DB: db}.IndexAll()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("indexed %d top-level parts and their sub-parts\n", count)
+	return nil
+}
+
+// runIntegrity reports cycles of sub-parts, dangling or invalid relations, and parts whose file verification code disagrees with their files,
+// failing if anything was found
+func runIntegrity(db *sqlx.DB, args []string) error {
+	flags := flag.NewFlagSet("integrity", flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := part.PartController{DB: db}.CheckIntegrity()
+	if err != nil {
+		return err
+	}
+
+	for _, cycle := range report.Cycles {
+		ids := make([]string, 0, len(cycle))
+		for _, id := range cycle {
+			ids = append(ids, id.String())
+		}
+		fmt.Printf("cycle: %s\n", strings.Join(ids, " "))
+	}
+	for _, v := range report.Relations {
+		fmt.Printf("%s %s: %s\n", v.Table, v.Key, v.Problem)
+	}
+	for _, v := range report.Mismatches {
+		fmt.Printf("part %s: file verification code %s, files have %s\n", v.PartID.String(), hex.EncodeToString(v.Stored), hex.EncodeToString(v.Computed))
+	}
+
+	if !report.OK() {
+		return errors.Errorf("%d cycles, %d relations, and %d file verification codes failed the check",
+			len(report.Cycles), len(report.Relations), len(report.Mismatches))
+	}
+
+	fmt.Println("no problems found")
+	return nil
+}
+
+// runCheckPolicy checks a part, or a partlist, against a license policy, printing every violation with the chain of parts leading to it,
+// and failing if there were any, so it can gate CI
+func runCheckPolicy(db *sqlx.DB, args []string) error {
+	var policyName, partIDValue string
+	var partlistID int64
+
+	flags := flag.NewFlagSet("check-policy", flag.ExitOnError)
+	flags.StringVar(&policyName, "policy", "", "Name of the policy to check")
+	flags.StringVar(&partIDValue, "part", "", "Part ID to check, with its sub-parts")
+	flags.Int64Var(&partlistID, "partlist", 0, "Partlist ID to check, with the partlists beneath it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if policyName == "" {
+		return errors.New("check-policy requires -policy")
+	}
+
+	controller := policy.PolicyController{DB: db}
+	p, err := controller.GetByName(policyName)
+	if err != nil {
+		return errors.Wrapf(err, "error getting policy \"%s\"", policyName)
+	}
+
+	var result *policy.Result
+	if partIDValue != "" {
+		partID, err := uuid.Parse(partIDValue)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing part id")
+		}
+
+		result, err = controller.CheckPart(*p, partID)
+		if err != nil {
+			return err
+		}
+	} else if partlistID > 0 {
+		result, err = controller.CheckPartList(*p, partlistID)
+		if err != nil {
+			return err
+		}
+	} else {
+		return errors.New("check-policy requires -part or -partlist")
+	}
+
+	for _, v := range result.Violations {
+		path := make([]string, 0, len(v.Path))
+		for _, id := range v.Path {
+			path = append(path, id.String())
+		}
+		message := v.Rule.Message
+		if message == "" {
+			message = v.Rule.Type + " " + strings.Join(v.Rule.Licenses, ",")
+		}
+		if v.ParentID != nil {
+			message += " within " + v.ParentID.String()
+		}
+
+		fmt.Printf("part %s (%s): %s\n  path: %s\n", v.PartID.String(), v.License, message, strings.Join(path, " > "))
+	}
+
+	if !result.Passed() {
+		return errors.Errorf("%d violations of policy \"%s\"", len(result.Violations), p.Name)
+	}
+
+	fmt.Printf("policy \"%s\" passed\n", p.Name)
+	return nil
+}
+
+func offlineController(db *sqlx.DB) (*offline.OfflineController, error) {
+	archiveController, err := server.NewArchiveController(db, config, config.Server.Threads)
+	if err != nil {
+		return nil, err
+	}
+
+	partController := part.PartController{DB: db, Instance: config.InstanceID(), EnforceVerificationCodes: config.Verification.Enforce}
+
+	return offline.NewOfflineController(db, archiveController, partController), nil
+}
@@ -180,6 +180,17 @@ func main() {
 		log.Fatal().Err(err).Msg("error connecting to database")
 	}
 
+	// run a command instead of the server if one was given
+	if flag.NArg() > 0 {
+		if ok, err := runCommand(db, flag.Args()); !ok {
+			log.Fatal().Str("command", flag.Arg(0)).Msg("unknown command")
+		} else if err != nil {
+			log.Fatal().Err(err).Str("command", flag.Arg(0)).Msg("command failed")
+		}
+
+		return
+	}
+
 	// initialize server
 	srv, err := server.NewServer("", argPort, db, config.Frontdoor.Host, config.Server.Threads, config, nil)
 	if err != nil {
@@ -0,0 +1,2 @@
+// gitoid contains utility functions for calculating git object ids of files and directories, and the gitoid and SWHID URIs naming them
+package gitoid
@@ -0,0 +1,262 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package gitoid
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalid is returned when a gitoid or SWHID cannot be parsed
+var ErrInvalid = errors.New("invalid content identifier")
+
+// Hash algorithms of gitoids
+const (
+	AlgorithmSha1   = "sha1"
+	AlgorithmSha256 = "sha256"
+)
+
+// Object types of SWHIDs, a content is a git blob and a directory a git tree
+const (
+	TypeContent   = "cnt"
+	TypeDirectory = "dir"
+)
+
+// NewBlobSha1 returns a hasher of the git blob id, in sha1, of size bytes of content written to it
+func NewBlobSha1(size int64) hash.Hash {
+	return newBlob(sha1.New(), size)
+}
+
+// NewBlobSha256 returns a hasher of the git blob id, in sha256, of size bytes of content written to it
+func NewBlobSha256(size int64) hash.Hash {
+	return newBlob(sha256.New(), size)
+}
+
+// newBlob writes the header of a git blob object to hasher
+func newBlob(hasher hash.Hash, size int64) hash.Hash {
+	fmt.Fprintf(hasher, "blob %d\x00", size)
+	return hasher
+}
+
+// URI returns the gitoid URI of a git blob id, gitoid:blob:sha1:<hex> or gitoid:blob:sha256:<hex> by the length of the id
+// gitoid:blob:sha256 URIs are OmniBOR artifact ids
+func URI(blobID []byte) string {
+	algorithm := AlgorithmSha256
+	if len(blobID) == sha1.Size {
+		algorithm = AlgorithmSha1
+	}
+
+	return "gitoid:blob:" + algorithm + ":" + hex.EncodeToString(blobID)
+}
+
+// ParseURI parses a gitoid URI of a blob, returning the hash algorithm and the blob id
+func ParseURI(uri string) (algorithm string, blobID []byte, err error) {
+	fields := strings.Split(strings.TrimSpace(uri), ":")
+	if len(fields) != 4 || !strings.EqualFold(fields[0], "gitoid") || !strings.EqualFold(fields[1], "blob") {
+		return "", nil, errors.Wrapf(ErrInvalid, "%q is not a gitoid of a blob", uri)
+	}
+
+	algorithm = strings.ToLower(fields[2])
+	blobID, err = hex.DecodeString(fields[3])
+	if err != nil {
+		return "", nil, errors.Wrapf(ErrInvalid, "%q: %s", uri, err.Error())
+	}
+	if (algorithm != AlgorithmSha1 || len(blobID) != sha1.Size) && (algorithm != AlgorithmSha256 || len(blobID) != sha256.Size) {
+		return "", nil, errors.Wrapf(ErrInvalid, "%q is not a sha1 or sha256 gitoid", uri)
+	}
+
+	return algorithm, blobID, nil
+}
+
+// SWHID returns the core SWHID of a git object id, swh:1:cnt:<hex> for the sha1 blob id of a file and swh:1:dir:<hex> for the tree id of a directory
+func SWHID(objectType string, id []byte) string {
+	return "swh:1:" + objectType + ":" + hex.EncodeToString(id)
+}
+
+// ParseSWHID parses an SWHID of a content or a directory, returning its object type and git object id
+// Qualifiers, such as ;origin=, are ignored
+func ParseSWHID(swhid string) (objectType string, id []byte, err error) {
+	core, _, _ := strings.Cut(strings.TrimSpace(swhid), ";")
+	fields := strings.Split(core, ":")
+	if len(fields) != 4 || fields[0] != "swh" || fields[1] != "1" {
+		return "", nil, errors.Wrapf(ErrInvalid, "%q is not an SWHID", swhid)
+	}
+	if fields[2] != TypeContent && fields[2] != TypeDirectory {
+		return "", nil, errors.Wrapf(ErrInvalid, "%q is not an SWHID of a content or directory", swhid)
+	}
+
+	id, err = hex.DecodeString(fields[3])
+	if err != nil || len(id) != sha1.Size {
+		return "", nil, errors.Wrapf(ErrInvalid, "%q does not have a sha1 object id", swhid)
+	}
+
+	return fields[2], id, nil
+}
+
+// Tree builds the git tree id of a directory from the paths and blob ids of the files beneath it, the way Software Heritage computes swh:1:dir SWHIDs.
+// Symbolic links are entries of mode 120000 whose blob is the link target, and empty directories are kept, though git leaves them out.
+// Anything else that is not a regular file, such as a device, has no git mode, and is left out.
+type Tree struct {
+	files   map[string]treeFile
+	subDirs map[string]*Tree
+}
+
+// git modes of the entries of a tree
+const (
+	modeFile       = "100644"
+	modeExecutable = "100755"
+	modeSymlink    = "120000"
+	modeDirectory  = "40000"
+)
+
+type treeFile struct {
+	blobID [sha1.Size]byte
+	mode   string
+}
+
+// NewTree returns an empty Tree, whose id is that of the empty directory
+func NewTree() *Tree {
+	return &Tree{files: make(map[string]treeFile), subDirs: make(map[string]*Tree)}
+}
+
+// Add adds the file at the slash separated path, relative to the directory, with the sha1 blob id of its content
+// Like git, a file is executable if its owner may execute it
+func (t *Tree) Add(path string, executable bool, blobID [sha1.Size]byte) error {
+	mode := modeFile
+	if executable {
+		mode = modeExecutable
+	}
+
+	return t.add(path, mode, blobID)
+}
+
+// AddSymlink adds the symbolic link at the slash separated path, relative to the directory, to target
+func (t *Tree) AddSymlink(path string, target string) error {
+	var blobID [sha1.Size]byte
+	hasher := NewBlobSha1(int64(len(target)))
+	hasher.Write([]byte(target))
+	copy(blobID[:], hasher.Sum(nil))
+
+	return t.add(path, modeSymlink, blobID)
+}
+
+// AddDir adds the directory at the slash separated path, relative to the directory, so it is kept if it is empty
+func (t *Tree) AddDir(path string) error {
+	names, err := splitTreePath(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.dir(path, names)
+	return err
+}
+
+// add adds an entry other than a directory
+func (t *Tree) add(path string, mode string, blobID [sha1.Size]byte) error {
+	names, err := splitTreePath(path)
+	if err != nil {
+		return err
+	}
+
+	dir, err := t.dir(path, names[:len(names)-1])
+	if err != nil {
+		return err
+	}
+
+	name := names[len(names)-1]
+	if _, ok := dir.subDirs[name]; ok {
+		return errors.Errorf("path %q of a file in a tree is a directory", path)
+	}
+	dir.files[name] = treeFile{blobID: blobID, mode: mode}
+
+	return nil
+}
+
+// dir returns the directory of the names beneath the tree, adding the directories it does not have yet
+func (t *Tree) dir(path string, names []string) (*Tree, error) {
+	dir := t
+	for _, name := range names {
+		if _, ok := dir.files[name]; ok {
+			return nil, errors.Errorf("path %q in a tree is beneath a file", path)
+		}
+
+		sub, ok := dir.subDirs[name]
+		if !ok {
+			sub = NewTree()
+			dir.subDirs[name] = sub
+		}
+		dir = sub
+	}
+
+	return dir, nil
+}
+
+// splitTreePath splits a slash separated path within a tree into its names
+func splitTreePath(path string) ([]string, error) {
+	names := strings.Split(path, "/")
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." {
+			return nil, errors.Errorf("invalid path %q in a tree", path)
+		}
+	}
+
+	return names, nil
+}
+
+// Sum returns the sha1 git tree id of the directory, which is also the id of its swh:1:dir SWHID
+func (t *Tree) Sum() [sha1.Size]byte {
+	type entry struct {
+		mode string
+		name string
+		id   [sha1.Size]byte
+	}
+
+	entries := make([]entry, 0, len(t.files)+len(t.subDirs))
+	for name, f := range t.files {
+		entries = append(entries, entry{mode: f.mode, name: name, id: f.blobID})
+	}
+	for name, sub := range t.subDirs {
+		entries = append(entries, entry{mode: modeDirectory, name: name, id: sub.Sum()})
+	}
+
+	// git orders the entries of a tree by name, each directory as if its name ended with /
+	sortName := func(e entry) string {
+		if e.mode == modeDirectory {
+			return e.name + "/"
+		}
+		return e.name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortName(entries[i]) < sortName(entries[j])
+	})
+
+	content := new(bytes.Buffer)
+	for _, e := range entries {
+		content.WriteString(e.mode + " " + e.name + "\x00")
+		content.Write(e.id[:])
+	}
+
+	var ret [sha1.Size]byte
+	hasher := sha1.New()
+	fmt.Fprintf(hasher, "tree %d\x00", content.Len())
+	hasher.Write(content.Bytes())
+	copy(ret[:], hasher.Sum(nil))
+
+	return ret
+}
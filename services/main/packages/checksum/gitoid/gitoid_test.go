@@ -0,0 +1,133 @@
+package gitoid
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func mustSha1(hexRepresentation string) [20]byte {
+	slice, err := hex.DecodeString(hexRepresentation)
+	if err != nil {
+		panic(err)
+	}
+
+	return [20]byte(slice)
+}
+
+func TestBlob(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		sha1    string
+		sha256  string
+	}{
+		{"", "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391", "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"},
+		{"hello\n", "ce013625030ba8dba906f756967f9e9ca394464a", "2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4"},
+	} {
+		hasherSha1 := NewBlobSha1(int64(len(test.content)))
+		hasherSha1.Write([]byte(test.content))
+		if got := hex.EncodeToString(hasherSha1.Sum(nil)); got != test.sha1 {
+			t.Errorf("sha1 blob id of %q: got %s, expected %s", test.content, got, test.sha1)
+		}
+
+		hasherSha256 := NewBlobSha256(int64(len(test.content)))
+		hasherSha256.Write([]byte(test.content))
+		if got := hex.EncodeToString(hasherSha256.Sum(nil)); got != test.sha256 {
+			t.Errorf("sha256 blob id of %q: got %s, expected %s", test.content, got, test.sha256)
+		}
+	}
+}
+
+func TestTree(t *testing.T) {
+	empty := NewTree()
+	if sum := empty.Sum(); hex.EncodeToString(sum[:]) != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Errorf("empty tree: got %x", sum)
+	}
+
+	// d-x sorts before d, as git compares directory d as d/
+	tree := NewTree()
+	for _, f := range []struct {
+		path       string
+		executable bool
+		blobID     string
+	}{
+		{"a", false, "ce013625030ba8dba906f756967f9e9ca394464a"},
+		{"d/b", false, "c1b0730e0133447badcfd47fd144e254807b06e1"},
+		{"d-x/c", false, "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{"e", true, "e25f1814e51579d5f55c0f1fe0135ddb28a47f4a"},
+	} {
+		if err := tree.Add(f.path, f.executable, mustSha1(f.blobID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sum := tree.Sum(); hex.EncodeToString(sum[:]) != "1d9f4fb40cb1b1c88a72aa461ace433d2537b68e" {
+		t.Errorf("tree: got %x, expected 1d9f4fb40cb1b1c88a72aa461ace433d2537b68e", sum)
+	}
+
+	for _, path := range []string{"", "/a", "a//b", "../a", "a/b"} {
+		if err := tree.Add(path, false, [20]byte{}); err == nil {
+			t.Errorf("expected an error adding %q", path)
+		}
+	}
+
+	// symbolic links and empty directories are kept, as Software Heritage keeps them
+	swh := NewTree()
+	if err := swh.Add("a", false, mustSha1("78981922613b2afb6025042ff6bd878ac1994e85")); err != nil {
+		t.Fatal(err)
+	}
+	if err := swh.AddSymlink("link", "a"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"empty", "nested/empty", "nested"} {
+		if err := swh.AddDir(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sum := swh.Sum(); hex.EncodeToString(sum[:]) != "cc81a70472ed54f1430ead5297180c040b086188" {
+		t.Errorf("tree with a symlink and empty directories: got %x, expected cc81a70472ed54f1430ead5297180c040b086188", sum)
+	}
+	if err := swh.AddDir("a/b"); err == nil {
+		t.Errorf("expected an error adding a directory beneath a file")
+	}
+	if err := swh.AddSymlink("empty", "a"); err == nil {
+		t.Errorf("expected an error adding a symlink at a directory")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		input     string
+		algorithm string
+		id        string
+		valid     bool
+	}{
+		{"gitoid:blob:sha1:ce013625030ba8dba906f756967f9e9ca394464a", AlgorithmSha1, "ce013625030ba8dba906f756967f9e9ca394464a", true},
+		{"gitoid:blob:sha256:2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4", AlgorithmSha256, "2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4", true},
+		{"gitoid:blob:sha256:ce013625030ba8dba906f756967f9e9ca394464a", "", "", false},
+		{"gitoid:tree:sha1:ce013625030ba8dba906f756967f9e9ca394464a", "", "", false},
+	} {
+		algorithm, id, err := ParseURI(test.input)
+		if (err == nil) != test.valid || algorithm != test.algorithm || hex.EncodeToString(id) != test.id {
+			t.Errorf("ParseURI(%q): got %s %x %v", test.input, algorithm, id, err)
+		}
+		if test.valid && URI(id) != test.input {
+			t.Errorf("URI(%x): got %s, expected %s", id, URI(id), test.input)
+		}
+	}
+
+	for _, test := range []struct {
+		input      string
+		objectType string
+		id         string
+		valid      bool
+	}{
+		{"swh:1:cnt:ce013625030ba8dba906f756967f9e9ca394464a", TypeContent, "ce013625030ba8dba906f756967f9e9ca394464a", true},
+		{"swh:1:dir:1d9f4fb40cb1b1c88a72aa461ace433d2537b68e;origin=https://example.com/repo.git", TypeDirectory, "1d9f4fb40cb1b1c88a72aa461ace433d2537b68e", true},
+		{"swh:1:rev:1d9f4fb40cb1b1c88a72aa461ace433d2537b68e", "", "", false},
+		{"swh:2:cnt:ce013625030ba8dba906f756967f9e9ca394464a", "", "", false},
+	} {
+		objectType, id, err := ParseSWHID(test.input)
+		if (err == nil) != test.valid || objectType != test.objectType || hex.EncodeToString(id) != test.id {
+			t.Errorf("ParseSWHID(%q): got %s %x %v", test.input, objectType, id, err)
+		}
+	}
+}
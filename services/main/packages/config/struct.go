@@ -22,6 +22,29 @@ type MainConfig struct {
 		SecretsDirectory string `toml:"secrets"` // Directory to find sensitive secrets
 	} `toml:"server"`
 
+	Instance struct { // Identity of this catalog instance, which partid://, fvcid://, and aliasid:// URIs reference
+		Domain string `toml:"domain"` // Domain name of the organization maintaining the catalog, e.g. windriver.com
+		ID     string `toml:"id"`     // Identifier of this catalog among those of the organization, e.g. spc
+	} `toml:"instance"`
+
+	Auth struct { // Authentication of API requests, by API tokens and OIDC/JWT bearer tokens
+		JWKS           string   `toml:"jwks"`            // Path to a JWKS file of the keys JWTs are signed with, JWTs are not accepted without one
+		Issuer         string   `toml:"issuer"`          // Required iss claim of JWTs, if set
+		Audience       string   `toml:"audience"`        // Required aud claim of JWTs, if set
+		NameClaim      string   `toml:"name_claim"`      // Claim of JWTs naming the user
+		RolesClaim     string   `toml:"roles_claim"`     // Claim of JWTs listing roles, a dot separated path into nested claims
+		AnonymousRoles []string `toml:"anonymous_roles"` // Roles of requests without credentials, none by default
+	} `toml:"auth"`
+
+	Archive struct { // Processing of uploaded archives
+		LinkEmbedded     bool `toml:"link_embedded"`      // Add directories holding exactly the files of a known part as that part, rather than adding their files
+		EmbeddedMinFiles int  `toml:"embedded_min_files"` // Least files a directory must have to be matched to a known part
+	} `toml:"archive"`
+
+	Verification struct { // Consistency of file verification codes with the files of their parts
+		Enforce bool `toml:"enforce"` // Refuse updates and files leaving a part with a file verification code that does not match its files
+	} `toml:"verification"`
+
 	Blob struct { // Configuration for object-storage
 		Endpoint string `toml:"endpoint"`
 		Region   string `toml:"region"`
@@ -35,6 +58,11 @@ type MainConfig struct {
 		Host string `toml:"host"`
 	}
 
+	Bundle struct { // Configuration for corresponding-source bundles
+		Directory string   `toml:"directory"` // Directory to write bundles to, defaults to a bundles directory in the upload directory
+		Copyleft  []string `toml:"copyleft"`  // Licenses whose parts must have their source delivered, a trailing * matches any suffix
+	} `toml:"bundle"`
+
 	Search struct {
 		InsertCost     int `toml:"insert"`
 		Deletecost     int `toml:"delete"`
@@ -48,6 +76,17 @@ func DefaultConfig() *MainConfig {
 	ret := new(MainConfig)
 	ret.Server.Port = 4200
 	ret.Server.Threads = 1
+	ret.Archive.EmbeddedMinFiles = 3
+	ret.Bundle.Copyleft = []string{"GPL*", "LGPL*", "AGPL*"}
+	ret.Instance.Domain = "localhost"
+	ret.Instance.ID = "catalog"
+	ret.Auth.NameClaim = "sub"
+	ret.Auth.RolesClaim = "roles"
 
 	return ret
 }
+
+// InstanceID returns the catalog instance identity, [domain-name]/[catalog-instance-id]
+func (config MainConfig) InstanceID() string {
+	return config.Instance.Domain + "/" + config.Instance.ID
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package archive
+
+import (
+	"database/sql"
+	"wrs/tk/packages/array/hash"
+	"wrs/tk/packages/checksum/gitoid"
+	"wrs/tk/packages/core/part"
+
+	"github.com/pkg/errors"
+)
+
+// ErrFileNotFound is returned when no file has the checksum or content identifier looked up
+var ErrFileNotFound error = errors.New("file not found")
+
+// CatalogedFile is a file as the catalog knows it, by its checksums and content identifiers, with every name it is known by
+// Sha512, GitoidSha1, and GitoidSha256 are nil for files cataloged before they were calculated
+type CatalogedFile struct {
+	Sha256       hash.Sha256 `db:"sha256"`
+	Size         int64       `db:"file_size"`
+	Md5          []byte      `db:"md5"`
+	Sha1         []byte      `db:"sha1"`
+	Sha512       []byte      `db:"sha512"`
+	GitoidSha1   []byte      `db:"gitoid_sha1"`   // git blob id, that of the file's swh:1:cnt SWHID
+	GitoidSha256 []byte      `db:"gitoid_sha256"` // git blob id in sha256, the file's OmniBOR artifact id
+	Names        []string    `db:"names"`
+}
+
+// FilePart is a part having a file, at path within it
+type FilePart struct {
+	PartID part.ID `db:"part_id"`
+	Path   string  `db:"path"`
+}
+
+// gitoidColumn parses a gitoid URI of a blob, returning the column of files and archives storing its id, and the id
+func gitoidColumn(uri string) (string, []byte, error) {
+	algorithm, blobID, err := gitoid.ParseURI(uri)
+	if err != nil {
+		return "", nil, err
+	}
+	if algorithm == gitoid.AlgorithmSha1 {
+		return "gitoid_sha1", blobID, nil
+	}
+
+	return "gitoid_sha256", blobID, nil
+}
+
+// contentSWHID parses an SWHID of a content, returning its git blob id
+func contentSWHID(swhid string) ([]byte, error) {
+	objectType, id, err := gitoid.ParseSWHID(swhid)
+	if err != nil {
+		return nil, err
+	}
+	if objectType != gitoid.TypeContent {
+		return nil, errors.Wrapf(gitoid.ErrInvalid, "%q is not an SWHID of a content", swhid)
+	}
+
+	return id, nil
+}
+
+// GetBySha512 returns the archive with the sha512
+func (controller ArchiveController) GetBySha512(sha512 []byte) (*Archive, error) {
+	if len(sha512) != 64 {
+		return nil, ErrNotFound
+	}
+
+	return controller.getByColumn("sha512", sha512)
+}
+
+// GetByGitoid returns the archive with the gitoid URI of a blob, gitoid:blob:sha1:<hex> or gitoid:blob:sha256:<hex>, an OmniBOR artifact id
+func (controller ArchiveController) GetByGitoid(uri string) (*Archive, error) {
+	column, blobID, err := gitoidColumn(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return controller.getByColumn(column, blobID)
+}
+
+// GetBySWHID returns the archive with the SWHID of a content, swh:1:cnt:<hex>
+func (controller ArchiveController) GetBySWHID(swhid string) (*Archive, error) {
+	blobID, err := contentSWHID(swhid)
+	if err != nil {
+		return nil, err
+	}
+
+	return controller.getByColumn("gitoid_sha1", blobID)
+}
+
+// getByColumn returns an archive by one of its content identifier columns, the first by sha256 if several have it
+func (controller ArchiveController) getByColumn(column string, value []byte) (*Archive, error) {
+	ret := new(Archive)
+	if err := controller.DB.QueryRowx("SELECT archive.* FROM archive WHERE "+column+"=$1 ORDER BY sha256 LIMIT 1", value).StructScan(ret); err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "error getting archive by %s:%x", column, value)
+	}
+
+	if err := controller.DB.Select(&ret.Aliases, "SELECT name FROM archive_alias WHERE archive_sha256=$1 ORDER BY name", ret.Sha256); err != nil {
+		return nil, errors.Wrapf(err, "error selecting archive_aliases")
+	}
+
+	return ret, nil
+}
+
+// GetFile returns the file with the sha256
+func (controller ArchiveController) GetFile(sha256 []byte) (*CatalogedFile, error) {
+	if len(sha256) != 32 {
+		return nil, ErrFileNotFound
+	}
+
+	return controller.getFileByColumn("sha256", sha256)
+}
+
+// GetFileBySha1 returns the file with the sha1
+func (controller ArchiveController) GetFileBySha1(sha1 []byte) (*CatalogedFile, error) {
+	if len(sha1) != 20 {
+		return nil, ErrFileNotFound
+	}
+
+	return controller.getFileByColumn("sha1", sha1)
+}
+
+// GetFileBySha512 returns the file with the sha512
+func (controller ArchiveController) GetFileBySha512(sha512 []byte) (*CatalogedFile, error) {
+	if len(sha512) != 64 {
+		return nil, ErrFileNotFound
+	}
+
+	return controller.getFileByColumn("sha512", sha512)
+}
+
+// GetFileByGitoid returns the file with the gitoid URI of a blob, gitoid:blob:sha1:<hex> or gitoid:blob:sha256:<hex>, an OmniBOR artifact id
+func (controller ArchiveController) GetFileByGitoid(uri string) (*CatalogedFile, error) {
+	column, blobID, err := gitoidColumn(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return controller.getFileByColumn(column, blobID)
+}
+
+// GetFileBySWHID returns the file with the SWHID of a content, swh:1:cnt:<hex>
+func (controller ArchiveController) GetFileBySWHID(swhid string) (*CatalogedFile, error) {
+	blobID, err := contentSWHID(swhid)
+	if err != nil {
+		return nil, err
+	}
+
+	return controller.getFileByColumn("gitoid_sha1", blobID)
+}
+
+// getFileByColumn returns a file by one of its checksum or content identifier columns, the first by sha256 if several have it
+func (controller ArchiveController) getFileByColumn(column string, value []byte) (*CatalogedFile, error) {
+	ret := new(CatalogedFile)
+	if err := controller.DB.QueryRowx(`SELECT sha256, file_size, md5, sha1, sha512, gitoid_sha1, gitoid_sha256 FROM file
+	WHERE `+column+`=$1 ORDER BY sha256 LIMIT 1`, value).StructScan(ret); err == sql.ErrNoRows {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "error getting file by %s:%x", column, value)
+	}
+
+	if err := controller.DB.Select(&ret.Names, "SELECT name FROM file_alias WHERE file_sha256=$1 ORDER BY name", ret.Sha256); err != nil {
+		return nil, errors.Wrapf(err, "error selecting file_aliases")
+	}
+
+	return ret, nil
+}
+
+// GetFileParts returns the parts having the file directly, with the path of the file within each
+func (controller ArchiveController) GetFileParts(sha256 []byte) ([]FilePart, error) {
+	ret := make([]FilePart, 0)
+	if err := controller.DB.Select(&ret, "SELECT part_id, path FROM part_has_file WHERE file_sha256=$1 ORDER BY part_id, path", sha256); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts of file %x", sha256)
+	}
+
+	return ret, nil
+}
@@ -30,3 +30,8 @@ func (p *ArchiveController) Download(archive *Archive) (*file.File, error) {
 
 	return f, nil
 }
+
+// DownloadFile retrieves a file's contents from file storage
+func (p *ArchiveController) DownloadFile(sha256 file.Sha256) (*file.File, error) {
+	return p.fileStorage.Retrieve(sha256)
+}
@@ -0,0 +1,2 @@
+// manifest identifies packages from the manifests found in archives, such as package.json or pom.xml, as package URLs
+package manifest
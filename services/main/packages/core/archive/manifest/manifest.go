@@ -0,0 +1,221 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/textproto"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"wrs/tk/packages/identifier"
+)
+
+// MaxSize is the largest manifest that will be read
+const MaxSize = 1 << 20
+
+// IsManifest checks if a file name is that of a supported manifest
+func IsManifest(name string) bool {
+	switch path.Base(name) {
+	case "package.json", "composer.json", "PKG-INFO", "METADATA", "Cargo.toml", "pom.xml", "go.mod":
+		return true
+	}
+
+	return strings.HasSuffix(name, ".spec")
+}
+
+// Identify returns the purl of the package described by a manifest.
+// nil is returned if the file is not a supported manifest, or does not name a package.
+func Identify(name string, content []byte) (*identifier.Purl, error) {
+	var purl *identifier.Purl
+	var err error
+	switch base := path.Base(name); {
+	case base == "package.json":
+		purl, err = identifyNpm(content)
+	case base == "composer.json":
+		purl, err = identifyComposer(content)
+	case base == "PKG-INFO" || base == "METADATA":
+		purl, err = identifyPypi(content)
+	case base == "Cargo.toml":
+		purl, err = identifyCargo(content)
+	case base == "pom.xml":
+		purl, err = identifyMaven(content)
+	case base == "go.mod":
+		purl, err = identifyGolang(content)
+	case strings.HasSuffix(base, ".spec"):
+		purl, err = identifyRpm(content)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing manifest %s", name)
+	}
+
+	return purl, nil
+}
+
+// newPurl returns a purl iff the name is set, and neither name nor version have unexpanded variables
+func newPurl(purlType string, namespace string, name string, version string) *identifier.Purl {
+	name, version = strings.TrimSpace(name), strings.TrimSpace(version)
+	if name == "" || strings.ContainsAny(name+version, "${}") {
+		return nil
+	}
+
+	purl := identifier.NewPurl(purlType, namespace, name, version)
+
+	return &purl
+}
+
+func identifyNpm(content []byte) (*identifier.Purl, error) {
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, err
+	}
+
+	var namespace string
+	name := pkg.Name
+	if i := strings.IndexByte(name, '/'); strings.HasPrefix(name, "@") && i > 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+
+	return newPurl("npm", namespace, name, pkg.Version), nil
+}
+
+func identifyComposer(content []byte) (*identifier.Purl, error) {
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, err
+	}
+
+	namespace, name, ok := strings.Cut(pkg.Name, "/")
+	if !ok {
+		return nil, nil
+	}
+
+	return newPurl("composer", namespace, name, pkg.Version), nil
+}
+
+// identifyPypi reads the Name and Version of the core metadata, which is in email header format
+func identifyPypi(content []byte) (*identifier.Purl, error) {
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(content))).ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return nil, err
+	}
+
+	return newPurl("pypi", "", header.Get("Name"), header.Get("Version")), nil
+}
+
+func identifyCargo(content []byte) (*identifier.Purl, error) {
+	var manifest struct {
+		Package struct {
+			Name    string `toml:"name"`
+			Version any    `toml:"version"` // may be a table when inherited from the workspace
+		} `toml:"package"`
+	}
+	if _, err := toml.Decode(string(content), &manifest); err != nil {
+		return nil, err
+	}
+
+	version, _ := manifest.Package.Version.(string)
+
+	return newPurl("cargo", "", manifest.Package.Name, version), nil
+}
+
+func identifyMaven(content []byte) (*identifier.Purl, error) {
+	var project struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Version    string `xml:"version"`
+		Parent     struct {
+			GroupID string `xml:"groupId"`
+			Version string `xml:"version"`
+		} `xml:"parent"`
+	}
+	if err := xml.Unmarshal(content, &project); err != nil {
+		return nil, err
+	}
+
+	// group and version are inherited from the parent if not given
+	if project.GroupID == "" {
+		project.GroupID = project.Parent.GroupID
+	}
+	if project.Version == "" {
+		project.Version = project.Parent.Version
+	}
+	if project.GroupID == "" {
+		return nil, nil
+	}
+
+	return newPurl("maven", project.GroupID, project.ArtifactID, project.Version), nil
+}
+
+// identifyGolang reads the module path, go.mod has no version
+func identifyGolang(content []byte) (*identifier.Purl, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			module := strings.Trim(fields[1], "\"`")
+			namespace, name := path.Split(module)
+
+			return newPurl("golang", namespace, name, ""), nil
+		}
+	}
+
+	return nil, scanner.Err()
+}
+
+// identifyRpm reads the Name and Version tags of the preamble, skipping any that use macros
+func identifyRpm(content []byte) (*identifier.Purl, error) {
+	var name, version string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "%description") { // end of the preamble
+			break
+		}
+
+		tag, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(tag)) {
+		case "name":
+			if name == "" {
+				name = value
+			}
+		case "version":
+			if version == "" {
+				version = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if strings.Contains(name+version, "%") {
+		return nil, nil
+	}
+
+	return newPurl("rpm", "", name, version), nil
+}
@@ -0,0 +1,97 @@
+package manifest
+
+import "testing"
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		want     string
+		wantNone bool
+	}{
+		{
+			name:    "npm scoped",
+			file:    "package/package.json",
+			content: `{"name": "@babel/core", "version": "7.21.0"}`,
+			want:    "pkg:npm/%40babel/core@7.21.0",
+		},
+		{
+			name:    "composer",
+			file:    "composer.json",
+			content: `{"name": "Monolog/Monolog", "version": "3.3.1"}`,
+			want:    "pkg:composer/monolog/monolog@3.3.1",
+		},
+		{
+			name:    "pypi",
+			file:    "Django-4.1/PKG-INFO",
+			content: "Metadata-Version: 2.1\nName: Django\nVersion: 4.1\nSummary: A web framework\n\nLong description\n",
+			want:    "pkg:pypi/django@4.1",
+		},
+		{
+			name:    "cargo",
+			file:    "Cargo.toml",
+			content: "[package]\nname = \"serde\"\nversion = \"1.0.152\"\n\n[dependencies]\nserde_derive = \"1\"\n",
+			want:    "pkg:cargo/serde@1.0.152",
+		},
+		{
+			name:    "cargo workspace version",
+			file:    "Cargo.toml",
+			content: "[package]\nname = \"tokio\"\nversion.workspace = true\n",
+			want:    "pkg:cargo/tokio",
+		},
+		{
+			name:    "maven inherits group from parent",
+			file:    "pom.xml",
+			content: `<project><parent><groupId>org.apache.commons</groupId><version>52</version></parent><artifactId>commons-io</artifactId><version>2.11.0</version></project>`,
+			want:    "pkg:maven/org.apache.commons/commons-io@2.11.0",
+		},
+		{
+			name:     "maven property version",
+			file:     "pom.xml",
+			content:  `<project><groupId>org.example</groupId><artifactId>app</artifactId><version>${revision}</version></project>`,
+			wantNone: true,
+		},
+		{
+			name:    "golang",
+			file:    "go.mod",
+			content: "module github.com/pkg/errors\n\ngo 1.14\n",
+			want:    "pkg:golang/github.com/pkg/errors",
+		},
+		{
+			name:    "rpm",
+			file:    "zlib.spec",
+			content: "Name:    zlib\nVersion: 1.2.13\nRelease: 1\n\n%description\nVersion: 2\n",
+			want:    "pkg:rpm/zlib@1.2.13",
+		},
+		{
+			name:     "rpm macros",
+			file:     "foo.spec",
+			content:  "Name: %{pkgname}\nVersion: 1.0\n",
+			wantNone: true,
+		},
+		{
+			name:     "not a manifest",
+			file:     "README",
+			content:  "Name: foo",
+			wantNone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Identify(tt.file, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("Identify() error = %v", err)
+			}
+			if tt.wantNone {
+				if got != nil {
+					t.Errorf("Identify() = %s, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.String() != tt.want {
+				t.Errorf("Identify() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
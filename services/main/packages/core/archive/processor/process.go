@@ -4,11 +4,14 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"wrs/tk/packages/checksum/gitoid"
+	"wrs/tk/packages/core/archive/manifest"
 	"wrs/tk/packages/core/archive/tree"
 
 	"github.com/pkg/errors"
@@ -78,7 +81,10 @@ func InitArchive(archivePath string) (*tree.Archive, error) {
 	hasherSha256 := sha256.New()
 	hasherMd5 := md5.New()
 	hasherSha1 := sha1.New()
-	hasher := io.MultiWriter(hasherSha256, hasherMd5, hasherSha1)
+	hasherSha512 := sha512.New()
+	hasherGitSha1 := gitoid.NewBlobSha1(ret.Size)
+	hasherGitSha256 := gitoid.NewBlobSha256(ret.Size)
+	hasher := io.MultiWriter(hasherSha256, hasherMd5, hasherSha1, hasherSha512, hasherGitSha1, hasherGitSha256)
 
 	if _, err := io.Copy(hasher, f); err != nil {
 		return ret, errors.Wrapf(err, "error hashing archive %s", archivePath)
@@ -87,6 +93,9 @@ func InitArchive(archivePath string) (*tree.Archive, error) {
 	copy(ret.Sha256[:], hasherSha256.Sum(nil))
 	copy(ret.Md5[:], hasherMd5.Sum(nil))
 	copy(ret.Sha1[:], hasherSha1.Sum(nil))
+	copy(ret.Sha512[:], hasherSha512.Sum(nil))
+	copy(ret.GitSha1[:], hasherGitSha1.Sum(nil))
+	copy(ret.GitSha256[:], hasherGitSha256.Sum(nil))
 
 	return ret, nil
 }
@@ -137,17 +146,63 @@ func (processor *ArchiveProcessor) ProcessArchive(archivePath string, archive *t
 		}
 	}
 
+	// git tree of the extracted files, sub-archives included as the files they are, with symlinks and empty directories as Software Heritage has them
+	gitTree := gitoid.NewTree()
+	treePath := func(path string) (string, error) {
+		rel, err := filepath.Rel(*archive.Extracted, path)
+		if err != nil {
+			return "", errors.Wrapf(err, "error finding path of %s within archive", path)
+		}
+
+		return filepath.ToSlash(rel), nil
+	}
+	addToTree := func(path string, info fs.FileInfo, blobID [20]byte) error {
+		rel, err := treePath(path)
+		if err != nil {
+			return err
+		}
+
+		return gitTree.Add(rel, info.Mode()&0o100 != 0, blobID) // git only checks whether the owner may execute a file
+	}
+	processFile := func(path string, info fs.FileInfo) error {
+		file, err := processor.processFile(archive, path, info)
+		if err != nil {
+			return err
+		}
+
+		return addToTree(path, info, file.GitSha1)
+	}
+
 	if err := filepath.Walk(*archive.Extracted, func(path string, info fs.FileInfo, err error) error {
 		// log.Debug().Str("path", path).Msg("Walked to path")
-		if info.IsDir() { // only want to process files, so just skip this entry
-			return nil
+		if info.IsDir() { // only want to process files, directories are only added to the tree, so empty ones are kept
+			if path == *archive.Extracted {
+				return nil
+			}
+			rel, err := treePath(path)
+			if err != nil {
+				return err
+			}
+
+			return gitTree.AddDir(rel)
+		} else if IsSymLink(info) { // symlinks are not followed, only added to the tree with their target
+			rel, err := treePath(path)
+			if err != nil {
+				return err
+			}
+			target, err := os.Readlink(path)
+			if err != nil {
+				return errors.Wrapf(err, "error reading symlink %s", path)
+			}
+
+			return gitTree.AddSymlink(rel, target)
 		} else if !info.Mode().IsRegular() { //skip irregular files
 			return nil
 		}
 
 		if rec := extract.IsExtractable(path); rec != 1.0 { // path does not look extractable
 			// process as normal file
-			return processor.processFile(archive, path, info)
+			return processFile(path, info)
 		}
 
 		// else process archive
@@ -156,6 +211,9 @@ func (processor *ArchiveProcessor) ProcessArchive(archivePath string, archive *t
 		if err != nil {
 			return err
 		}
+		if err := addToTree(path, info, newArchive.GitSha1); err != nil {
+			return err
+		}
 
 		sub, ok := processor.ArchiveMap[newArchive.Sha256]
 		if ok { // archive already processed previously
@@ -173,7 +231,8 @@ func (processor *ArchiveProcessor) ProcessArchive(archivePath string, archive *t
 		if err := processor.extractArchive(path, sub); err != nil {
 			if _, ok := err.(ErrExtract); ok {
 				// log.Debug().Err(err).Str("path", path).Interface("sub", sub).Msg("Error extracting sub-archive, so treating as file")
-				return processor.processFile(archive, path, info) // error extracting, so process as file
+				_, err := processor.processFile(archive, path, info) // error extracting, so process as file, already in the tree
+				return err
 			}
 
 			// return unexpected error
@@ -196,6 +255,9 @@ func (processor *ArchiveProcessor) ProcessArchive(archivePath string, archive *t
 		return archive, err
 	}
 
+	gitTreeSha1 := gitTree.Sum()
+	archive.GitTreeSha1 = gitTreeSha1[:]
+
 	return archive, nil
 }
 
@@ -248,7 +310,10 @@ func InitFile(filePath string) (*tree.File, error) {
 	hasherSha256 := sha256.New()
 	hasherMd5 := md5.New()
 	hasherSha1 := sha1.New()
-	hasher := io.MultiWriter(hasherSha256, hasherMd5, hasherSha1)
+	hasherSha512 := sha512.New()
+	hasherGitSha1 := gitoid.NewBlobSha1(ret.Size)
+	hasherGitSha256 := gitoid.NewBlobSha256(ret.Size)
+	hasher := io.MultiWriter(hasherSha256, hasherMd5, hasherSha1, hasherSha512, hasherGitSha1, hasherGitSha256)
 
 	if _, err := io.Copy(hasher, f); err != nil {
 		return ret, errors.Wrapf(err, "error hashing file %s", filePath)
@@ -257,6 +322,9 @@ func InitFile(filePath string) (*tree.File, error) {
 	copy(ret.Sha256[:], hasherSha256.Sum(nil))
 	copy(ret.Md5[:], hasherMd5.Sum(nil))
 	copy(ret.Sha1[:], hasherSha1.Sum(nil))
+	copy(ret.Sha512[:], hasherSha512.Sum(nil))
+	copy(ret.GitSha1[:], hasherGitSha1.Sum(nil))
+	copy(ret.GitSha256[:], hasherGitSha256.Sum(nil))
 
 	return ret, nil
 }
@@ -269,13 +337,14 @@ func IsSymLink(info fs.FileInfo) bool {
 	return false
 }
 
-func (process *ArchiveProcessor) processFile(archive *tree.Archive, path string, info fs.FileInfo) error {
+// processFile adds the file at path to the archive, returning it
+func (process *ArchiveProcessor) processFile(archive *tree.Archive, path string, info fs.FileInfo) (*tree.File, error) {
 	if IsSymLink(info) {
 		log.Debug().Str("path", path).Interface("info", info).Msg("You shouldn't be processing this symlink")
 	}
 	newFile, err := InitFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	file, ok := process.FileMap[newFile.Sha256]
@@ -284,7 +353,7 @@ func (process *ArchiveProcessor) processFile(archive *tree.Archive, path string,
 		file = newFile
 		if process.VisitFile != nil {
 			if err := process.VisitFile(path, newFile); err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
@@ -294,5 +363,42 @@ func (process *ArchiveProcessor) processFile(archive *tree.Archive, path string,
 		File: file,
 	})
 
-	return nil
+	process.identifyManifest(archive, path, info)
+
+	return file, nil
+}
+
+// identifyManifest adds the purl of a manifest to the archive
+// Only manifests at the top of the archive, or in its top directory, describe the archive itself
+// Manifests that cannot be read or parsed are skipped
+func (process *ArchiveProcessor) identifyManifest(archive *tree.Archive, path string, info fs.FileInfo) {
+	if archive.Extracted == nil || info.Size() > manifest.MaxSize || !manifest.IsManifest(info.Name()) {
+		return
+	}
+
+	rel, err := filepath.Rel(*archive.Extracted, path)
+	if err != nil || strings.Count(filepath.ToSlash(rel), "/") > 1 {
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("error reading manifest")
+		return
+	}
+
+	purl, err := manifest.Identify(rel, content)
+	if err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("error identifying manifest")
+		return
+	} else if purl == nil {
+		return
+	}
+
+	for _, v := range archive.Purls {
+		if v == purl.String() {
+			return
+		}
+	}
+	archive.Purls = append(archive.Purls, purl.String())
 }
@@ -14,6 +14,7 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
@@ -24,16 +25,19 @@ import (
 	"wrs/tk/packages/array/hash"
 	"wrs/tk/packages/blob"
 	"wrs/tk/packages/blob/file"
+	"wrs/tk/packages/checksum/gitoid"
 	"wrs/tk/packages/core/archive/processor"
 	"wrs/tk/packages/core/archive/sync"
 	"wrs/tk/packages/core/archive/tree"
 	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/similarity"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -41,7 +45,8 @@ import (
 )
 
 type ArchiveController struct {
-	DB *sqlx.DB
+	DB          *sqlx.DB
+	SyncOptions sync.Options // how archive trees are added to the catalog
 
 	fileStorage    blob.Storage
 	archiveStorage blob.Storage
@@ -200,17 +205,21 @@ func (p *ArchiveController) Process(arch *Archive) error {
 	return <-ret
 }
 
+// fillContentIdentifiers sets the content identifiers of an archive upserted, keeping those it had if none were inserted
+const fillContentIdentifiers = `sha512=COALESCE(EXCLUDED.sha512, archive.sha512),
+	gitoid_sha1=COALESCE(EXCLUDED.gitoid_sha1, archive.gitoid_sha1), gitoid_sha256=COALESCE(EXCLUDED.gitoid_sha256, archive.gitoid_sha256)`
+
 // TODOC
 func (p *ArchiveController) visitArchive(archivePath string, archive *tree.Archive) error { // Visit Archive
 	log.Debug().Str("archivePath", archivePath).Msg("Uploading Archive")
 
 	var remoteArchive Archive
 	// Upsert archive inherent values
-	if err := p.DB.QueryRowx(`INSERT INTO archive (sha256, archive_size, md5, sha1)
-	VALUES ($1, $2, $3, $4)
-	ON CONFLICT (sha256) DO UPDATE SET sha256=EXCLUDED.sha256
+	if err := p.DB.QueryRowx(`INSERT INTO archive (sha256, archive_size, md5, sha1, sha512, gitoid_sha1, gitoid_sha256)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (sha256) DO UPDATE SET `+fillContentIdentifiers+`
 	RETURNING *`,
-		archive.Sha256[:], archive.Size, archive.Md5[:], archive.Sha1[:]).StructScan(&remoteArchive); err != nil {
+		archive.Sha256[:], archive.Size, archive.Md5[:], archive.Sha1[:], archive.Sha512[:], archive.GitSha1[:], archive.GitSha256[:]).StructScan(&remoteArchive); err != nil {
 		return errors.Wrapf(err, "error upserting archive")
 	}
 
@@ -290,12 +299,17 @@ func (p *ArchiveController) process(arch *Archive, fileName string) error {
 	}
 	log.Debug().Interface("arch", arch).Str(zerolog.CallerFieldName, "ArchiveController.process").Msg("Created archive tree")
 
-	partID, err := sync.SyncTree(p.DB, &part.PartController{DB: p.DB}, rootArchive) // TODO properly obtain part controller
+	partID, err := sync.SyncTree(p.DB, &part.PartController{DB: p.DB}, rootArchive, p.SyncOptions) // TODO properly obtain part controller
 	if err != nil {
 		return err
 	}
 	log.Debug().Interface("arch", arch).Str(zerolog.CallerFieldName, "ArchiveController.process").Str("partID", partID.String()).Msg("Synced archive tree")
 
+	if err := (similarity.SimilarityController{DB: p.DB}).Index(uuid.UUID(partID)); err != nil {
+		// signatures are rebuilt by the signatures command, the archive itself has been processed
+		log.Error().Err(err).Str(zerolog.CallerFieldName, "ArchiveController.process").Str("partID", partID.String()).Msg("error indexing part similarity")
+	}
+
 	return nil
 }
 
@@ -306,11 +320,11 @@ func (p *ArchiveController) SyncArchive(db *sqlx.DB, localArchive *Archive) erro
 	var remoteArchive Archive
 
 	// Upsert archive inherent values
-	if err := db.QueryRowx(`INSERT INTO archive(sha256, archive_size, md5, sha1) 
-	VALUES ($1, $2, $3, $4) 
-	ON CONFLICT (sha256) DO UPDATE SET sha256=EXCLUDED.sha256
-	RETURNING *`, // Meaningless update is required, if no insert or update is made RETURNING will return nothing
-		localArchive.Sha256, localArchive.Size, localArchive.Md5, localArchive.Sha1).StructScan(&remoteArchive); err != nil {
+	if err := db.QueryRowx(`INSERT INTO archive(sha256, archive_size, md5, sha1, sha512, gitoid_sha1, gitoid_sha256) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7) 
+	ON CONFLICT (sha256) DO UPDATE SET `+fillContentIdentifiers+`
+	RETURNING *`, // An update is required, if no insert or update is made RETURNING will return nothing
+		localArchive.Sha256, localArchive.Size, localArchive.Md5, localArchive.Sha1, localArchive.Sha512, localArchive.GitoidSha1, localArchive.GitoidSha256).StructScan(&remoteArchive); err != nil {
 		return errors.Wrapf(err, "error scanning remote archive")
 	}
 
@@ -379,6 +393,11 @@ type Archive struct {
 	InsertDate  time.Time      `db:"insert_date"`
 	StoragePath sql.NullString `db:"storage_path"`
 	Aliases     []string       `db:"names"`
+
+	// Content identifiers, nil for archives cataloged before they were calculated
+	Sha512       []byte `db:"sha512"`
+	GitoidSha1   []byte `db:"gitoid_sha1"`   // git blob id, that of the archive's swh:1:cnt SWHID
+	GitoidSha256 []byte `db:"gitoid_sha256"` // git blob id in sha256, the archive's OmniBOR artifact id
 }
 
 // InitArchive loads an Archive from the local file system.
@@ -403,6 +422,9 @@ func InitArchive(source string, name string) (*Archive, error) {
 	md5Hasher := md5.New()
 	sha1Hasher := sha1.New()
 	sha256Hasher := sha256.New()
+	sha512Hasher := sha512.New()
+	gitSha1Hasher := gitoid.NewBlobSha1(size)
+	gitSha256Hasher := gitoid.NewBlobSha256(size)
 
 	f, err := os.Open(source)
 	if err != nil {
@@ -436,6 +458,18 @@ func InitArchive(source string, name string) (*Archive, error) {
 			err = errors.Wrapf(err, "error calculating sha256")
 			return nil, err
 		}
+		if _, err := sha512Hasher.Write(buf); err != nil {
+			err = errors.Wrapf(err, "error calculating sha512")
+			return nil, err
+		}
+		if _, err := gitSha1Hasher.Write(buf); err != nil {
+			err = errors.Wrapf(err, "error calculating gitoid sha1")
+			return nil, err
+		}
+		if _, err := gitSha256Hasher.Write(buf); err != nil {
+			err = errors.Wrapf(err, "error calculating gitoid sha256")
+			return nil, err
+		}
 	}
 
 	md5 := md5Hasher.Sum(nil)
@@ -450,6 +484,9 @@ func InitArchive(source string, name string) (*Archive, error) {
 	copy(ret.Md5[:], md5)
 	copy(ret.Sha1[:], sha1)
 	copy(ret.Sha256[:], sha256)
+	ret.Sha512 = sha512Hasher.Sum(nil)
+	ret.GitoidSha1 = gitSha1Hasher.Sum(nil)
+	ret.GitoidSha256 = gitSha256Hasher.Sum(nil)
 
 	return ret, nil
 }
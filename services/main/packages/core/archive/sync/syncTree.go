@@ -8,22 +8,41 @@ import (
 	"wrs/tk/packages/core/archive/filename"
 	"wrs/tk/packages/core/archive/tree"
 	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/identifier"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
-func SyncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archive) (uuid.UUID, error) {
+// Options of syncing archive trees
+type Options struct {
+	LinkEmbedded     bool // add directories matching the file verification code of known parts as those parts, rather than adding their files
+	EmbeddedMinFiles int  // least files a directory must have to be matched to a known part
+}
+
+// fillArchiveIdentifiers sets the content identifiers of an archive upserted, those of archives cataloged before they were calculated included
+const fillArchiveIdentifiers = `sha512=EXCLUDED.sha512, gitoid_sha1=EXCLUDED.gitoid_sha1, gitoid_sha256=EXCLUDED.gitoid_sha256`
+
+func SyncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archive, options Options) (uuid.UUID, error) {
 	prt, err := partController.GetByVerificationCode(root.FileVerificationCode)
 	if err == nil {
 		// upsert archive and archive_alias
-		if _, err := db.Exec(`INSERT INTO archive (sha256, archive_size, md5, sha1, part_id) VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (sha256) DO UPDATE SET part_id=EXCLUDED.part_id`,
-			root.Sha256[:], root.Size, root.Md5[:], root.Sha1[:], prt.PartID); err != nil {
+		if _, err := db.Exec(`INSERT INTO archive (sha256, archive_size, md5, sha1, part_id, sha512, gitoid_sha1, gitoid_sha256) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (sha256) DO UPDATE SET part_id=EXCLUDED.part_id, `+fillArchiveIdentifiers,
+			root.Sha256[:], root.Size, root.Md5[:], root.Sha1[:], prt.PartID, root.Sha512[:], root.GitSha1[:], root.GitSha256[:]); err != nil {
 			return uuid.Nil, errors.Wrapf(err, "error upserting archive")
 		}
 
+		// the part keeps the directory of the archive it was first extracted from
+		if root.GitTreeSha1 != nil {
+			if _, err := db.Exec(`UPDATE part SET git_tree_sha1=$1 WHERE part_id=$2 AND git_tree_sha1 IS NULL`, root.GitTreeSha1, prt.PartID); err != nil {
+				return uuid.Nil, errors.Wrapf(err, "error updating git_tree_sha1 of part %s", prt.PartID.String())
+			}
+		}
+
 		if _, err := db.Exec(`INSERT INTO archive_alias (archive_sha256, name) VALUES ($1, $2) ON CONFLICT (archive_sha256, name) DO NOTHING`,
 			root.Sha256[:], root.GetName()); err != nil {
 			return uuid.Nil, errors.Wrapf(err, "error upserting archive_alias")
@@ -32,7 +51,7 @@ func SyncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archi
 		return uuid.UUID(prt.PartID), nil
 	} else if err == part.ErrNotFound {
 		// Insert entire tree
-		return syncTree(db, partController, root)
+		return syncTree(db, partController, root, options)
 	} else { // unexpected error
 		return uuid.Nil, err
 	}
@@ -52,7 +71,62 @@ func trimPath(path string) string {
 	return filepath.Join(strings.Split(path, "/")[1:]...)
 }
 
-func syncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archive) (uuid.UUID, error) {
+// embeddedPart is a directory of an archive holding exactly the files of a known part
+type embeddedPart struct {
+	tree.Subtree
+	PartID part.ID
+}
+
+// findEmbeddedParts matches the directories of an archive to known parts by file verification code, outermost directories first.
+// Directories beneath a matched directory are not matched, nor are those holding only sub-archives, which are matched as archives.
+func findEmbeddedParts(partController *part.PartController, root *tree.Archive, options Options) ([]embeddedPart, error) {
+	subtrees, err := tree.Subtrees(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error calculating verification codes of directories")
+	}
+
+	ret := make([]embeddedPart, 0)
+	for _, subtree := range subtrees {
+		if subtree.ArchivesOnly || subtree.FileCount < options.EmbeddedMinFiles || embeddedIn(ret, subtree.Path) {
+			continue
+		}
+
+		prt, err := partController.GetByVerificationCode(subtree.FileVerificationCode)
+		if err == part.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		log.Info().Str(zerolog.CallerFieldName, "sync.findEmbeddedParts").Str("archive", root.GetName()).Str("path", trimPath(subtree.Path)).
+			Str("partID", prt.PartID.String()).Bool("link", options.LinkEmbedded).Msg("found known part embedded in archive")
+		ret = append(ret, embeddedPart{Subtree: subtree, PartID: prt.PartID})
+	}
+
+	return ret, nil
+}
+
+// embeddedIn returns whether path is beneath the directory of an embedded part
+func embeddedIn(embedded []embeddedPart, path string) bool {
+	for _, e := range embedded {
+		if e.Contains(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func syncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archive, options Options) (uuid.UUID, error) {
+	found, err := findEmbeddedParts(partController, root, options)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	embedded := found
+	if !options.LinkEmbedded {
+		embedded = nil
+	}
+
 	var name, version, label sql.NullString
 	if pkgName, pkgVersion, err := filename.GetPkgNameVersion(root.GetName()); err != nil {
 		return uuid.Nil, err
@@ -82,10 +156,36 @@ func syncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archi
 		return partID, errors.Wrapf(err, "error creating part for archive")
 	}
 
-	// Upsert all files and file_aliases
+	// Add identifiers, a generic purl from the name and version parsed out of the filename, and any found in manifests
+	purls := root.Purls
+	if version.Valid {
+		purls = append([]string{identifier.NewPurl("generic", "", name.String, version.String).String()}, purls...)
+	}
+	for _, purl := range purls {
+		if _, err := partController.AddIdentifier(part.ID(partID), identifier.TypePurl, purl); err != nil {
+			return partID, errors.Wrapf(err, "error adding identifier %s to part", purl)
+		}
+	}
+
+	// Record the embedded parts found, linked or not, for curators to review
+	for _, e := range found {
+		if _, err := db.Exec(`INSERT INTO part_embedded (part_id, path, embedded_id, linked) VALUES ($1, $2, $3, $4) ON CONFLICT (part_id, path) DO NOTHING`,
+			partID, trimPath(e.Path), e.PartID, options.LinkEmbedded); err != nil {
+			return partID, errors.Wrapf(err, "error recording embedded part (%s, %s, %s)", partID, e.PartID.String(), e.Path)
+		}
+	}
+
+	// Upsert all files and file_aliases, other than those of embedded parts
 	for _, subFile := range root.Files {
-		if _, err := db.Exec(`INSERT INTO file (sha256, file_size, md5, sha1) VALUES ($1, $2, $3, $4) ON CONFLICT (sha256) DO NOTHING`,
-			subFile.Sha256[:], subFile.Size, subFile.Md5[:], subFile.Sha1[:]); err != nil {
+		if embeddedIn(embedded, subFile.GetPath()) {
+			continue
+		}
+
+		// files cataloged before their content identifiers were calculated are given them
+		if _, err := db.Exec(`INSERT INTO file (sha256, file_size, md5, sha1, sha512, gitoid_sha1, gitoid_sha256) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (sha256) DO UPDATE SET sha512=EXCLUDED.sha512, gitoid_sha1=EXCLUDED.gitoid_sha1, gitoid_sha256=EXCLUDED.gitoid_sha256
+		WHERE file.sha512 IS NULL OR file.gitoid_sha1 IS NULL OR file.gitoid_sha256 IS NULL`,
+			subFile.Sha256[:], subFile.Size, subFile.Md5[:], subFile.Sha1[:], subFile.Sha512[:], subFile.GitSha1[:], subFile.GitSha256[:]); err != nil {
 			return partID, errors.Wrapf(err, "error inserting file")
 		}
 
@@ -102,10 +202,13 @@ func syncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archi
 
 	// Recursively sync all sub-archives
 	for _, subArchive := range root.Archives {
-		subPartID, err := SyncTree(db, partController, subArchive.Archive)
+		subPartID, err := SyncTree(db, partController, subArchive.Archive, options)
 		if err != nil {
 			return partID, errors.Wrapf(err, "error syncing sub-archive")
 		}
+		if embeddedIn(embedded, subArchive.Path) { // the sub-archive is recorded, but is already a part of the embedded part
+			continue
+		}
 
 		if _, err := db.Exec(`INSERT INTO part_has_part (parent_id, child_id, path) 
 		VALUES ($1, $2, $3)`,
@@ -114,9 +217,17 @@ func syncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archi
 		}
 	}
 
-	// set file_verification_code
-	if result, err := db.Exec(`UPDATE part SET file_verification_code=$1 WHERE part_id=$2`,
-		root.FileVerificationCode, partID); err != nil {
+	// Add embedded parts at their directories
+	for _, e := range embedded {
+		if _, err := db.Exec(`INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, $3)`,
+			partID, e.PartID, trimPath(e.Path)); err != nil {
+			return partID, errors.Wrapf(err, "error adding embedded part (%s, %s, %s)", partID, e.PartID.String(), e.Path)
+		}
+	}
+
+	// set file_verification_code, file_verification_code_one, and git_tree_sha1
+	if result, err := db.Exec(`UPDATE part SET file_verification_code=$1, file_verification_code_one=$3, git_tree_sha1=$4 WHERE part_id=$2`,
+		root.FileVerificationCode, partID, root.FileVerificationCodeOne, root.GitTreeSha1); err != nil {
 		return partID, errors.Wrapf(err, "error updating file_verification_code of part: \"%s\"", partID.String())
 	} else {
 		count, err := result.RowsAffected()
@@ -129,9 +240,9 @@ func syncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archi
 	}
 
 	// Insert archive and archive_alias
-	if _, err := db.Exec(`INSERT INTO archive (sha256, archive_size, md5, sha1, part_id) VALUES ($1, $2, $3, $4, $5)
-	ON CONFLICT (sha256) DO UPDATE SET part_id=EXCLUDED.part_id`,
-		root.Sha256[:], root.Size, root.Md5[:], root.Sha1[:], partID); err != nil {
+	if _, err := db.Exec(`INSERT INTO archive (sha256, archive_size, md5, sha1, part_id, sha512, gitoid_sha1, gitoid_sha256) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (sha256) DO UPDATE SET part_id=EXCLUDED.part_id, `+fillArchiveIdentifiers,
+		root.Sha256[:], root.Size, root.Md5[:], root.Sha1[:], partID, root.Sha512[:], root.GitSha1[:], root.GitSha256[:]); err != nil {
 		return partID, errors.Wrapf(err, "error inserting root archive")
 	}
 
@@ -144,9 +255,9 @@ func syncTree(db *sqlx.DB, partController *part.PartController, root *tree.Archi
 	if len(root.DuplicateArchives) > 0 {
 		for _, v := range root.DuplicateArchives {
 			// Insert archive and archive_alias
-			if _, err := db.Exec(`INSERT INTO archive (sha256, archive_size, md5, sha1, part_id) VALUES ($1, $2, $3, $4, $5)
-	ON CONFLICT (sha256) DO UPDATE SET part_id=EXCLUDED.part_id`,
-				v.Sha256[:], v.Size, v.Md5[:], v.Sha1[:], partID); err != nil {
+			if _, err := db.Exec(`INSERT INTO archive (sha256, archive_size, md5, sha1, part_id, sha512, gitoid_sha1, gitoid_sha256) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (sha256) DO UPDATE SET part_id=EXCLUDED.part_id, `+fillArchiveIdentifiers,
+				v.Sha256[:], v.Size, v.Md5[:], v.Sha1[:], partID, v.Sha512[:], v.GitSha1[:], v.GitSha256[:]); err != nil {
 				return partID, errors.Wrapf(err, "error inserting root archive")
 			}
 
@@ -10,10 +10,13 @@ import (
 type Sha256 [32]byte
 
 type File struct {
-	Sha256 Sha256
-	Size   int64
-	Md5    [16]byte
-	Sha1   [20]byte
+	Sha256    Sha256
+	Size      int64
+	Md5       [16]byte
+	Sha1      [20]byte
+	Sha512    [64]byte
+	GitSha1   [20]byte // git blob id, also that of the file's swh:1:cnt SWHID
+	GitSha256 [32]byte // git blob id in sha256, the file's OmniBOR artifact id
 }
 
 type SubFile struct {
@@ -31,10 +34,13 @@ func (sf SubFile) GetName() string {
 
 type ArchiveIdentifiers struct {
 	// Identifying information
-	Sha256 Sha256
-	Size   int64
-	Md5    [16]byte
-	Sha1   [20]byte
+	Sha256    Sha256
+	Size      int64
+	Md5       [16]byte
+	Sha1      [20]byte
+	Sha512    [64]byte
+	GitSha1   [20]byte // git blob id of the archive file itself
+	GitSha256 [32]byte
 	// Misc
 	Name string
 }
@@ -49,10 +55,13 @@ type Archive struct {
 	Files    []SubFile
 	Archives []SubArchive
 
-	TmpPath              *string
-	Extracted            *string
-	FileVerificationCode []byte
-	DuplicateArchives    []ArchiveIdentifiers // All archives should be inserted into the database, but the purpose of the trees is actually to turn them into parts, so a separate list of duplicates is required
+	TmpPath                 *string
+	Extracted               *string
+	FileVerificationCode    []byte
+	FileVerificationCodeOne []byte               // FVC1, whose payload is the SPDX package verification code
+	GitTreeSha1             []byte               // git tree id of the extracted archive, that of its swh:1:dir SWHID
+	Purls                   []string             // Packages identified from manifests near the top of the archive
+	DuplicateArchives       []ArchiveIdentifiers // All archives should be inserted into the database, but the purpose of the trees is actually to turn them into parts, so a separate list of duplicates is required
 }
 
 func (a *Archive) Close() error {
@@ -0,0 +1,114 @@
+package tree
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.devstar.cloud/ip-systems/verification-code.git/code"
+)
+
+// Subtree is a directory of an archive, and the verification code of the files beneath it, including those of sub-archives beneath it
+type Subtree struct {
+	Path                 string
+	FileVerificationCode []byte
+	FileCount            int
+	ArchivesOnly         bool // whether every file beneath the directory is of a sub-archive
+}
+
+// Subtrees calculates the verification codes of every directory of an archive, outermost directories first.
+// Directories holding every file of the archive are left out, as they have the verification code of the archive itself.
+func Subtrees(root *Archive) ([]Subtree, error) {
+	type directory struct {
+		sha256s    [][32]byte
+		looseFiles int
+	}
+
+	directories := make(map[string]*directory)
+	add := func(path string, sha256s [][32]byte, looseFiles int) {
+		for dir := parentDirectory(path); dir != ""; dir = parentDirectory(dir) {
+			d, ok := directories[dir]
+			if !ok {
+				d = new(directory)
+				directories[dir] = d
+			}
+
+			d.sha256s = append(d.sha256s, sha256s...)
+			d.looseFiles += looseFiles
+		}
+	}
+
+	total := 0
+	for _, file := range root.Files {
+		add(file.GetPath(), [][32]byte{file.Sha256}, 1)
+		total++
+	}
+	for _, subArchive := range root.Archives {
+		subSha256s := archiveSha256s(subArchive.Archive)
+		add(subArchive.Path, subSha256s, 0)
+		total += len(subSha256s)
+	}
+
+	ret := make([]Subtree, 0, len(directories))
+	for path, d := range directories {
+		if len(d.sha256s) == total {
+			continue
+		}
+
+		hasher := code.NewVersionTwo().(*code.VersionTwoHasher)
+		for i := range d.sha256s {
+			if err := hasher.AddSha256(d.sha256s[i][:]); err != nil {
+				return nil, err
+			}
+		}
+
+		ret = append(ret, Subtree{
+			Path:                 path,
+			FileVerificationCode: hasher.Sum(),
+			FileCount:            len(d.sha256s),
+			ArchivesOnly:         d.looseFiles == 0,
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if depth(ret[i].Path) != depth(ret[j].Path) {
+			return depth(ret[i].Path) < depth(ret[j].Path)
+		}
+
+		return ret[i].Path < ret[j].Path
+	})
+
+	return ret, nil
+}
+
+// Contains returns whether path is beneath the directory of the subtree
+func (s Subtree) Contains(path string) bool {
+	return strings.HasPrefix(path, s.Path+"/")
+}
+
+// archiveSha256s lists the sha256 of every file of an archive and its sub-archives, as its verification code is calculated from
+func archiveSha256s(archive *Archive) [][32]byte {
+	ret := make([][32]byte, 0, len(archive.Files))
+	for _, file := range archive.Files {
+		ret = append(ret, file.Sha256)
+	}
+	for _, subArchive := range archive.Archives {
+		ret = append(ret, archiveSha256s(subArchive.Archive)...)
+	}
+
+	return ret
+}
+
+// parentDirectory returns the directory containing path, or an empty string at the top of the archive
+func parentDirectory(path string) string {
+	dir := filepath.Dir(path)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+
+	return dir
+}
+
+func depth(path string) int {
+	return strings.Count(strings.Trim(path, "/"), "/")
+}
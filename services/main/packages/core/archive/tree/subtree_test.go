@@ -0,0 +1,52 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSubtrees(t *testing.T) {
+	file := func(path string, sha256 string) SubFile {
+		return SubFile{Path: path, File: &File{Sha256: MustSha256(sha256)}}
+	}
+	readme := file("tmp/product/README", "80f3d9f67e1e3b664e50d1e932b5489b3a3e547d0a6ea97e0d0c888864d6dec6")
+	zlib := []SubFile{
+		file("tmp/product/zlib/adler32.c", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"),
+		file("tmp/product/zlib/crc32.c", "a33f0b33767fc513e888d765c05ca0e541c83c4908b0b1a62474ed827aa40844"),
+	}
+	nested := &Archive{Files: []SubFile{file("tmp/crt", "837f0da343583b0995e51de26b6fb848103221b5fdfdd1d742746765042bd5ec")}}
+
+	known := &Archive{Files: zlib}
+	if err := CalculateVerificationCodes(known); err != nil {
+		t.Fatal(err)
+	}
+	root := &Archive{
+		Files:    append([]SubFile{readme}, zlib...),
+		Archives: []SubArchive{{Path: "tmp/product/vendor/crt.tar.gz", Archive: nested}},
+	}
+
+	subtrees, err := Subtrees(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Subtree{
+		{Path: "tmp/product/vendor", FileCount: 1, ArchivesOnly: true},
+		{Path: "tmp/product/zlib", FileCount: 2, FileVerificationCode: known.FileVerificationCode},
+	}
+	if len(subtrees) != len(want) {
+		t.Fatalf("Subtrees() got %d subtrees, want %d: %v", len(subtrees), len(want), subtrees)
+	}
+	for i := range want {
+		got := subtrees[i]
+		if got.Path != want[i].Path || got.FileCount != want[i].FileCount || got.ArchivesOnly != want[i].ArchivesOnly {
+			t.Errorf("Subtrees()[%d] got %s with %d files, want %s with %d files", i, got.Path, got.FileCount, want[i].Path, want[i].FileCount)
+		}
+		if want[i].FileVerificationCode != nil && !bytes.Equal(got.FileVerificationCode, want[i].FileVerificationCode) {
+			t.Errorf("Subtrees()[%d] got code %x, want %x", i, got.FileVerificationCode, want[i].FileVerificationCode)
+		}
+	}
+	if !subtrees[1].Contains("tmp/product/zlib/crc32.c") || subtrees[1].Contains("tmp/product/zlib2/crc32.c") {
+		t.Errorf("Contains() does not match the paths beneath the subtree")
+	}
+}
@@ -2,41 +2,56 @@ package tree
 
 import (
 	"bytes"
+	"encoding/hex"
 	"sort"
 
 	"gitlab.devstar.cloud/ip-systems/verification-code.git/code"
 )
 
+// CalculateVerificationCodes calculates the file verification codes 2 and 1 of an archive and every archive beneath it
+// The payload of the code 1 is the SPDX package verification code, the sha1 of the sorted sha1s of the files
 func CalculateVerificationCodes(root *Archive) error {
-	if _, err := calculateVerificationCodes(root); err != nil {
+	if _, _, err := calculateVerificationCodes(root); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func calculateVerificationCodes(root *Archive) ([][32]byte, error) {
+func calculateVerificationCodes(root *Archive) ([][32]byte, [][20]byte, error) {
 	hasher := code.NewVersionTwo().(*code.VersionTwoHasher)
+	hasherOne := code.NewVersionOne().(*code.VersionOneHasher)
 
 	sha256Accumulator := make([][32]byte, 0)
+	sha1Accumulator := make([][20]byte, 0)
 	for _, file := range root.Files {
 		sha256Accumulator = append(sha256Accumulator, file.Sha256)
 		if err := hasher.AddSha256(file.Sha256[:]); err != nil {
-			return sha256Accumulator, err
+			return sha256Accumulator, sha1Accumulator, err
+		}
+		sha1Accumulator = append(sha1Accumulator, file.Sha1)
+		if err := hasherOne.AddSha1Hex(hex.EncodeToString(file.Sha1[:])); err != nil {
+			return sha256Accumulator, sha1Accumulator, err
 		}
 	}
 
 	if root.Archives != nil && len(root.Archives) > 0 {
 		for _, subArchive := range root.Archives {
-			subSha256s, err := calculateVerificationCodes(subArchive.Archive)
+			subSha256s, subSha1s, err := calculateVerificationCodes(subArchive.Archive)
 			if err != nil {
-				return sha256Accumulator, err
+				return sha256Accumulator, sha1Accumulator, err
 			}
 
 			for i := range subSha256s { // index is used to prevent the slice we create from changing on the next iteration
 				sha256Accumulator = append(sha256Accumulator, subSha256s[i])
 				if err := hasher.AddSha256(subSha256s[i][:]); err != nil {
-					return sha256Accumulator, err
+					return sha256Accumulator, sha1Accumulator, err
+				}
+			}
+			for i := range subSha1s {
+				sha1Accumulator = append(sha1Accumulator, subSha1s[i])
+				if err := hasherOne.AddSha1Hex(hex.EncodeToString(subSha1s[i][:])); err != nil {
+					return sha256Accumulator, sha1Accumulator, err
 				}
 			}
 		}
@@ -48,6 +63,7 @@ func calculateVerificationCodes(root *Archive) ([][32]byte, error) {
 	})
 
 	root.FileVerificationCode = hasher.Sum()
+	root.FileVerificationCodeOne = hasherOne.Sum()
 
 	if len(root.Files) == 0 && // if archive has no files
 		(root.Archives != nil && len(root.Archives) == 1) { // and archive has one sub-archive
@@ -58,14 +74,18 @@ func calculateVerificationCodes(root *Archive) ([][32]byte, error) {
 			duplicate.Sha256 = sub.Sha256
 			duplicate.Md5 = sub.Md5
 			duplicate.Sha1 = sub.Sha1
+			duplicate.Sha512 = sub.Sha512
+			duplicate.GitSha1 = sub.GitSha1
+			duplicate.GitSha256 = sub.GitSha256
 			duplicate.Size = sub.Size
 			duplicate.Name = sub.Name
 
 			root.Files = sub.Files
 			root.Archives = sub.Archives
+			root.GitTreeSha1 = sub.GitTreeSha1
 			root.DuplicateArchives = []ArchiveIdentifiers{*duplicate}
 		}
 	}
 
-	return sha256Accumulator, nil
+	return sha256Accumulator, sha1Accumulator, nil
 }
@@ -102,18 +102,20 @@ func TestCalculateVerificationCodes(t *testing.T) {
 		root *Archive
 	}
 	tests := []struct {
-		name       string
-		args       args
-		wantErr    bool
-		wantFVCode []byte
+		name          string
+		args          args
+		wantErr       bool
+		wantFVCode    []byte
+		wantFVCodeOne []byte
 	}{
 		{
 			name: "sub-archive with symlink",
 			args: args{
 				root: treeContainerSymlinkError,
 			},
-			wantErr:    false,
-			wantFVCode: MustHex("4656433200ca036383b3b7394126e7311bac9987a2d80fc42258ad61f84eaa096deb003eab"),
+			wantErr:       false,
+			wantFVCode:    MustHex("4656433200ca036383b3b7394126e7311bac9987a2d80fc42258ad61f84eaa096deb003eab"),
+			wantFVCodeOne: MustHex("4656433100a0b0daf8d35c295e4a1331b1a04ccf1c657199f4"),
 		},
 	}
 	for _, tt := range tests {
@@ -125,6 +127,9 @@ func TestCalculateVerificationCodes(t *testing.T) {
 			if !tt.wantErr && !bytes.Equal(tt.args.root.FileVerificationCode, tt.wantFVCode) {
 				t.Errorf("CalculateVerificationCodes() got = %x, want %x", tt.args.root.FileVerificationCode, tt.wantFVCode)
 			}
+			if !tt.wantErr && !bytes.Equal(tt.args.root.FileVerificationCodeOne, tt.wantFVCodeOne) {
+				t.Errorf("CalculateVerificationCodes() got code one = %x, want %x", tt.args.root.FileVerificationCodeOne, tt.wantFVCodeOne)
+			}
 		})
 	}
 }
@@ -0,0 +1,34 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+
+	"wrs/tk/packages/blob/file"
+
+	"github.com/pkg/errors"
+)
+
+// UploadFile stores a file's contents in file storage
+func (p *ArchiveController) UploadFile(r io.Reader, info *file.FileInfo) error {
+	return p.fileStorage.Store(r, info)
+}
+
+// UploadArchive stores an archive's contents in archive storage, and sets its storage path if the archive had none
+func (p *ArchiveController) UploadArchive(r io.Reader, info *file.FileInfo) error {
+	if err := p.archiveStorage.Store(r, info); err != nil {
+		return err
+	}
+
+	if _, err := p.DB.Exec("UPDATE archive SET storage_path=$1 WHERE sha256=$2 AND (storage_path IS NULL OR storage_path='')",
+		fmt.Sprintf("s3://%s/%x", p.bucket, info.Sha256[:]), info.Sha256[:]); err != nil {
+		return errors.Wrapf(err, "error updating storage path of archive %x", info.Sha256[:])
+	}
+
+	return nil
+}
+
+// DownloadArchive retrieves an archive's contents from archive storage
+func (p *ArchiveController) DownloadArchive(sha256 file.Sha256) (*file.File, error) {
+	return p.archiveStorage.Retrieve(sha256)
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Actions of entries
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionAlias  = "alias"
+	ActionDelete = "delete"
+	ActionRevert = "revert"
+	ActionMerge  = "merge"
+	ActionRelate = "relate"
+)
+
+// Fields of changes that are not columns
+const (
+	FieldAlias    = "alias"    // key is the alias
+	FieldDocument = "document" // key is the document key, and title its title if it has one
+	FieldPart     = "part"     // key is the id of a part listed by a partlist
+	FieldMerge    = "merge"    // key is the id of the other part of a merge, the part removed for the part kept, and the part kept for the part removed
+	FieldRelation = "relation" // key is the relationship type and the id of the related part, and old and new the comment and metadata of the relationship as JSON
+)
+
+// Change is a field-level diff, old or new are nil if the field had or has no value
+type Change struct {
+	Field string  `json:"field"`
+	Key   string  `json:"key,omitempty"`
+	Title *string `json:"title,omitempty"`
+	Old   *string `json:"old"`
+	New   *string `json:"new"`
+}
+
+// Changes are the changes of an entry
+type Changes []Change
+
+func (changes *Changes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*changes = make(Changes, 0)
+		return nil
+	case []byte:
+		return json.Unmarshal(v, changes)
+	case string:
+		return json.Unmarshal([]byte(v), changes)
+	default:
+		return errors.Errorf("failed to scan changes from %T", value)
+	}
+}
+
+// Entry is a revision of a part or a partlist
+type Entry struct {
+	ID         int64         `db:"id"`
+	PartID     uuid.NullUUID `db:"part_id"`
+	PartListID sql.NullInt64 `db:"partlist_id"`
+	Revision   int64         `db:"revision"`
+	Action     string        `db:"action"`
+	Actor      string        `db:"actor"`
+	Changes    Changes       `db:"changes"`
+	InsertDate time.Time     `db:"insert_date"`
+}
+
+// Diff returns the change of a field from old to new, or nil if it did not change
+func Diff(field string, old *string, new *string) *Change {
+	if old == nil && new == nil || old != nil && new != nil && *old == *new {
+		return nil
+	}
+
+	return &Change{Field: field, Old: old, New: new}
+}
+
+// RecordPart appends the next revision of a part to the audit log, in the transaction making the changes.
+// The part is locked until the transaction ends, and nothing is recorded if there are no changes.
+func RecordPart(tx *sqlx.Tx, actor string, partID uuid.UUID, action string, changes []Change) (*Entry, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec("SELECT 1 FROM part WHERE part_id=$1 FOR UPDATE", partID); err != nil {
+		return nil, errors.Wrapf(err, "error locking part %s", partID.String())
+	}
+
+	return record(tx, actor, "part_id", partID, action, changes)
+}
+
+// RecordPartList appends the next revision of a partlist to the audit log, in the transaction making the changes.
+// The partlist is locked until the transaction ends, and nothing is recorded if there are no changes.
+func RecordPartList(tx *sqlx.Tx, actor string, partlistID int64, action string, changes []Change) (*Entry, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec("SELECT 1 FROM partlist WHERE id=$1 FOR UPDATE", partlistID); err != nil {
+		return nil, errors.Wrapf(err, "error locking partlist %d", partlistID)
+	}
+
+	return record(tx, actor, "partlist_id", partlistID, action, changes)
+}
+
+func record(tx *sqlx.Tx, actor string, column string, id any, action string, changes []Change) (*Entry, error) {
+	if actor == "" {
+		actor = System
+	}
+
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling changes")
+	}
+
+	var ret Entry
+	if err := tx.QueryRowx(`INSERT INTO audit_log (`+column+`, revision, action, actor, changes)
+	SELECT $1, COALESCE(MAX(revision), 0)+1, $2, $3, $4 FROM audit_log WHERE `+column+`=$1
+	RETURNING *`,
+		id, action, actor, b).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "error inserting audit_log")
+	}
+
+	return &ret, nil
+}
+
+// GetPartHistory returns the revisions of a part, oldest first
+func GetPartHistory(db *sqlx.DB, partID uuid.UUID) ([]Entry, error) {
+	ret := make([]Entry, 0)
+	if err := db.Select(&ret, "SELECT * FROM audit_log WHERE part_id=$1 ORDER BY revision", partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting history of part %s", partID.String())
+	}
+
+	return ret, nil
+}
+
+// GetPartListHistory returns the revisions of a partlist, oldest first
+func GetPartListHistory(db *sqlx.DB, partlistID int64) ([]Entry, error) {
+	ret := make([]Entry, 0)
+	if err := db.Select(&ret, "SELECT * FROM audit_log WHERE partlist_id=$1 ORDER BY revision", partlistID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting history of partlist %d", partlistID)
+	}
+
+	return ret, nil
+}
+
+// changeKey identifies what a change is to, the same field of the same alias or document
+func changeKey(change Change) string {
+	key := change.Field + "\x00" + change.Key
+	if change.Title != nil {
+		key += "\x00" + *change.Title
+	}
+
+	return key
+}
+
+// Revert returns the changes undoing every revision after the given one, oldest changed fields first.
+// Old is the value the log last recorded, and New the value the field had at the revision.
+func Revert(entries []Entry, revision int64) []Change {
+	reverted := make(map[string]*Change)
+	order := make([]string, 0)
+	for _, entry := range entries {
+		if entry.Revision <= revision {
+			continue
+		}
+
+		for _, change := range entry.Changes {
+			key := changeKey(change)
+			if v, ok := reverted[key]; ok {
+				v.Old = change.New // the latest value
+				continue
+			}
+
+			reverted[key] = &Change{Field: change.Field, Key: change.Key, Title: change.Title, Old: change.New, New: change.Old}
+			order = append(order, key)
+		}
+	}
+
+	ret := make([]Change, 0, len(order))
+	for _, key := range order {
+		if v := reverted[key]; Diff(v.Field, v.Old, v.New) != nil {
+			ret = append(ret, *v)
+		}
+	}
+	return ret
+}
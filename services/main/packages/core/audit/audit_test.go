@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRevert(t *testing.T) {
+	s := func(v string) *string { return &v }
+	entries := []Entry{
+		{Revision: 1, Action: ActionCreate, Changes: Changes{{Field: "name", New: s("busybox")}}},
+		{Revision: 2, Action: ActionUpdate, Changes: Changes{{Field: "license", New: s("GPL-2.0-only")}}},
+		{Revision: 3, Action: ActionUpdate, Changes: Changes{{Field: "license", Old: s("GPL-2.0-only"), New: s("MIT")}}},
+		{Revision: 4, Action: ActionAlias, Changes: Changes{{Field: FieldAlias, Key: "bb", New: s("bb")}}},
+		{Revision: 5, Action: ActionUpdate, Changes: Changes{{Field: "license", Old: s("MIT"), New: s("GPL-2.0-only")}}},
+	}
+
+	tests := []struct {
+		name     string
+		revision int64
+		want     []Change
+	}{
+		{name: "latest", revision: 5, want: []Change{}},
+		{name: "field changed back", revision: 2, want: []Change{
+			{Field: FieldAlias, Key: "bb", Old: s("bb")},
+		}},
+		{name: "field changed twice", revision: 3, want: []Change{
+			{Field: FieldAlias, Key: "bb", Old: s("bb")},
+			{Field: "license", Old: s("GPL-2.0-only"), New: s("MIT")},
+		}},
+		{name: "before creation", revision: 0, want: []Change{
+			{Field: "name", Old: s("busybox")},
+			{Field: "license", Old: s("GPL-2.0-only")},
+			{Field: FieldAlias, Key: "bb", Old: s("bb")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Revert(entries, tt.revision); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Revert() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	s := func(v string) *string { return &v }
+	if Diff("name", nil, nil) != nil || Diff("name", s("a"), s("a")) != nil {
+		t.Errorf("Diff() of unchanged field is not nil")
+	}
+	if got := Diff("name", s("a"), nil); got == nil || *got.Old != "a" || got.New != nil {
+		t.Errorf("Diff() = %+v, want a to nil", got)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package audit
+
+import (
+	"context"
+)
+
+type Key int
+
+// ActorKey guarentees uniqueness for use as a context value key.
+const ActorKey Key = iota
+
+// Actors of changes not made by a named user
+const (
+	Anonymous = "anonymous" // a request without a credential
+	System    = "system"    // a change not made by a request, such as archive processing
+)
+
+// ContextWithActor returns a context changes made with are attributed to actor
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ActorKey, actor)
+}
+
+// GetActor returns who changes made with the context are attributed to, Anonymous if no one
+func GetActor(ctx context.Context) string {
+	if actor, ok := ctx.Value(ActorKey).(string); ok && actor != "" {
+		return actor
+	}
+
+	return Anonymous
+}
@@ -0,0 +1,3 @@
+// audit keeps the append-only history of changes to the curated data of parts and partlists.
+// Every change is written as a numbered revision of field-level diffs, in the same transaction as the change itself, along with who made it and when.
+package audit
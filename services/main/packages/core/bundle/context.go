@@ -0,0 +1,38 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bundle
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+type Key int
+
+// BundleKey guarentees uniqueness for use as a context value key.
+const BundleKey Key = iota
+
+// Get BundleController or return an error
+func GetBundleController(ctx context.Context) (*BundleController, error) {
+	switch contextValue := ctx.Value(BundleKey).(type) {
+	case *BundleController:
+		if contextValue == nil {
+			return nil, errors.New("BundleController is nil")
+		}
+
+		return contextValue, nil
+	case nil: // not found
+		return nil, errors.New("BundleController not found")
+	default:
+		return nil, errors.Wrapf(errors.New("unexpected type"), "got %#v", contextValue)
+	}
+}
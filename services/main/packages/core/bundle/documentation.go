@@ -0,0 +1,3 @@
+// bundle contains the controller for building corresponding-source bundles of a part list.
+// A bundle is a tarball of the original archive, or the files, of every part with a copyleft license, along with a manifest, checksums, and license texts.
+package bundle
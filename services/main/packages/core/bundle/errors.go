@@ -0,0 +1,18 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bundle
+
+import "fmt"
+
+var ErrNotFound = fmt.Errorf("source bundle not found")
+
+// ErrRunning is returned when building a bundle that is already being built
+var ErrRunning = fmt.Errorf("source bundle is already being built")
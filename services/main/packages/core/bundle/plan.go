@@ -0,0 +1,351 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"wrs/tk/packages/blob/file"
+	"wrs/tk/packages/core/license"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
+
+	"github.com/pkg/errors"
+)
+
+// Sources of bundle members
+const (
+	SourceArchive = "archive" // the original archive of a part
+	SourceFile    = "file"    // a file of a part rebuilt from file storage, used when no archive is stored
+	SourceLicense = "license" // the text of a license from the registry
+)
+
+// member is a file to be written into the bundle
+type member struct {
+	Path   string
+	PartID *part.ID
+	Source string
+	Size   int64
+	open   func() (io.ReadCloser, error)
+}
+
+// selectedPart is a part whose source is delivered by the bundle
+type selectedPart struct {
+	Part      part.Part
+	Directory string
+	Source    string
+}
+
+// plan is everything a bundle will contain, in the order it is written
+type plan struct {
+	PartList partlist.PartList
+	Licenses []string
+	Parts    []selectedPart
+	Members  []member
+}
+
+// planner selects the parts of a part list with a copyleft license, and lists the members of their bundle
+type planner struct {
+	controller *BundleController
+	patterns   []string
+
+	resolved map[string]string
+	visited  map[part.ID]bool
+	plan     plan
+}
+
+// matches returns true if any license in the expression matches one of the copyleft patterns.
+// Every option of a choice is considered, so sources are included whenever a copyleft license may apply.
+func (p *planner) matches(expression string) (bool, error) {
+	if strings.TrimSpace(expression) == "" {
+		return false, nil
+	}
+
+	identifiers := []string{expression}
+	if parsed, err := license.ParseExpression(expression); err == nil {
+		identifiers = parsed.Licenses()
+	}
+
+	for _, identifier := range identifiers {
+		licenseID, ok := p.resolved[identifier]
+		if !ok {
+			l, err := p.controller.LicenseController.GetByIdentifier(identifier)
+			if err == nil {
+				licenseID = l.LicenseID
+			} else if err == license.ErrNotFound {
+				licenseID = identifier
+			} else {
+				return false, err
+			}
+			p.resolved[identifier] = licenseID
+		}
+
+		for _, pattern := range p.patterns {
+			if license.MatchLicense(pattern, licenseID) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// visitPartList selects parts from the part list, then from each nested part list
+func (p *planner) visitPartList(list partlist.PartList, visitedLists map[int64]bool) error {
+	if visitedLists[list.ID] {
+		return nil
+	}
+	visitedLists[list.ID] = true
+
+	parts, err := p.controller.PartListController.GetParts(list.ID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting parts of partlist %d", list.ID)
+	}
+	for _, v := range parts {
+		if err := p.visitPart(*v); err != nil {
+			return err
+		}
+	}
+
+	children, err := p.controller.PartListController.GetByParentID(list.ID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting children of partlist %d", list.ID)
+	}
+	for _, child := range children {
+		if err := p.visitPartList(child, visitedLists); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// visitPart selects the part if its license matches, otherwise it looks for matching sub-parts.
+// The source of a selected part already contains its sub-parts, so they are not visited.
+func (p *planner) visitPart(prt part.Part) error {
+	if p.visited[prt.PartID] {
+		return nil
+	}
+	p.visited[prt.PartID] = true
+
+	matches, err := p.matches(prt.License.String)
+	if err != nil {
+		return err
+	}
+	if matches {
+		return p.selectPart(prt)
+	}
+
+	subParts, err := p.controller.PartController.SubParts(prt.PartID)
+	if err != nil {
+		return err
+	}
+	for _, v := range subParts {
+		subPart, err := p.controller.PartController.GetByID(v.ID)
+		if err != nil {
+			return errors.Wrapf(err, "error getting sub-part %s", v.ID.String())
+		}
+
+		if err := p.visitPart(*subPart); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// partDirectory names the directory a part's source is written to, unique by including the start of the part id
+func partDirectory(prt part.Part) string {
+	name := prt.Label.String
+	if name == "" {
+		name = strings.Trim(prt.Name.String+"-"+prt.Version.String, "-")
+	}
+	if name == "" {
+		name = "part"
+	}
+
+	return path.Join("sources", fmt.Sprintf("%s-%s", sanitize(name), prt.PartID.String()[:8]))
+}
+
+// sanitize makes a name safe to use as a single path component
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', 0:
+			return '_'
+		}
+		return r
+	}, strings.TrimLeft(name, "."))
+}
+
+// selectPart adds the part's original archive, or failing that its files, as members
+func (p *planner) selectPart(prt part.Part) error {
+	selected := selectedPart{
+		Part:      prt,
+		Directory: partDirectory(prt),
+	}
+	partID := prt.PartID
+
+	archives, err := p.controller.ArchiveController.GetByPart(prt.PartID)
+	if err != nil {
+		return err
+	}
+	for _, v := range archives {
+		if !v.StoragePath.Valid || v.StoragePath.String == "" {
+			continue
+		}
+
+		arch := v
+		name := fmt.Sprintf("%x", arch.Sha256[:])
+		if len(arch.Aliases) > 0 {
+			name = sanitize(arch.Aliases[0])
+		}
+
+		selected.Source = SourceArchive
+		p.plan.Members = append(p.plan.Members, member{
+			Path:   path.Join(selected.Directory, name),
+			PartID: &partID,
+			Source: SourceArchive,
+			Size:   arch.Size,
+			open: func() (io.ReadCloser, error) {
+				return p.controller.ArchiveController.Download(&arch)
+			},
+		})
+		break
+	}
+
+	if selected.Source == "" {
+		files, err := p.controller.partFiles(prt.PartID)
+		if err != nil {
+			return err
+		}
+
+		selected.Source = SourceFile
+		for _, v := range files {
+			sha256 := v.Sha256
+			p.plan.Members = append(p.plan.Members, member{
+				Path:   path.Join(selected.Directory, v.Path),
+				PartID: &partID,
+				Source: SourceFile,
+				Size:   v.Size,
+				open: func() (io.ReadCloser, error) {
+					return p.controller.ArchiveController.DownloadFile(file.Sha256(sha256))
+				},
+			})
+		}
+	}
+
+	p.plan.Parts = append(p.plan.Parts, selected)
+	return nil
+}
+
+// addLicenseTexts adds the registered text of every license of the selected parts
+func (p *planner) addLicenseTexts() error {
+	seen := make(map[string]bool)
+	for _, selected := range p.plan.Parts {
+		if !selected.Part.License.Valid || strings.TrimSpace(selected.Part.License.String) == "" {
+			continue
+		}
+
+		licenses, err := p.controller.LicenseController.GetByLicenseExpression(selected.Part.License.String)
+		if err != nil {
+			continue // unparsable expressions are listed as is in the manifest
+		}
+
+		for _, v := range licenses {
+			if seen[v.LicenseID] || !v.Text.Valid {
+				continue
+			}
+			seen[v.LicenseID] = true
+
+			text := v.Text.String
+			p.plan.Members = append(p.plan.Members, member{
+				Path:   path.Join("licenses", sanitize(v.LicenseID)+".txt"),
+				Source: SourceLicense,
+				Size:   int64(len(text)),
+				open: func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader(text)), nil
+				},
+			})
+		}
+	}
+
+	return nil
+}
+
+// buildPlan lists every member of the part list's bundle, in the order they are written
+func (controller *BundleController) buildPlan(partlistID int64, patterns []string) (*plan, error) {
+	list, err := controller.PartListController.GetByID(partlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := planner{
+		controller: controller,
+		patterns:   patterns,
+		resolved:   make(map[string]string),
+		visited:    make(map[part.ID]bool),
+		plan: plan{
+			PartList: *list,
+			Licenses: patterns,
+			Parts:    make([]selectedPart, 0),
+			Members:  make([]member, 0),
+		},
+	}
+
+	if err := p.visitPartList(*list, make(map[int64]bool)); err != nil {
+		return nil, err
+	}
+	if err := p.addLicenseTexts(); err != nil {
+		return nil, err
+	}
+
+	return &p.plan, nil
+}
+
+// partFile is a file of a part or its sub-parts, with its path from the root of the part
+type partFile struct {
+	Path   string `db:"path"`
+	Sha256 []byte `db:"sha256"`
+	Size   int64  `db:"file_size"`
+}
+
+// partFiles lists the files of the part and all of its sub-parts, with sub-part paths used as directories
+func (controller *BundleController) partFiles(partID part.ID) ([]partFile, error) {
+	rows, err := controller.DB.Queryx(`WITH RECURSIVE tree(part_id, prefix) AS (
+		SELECT $1::UUID, ''::TEXT
+		UNION SELECT part_has_part.child_id, tree.prefix || part_has_part.path || '/'
+		FROM part_has_part INNER JOIN tree ON part_has_part.parent_id=tree.part_id
+	)
+	SELECT DISTINCT tree.prefix || part_has_file.path AS path, file.sha256, file.file_size
+	FROM tree
+	INNER JOIN part_has_file ON part_has_file.part_id=tree.part_id
+	INNER JOIN file ON file.sha256=part_has_file.file_sha256
+	ORDER BY path`, partID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting files of part %s", partID.String())
+	}
+	defer rows.Close()
+
+	ret := make([]partFile, 0)
+	for rows.Next() {
+		var tmp partFile
+		if err := rows.StructScan(&tmp); err != nil {
+			return nil, errors.Wrapf(err, "error scanning files of part %s", partID.String())
+		}
+
+		ret = append(ret, tmp)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bundle
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"wrs/tk/packages/core/archive"
+	"wrs/tk/packages/core/license"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Statuses of a bundle
+const (
+	StatusPending  = "pending"
+	StatusRunning  = "running"
+	StatusComplete = "complete"
+	StatusFailed   = "failed"
+)
+
+// Licenses is stored as a JSONB array
+type Licenses []string
+
+// Scan implements database/sql.scanner interface
+func (licenses *Licenses) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*licenses = make(Licenses, 0)
+		return nil
+	case []byte:
+		return json.Unmarshal(v, licenses)
+	case string:
+		return json.Unmarshal([]byte(v), licenses)
+	default:
+		return errors.Errorf("failed to scan licenses from %T", value)
+	}
+}
+
+// Value implements database/sql/driver.Valuer interface
+func (licenses Licenses) Value() (driver.Value, error) {
+	if licenses == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(licenses)
+}
+
+// Bundle is a corresponding-source bundle of a part list
+type Bundle struct {
+	ID          int64          `db:"id"`
+	PartListID  sql.NullInt64  `db:"partlist_id"`
+	Licenses    Licenses       `db:"licenses"`
+	Status      string         `db:"status"`
+	StoragePath sql.NullString `db:"storage_path"`
+	Size        int64          `db:"size"`
+	Error       sql.NullString `db:"error"`
+	InsertDate  time.Time      `db:"insert_date"`
+	UpdateDate  time.Time      `db:"update_date"`
+}
+
+type BundleController struct {
+	DB                 *sqlx.DB
+	ArchiveController  *archive.ArchiveController
+	PartController     part.PartController
+	PartListController partlist.PartListController
+	LicenseController  *license.LicenseController
+
+	directory string
+	copyleft  []string
+
+	lock    sync.Mutex
+	running map[int64]bool
+}
+
+// NewBundleController creates a controller writing bundles into directory, and selecting parts by the default copyleft licenses when a bundle does not list its own
+func NewBundleController(db *sqlx.DB, archiveController *archive.ArchiveController, licenseController *license.LicenseController, directory string, copyleft []string) *BundleController {
+	return &BundleController{
+		DB:                 db,
+		ArchiveController:  archiveController,
+		PartController:     part.PartController{DB: db},
+		PartListController: partlist.PartListController{DB: db},
+		LicenseController:  licenseController,
+		directory:          directory,
+		copyleft:           copyleft,
+		running:            make(map[int64]bool),
+	}
+}
+
+// GetByID returns the bundle with the given id
+func (controller *BundleController) GetByID(id int64) (*Bundle, error) {
+	var ret Bundle
+	if err := controller.DB.QueryRowx("SELECT * FROM source_bundle WHERE id=$1", id).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting source bundle %d", id)
+	}
+
+	return &ret, nil
+}
+
+// CreateBundle records a new bundle of the part list, and starts building it in the background.
+// If licenses is empty, the configured copyleft licenses are used.
+func (controller *BundleController) CreateBundle(partlistID int64, licenses []string) (*Bundle, error) {
+	if _, err := controller.PartListController.GetByID(partlistID); err != nil {
+		return nil, err
+	}
+	if len(licenses) == 0 {
+		licenses = controller.copyleft
+	}
+	if len(licenses) == 0 {
+		return nil, errors.New("no copyleft licenses given or configured")
+	}
+
+	var ret Bundle
+	if err := controller.DB.QueryRowx("INSERT INTO source_bundle (partlist_id, licenses) VALUES ($1, $2) RETURNING *",
+		partlistID, Licenses(licenses)).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "error inserting source bundle")
+	}
+
+	return controller.Start(ret.ID)
+}
+
+// Start builds the bundle in the background, continuing from where a previous attempt stopped.
+// A complete bundle is returned as is.
+func (controller *BundleController) Start(id int64) (*Bundle, error) {
+	bundle, err := controller.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Status == StatusComplete {
+		return bundle, nil
+	}
+
+	controller.lock.Lock()
+	if controller.running[id] {
+		controller.lock.Unlock()
+		return nil, ErrRunning
+	}
+	controller.running[id] = true
+	controller.lock.Unlock()
+
+	if err := controller.setStatus(id, StatusRunning, nil); err != nil {
+		controller.release(id)
+		return nil, err
+	}
+
+	go func() {
+		defer controller.release(id)
+
+		if err := controller.build(*bundle); err != nil {
+			log.Error().Err(err).Int64("bundle_id", id).Msg("error building source bundle")
+			message := err.Error()
+			if err := controller.setStatus(id, StatusFailed, &message); err != nil {
+				log.Error().Err(err).Int64("bundle_id", id).Msg("error marking source bundle as failed")
+			}
+			return
+		}
+
+		if err := controller.setStatus(id, StatusComplete, nil); err != nil {
+			log.Error().Err(err).Int64("bundle_id", id).Msg("error marking source bundle as complete")
+		}
+	}()
+
+	return controller.GetByID(id)
+}
+
+func (controller *BundleController) release(id int64) {
+	controller.lock.Lock()
+	delete(controller.running, id)
+	controller.lock.Unlock()
+}
+
+func (controller *BundleController) setStatus(id int64, status string, message *string) error {
+	if _, err := controller.DB.Exec("UPDATE source_bundle SET status=$1, error=$2, update_date=NOW() WHERE id=$3",
+		status, message, id); err != nil {
+		return errors.Wrapf(err, "error updating status of source bundle %d", id)
+	}
+
+	return nil
+}
+
+// root is the directory every member of the bundle is written under
+func root(bundle Bundle) string {
+	return fmt.Sprintf("source-bundle-%d", bundle.ID)
+}
+
+// build writes the bundle's tarball, skipping members committed by a previous attempt.
+// The tarball is truncated to the end of the last committed member before continuing.
+func (controller *BundleController) build(bundle Bundle) error {
+	if !bundle.PartListID.Valid {
+		return errors.New("source bundle's partlist was deleted")
+	}
+
+	storagePath := bundle.StoragePath.String
+	if !bundle.StoragePath.Valid {
+		if err := os.MkdirAll(controller.directory, 0755); err != nil {
+			return errors.Wrapf(err, "error creating bundle directory")
+		}
+
+		storagePath = filepath.Join(controller.directory, root(bundle)+".tar")
+		if _, err := controller.DB.Exec("UPDATE source_bundle SET storage_path=$1 WHERE id=$2", storagePath, bundle.ID); err != nil {
+			return errors.Wrapf(err, "error updating storage path of source bundle %d", bundle.ID)
+		}
+	}
+
+	f, err := os.OpenFile(storagePath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %s", storagePath)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "error stating %s", storagePath)
+	}
+
+	// forget members that were committed but did not make it to disk
+	if _, err := controller.DB.Exec("DELETE FROM source_bundle_entry WHERE bundle_id=$1 AND end_offset>$2", bundle.ID, stat.Size()); err != nil {
+		return errors.Wrapf(err, "error deleting lost entries of source bundle %d", bundle.ID)
+	}
+
+	done := make(map[string]Entry)
+	var offset int64
+	rows, err := controller.DB.Queryx("SELECT * FROM source_bundle_entry WHERE bundle_id=$1", bundle.ID)
+	if err != nil {
+		return errors.Wrapf(err, "error selecting entries of source bundle %d", bundle.ID)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry Entry
+		if err := rows.StructScan(&entry); err != nil {
+			return errors.Wrapf(err, "error scanning entry of source bundle %d", bundle.ID)
+		}
+
+		done[entry.Path] = entry
+		if entry.EndOffset > offset {
+			offset = entry.EndOffset
+		}
+	}
+	rows.Close()
+
+	if err := f.Truncate(offset); err != nil {
+		return errors.Wrapf(err, "error truncating %s", storagePath)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "error seeking %s", storagePath)
+	}
+
+	p, err := controller.buildPlan(bundle.PartListID.Int64, bundle.Licenses)
+	if err != nil {
+		return err
+	}
+
+	bw := newBundleWriter(f, offset, root(bundle))
+	entries, err := bw.writeMembers(p.Members, done, func(entry Entry) error {
+		if err := f.Sync(); err != nil {
+			return errors.Wrapf(err, "error syncing %s", storagePath)
+		}
+
+		if _, err := controller.DB.Exec(`INSERT INTO source_bundle_entry (bundle_id, path, part_id, source, sha256, size, end_offset)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			bundle.ID, entry.Path, entry.PartID, entry.Source, entry.Sha256, entry.Size, entry.EndOffset); err != nil {
+			return errors.Wrapf(err, "error inserting entry %s of source bundle %d", entry.Path, bundle.ID)
+		}
+		if _, err := controller.DB.Exec("UPDATE source_bundle SET size=$1, update_date=NOW() WHERE id=$2", entry.EndOffset, bundle.ID); err != nil {
+			return errors.Wrapf(err, "error updating size of source bundle %d", bundle.ID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := bw.finish(p, entries); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Wrapf(err, "error syncing %s", storagePath)
+	}
+
+	if _, err := controller.DB.Exec("UPDATE source_bundle SET size=$1, update_date=NOW() WHERE id=$2", bw.counter.offset, bundle.ID); err != nil {
+		return errors.Wrapf(err, "error updating size of source bundle %d", bundle.ID)
+	}
+
+	return nil
+}
+
+// Open opens the tarball of a complete bundle
+func (controller *BundleController) Open(bundle Bundle) (*os.File, error) {
+	if bundle.Status != StatusComplete || !bundle.StoragePath.Valid {
+		return nil, errors.Errorf("source bundle %d is %s", bundle.ID, bundle.Status)
+	}
+
+	f, err := os.Open(bundle.StoragePath.String)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening source bundle %d", bundle.ID)
+	}
+
+	return f, nil
+}
+
+// Stream writes a bundle of the part list directly to w, without storing or recording it.
+// If licenses is empty, the configured copyleft licenses are used.
+func (controller *BundleController) Stream(w io.Writer, partlistID int64, licenses []string) error {
+	if len(licenses) == 0 {
+		licenses = controller.copyleft
+	}
+
+	p, err := controller.buildPlan(partlistID, licenses)
+	if err != nil {
+		return err
+	}
+
+	bw := newBundleWriter(w, 0, fmt.Sprintf("source-bundle-partlist-%d", partlistID))
+	entries, err := bw.writeMembers(p.Members, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return bw.finish(p, entries)
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Entry is a member that has been written to a bundle
+type Entry struct {
+	BundleID  int64   `db:"bundle_id"`
+	Path      string  `db:"path"`
+	PartID    *string `db:"part_id"`
+	Source    string  `db:"source"`
+	Sha256    []byte  `db:"sha256"`
+	Size      int64   `db:"size"`
+	EndOffset int64   `db:"end_offset"`
+}
+
+// Manifest is written to manifest.json at the end of every bundle
+type Manifest struct {
+	PartListID    int64          `json:"partlist_id"`
+	PartListName  string         `json:"partlist_name"`
+	Licenses      []string       `json:"copyleft_licenses"`
+	GeneratedDate time.Time      `json:"generated_date"`
+	Parts         []ManifestPart `json:"parts"`
+	Files         []ManifestFile `json:"files"`
+}
+
+// ManifestPart is a part whose source was included, and the directory it can be found in
+type ManifestPart struct {
+	PartID    string `json:"part_id"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	License   string `json:"license"`
+	Directory string `json:"directory"`
+	Source    string `json:"source"`
+}
+
+// ManifestFile is a member of the bundle
+type ManifestFile struct {
+	Path   string `json:"path"`
+	PartID string `json:"part_id,omitempty"`
+	Source string `json:"source"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// countingWriter tracks the offset of the tarball, starting from where a resumed bundle was truncated
+type countingWriter struct {
+	w      io.Writer
+	offset int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.offset += int64(n)
+	return n, err
+}
+
+// bundleWriter writes a plan as a tarball under a single root directory
+type bundleWriter struct {
+	root    string
+	counter *countingWriter
+	tw      *tar.Writer
+	now     time.Time
+}
+
+func newBundleWriter(w io.Writer, offset int64, root string) *bundleWriter {
+	counter := &countingWriter{w: w, offset: offset}
+	return &bundleWriter{
+		root:    root,
+		counter: counter,
+		tw:      tar.NewWriter(counter),
+		now:     time.Now().UTC(),
+	}
+}
+
+// writeFile writes a single tar member, returning its sha256
+func (bw *bundleWriter) writeFile(name string, size int64, r io.Reader) ([]byte, error) {
+	if err := bw.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(bw.root, name),
+		Size:     size,
+		Mode:     0644,
+		ModTime:  bw.now,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return nil, errors.Wrapf(err, "error writing header of %s", name)
+	}
+
+	hasher := sha256.New()
+	n, err := io.Copy(bw.tw, io.TeeReader(r, hasher))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error writing %s", name)
+	}
+	if n != size {
+		return nil, errors.Errorf("wrote %d bytes of %s, expected %d", n, name, size)
+	}
+
+	// write the member's padding so the offset is at a member boundary
+	if err := bw.tw.Flush(); err != nil {
+		return nil, errors.Wrapf(err, "error flushing %s", name)
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+// writeMembers writes every member of the plan that is not already done, calling commit after each is written.
+// It returns every entry of the bundle in plan order.
+func (bw *bundleWriter) writeMembers(members []member, done map[string]Entry, commit func(Entry) error) ([]Entry, error) {
+	ret := make([]Entry, 0, len(members))
+	for _, m := range members {
+		if entry, ok := done[m.Path]; ok {
+			ret = append(ret, entry)
+			continue
+		}
+
+		r, err := m.open()
+		if err != nil {
+			return ret, errors.Wrapf(err, "error opening %s", m.Path)
+		}
+		sum, err := bw.writeFile(m.Path, m.Size, r)
+		r.Close()
+		if err != nil {
+			return ret, err
+		}
+
+		entry := Entry{
+			Path:      m.Path,
+			Source:    m.Source,
+			Sha256:    sum,
+			Size:      m.Size,
+			EndOffset: bw.counter.offset,
+		}
+		if m.PartID != nil {
+			partID := m.PartID.String()
+			entry.PartID = &partID
+		}
+		if commit != nil {
+			if err := commit(entry); err != nil {
+				return ret, err
+			}
+		}
+
+		ret = append(ret, entry)
+	}
+
+	return ret, nil
+}
+
+// finish writes manifest.json and SHA256SUMS, then closes the tarball
+func (bw *bundleWriter) finish(p *plan, entries []Entry) error {
+	manifest := Manifest{
+		PartListID:    p.PartList.ID,
+		PartListName:  p.PartList.Name,
+		Licenses:      p.Licenses,
+		GeneratedDate: bw.now,
+		Parts:         make([]ManifestPart, 0, len(p.Parts)),
+		Files:         make([]ManifestFile, 0, len(entries)),
+	}
+	for _, v := range p.Parts {
+		manifest.Parts = append(manifest.Parts, ManifestPart{
+			PartID:    v.Part.PartID.String(),
+			Name:      v.Part.Name.String,
+			Version:   v.Part.Version.String,
+			License:   v.Part.License.String,
+			Directory: v.Directory,
+			Source:    v.Source,
+		})
+	}
+
+	var sums bytes.Buffer
+	for _, v := range entries {
+		file := ManifestFile{
+			Path:   v.Path,
+			Source: v.Source,
+			Size:   v.Size,
+			Sha256: hex.EncodeToString(v.Sha256),
+		}
+		if v.PartID != nil {
+			file.PartID = *v.PartID
+		}
+		manifest.Files = append(manifest.Files, file)
+
+		fmt.Fprintf(&sums, "%s  %s\n", file.Sha256, v.Path)
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "error encoding manifest")
+	}
+
+	if _, err := bw.writeFile("manifest.json", int64(len(encoded)), bytes.NewReader(encoded)); err != nil {
+		return err
+	}
+	if _, err := bw.writeFile("SHA256SUMS", int64(sums.Len()), &sums); err != nil {
+		return err
+	}
+
+	return bw.tw.Close()
+}
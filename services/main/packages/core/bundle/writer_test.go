@@ -0,0 +1,89 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"wrs/tk/packages/core/partlist"
+)
+
+func testMember(name string, content string) member {
+	return member{
+		Path:   name,
+		Source: SourceFile,
+		Size:   int64(len(content)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+}
+
+func TestBundleWriterResume(t *testing.T) {
+	p := &plan{
+		PartList: partlist.PartList{ID: 1, Name: "product"},
+		Licenses: []string{"GPL*"},
+		Members: []member{
+			testMember("sources/a/a.c", "int a;\n"),
+			testMember("sources/b/b.c", "int b;\n"),
+			testMember("licenses/_gpl-2.0.txt", "GNU GENERAL PUBLIC LICENSE\n"),
+		},
+	}
+
+	// first attempt fails while writing the second member
+	var buf bytes.Buffer
+	failure := errors.New("storage unavailable")
+	first := newBundleWriter(&buf, 0, "source-bundle-1")
+	done := make(map[string]Entry)
+	if _, err := first.writeMembers(p.Members, nil, func(entry Entry) error {
+		if len(done) == 1 {
+			return failure
+		}
+		done[entry.Path] = entry
+		return nil
+	}); err != failure {
+		t.Fatalf("writeMembers() error = %v, want %v", err, failure)
+	}
+
+	// resume from the end of the committed member, discarding anything written after it
+	offset := done["sources/a/a.c"].EndOffset
+	resumed := bytes.NewBuffer(buf.Bytes()[:offset])
+	second := newBundleWriter(resumed, offset, "source-bundle-1")
+	entries, err := second.writeMembers(p.Members, done, func(Entry) error { return nil })
+	if err != nil {
+		t.Fatalf("writeMembers() error = %v", err)
+	}
+	if err := second.finish(p, entries); err != nil {
+		t.Fatalf("finish() error = %v", err)
+	}
+	if second.counter.offset != int64(resumed.Len()) {
+		t.Errorf("offset = %d, want %d", second.counter.offset, resumed.Len())
+	}
+
+	names := make([]string, 0)
+	tr := tar.NewReader(resumed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("reading resumed tarball: %v", err)
+		}
+
+		names = append(names, header.Name)
+	}
+
+	want := []string{
+		"source-bundle-1/sources/a/a.c",
+		"source-bundle-1/sources/b/b.c",
+		"source-bundle-1/licenses/_gpl-2.0.txt",
+		"source-bundle-1/manifest.json",
+		"source-bundle-1/SHA256SUMS",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("tarball members = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package diff
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"path"
+	"sort"
+
+	"wrs/tk/packages/blob/file"
+	"wrs/tk/packages/core/archive"
+	"wrs/tk/packages/core/part"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Statuses of file changes
+const (
+	StatusAdded    = "added"
+	StatusRemoved  = "removed"
+	StatusModified = "modified"
+	StatusRenamed  = "renamed" // the same contents at another path
+)
+
+// maxTextSize is the size of the largest files diffed line by line
+const maxTextSize = 1 << 20
+
+// File is a file of a part or its sub-parts, with its path from the root of the part
+type File struct {
+	Path   string `db:"path"`
+	Sha256 []byte `db:"sha256"`
+	Size   int64  `db:"file_size"`
+}
+
+// FileChange is a file added, removed, modified, or renamed from one part to another
+type FileChange struct {
+	Status string
+	Path   string  // path in the part compared to, or in the part compared from if removed
+	Old    *File   // file in the part compared from, nil if added
+	New    *File   // file in the part compared to, nil if removed
+	Text   *string // unified diff of a modified text file, if requested
+}
+
+// LicenseChange is a change of the license of the parts compared, or of their sub-parts at the same path
+type LicenseChange struct {
+	Path string // path of the sub-part, empty for the parts compared
+	Old  *string
+	New  *string
+}
+
+// Diff is the difference of the contents and licenses of two parts
+type Diff struct {
+	From      part.ID
+	To        part.ID
+	Unchanged int64
+	Files     []FileChange
+	Licenses  []LicenseChange
+}
+
+type DiffController struct {
+	DB                *sqlx.DB
+	ArchiveController *archive.ArchiveController
+}
+
+// Compare returns the files added, removed, modified, and renamed from one part to another, across the parts and their sub-parts, and their license changes.
+// If text is true, modified text files up to maxTextSize are diffed line by line from file storage.
+func (controller *DiffController) Compare(fromID part.ID, toID part.ID, text bool) (*Diff, error) {
+	partController := part.PartController{DB: controller.DB}
+	from, err := partController.GetByID(fromID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "part %s", fromID.String())
+	}
+	to, err := partController.GetByID(toID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "part %s", toID.String())
+	}
+
+	fromFiles, err := controller.files(from.PartID)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := controller.files(to.PartID)
+	if err != nil {
+		return nil, err
+	}
+	fromLicenses, err := controller.licenses(from.PartID)
+	if err != nil {
+		return nil, err
+	}
+	toLicenses, err := controller.licenses(to.PartID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := Diff{From: from.PartID, To: to.PartID, Licenses: compareLicenses(fromLicenses, toLicenses)}
+	ret.Files, ret.Unchanged = compareFiles(fromFiles, toFiles)
+	if text {
+		for i, change := range ret.Files {
+			if change.Status != StatusModified || change.Old.Size > maxTextSize || change.New.Size > maxTextSize {
+				continue
+			}
+
+			if ret.Files[i].Text, err = controller.textDiff(change); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &ret, nil
+}
+
+// files lists the files of the part and all of its sub-parts, with sub-part paths used as directories
+func (controller *DiffController) files(partID part.ID) ([]File, error) {
+	ret := make([]File, 0)
+	if err := controller.DB.Select(&ret, `WITH RECURSIVE tree(part_id, prefix) AS (
+		SELECT $1::UUID, ''::TEXT
+		UNION SELECT part_has_part.child_id, tree.prefix || part_has_part.path || '/'
+		FROM part_has_part INNER JOIN tree ON part_has_part.parent_id=tree.part_id
+	)
+	SELECT DISTINCT tree.prefix || part_has_file.path AS path, file.sha256, file.file_size
+	FROM tree
+	INNER JOIN part_has_file ON part_has_file.part_id=tree.part_id
+	INNER JOIN file ON file.sha256=part_has_file.file_sha256
+	ORDER BY path`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting files of part %s", partID.String())
+	}
+
+	return ret, nil
+}
+
+// partLicense is the license of a part or one of its sub-parts, with its path from the root of the part
+type partLicense struct {
+	Path    string         `db:"path"`
+	License sql.NullString `db:"license"`
+}
+
+// licenses lists the licenses of the part and all of its sub-parts
+func (controller *DiffController) licenses(partID part.ID) ([]partLicense, error) {
+	ret := make([]partLicense, 0)
+	if err := controller.DB.Select(&ret, `WITH RECURSIVE tree(part_id, prefix) AS (
+		SELECT $1::UUID, ''::TEXT
+		UNION SELECT part_has_part.child_id, tree.prefix || part_has_part.path || '/'
+		FROM part_has_part INNER JOIN tree ON part_has_part.parent_id=tree.part_id
+	)
+	SELECT DISTINCT RTRIM(tree.prefix, '/') AS path, part.license
+	FROM tree INNER JOIN part ON part.part_id=tree.part_id
+	ORDER BY path`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting licenses of part %s", partID.String())
+	}
+
+	return ret, nil
+}
+
+// textDiff returns the unified diff of a modified file, or nil if either version is binary or they are too different
+func (controller *DiffController) textDiff(change FileChange) (*string, error) {
+	from, err := controller.readText(change.Old)
+	if err != nil || from == nil {
+		return nil, err
+	}
+	to, err := controller.readText(change.New)
+	if err != nil || to == nil {
+		return nil, err
+	}
+
+	ret, ok := Unified("a/"+change.Old.Path, "b/"+change.New.Path, *from, *to, 3)
+	if !ok {
+		return nil, nil
+	}
+
+	return &ret, nil
+}
+
+// readText reads a file from file storage, returning nil if it is binary
+func (controller *DiffController) readText(f *File) (*string, error) {
+	r, err := controller.ArchiveController.DownloadFile(file.Sha256(f.Sha256))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving %s", f.Path)
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(io.LimitReader(r, maxTextSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", f.Path)
+	}
+
+	// a NUL byte in the first 8000 bytes is taken as binary, the way git and diff do
+	head := b
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	ret := string(b)
+	return &ret, nil
+}
+
+// compareFiles returns the changes from one list of files to another, sorted by path, and the count of unchanged files.
+// A file only in the second list with the contents of a file only in the first is renamed, preferring files of the same name.
+func compareFiles(from []File, to []File) ([]FileChange, int64) {
+	fromByPath := make(map[string]File, len(from))
+	for _, f := range from {
+		fromByPath[f.Path] = f
+	}
+	toByPath := make(map[string]File, len(to))
+	for _, f := range to {
+		toByPath[f.Path] = f
+	}
+
+	ret := make([]FileChange, 0)
+	var unchanged int64
+	removed := make(map[string][]File) // files only in from, by sha256
+	for _, f := range fromByPath {
+		if _, ok := toByPath[f.Path]; !ok {
+			key := hex.EncodeToString(f.Sha256)
+			removed[key] = append(removed[key], f)
+		}
+	}
+	for _, files := range removed {
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	}
+
+	added := make([]File, 0)
+	for _, t := range toByPath {
+		t := t
+		if f, ok := fromByPath[t.Path]; !ok {
+			added = append(added, t)
+		} else if bytes.Equal(f.Sha256, t.Sha256) {
+			unchanged++
+		} else {
+			ret = append(ret, FileChange{Status: StatusModified, Path: t.Path, Old: &f, New: &t})
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].Path < added[j].Path })
+
+	for _, t := range added {
+		t := t
+		key := hex.EncodeToString(t.Sha256)
+		candidates := removed[key]
+		if len(candidates) == 0 {
+			ret = append(ret, FileChange{Status: StatusAdded, Path: t.Path, New: &t})
+			continue
+		}
+
+		pick := 0
+		for i, c := range candidates {
+			if path.Base(c.Path) == path.Base(t.Path) {
+				pick = i
+				break
+			}
+		}
+		f := candidates[pick]
+		removed[key] = append(candidates[:pick:pick], candidates[pick+1:]...)
+		ret = append(ret, FileChange{Status: StatusRenamed, Path: t.Path, Old: &f, New: &t})
+	}
+
+	for _, files := range removed {
+		for _, f := range files {
+			f := f
+			ret = append(ret, FileChange{Status: StatusRemoved, Path: f.Path, Old: &f})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Path < ret[j].Path })
+	return ret, unchanged
+}
+
+// compareLicenses returns the license changes of parts at the same path, sorted by path
+func compareLicenses(from []partLicense, to []partLicense) []LicenseChange {
+	licenses := make(map[string]*LicenseChange)
+	paths := make([]string, 0)
+	get := func(p string) *LicenseChange {
+		if _, ok := licenses[p]; !ok {
+			licenses[p] = &LicenseChange{Path: p}
+			paths = append(paths, p)
+		}
+		return licenses[p]
+	}
+	for _, v := range from {
+		if v.License.Valid {
+			license := v.License.String
+			get(v.Path).Old = &license
+		}
+	}
+	for _, v := range to {
+		if v.License.Valid {
+			license := v.License.String
+			get(v.Path).New = &license
+		}
+	}
+
+	sort.Strings(paths)
+	ret := make([]LicenseChange, 0)
+	for _, p := range paths {
+		if v := licenses[p]; v.Old == nil || v.New == nil || *v.Old != *v.New {
+			ret = append(ret, *v)
+		}
+	}
+
+	return ret
+}
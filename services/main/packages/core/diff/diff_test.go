@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareFiles(t *testing.T) {
+	from := []File{
+		{Path: "Makefile", Sha256: []byte{1}},
+		{Path: "README", Sha256: []byte{2}},
+		{Path: "src/old.c", Sha256: []byte{3}},
+		{Path: "src/gone.c", Sha256: []byte{4}},
+		{Path: "a/copy.h", Sha256: []byte{5}},
+		{Path: "b/copy.h", Sha256: []byte{5}},
+	}
+	to := []File{
+		{Path: "Makefile", Sha256: []byte{1}},
+		{Path: "README", Sha256: []byte{6}},
+		{Path: "src/new.c", Sha256: []byte{3}},
+		{Path: "src/added.c", Sha256: []byte{7}},
+		{Path: "c/copy.h", Sha256: []byte{5}},
+	}
+
+	changes, unchanged := compareFiles(from, to)
+	if unchanged != 1 {
+		t.Errorf("expected 1 unchanged file, got %d", unchanged)
+	}
+
+	got := make([][3]string, 0, len(changes))
+	for _, c := range changes {
+		old := ""
+		if c.Old != nil {
+			old = c.Old.Path
+		}
+		got = append(got, [3]string{c.Status, c.Path, old})
+	}
+	want := [][3]string{
+		{StatusModified, "README", "README"},
+		{StatusRemoved, "b/copy.h", "b/copy.h"},
+		{StatusRenamed, "c/copy.h", "a/copy.h"},
+		{StatusAdded, "src/added.c", ""},
+		{StatusRemoved, "src/gone.c", "src/gone.c"},
+		{StatusRenamed, "src/new.c", "src/old.c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCompareLicenses(t *testing.T) {
+	l := func(path string, license string) partLicense {
+		return partLicense{Path: path, License: sql.NullString{String: license, Valid: license != ""}}
+	}
+	from := []partLicense{l("", "GPL-2.0-only"), l("libbb", "GPL-2.0-only"), l("docs", ""), l("old", "MIT")}
+	to := []partLicense{l("", "GPL-2.0-only"), l("libbb", "GPL-2.0-or-later"), l("docs", "CC-BY-4.0")}
+
+	got := make([]string, 0)
+	for _, c := range compareLicenses(from, to) {
+		old, new := "", ""
+		if c.Old != nil {
+			old = *c.Old
+		}
+		if c.New != nil {
+			new = *c.New
+		}
+		got = append(got, c.Path+":"+old+">"+new)
+	}
+	want := []string{"docs:>CC-BY-4.0", "libbb:GPL-2.0-only>GPL-2.0-or-later", "old:MIT>"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "same",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			from: "a\nb\nc\n",
+			to:   "a\nB\nc\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "added to empty",
+			from: "",
+			to:   "a\n",
+			want: "--- a/f\n+++ b/f\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name: "separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n",
+		},
+	}
+
+	for _, test := range tests {
+		got, ok := Unified("a/f", "b/f", test.from, test.to, 1)
+		if !ok {
+			t.Errorf("%s: unexpected failure", test.name)
+		} else if got != test.want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.name, test.want, got)
+		}
+	}
+
+	if _, ok := Unified("a/f", "b/f", strings.Repeat("a\n", maxEdits), strings.Repeat("b\n", maxEdits), 3); ok {
+		t.Errorf("expected texts differing by more than %d lines to fail", maxEdits)
+	}
+}
@@ -0,0 +1,3 @@
+// diff compares the contents of two parts, such as two versions of a package, file by file and license by license.
+// Files are compared across the parts and all of their sub-parts, by path and sha256, and modified text files can be diffed line by line.
+package diff
@@ -0,0 +1,200 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxEdits is the most lines a unified diff may add and remove, beyond which files are too different to diff
+const maxEdits = 2000
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns the unified diff of two texts with context lines around changes, an empty string if they are the same.
+// False is returned if the texts differ by more than maxEdits lines.
+func Unified(fromName string, toName string, from string, to string, context int) (string, bool) {
+	ops, ok := editScript(splitLines(from), splitLines(to))
+	if !ok {
+		return "", false
+	}
+
+	hunks := make([]string, 0)
+	for start := 0; start < len(ops); {
+		// find the next change, and every change within twice the context of it
+		first := start
+		for first < len(ops) && ops[first].kind == opEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first + 1; i < len(ops) && i <= last+2*context+1; i++ {
+			if ops[i].kind != opEqual {
+				last = i
+			}
+		}
+
+		begin, end := first-context, last+context+1
+		if begin < 0 {
+			begin = 0
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		hunks = append(hunks, hunk(ops, begin, end))
+		start = end
+	}
+	if len(hunks) == 0 {
+		return "", true
+	}
+
+	return fmt.Sprintf("--- %s\n+++ %s\n%s", fromName, toName, strings.Join(hunks, "")), true
+}
+
+// hunk formats the operations from begin to end as a hunk
+func hunk(ops []op, begin int, end int) string {
+	fromLine, toLine := 0, 0
+	for _, o := range ops[:begin] {
+		if o.kind != opInsert {
+			fromLine++
+		}
+		if o.kind != opDelete {
+			toLine++
+		}
+	}
+
+	var body strings.Builder
+	fromCount, toCount := 0, 0
+	for _, o := range ops[begin:end] {
+		if o.kind != opInsert {
+			fromCount++
+		}
+		if o.kind != opDelete {
+			toCount++
+		}
+		body.WriteByte(byte(o.kind))
+		body.WriteString(o.line)
+		body.WriteByte('\n')
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount), body.String())
+}
+
+// hunkRange formats the start and length of a hunk, starts are 1-based unless the hunk is empty on that side
+func hunkRange(line int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line+1)
+	}
+
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
+
+// editScript returns the shortest script of equal, deleted, and inserted lines turning a into b, by Myers' algorithm.
+// False is returned if it is longer than maxEdits.
+func editScript(a []string, b []string) ([]op, bool) {
+	n, m := len(a), len(b)
+	// trace[d] holds the furthest x reached on each diagonal k, -d <= k <= d, with d edits
+	trace := make([][]int, 0)
+	get := func(d int, k int) int {
+		if d < 0 || k < -d || k > d {
+			return 0
+		}
+		return trace[d][k+d]
+	}
+
+	for d := 0; d <= maxEdits; d++ {
+		v := make([]int, 2*d+1)
+		trace = append(trace, v)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && get(d-1, k-1) < get(d-1, k+1) {
+				x = get(d-1, k+1)
+			} else {
+				x = get(d-1, k-1) + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+d] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, get), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// backtrack follows the trace of editScript back from the end of a and b to their start
+func backtrack(a []string, b []string, trace [][]int, get func(int, int) int) []op {
+	ret := make([]op, 0, len(a)+len(b))
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && get(d-1, k-1) < get(d-1, k+1) {
+			prevK = k + 1
+		}
+		prevX := get(d-1, prevK)
+		prevY := prevX - prevK
+		if d == 0 {
+			prevX, prevY = 0, 0
+		}
+
+		for x > prevX && y > prevY {
+			ret = append(ret, op{opEqual, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ret = append(ret, op{opInsert, b[y-1]})
+			} else {
+				ret = append(ret, op{opDelete, a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+
+	return ret
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,3 @@
+// family orders the versions of a part, the parts sharing a family name, by the version scheme of their ecosystem.
+// The scheme of a family is set by curators, or inferred from the purls of its parts, so Debian packages are ordered as dpkg orders them, Python packages by PEP 440, and so on.
+package family
@@ -0,0 +1,222 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package family
+
+import (
+	"database/sql"
+	"sort"
+
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/version"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Family is the versions of a part, the parts sharing a family name, oldest first.
+// Parts of the family without a version are left out.
+type Family struct {
+	Name        string
+	Scheme      string // version scheme the versions are ordered by
+	SchemeIsSet bool   // whether the scheme was set for the family, rather than inferred from the purls of its parts
+	Parts       []part.Part
+}
+
+// Latest returns the latest version of the family, or nil if it has no versions
+func (f Family) Latest() *part.Part {
+	if len(f.Parts) == 0 {
+		return nil
+	}
+
+	return &f.Parts[len(f.Parts)-1]
+}
+
+// Newer returns the versions of the family after a version, oldest first
+func (f Family) Newer(v string) []part.Part {
+	ret := make([]part.Part, 0)
+	for _, p := range f.Parts {
+		if version.CompareScheme(f.Scheme, p.Version.String, v) > 0 {
+			ret = append(ret, p)
+		}
+	}
+
+	return ret
+}
+
+// sortParts sorts parts by version, oldest first, and by id among parts of the same version
+func sortParts(scheme string, parts []part.Part) {
+	sort.SliceStable(parts, func(i, j int) bool {
+		if c := version.CompareScheme(scheme, parts[i].Version.String, parts[j].Version.String); c != 0 {
+			return c < 0
+		}
+
+		return parts[i].PartID.String() < parts[j].PartID.String()
+	})
+}
+
+// Outdated is a part listed by a partlist that is behind the latest version of its family
+type Outdated struct {
+	PartListID int64
+	PartID     part.ID
+	Family     string
+	Latest     part.ID
+}
+
+type FamilyController struct {
+	DB             *sqlx.DB
+	PartController *part.PartController
+}
+
+// Get returns a family with its versions in order
+func (controller FamilyController) Get(name string) (*Family, error) {
+	ret := Family{Name: name, Scheme: version.SchemeGeneric}
+
+	var scheme string
+	if err := controller.DB.Get(&scheme, "SELECT scheme FROM version_family WHERE name=$1", name); err == nil {
+		ret.Scheme, ret.SchemeIsSet = scheme, true
+	} else if err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "error selecting version family %s", name)
+	}
+
+	partIDs := make([]part.ID, 0)
+	if err := controller.DB.Select(&partIDs, "SELECT part_id FROM part WHERE family_name=$1 AND version IS NOT NULL AND version<>''", name); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts of version family %s", name)
+	}
+	if len(partIDs) == 0 && !ret.SchemeIsSet {
+		return nil, ErrFamilyNotFound
+	}
+
+	ret.Parts = make([]part.Part, 0, len(partIDs))
+	for _, partID := range partIDs {
+		p, err := controller.PartController.GetByID(partID)
+		if err != nil {
+			return nil, err
+		}
+
+		ret.Parts = append(ret.Parts, *p)
+	}
+
+	if !ret.SchemeIsSet {
+		inferred, err := controller.inferScheme(name)
+		if err != nil {
+			return nil, err
+		}
+		ret.Scheme = inferred
+	}
+	sortParts(ret.Scheme, ret.Parts)
+
+	return &ret, nil
+}
+
+// inferScheme returns the version scheme of the purl type most parts of a family are identified by, or generic
+func (controller FamilyController) inferScheme(name string) (string, error) {
+	var purlType string
+	if err := controller.DB.Get(&purlType, `SELECT split_part(split_part(i.value, '/', 1), ':', 2) AS purl_type
+	FROM part_identifier i INNER JOIN part p ON p.part_id=i.part_id
+	WHERE p.family_name=$1 AND i.type='purl' AND i.value NOT LIKE 'pkg:generic/%'
+	GROUP BY purl_type ORDER BY COUNT(*) DESC, purl_type LIMIT 1`, name); err == sql.ErrNoRows {
+		return version.SchemeGeneric, nil
+	} else if err != nil {
+		return "", errors.Wrapf(err, "error inferring version scheme of family %s", name)
+	}
+
+	return version.SchemeOf(purlType), nil
+}
+
+// SetScheme sets the version scheme a family is ordered by
+func (controller FamilyController) SetScheme(name string, scheme string) (*Family, error) {
+	valid := false
+	for _, v := range version.Schemes {
+		valid = valid || v == scheme
+	}
+	if !valid {
+		return nil, errors.Wrapf(ErrInvalidScheme, "%s", scheme)
+	}
+
+	if _, err := controller.DB.Exec(`INSERT INTO version_family (name, scheme) VALUES ($1, $2)
+	ON CONFLICT (name) DO UPDATE SET scheme=EXCLUDED.scheme, update_date=NOW()`, name, scheme); err != nil {
+		return nil, errors.Wrapf(err, "error setting version scheme of family %s", name)
+	}
+
+	return controller.Get(name)
+}
+
+// NewerVersions returns the versions of the family of a part after the version of the part, oldest first
+func (controller FamilyController) NewerVersions(partID uuid.UUID) ([]part.Part, error) {
+	p, err := controller.PartController.GetByID(part.ID(partID))
+	if err != nil {
+		return nil, err
+	}
+	if !p.FamilyName.Valid || p.FamilyName.String == "" || p.Version.String == "" {
+		return []part.Part{}, nil
+	}
+
+	f, err := controller.Get(p.FamilyName.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Newer(p.Version.String), nil
+}
+
+// Latest returns the latest version of a family, or nil if it has no versions
+func (controller FamilyController) Latest(name string) (*part.Part, error) {
+	f, err := controller.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Latest(), nil
+}
+
+// Outdated returns the parts listed by partlists that are behind the latest version of their family, by partlist then family.
+// If partlistID is given, only the partlist and the partlists beneath it are reported on.
+func (controller FamilyController) Outdated(partlistID *int64) ([]Outdated, error) {
+	var members []struct {
+		PartListID int64   `db:"partlist_id"`
+		PartID     part.ID `db:"part_id"`
+		Family     string  `db:"family_name"`
+		Version    string  `db:"version"`
+	}
+	query := `SELECT l.partlist_id, p.part_id, p.family_name, p.version FROM partlist_has_part l INNER JOIN part p ON p.part_id=l.part_id
+	WHERE p.family_name IS NOT NULL AND p.family_name<>'' AND p.version IS NOT NULL AND p.version<>''`
+	args := make([]any, 0)
+	if partlistID != nil {
+		query = `WITH RECURSIVE lists AS (
+			SELECT id FROM partlist WHERE id=$1
+			UNION SELECT partlist.id FROM partlist INNER JOIN lists ON partlist.parent_id=lists.id
+		) ` + query + ` AND l.partlist_id IN (SELECT id FROM lists)`
+		args = append(args, *partlistID)
+	}
+	if err := controller.DB.Select(&members, query+" ORDER BY l.partlist_id, p.family_name, p.version", args...); err != nil {
+		return nil, errors.Wrapf(err, "error selecting partlist members with version families")
+	}
+
+	families := make(map[string]*Family)
+	ret := make([]Outdated, 0)
+	for _, m := range members {
+		f, ok := families[m.Family]
+		if !ok {
+			var err error
+			if f, err = controller.Get(m.Family); err != nil {
+				return nil, err
+			}
+			families[m.Family] = f
+		}
+
+		if latest := f.Latest(); latest != nil && version.CompareScheme(f.Scheme, m.Version, latest.Version.String) < 0 {
+			ret = append(ret, Outdated{PartListID: m.PartListID, PartID: m.PartID, Family: m.Family, Latest: latest.PartID})
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,66 @@
+package family
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/version"
+)
+
+func TestFamily(t *testing.T) {
+	versions := func(parts []part.Part) []string {
+		ret := make([]string, 0, len(parts))
+		for _, p := range parts {
+			ret = append(ret, p.Version.String)
+		}
+		return ret
+	}
+
+	tests := []struct {
+		scheme   string
+		versions []string
+		want     []string
+		newer    string
+		wantNew  []string
+	}{
+		{
+			scheme:   version.SchemeDebian,
+			versions: []string{"1.3-1", "1.2.13.dfsg-1", "1:1.0-1", "1.3~rc1-1"},
+			want:     []string{"1.2.13.dfsg-1", "1.3~rc1-1", "1.3-1", "1:1.0-1"},
+			newer:    "1.3~rc1-1",
+			wantNew:  []string{"1.3-1", "1:1.0-1"},
+		},
+		{
+			scheme:   version.SchemePEP440,
+			versions: []string{"2.0", "2.0rc1", "1.10", "2.0.post1"},
+			want:     []string{"1.10", "2.0rc1", "2.0", "2.0.post1"},
+			newer:    "2.0",
+			wantNew:  []string{"2.0.post1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			f := Family{Scheme: tt.scheme}
+			for _, v := range tt.versions {
+				f.Parts = append(f.Parts, part.Part{Version: sql.NullString{String: v, Valid: true}})
+			}
+			sortParts(f.Scheme, f.Parts)
+
+			if got := versions(f.Parts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortParts() = %v, want %v", got, tt.want)
+			}
+			if got := f.Latest().Version.String; got != tt.want[len(tt.want)-1] {
+				t.Errorf("Latest() = %s, want %s", got, tt.want[len(tt.want)-1])
+			}
+			if got := versions(f.Newer(tt.newer)); !reflect.DeepEqual(got, tt.wantNew) {
+				t.Errorf("Newer() = %v, want %v", got, tt.wantNew)
+			}
+		})
+	}
+
+	if (Family{}).Latest() != nil {
+		t.Errorf("Latest() of a family without versions is not nil")
+	}
+}
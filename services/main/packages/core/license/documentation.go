@@ -1,2 +1,3 @@
-// license contains the controller for looking up licenses
+// license contains the controller for the license registry, and parsing of license expressions.
+// Licenses are identified by an internal id derived from their SPDX identifier, and may be looked up by any of their aliases.
 package license
@@ -0,0 +1,254 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package license
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Operators accepted in a license expression, from lowest to highest precedence
+const (
+	OperatorOr   = "OR"
+	OperatorAnd  = "AND"
+	OperatorWith = "WITH"
+)
+
+// Expression is a parsed license expression.
+// A leaf holds a license Identifier, every other node holds an Operator with a Left and Right operand.
+// The Right operand of WITH is an exception identifier rather than a license.
+type Expression struct {
+	Operator   string
+	Identifier string
+	Left       *Expression
+	Right      *Expression
+}
+
+// IsLeaf returns true if the expression is a single identifier
+func (e *Expression) IsLeaf() bool {
+	return e.Operator == ""
+}
+
+// Licenses lists every unique license identifier in the expression, in the order they first appear.
+// Exceptions given to WITH are not licenses and are not listed.
+func (e *Expression) Licenses() []string {
+	ret := make([]string, 0)
+	seen := make(map[string]bool)
+
+	var visit func(node *Expression)
+	visit = func(node *Expression) {
+		if node == nil {
+			return
+		}
+		if node.IsLeaf() {
+			if !seen[node.Identifier] {
+				seen[node.Identifier] = true
+				ret = append(ret, node.Identifier)
+			}
+			return
+		}
+
+		visit(node.Left)
+		if node.Operator != OperatorWith {
+			visit(node.Right)
+		}
+	}
+	visit(e)
+
+	return ret
+}
+
+// String formats the expression, only adding parentheses where precedence requires it
+func (e *Expression) String() string {
+	if e.IsLeaf() {
+		return e.Identifier
+	}
+
+	wrap := func(child *Expression) string {
+		if !child.IsLeaf() && precedence(child.Operator) < precedence(e.Operator) {
+			return "(" + child.String() + ")"
+		}
+
+		return child.String()
+	}
+
+	return wrap(e.Left) + " " + e.Operator + " " + wrap(e.Right)
+}
+
+func precedence(operator string) int {
+	switch operator {
+	case OperatorOr:
+		return 1
+	case OperatorAnd:
+		return 2
+	case OperatorWith:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// ParseExpression parses a license expression such as "_gpl-2.0 AND (_mit OR CUSTOM[foo bar])".
+// Operators are case-insensitive, and CUSTOM[...] identifiers may contain whitespace and parentheses.
+func ParseExpression(expression string) (*Expression, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty license expression")
+	}
+
+	p := expressionParser{tokens: tokens}
+	ret, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.position < len(p.tokens) {
+		return nil, errors.Errorf("unexpected \"%s\" in license expression", p.tokens[p.position])
+	}
+
+	return ret, nil
+}
+
+// tokenizeExpression splits an expression into parentheses, operators, and identifiers
+func tokenizeExpression(expression string) ([]string, error) {
+	tokens := make([]string, 0)
+
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(expression[i:], "CUSTOM["):
+			end := strings.IndexByte(expression[i:], ']')
+			if end < 0 {
+				return nil, errors.Errorf("unterminated CUSTOM identifier in license expression \"%s\"", expression)
+			}
+			tokens = append(tokens, expression[i:i+end+1])
+			i += end + 1
+		default:
+			start := i
+			for i < len(expression) && !strings.ContainsRune(" \t\n\r()", rune(expression[i])) {
+				i++
+			}
+			tokens = append(tokens, expression[start:i])
+		}
+	}
+
+	return tokens, nil
+}
+
+type expressionParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *expressionParser) peekOperator(operator string) bool {
+	return p.position < len(p.tokens) && strings.ToUpper(p.tokens[p.position]) == operator
+}
+
+func (p *expressionParser) parseOr() (*Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekOperator(OperatorOr) {
+		p.position++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expression{Operator: OperatorOr, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (*Expression, error) {
+	left, err := p.parseWith()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekOperator(OperatorAnd) {
+		p.position++
+		right, err := p.parseWith()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expression{Operator: OperatorAnd, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseWith() (*Expression, error) {
+	left, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekOperator(OperatorWith) {
+		if !left.IsLeaf() {
+			return nil, errors.New("WITH must follow a single license identifier")
+		}
+		p.position++
+		exception, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		left = &Expression{Operator: OperatorWith, Left: left, Right: exception}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseAtom() (*Expression, error) {
+	if p.position >= len(p.tokens) {
+		return nil, errors.New("unexpected end of license expression")
+	}
+
+	if p.tokens[p.position] == "(" {
+		p.position++
+		ret, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.position >= len(p.tokens) || p.tokens[p.position] != ")" {
+			return nil, errors.New("missing closing parenthesis in license expression")
+		}
+		p.position++
+
+		return ret, nil
+	}
+
+	return p.parseIdentifier()
+}
+
+func (p *expressionParser) parseIdentifier() (*Expression, error) {
+	if p.position >= len(p.tokens) {
+		return nil, errors.New("unexpected end of license expression")
+	}
+
+	token := p.tokens[p.position]
+	switch strings.ToUpper(token) {
+	case "(", ")", OperatorAnd, OperatorOr, OperatorWith:
+		return nil, errors.Errorf("expected license identifier but found \"%s\"", token)
+	}
+	p.position++
+
+	return &Expression{Identifier: token}, nil
+}
@@ -0,0 +1,128 @@
+package license
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		name         string
+		expression   string
+		wantString   string
+		wantLicenses []string
+		wantErr      bool
+	}{
+		{
+			name:         "single license",
+			expression:   "_mit",
+			wantString:   "_mit",
+			wantLicenses: []string{"_mit"},
+		},
+		{
+			name:         "and binds tighter than or",
+			expression:   "_gpl-2.0 OR _mit AND _bsd-3-clause",
+			wantString:   "_gpl-2.0 OR _mit AND _bsd-3-clause",
+			wantLicenses: []string{"_gpl-2.0", "_mit", "_bsd-3-clause"},
+		},
+		{
+			name:         "parentheses kept where required",
+			expression:   "(_gpl-2.0 OR _mit) AND _bsd-3-clause",
+			wantString:   "(_gpl-2.0 OR _mit) AND _bsd-3-clause",
+			wantLicenses: []string{"_gpl-2.0", "_mit", "_bsd-3-clause"},
+		},
+		{
+			name:         "redundant parentheses dropped",
+			expression:   "((_mit))",
+			wantString:   "_mit",
+			wantLicenses: []string{"_mit"},
+		},
+		{
+			name:         "lower case operators",
+			expression:   "GPL-2.0-only or MIT",
+			wantString:   "GPL-2.0-only OR MIT",
+			wantLicenses: []string{"GPL-2.0-only", "MIT"},
+		},
+		{
+			name:         "with exception is not a license",
+			expression:   "GPL-2.0-or-later WITH Classpath-exception-2.0 AND MIT",
+			wantString:   "GPL-2.0-or-later WITH Classpath-exception-2.0 AND MIT",
+			wantLicenses: []string{"GPL-2.0-or-later", "MIT"},
+		},
+		{
+			name:         "custom license with spaces",
+			expression:   "CUSTOM[Foo Corp (internal)] OR _mit",
+			wantString:   "CUSTOM[Foo Corp (internal)] OR _mit",
+			wantLicenses: []string{"CUSTOM[Foo Corp (internal)]", "_mit"},
+		},
+		{
+			name:         "duplicate licenses listed once",
+			expression:   "_mit AND (_mit OR _isc)",
+			wantString:   "_mit AND (_mit OR _isc)",
+			wantLicenses: []string{"_mit", "_isc"},
+		},
+		{
+			name:       "empty",
+			expression: "  ",
+			wantErr:    true,
+		},
+		{
+			name:       "dangling operator",
+			expression: "_mit AND",
+			wantErr:    true,
+		},
+		{
+			name:       "missing closing parenthesis",
+			expression: "(_mit OR _isc",
+			wantErr:    true,
+		},
+		{
+			name:       "unterminated custom",
+			expression: "CUSTOM[foo",
+			wantErr:    true,
+		},
+		{
+			name:       "with after expression",
+			expression: "(_mit OR _isc) WITH foo",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpression(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseExpression() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.wantString {
+				t.Errorf("ParseExpression().String() = %v, want %v", got.String(), tt.wantString)
+			}
+			if licenses := got.Licenses(); !reflect.DeepEqual(licenses, tt.wantLicenses) {
+				t.Errorf("ParseExpression().Licenses() = %v, want %v", licenses, tt.wantLicenses)
+			}
+		})
+	}
+}
+
+func TestInternalID(t *testing.T) {
+	tests := []struct {
+		spdxID string
+		want   string
+	}{
+		{spdxID: "MIT", want: "_mit"},
+		{spdxID: "GPL-2.0-only", want: "_gpl-2.0"},
+		{spdxID: "GPL-2.0-or-later", want: "_gpl-2.0+"},
+		{spdxID: "GPL-2.0+", want: "_gpl-2.0+"},
+		{spdxID: "Apache-2.0", want: "_apache-2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spdxID, func(t *testing.T) {
+			if got := InternalID(tt.spdxID); got != tt.want {
+				t.Errorf("InternalID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
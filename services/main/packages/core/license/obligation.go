@@ -0,0 +1,108 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package license
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// Obligations a license can place on anyone distributing a part under it
+const (
+	// ObligationAttribution requires copyright and license notices to be reproduced
+	ObligationAttribution = "attribution"
+	// ObligationSourceOffer requires source code to be provided or offered
+	ObligationSourceOffer = "source_offer"
+	// ObligationChangeNotice requires modified files to carry notices stating they were changed
+	ObligationChangeNotice = "change_notice"
+	// ObligationPatent marks licenses that grant or terminate patent rights
+	ObligationPatent = "patent"
+)
+
+// ObligationTypes lists every obligation in the order they are reported
+var ObligationTypes = []string{ObligationAttribution, ObligationSourceOffer, ObligationChangeNotice, ObligationPatent}
+
+// Obligation is an obligation of a license, with an optional description of what it means for that license
+type Obligation struct {
+	LicenseID   string         `json:"license_id" db:"license_id"`
+	Obligation  string         `json:"obligation" db:"obligation"`
+	Description sql.NullString `json:"description" db:"description"`
+}
+
+func validObligation(obligation string) bool {
+	for _, v := range ObligationTypes {
+		if v == obligation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetObligations lists the obligations of the given license
+func (controller LicenseController) GetObligations(licenseID string) ([]Obligation, error) {
+	rows, err := controller.DB.Queryx("SELECT * FROM license_obligation WHERE license_id=$1", licenseID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting obligations of license %s", licenseID)
+	}
+	defer rows.Close()
+
+	found := make(map[string]Obligation)
+	for rows.Next() {
+		var tmp Obligation
+		if err := rows.StructScan(&tmp); err != nil {
+			return nil, errors.Wrapf(err, "error scanning license obligation")
+		}
+
+		found[tmp.Obligation] = tmp
+	}
+	rows.Close()
+
+	ret := make([]Obligation, 0, len(found))
+	for _, v := range ObligationTypes {
+		if obligation, ok := found[v]; ok {
+			ret = append(ret, obligation)
+		}
+	}
+
+	return ret, nil
+}
+
+// SetObligation upserts an obligation of the given license, replacing the description if it already exists
+func (controller LicenseController) SetObligation(licenseID string, obligation string, description string) (*Obligation, error) {
+	if !validObligation(obligation) {
+		return nil, errors.Errorf("unknown obligation \"%s\"", obligation)
+	}
+
+	var ret Obligation
+	if err := controller.DB.QueryRowx(`INSERT INTO license_obligation (license_id, obligation, description) VALUES ($1, $2, $3)
+	ON CONFLICT (license_id, obligation) DO UPDATE SET description=EXCLUDED.description
+	RETURNING *`,
+		licenseID, obligation, sql.NullString{Valid: description != "", String: description}).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "error upserting obligation %s of license %s", obligation, licenseID)
+	}
+
+	return &ret, nil
+}
+
+// DeleteObligation removes an obligation from the given license
+func (controller LicenseController) DeleteObligation(licenseID string, obligation string) error {
+	res, err := controller.DB.Exec("DELETE FROM license_obligation WHERE license_id=$1 AND obligation=$2", licenseID, obligation)
+	if err != nil {
+		return errors.Wrapf(err, "error deleting obligation %s of license %s", obligation, licenseID)
+	}
+	if count, _ := res.RowsAffected(); count < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
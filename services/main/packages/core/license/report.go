@@ -0,0 +1,297 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package license
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
+
+	"github.com/pkg/errors"
+)
+
+// ObligationReportEntry is a part found in the reported part list, or beneath one of its parts, and the obligations triggered by its concluded license.
+// When the license offers a choice, the obligations of every option are listed.
+// A sub-part has the id of the listed part it was found beneath, and its path within it; both are empty for listed parts.
+type ObligationReportEntry struct {
+	PartListID   int64                `json:"partlist_id"`
+	PartListName string               `json:"partlist_name"`
+	PartID       string               `json:"part_id"`
+	ListedPartID string               `json:"listed_part_id,omitempty"`
+	Path         string               `json:"path,omitempty"`
+	Name         string               `json:"name"`
+	Version      string               `json:"version"`
+	License      string               `json:"license"`
+	Licenses     []string             `json:"licenses"`
+	Unregistered []string             `json:"unregistered"`
+	Obligations  []ReportedObligation `json:"obligations"`
+}
+
+// ReportedObligation is an obligation triggered by one of the licenses of a reported part
+type ReportedObligation struct {
+	LicenseID   string `json:"license_id"`
+	Obligation  string `json:"obligation"`
+	Description string `json:"description,omitempty"`
+}
+
+// ObligationTypes lists each obligation of the entry once, in the order of ObligationTypes
+func (entry ObligationReportEntry) ObligationTypes() []string {
+	found := make(map[string]bool)
+	for _, v := range entry.Obligations {
+		found[v.Obligation] = true
+	}
+
+	ret := make([]string, 0, len(found))
+	for _, v := range ObligationTypes {
+		if found[v] {
+			ret = append(ret, v)
+		}
+	}
+
+	return ret
+}
+
+// ObligationReport lists the obligations of every part in a part list and its nested part lists
+type ObligationReport struct {
+	PartListID    int64                   `json:"partlist_id"`
+	PartListName  string                  `json:"partlist_name"`
+	GeneratedDate time.Time               `json:"generated_date"`
+	Entries       []ObligationReportEntry `json:"entries"`
+}
+
+// ObligationReport builds the obligation report of the given part list, walking nested part lists depth first
+// The sub-parts of listed parts are reported too, each once per part list, at its nearest path beneath the first listed part it is found under
+func (controller LicenseController) ObligationReport(partlistID int64) (*ObligationReport, error) {
+	root, err := controller.PartListController.GetByID(partlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := ObligationReport{
+		PartListID:    root.ID,
+		PartListName:  root.Name,
+		GeneratedDate: time.Now().UTC(),
+		Entries:       make([]ObligationReportEntry, 0),
+	}
+
+	licenses := make(map[string]*License)
+	obligations := make(map[string][]Obligation)
+	visited := make(map[int64]bool)
+
+	var walk func(list partlist.PartList) error
+	walk = func(list partlist.PartList) error {
+		if visited[list.ID] {
+			return nil
+		}
+		visited[list.ID] = true
+
+		parts, err := controller.PartListController.GetParts(list.ID)
+		if err != nil {
+			return errors.Wrapf(err, "error getting parts of partlist %d", list.ID)
+		}
+
+		// listed parts are reported as listed, even when also beneath another listed part
+		seen := make(map[part.ID]bool)
+		for _, p := range parts {
+			seen[p.PartID] = true
+		}
+		for _, p := range parts {
+			entry, err := controller.obligationReportEntry(list, p, licenses, obligations)
+			if err != nil {
+				return err
+			}
+			report.Entries = append(report.Entries, *entry)
+
+			descendants, err := controller.PartController.Descendants(p.PartID, 0)
+			if err != nil {
+				return err
+			}
+			for i := range descendants {
+				if seen[descendants[i].PartID] {
+					continue
+				}
+				seen[descendants[i].PartID] = true
+
+				entry, err := controller.obligationReportEntry(list, &descendants[i].Part, licenses, obligations)
+				if err != nil {
+					return err
+				}
+				entry.ListedPartID = p.PartID.String()
+				entry.Path = descendants[i].Path
+				report.Entries = append(report.Entries, *entry)
+			}
+		}
+
+		children, err := controller.PartListController.GetByParentID(list.ID)
+		if err != nil {
+			return errors.Wrapf(err, "error getting children of partlist %d", list.ID)
+		}
+		for _, child := range children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+	if err := walk(*root); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// obligationReportEntry resolves the licenses of a part and their obligations, caching lookups by identifier
+func (controller LicenseController) obligationReportEntry(list partlist.PartList, p *part.Part, licenses map[string]*License, obligations map[string][]Obligation) (*ObligationReportEntry, error) {
+	entry := ObligationReportEntry{
+		PartListID:   list.ID,
+		PartListName: list.Name,
+		PartID:       p.PartID.String(),
+		Name:         p.Name.String,
+		Version:      p.Version.String,
+		License:      p.License.String,
+		Licenses:     make([]string, 0),
+		Unregistered: make([]string, 0),
+		Obligations:  make([]ReportedObligation, 0),
+	}
+	if strings.TrimSpace(entry.License) == "" {
+		return &entry, nil
+	}
+
+	var identifiers []string
+	if expression, err := ParseExpression(entry.License); err == nil {
+		identifiers = expression.Licenses()
+	} else {
+		identifiers = []string{entry.License}
+	}
+
+	for _, identifier := range identifiers {
+		l, ok := licenses[identifier]
+		if !ok {
+			var err error
+			if l, err = controller.GetByIdentifier(identifier); err != nil && err != ErrNotFound {
+				return nil, err
+			}
+			licenses[identifier] = l
+		}
+		if l == nil {
+			entry.Unregistered = append(entry.Unregistered, identifier)
+			continue
+		}
+		entry.Licenses = append(entry.Licenses, l.LicenseID)
+
+		if _, ok := obligations[l.LicenseID]; !ok {
+			found, err := controller.GetObligations(l.LicenseID)
+			if err != nil {
+				return nil, err
+			}
+			obligations[l.LicenseID] = found
+		}
+		for _, v := range obligations[l.LicenseID] {
+			entry.Obligations = append(entry.Obligations, ReportedObligation{
+				LicenseID:   v.LicenseID,
+				Obligation:  v.Obligation,
+				Description: v.Description.String,
+			})
+		}
+	}
+
+	return &entry, nil
+}
+
+// WriteJSON writes the report as a JSON object
+func (report ObligationReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
+
+// WriteCSV writes a row per part, with a column per obligation type marking if that part triggers it
+func (report ObligationReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"partlist_id", "partlist_name", "part_id", "listed_part_id", "path", "name", "version", "license", "licenses", "unregistered"}
+	header = append(header, ObligationTypes...)
+	if err := writer.Write(header); err != nil {
+		return errors.Wrapf(err, "error writing csv header")
+	}
+
+	for _, entry := range report.Entries {
+		triggered := make(map[string]bool)
+		for _, v := range entry.ObligationTypes() {
+			triggered[v] = true
+		}
+
+		row := []string{
+			strconv.FormatInt(entry.PartListID, 10),
+			entry.PartListName,
+			entry.PartID,
+			entry.ListedPartID,
+			entry.Path,
+			entry.Name,
+			entry.Version,
+			entry.License,
+			strings.Join(entry.Licenses, " "),
+			strings.Join(entry.Unregistered, " "),
+		}
+		for _, v := range ObligationTypes {
+			row = append(row, strconv.FormatBool(triggered[v]))
+		}
+
+		if err := writer.Write(row); err != nil {
+			return errors.Wrapf(err, "error writing csv row of part %s", entry.PartID)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+var obligationReportTemplate = template.Must(template.New("obligations").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>License Obligations: {{.PartListName}}</title>
+<style>
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 4px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>License Obligations: {{.PartListName}}</h1>
+<p>Generated {{.GeneratedDate.Format "2006-01-02 15:04:05 MST"}}</p>
+<table>
+<tr><th>Part List</th><th>Part</th><th>Version</th><th>License</th><th>Obligations</th></tr>
+{{- range .Entries}}
+<tr>
+<td>{{.PartListName}}</td>
+<td>{{if .Name}}{{.Name}}{{else}}{{.PartID}}{{end}}{{if .Path}}<br><em>at {{.Path}}</em>{{end}}</td>
+<td>{{.Version}}</td>
+<td>{{.License}}{{if .Unregistered}}<br><em>unregistered: {{range $i, $v := .Unregistered}}{{if $i}}, {{end}}{{$v}}{{end}}</em>{{end}}</td>
+<td><ul>{{range .Obligations}}<li>{{.Obligation}} ({{.LicenseID}}){{if .Description}}: {{.Description}}{{end}}</li>{{end}}</ul></td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a standalone HTML page
+func (report ObligationReport) WriteHTML(w io.Writer) error {
+	return obligationReportTemplate.Execute(w, report)
+}
@@ -0,0 +1,51 @@
+package license
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestObligationReportWriteCSV(t *testing.T) {
+	report := ObligationReport{
+		PartListID:   1,
+		PartListName: "product",
+		Entries: []ObligationReportEntry{
+			{
+				PartListID:   2,
+				PartListName: "product, platform",
+				PartID:       "00000000-0000-0000-0000-000000000001",
+				Name:         "busybox",
+				Version:      "1.36.0",
+				License:      "_gpl-2.0 AND CUSTOM[busybox exception]",
+				Licenses:     []string{"_gpl-2.0"},
+				Unregistered: []string{"CUSTOM[busybox exception]"},
+				Obligations: []ReportedObligation{
+					{LicenseID: "_gpl-2.0", Obligation: ObligationAttribution},
+					{LicenseID: "_gpl-2.0", Obligation: ObligationChangeNotice},
+					{LicenseID: "_gpl-2.0", Obligation: ObligationSourceOffer},
+				},
+			},
+			{
+				PartListID:   2,
+				PartListName: "product, platform",
+				PartID:       "00000000-0000-0000-0000-000000000002",
+				ListedPartID: "00000000-0000-0000-0000-000000000001",
+				Path:         "lib/unlicensed",
+				Name:         "unlicensed",
+			},
+		},
+	}
+
+	want := `partlist_id,partlist_name,part_id,listed_part_id,path,name,version,license,licenses,unregistered,attribution,source_offer,change_notice,patent
+2,"product, platform",00000000-0000-0000-0000-000000000001,,,busybox,1.36.0,_gpl-2.0 AND CUSTOM[busybox exception],_gpl-2.0,CUSTOM[busybox exception],true,true,true,false
+2,"product, platform",00000000-0000-0000-0000-000000000002,00000000-0000-0000-0000-000000000001,lib/unlicensed,unlicensed,,,,,false,false,false,false
+`
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() = %v, want %v", got, want)
+	}
+}
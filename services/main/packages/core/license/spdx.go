@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package license
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// spdxLicenseList is the layout of licenses.json published by https://github.com/spdx/license-list-data
+type spdxLicenseList struct {
+	LicenseListVersion string `json:"licenseListVersion"`
+	Licenses           []struct {
+		LicenseID             string   `json:"licenseId"`
+		Name                  string   `json:"name"`
+		Reference             string   `json:"reference"`
+		IsDeprecatedLicenseID bool     `json:"isDeprecatedLicenseId"`
+		IsOsiApproved         bool     `json:"isOsiApproved"`
+		IsFsfLibre            bool     `json:"isFsfLibre"`
+		SeeAlso               []string `json:"seeAlso"`
+	} `json:"licenses"`
+}
+
+// spdxRecord is stored as the record of each imported license
+type spdxRecord struct {
+	ListVersion string   `json:"spdx_list_version"`
+	Reference   string   `json:"reference,omitempty"`
+	OsiApproved bool     `json:"osi_approved"`
+	FsfLibre    bool     `json:"fsf_libre"`
+	SeeAlso     []string `json:"see_also,omitempty"`
+}
+
+// ImportSPDXLicenseList upserts every license from an SPDX licenses.json into the registry, and returns the number of licenses imported.
+// Current identifiers become licenses named with InternalID, deprecated identifiers become aliases of the license they map to if it exists.
+// Texts and records of existing licenses are not overwritten.
+func (controller LicenseController) ImportSPDXLicenseList(r io.Reader) (int64, error) {
+	var list spdxLicenseList
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return 0, errors.Wrapf(err, "error decoding SPDX license list")
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return 0, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	var count int64
+	deprecated := make([]string, 0)
+	for _, v := range list.Licenses {
+		if v.IsDeprecatedLicenseID {
+			deprecated = append(deprecated, v.LicenseID)
+			continue
+		}
+
+		record, err := json.Marshal(spdxRecord{
+			ListVersion: list.LicenseListVersion,
+			Reference:   v.Reference,
+			OsiApproved: v.IsOsiApproved,
+			FsfLibre:    v.IsFsfLibre,
+			SeeAlso:     v.SeeAlso,
+		})
+		if err != nil {
+			return count, errors.Wrapf(err, "error encoding record of %s", v.LicenseID)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO license (license_id, name, spdx_id, record) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (license_id) DO UPDATE SET name=EXCLUDED.name, spdx_id=EXCLUDED.spdx_id`,
+			InternalID(v.LicenseID), v.Name, v.LicenseID, record); err != nil {
+			return count, errors.Wrapf(err, "error upserting license %s", v.LicenseID)
+		}
+		count++
+	}
+
+	for _, v := range deprecated {
+		if _, err := tx.Exec(`INSERT INTO license_alias (alias, license_id)
+		SELECT $1, license_id FROM license WHERE license_id=$2
+		ON CONFLICT (alias) DO NOTHING`,
+			v, InternalID(v)); err != nil {
+			return count, errors.Wrapf(err, "error inserting alias %s", v)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrapf(err, "error committing SPDX license list")
+	}
+
+	return count, nil
+}
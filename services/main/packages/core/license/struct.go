@@ -11,34 +11,279 @@
 package license
 
 import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
 	"wrs/tk/packages/core/archive"
 	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 )
 
+// License is an entry in the license registry.
+// LicenseID is the internal identifier used in license expressions (e.g. _gpl-2.0 or CUSTOM[foo]),
+// SpdxID is the equivalent SPDX identifier if one exists.
 type License struct {
-	Name string `json:"license_name" db:"expression"`
+	LicenseID  string          `json:"license_id" db:"license_id"`
+	Name       string          `json:"name" db:"name"`
+	SpdxID     sql.NullString  `json:"spdx_id" db:"spdx_id"`
+	Custom     bool            `json:"custom" db:"custom"`
+	Record     json.RawMessage `json:"record" db:"record"`
+	Text       sql.NullString  `json:"text" db:"text"`
+	InsertDate time.Time       `json:"insert_date" db:"insert_date"`
 }
 
 type LicenseController struct {
-	DB                *sqlx.DB
-	PartController    part.PartController
-	ArchiveController *archive.ArchiveController
+	DB                 *sqlx.DB
+	PartController     part.PartController
+	PartListController partlist.PartListController
+	ArchiveController  *archive.ArchiveController
 }
 
-// TODO should this be removed entirely?
-func (controller LicenseController) GetByLicenseExpression(expression string) (*License, error) {
+// IsCustom returns true if the license identifier is in the CUSTOM[<identifier>] form used for licenses without an SPDX equivalent
+func IsCustom(licenseID string) bool {
+	return strings.HasPrefix(licenseID, "CUSTOM[") && strings.HasSuffix(licenseID, "]") && len(licenseID) > len("CUSTOM[]")
+}
+
+// InternalID converts an SPDX identifier into the internal license identifier.
+// The identifier is lower-cased and prefixed with an underscore, "-only" is dropped and "-or-later" becomes "+".
+// e.g. GPL-2.0-only -> _gpl-2.0, GPL-2.0-or-later -> _gpl-2.0+
+func InternalID(spdxID string) string {
+	id := strings.ToLower(spdxID)
+	id = strings.TrimSuffix(id, "-only")
+	if strings.HasSuffix(id, "-or-later") {
+		id = strings.TrimSuffix(id, "-or-later") + "+"
+	}
+
+	return "_" + id
+}
+
+// CanonicalID converts an SPDX or internal license identifier into the lower-cased internal form, so they can be compared.
+// CUSTOM[<identifier>] identifiers are returned as is.
+func CanonicalID(identifier string) string {
+	if IsCustom(identifier) {
+		return identifier
+	}
+	if strings.HasPrefix(identifier, "_") {
+		return strings.ToLower(identifier)
+	}
+
+	return InternalID(identifier)
+}
+
+// MatchLicense reports if the license identifier matches the pattern, comparing both in their canonical form.
+// A pattern ending with * matches every license starting with it, e.g. AGPL* matches _agpl-3.0 and _agpl-3.0+
+func MatchLicense(pattern string, identifier string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(CanonicalID(identifier), CanonicalID(prefix))
+	}
+
+	return CanonicalID(identifier) == CanonicalID(pattern)
+}
+
+// validLicenseID checks that the identifier can be used as a single license within an expression
+func validLicenseID(licenseID string) bool {
+	if IsCustom(licenseID) {
+		return !strings.Contains(licenseID[len("CUSTOM["):len(licenseID)-1], "]")
+	}
+
+	expression, err := ParseExpression(licenseID)
+	return err == nil && expression.IsLeaf()
+}
+
+// GetByID returns the license with the exact internal identifier
+func (controller LicenseController) GetByID(licenseID string) (*License, error) {
 	var ret License
-	ret.Name = expression
+	if err := controller.DB.QueryRowx("SELECT * FROM license WHERE license_id=$1", licenseID).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
 
-	// TODO check that a part has this license?
+		return nil, errors.Wrapf(err, "error selecting license %s", licenseID)
+	}
 
 	return &ret, nil
 }
 
-func (controller LicenseController) GetByPart(verificationCode []byte, partID *part.ID) (*License, error) {
+// GetByIdentifier returns the license matching the given internal identifier, SPDX identifier, or alias.
+// SPDX identifiers and aliases are matched case-insensitively.
+func (controller LicenseController) GetByIdentifier(identifier string) (*License, error) {
+	var ret License
+	if err := controller.DB.QueryRowx(`SELECT l.* FROM license l
+	WHERE l.license_id=$1
+	OR LOWER(l.spdx_id)=LOWER($1)
+	OR l.license_id IN (SELECT la.license_id FROM license_alias la WHERE LOWER(la.alias)=LOWER($1))
+	ORDER BY (l.license_id=$1) DESC
+	LIMIT 1`, identifier).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting license by identifier %s", identifier)
+	}
+
+	return &ret, nil
+}
+
+// List returns every license in the registry ordered by identifier.
+// If search is not empty, only licenses with an identifier, name, SPDX identifier or alias containing search are returned.
+func (controller LicenseController) List(search string) ([]License, error) {
+	rows, err := controller.DB.Queryx(`SELECT l.* FROM license l
+	WHERE $1=''
+	OR l.license_id ILIKE '%' || $1 || '%'
+	OR l.name ILIKE '%' || $1 || '%'
+	OR l.spdx_id ILIKE '%' || $1 || '%'
+	OR l.license_id IN (SELECT la.license_id FROM license_alias la WHERE la.alias ILIKE '%' || $1 || '%')
+	ORDER BY l.license_id`, search)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting licenses")
+	}
+	defer rows.Close()
+
+	ret := make([]License, 0)
+	for rows.Next() {
+		var tmp License
+		if err := rows.StructScan(&tmp); err != nil {
+			return nil, errors.Wrapf(err, "error scanning licenses")
+		}
+
+		ret = append(ret, tmp)
+	}
+
+	return ret, nil
+}
+
+// CreateLicense inserts a new license and any aliases, and returns the newly created license.
+// Identifiers in the CUSTOM[<identifier>] form are marked as custom, and are expected to have their text attached.
+func (controller LicenseController) CreateLicense(license License, aliases []string) (*License, error) {
+	if license.LicenseID == "" {
+		return nil, errors.New("CreateLicense was given a license without an id")
+	}
+	if license.Name == "" {
+		return nil, errors.New("CreateLicense was given a license without a name")
+	}
+	if !validLicenseID(license.LicenseID) {
+		return nil, errors.Errorf("invalid license id \"%s\"", license.LicenseID)
+	}
+	if len(license.Record) == 0 {
+		license.Record = json.RawMessage("{}")
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	var newLicense License
+	if err := tx.QueryRowx(`INSERT INTO license
+	(license_id, name, spdx_id, custom, record, text)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING *`,
+		license.LicenseID, license.Name, license.SpdxID, IsCustom(license.LicenseID), license.Record, license.Text).
+		StructScan(&newLicense); err != nil {
+		return nil, errors.Wrapf(err, "error inserting license")
+	}
+
+	for _, alias := range aliases {
+		if err := createAlias(tx, newLicense.LicenseID, alias); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing license %s", newLicense.LicenseID)
+	}
+
+	return &newLicense, nil
+}
+
+// CreateAlias upserts an alias to the given license
+// If the alias exists and is associated with a different license, an error is returned
+func (controller LicenseController) CreateAlias(licenseID string, alias string) error {
+	return createAlias(controller.DB, licenseID, alias)
+}
+
+// createAlias upserts an alias to the given license with q, such as the transaction creating the license
+func createAlias(q sqlx.Queryer, licenseID string, alias string) error {
+	var aliasesLicenseID string
+	if err := q.QueryRowx(`INSERT INTO license_alias (alias, license_id) VALUES ($1, $2)
+	ON CONFLICT (alias) DO UPDATE SET alias=EXCLUDED.alias
+	RETURNING license_id`, // meaningless update required for return
+		alias, licenseID).Scan(&aliasesLicenseID); err != nil {
+		return errors.Wrapf(err, "error inserting license_alias")
+	}
+
+	if aliasesLicenseID != licenseID {
+		return errors.Errorf("alias %s already belongs to license %s", alias, aliasesLicenseID)
+	}
+
+	return nil
+}
+
+// GetAliases lists every alias of the given license
+func (controller LicenseController) GetAliases(licenseID string) ([]string, error) {
+	rows, err := controller.DB.Queryx("SELECT alias FROM license_alias WHERE license_id=$1 ORDER BY alias", licenseID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting license aliases")
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0)
+	for rows.Next() {
+		var tmp string
+		if err := rows.Scan(&tmp); err != nil {
+			return nil, errors.Wrapf(err, "error scanning license alias")
+		}
+
+		ret = append(ret, tmp)
+	}
+
+	return ret, nil
+}
+
+// AttachText sets the full text of the given license, replacing any existing text
+func (controller LicenseController) AttachText(licenseID string, text string) (*License, error) {
+	res, err := controller.DB.Exec("UPDATE license SET text=$1 WHERE license_id=$2", text, licenseID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error updating text of license %s", licenseID)
+	}
+	if count, _ := res.RowsAffected(); count < 1 {
+		return nil, ErrNotFound
+	}
+
+	return controller.GetByID(licenseID)
+}
+
+// GetByLicenseExpression parses the expression and returns the registered license of every identifier in it.
+// Identifiers that are not in the registry are skipped.
+func (controller LicenseController) GetByLicenseExpression(expression string) ([]License, error) {
+	parsed, err := ParseExpression(expression)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing license expression \"%s\"", expression)
+	}
+
+	ret := make([]License, 0)
+	for _, identifier := range parsed.Licenses() {
+		license, err := controller.GetByIdentifier(identifier)
+		if err == ErrNotFound {
+			log.Debug().Str("identifier", identifier).Str("expression", expression).Msg("license not in registry")
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, *license)
+	}
+
+	return ret, nil
+}
+
+func (controller LicenseController) GetByPart(verificationCode []byte, partID *part.ID) ([]License, error) {
 	container, err := controller.PartController.GetBy(verificationCode, partID)
 	if err != nil {
 		if err == part.ErrNotFound {
@@ -54,7 +299,7 @@ func (controller LicenseController) GetByPart(verificationCode []byte, partID *p
 	return nil, ErrNotFound
 }
 
-func (controller LicenseController) GetByArchive(sha256 []byte, sha1 []byte, name string) (*License, error) {
+func (controller LicenseController) GetByArchive(sha256 []byte, sha1 []byte, name string) ([]License, error) {
 	arch, err := controller.ArchiveController.GetBy(sha256, sha1, name)
 	if err != nil {
 		if err == archive.ErrNotFound {
@@ -71,15 +316,15 @@ func (controller LicenseController) GetByArchive(sha256 []byte, sha1 []byte, nam
 	return nil, ErrNotFound
 }
 
-func (controller LicenseController) GetByContainer(verificationCode []byte, sha256 []byte, sha1 []byte, name string, partID *part.ID) (*License, error) {
-	if license, err := controller.GetByPart(verificationCode, partID); err == nil {
-		return license, nil
+func (controller LicenseController) GetByContainer(verificationCode []byte, sha256 []byte, sha1 []byte, name string, partID *part.ID) ([]License, error) {
+	if licenses, err := controller.GetByPart(verificationCode, partID); err == nil {
+		return licenses, nil
 	} else if err != ErrNotFound {
 		return nil, err
 	}
 
-	if license, err := controller.GetByArchive(sha256, sha1, name); err == nil {
-		return license, nil
+	if licenses, err := controller.GetByArchive(sha256, sha1, name); err == nil {
+		return licenses, nil
 	} else if err != ErrNotFound {
 		return nil, err
 	}
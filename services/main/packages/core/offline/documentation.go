@@ -0,0 +1,4 @@
+// offline moves curated parts and partlists between catalog instances that cannot reach each other, such as those on air-gapped networks.
+// An offline bundle is a tarball of a manifest, NDJSON records of parts and partlists, and the contents of their files and archives named by sha256.
+// Exports can be incremental, leaving out whatever an earlier export already delivered, and imports merge into the receiving catalog without overwriting its curated data.
+package offline
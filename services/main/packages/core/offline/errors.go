@@ -0,0 +1,21 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package offline
+
+import "fmt"
+
+var ErrNotFound = fmt.Errorf("offline export not found")
+
+// ErrIncomplete is returned when an export that was never written in full is used as the base of an incremental export
+var ErrIncomplete = fmt.Errorf("offline export is incomplete")
+
+// ErrFormat is returned when importing something that is not an offline bundle this version understands
+var ErrFormat = fmt.Errorf("not a supported offline bundle")
@@ -0,0 +1,579 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package offline
+
+import (
+	"archive/tar"
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+	"wrs/tk/packages/blob/file"
+	"wrs/tk/packages/core/part"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Kinds of export entries
+const (
+	kindPart     = "part"
+	kindPartList = "partlist"
+	kindBlob     = "blob"
+)
+
+// ExportOptions selects what an export contains, at least one part or a partlist must be given
+type ExportOptions struct {
+	PartIDs    []part.ID // parts to export, along with their sub-parts and the parts comprising them
+	PartListID *int64    // partlist to export, along with the partlists beneath it and every part they list
+	SinceID    *int64    // complete export whose records and blobs are left out, unless changed since
+	Blobs      bool      // include the contents of files and archives
+}
+
+// blob is the content of a file or an archive to put in a bundle
+type blob struct {
+	sha256  file.Sha256
+	size    int64
+	archive bool
+}
+
+// exporter collects the records of an export, parts always after their sub-parts and the parts comprising them
+type exporter struct {
+	controller *OfflineController
+	visited    map[string]bool
+	parts      []PartRecord
+	partlists  []PartListRecord
+	blobs      map[string]blob
+}
+
+// Export writes an offline bundle to w, and records it so later exports can be incremental to it.
+// The export is only marked complete, and usable as the base of an incremental export, once the whole bundle was written.
+func (controller *OfflineController) Export(w io.Writer, options ExportOptions) (*Export, error) {
+	if len(options.PartIDs) == 0 && options.PartListID == nil {
+		return nil, errors.New("an offline export needs parts or a partlist")
+	}
+
+	since := make(map[string][]byte)
+	if options.SinceID != nil {
+		base, err := controller.GetExport(*options.SinceID)
+		if err != nil {
+			return nil, err
+		}
+		if !base.Complete {
+			return nil, errors.Wrapf(ErrIncomplete, "offline export %d", base.ID)
+		}
+
+		if since, err = controller.entries(base.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	e := exporter{
+		controller: controller,
+		visited:    make(map[string]bool),
+		blobs:      make(map[string]blob),
+	}
+	for _, id := range options.PartIDs {
+		if err := e.visitPart(id); err != nil {
+			return nil, err
+		}
+	}
+	if options.PartListID != nil {
+		if err := e.visitPartList(*options.PartListID, nil, make(map[int64]bool)); err != nil {
+			return nil, err
+		}
+	}
+
+	partIDs := make(PartIDs, 0, len(options.PartIDs))
+	for _, id := range options.PartIDs {
+		partIDs = append(partIDs, id.String())
+	}
+	partIDsJSON, err := json.Marshal(partIDs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling part ids")
+	}
+
+	var ret Export
+	if err := controller.DB.QueryRowx(`INSERT INTO offline_export (since_id, part_ids, partlist_id, blobs) VALUES ($1, $2, $3, $4) RETURNING *`,
+		options.SinceID, partIDsJSON, options.PartListID, options.Blobs).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "error inserting offline export")
+	}
+
+	// keep what is new or changed since the base export, and remember everything the receiver will have
+	entries := make(map[string][]byte)
+	for key, value := range since {
+		if kind, _, _ := cutKey(key); kind == kindBlob {
+			entries[key] = value
+		}
+	}
+
+	var partsBuffer, partlistsBuffer bytes.Buffer
+	manifest := Manifest{
+		Format:   Format,
+		Version:  FormatVersion,
+		Instance: controller.PartController.Instance,
+		ExportID: ret.ID,
+		SinceID:  options.SinceID,
+		Created:  time.Now().UTC(),
+	}
+	for _, record := range e.parts {
+		changed, err := track(entries, since, kindPart, record.PartID, record)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+
+		if err := json.NewEncoder(&partsBuffer).Encode(record); err != nil {
+			return nil, errors.Wrapf(err, "error encoding part %s", record.PartID)
+		}
+		manifest.Parts++
+	}
+	for _, record := range e.partlists {
+		changed, err := track(entries, since, kindPartList, fmt.Sprint(record.ID), record)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+
+		if err := json.NewEncoder(&partlistsBuffer).Encode(record); err != nil {
+			return nil, errors.Wrapf(err, "error encoding partlist %d", record.ID)
+		}
+		manifest.PartLists++
+	}
+
+	blobs := make([]string, 0)
+	if options.Blobs {
+		for sha := range e.blobs {
+			if _, ok := since[kindBlob+":"+sha]; ok {
+				continue
+			}
+
+			blobs = append(blobs, sha)
+			entries[kindBlob+":"+sha] = nil
+		}
+		sort.Strings(blobs)
+	}
+	manifest.Blobs = len(blobs)
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling manifest")
+	}
+
+	tw := tar.NewWriter(w)
+	root := fmt.Sprintf("offline-export-%d", ret.ID)
+	writeMember := func(name string, size int64, r io.Reader) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    root + "/" + name,
+			Mode:    0644,
+			Size:    size,
+			ModTime: manifest.Created,
+		}); err != nil {
+			return errors.Wrapf(err, "error writing header of %s", name)
+		}
+
+		if n, err := io.Copy(tw, r); err != nil {
+			return errors.Wrapf(err, "error writing %s", name)
+		} else if n != size {
+			return errors.Errorf("wrote %d bytes of %s, expected %d", n, name, size)
+		}
+
+		return nil
+	}
+
+	if err := writeMember(manifestName, int64(len(manifestJSON)), bytes.NewReader(manifestJSON)); err != nil {
+		return nil, err
+	}
+	if err := writeMember(partsName, int64(partsBuffer.Len()), &partsBuffer); err != nil {
+		return nil, err
+	}
+	if err := writeMember(partlistsName, int64(partlistsBuffer.Len()), &partlistsBuffer); err != nil {
+		return nil, err
+	}
+	for _, sha := range blobs {
+		b := e.blobs[sha]
+
+		var f *file.File
+		if b.archive {
+			f, err = controller.ArchiveController.DownloadArchive(b.sha256)
+		} else {
+			f, err = controller.ArchiveController.DownloadFile(b.sha256)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "error retrieving blob %s", sha)
+		}
+
+		err = writeMember(blobDirectory+"/"+sha, b.size, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrapf(err, "error closing offline bundle")
+	}
+
+	if err := controller.complete(ret.ID, entries); err != nil {
+		return nil, err
+	}
+	ret.Complete = true
+
+	return &ret, nil
+}
+
+// track records the digest of a record in entries, and reports whether it changed since the base export
+func track(entries map[string][]byte, since map[string][]byte, kind string, key string, record any) (bool, error) {
+	sum, err := digest(record)
+	if err != nil {
+		return false, errors.Wrapf(err, "error digesting %s %s", kind, key)
+	}
+	entries[kind+":"+key] = sum
+
+	previous, ok := since[kind+":"+key]
+	return !ok || !bytes.Equal(previous, sum), nil
+}
+
+// cutKey splits an entry key into its kind and key
+func cutKey(entryKey string) (string, string, bool) {
+	for i := 0; i < len(entryKey); i++ {
+		if entryKey[i] == ':' {
+			return entryKey[:i], entryKey[i+1:], true
+		}
+	}
+
+	return entryKey, "", false
+}
+
+// entries returns the entries of an export, keyed by kind:key
+func (controller *OfflineController) entries(exportID int64) (map[string][]byte, error) {
+	rows, err := controller.DB.Queryx("SELECT kind, key, digest FROM offline_export_entry WHERE export_id=$1", exportID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting entries of offline export %d", exportID)
+	}
+	defer rows.Close()
+
+	ret := make(map[string][]byte)
+	for rows.Next() {
+		var kind, key string
+		var sum []byte
+		if err := rows.Scan(&kind, &key, &sum); err != nil {
+			return nil, errors.Wrapf(err, "error scanning entry of offline export %d", exportID)
+		}
+
+		ret[kind+":"+key] = sum
+	}
+
+	return ret, nil
+}
+
+// complete records the entries of an export, and marks it complete
+func (controller *OfflineController) complete(exportID int64, entries map[string][]byte) error {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Preparex("INSERT INTO offline_export_entry (export_id, kind, key, digest) VALUES ($1, $2, $3, $4)")
+	if err != nil {
+		return errors.Wrapf(err, "error preparing offline export entry insert")
+	}
+	defer stmt.Close()
+
+	for entryKey, sum := range entries {
+		kind, key, _ := cutKey(entryKey)
+		if _, err := stmt.Exec(exportID, kind, key, sum); err != nil {
+			return errors.Wrapf(err, "error inserting entry %s of offline export %d", entryKey, exportID)
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE offline_export SET complete=TRUE WHERE id=$1", exportID); err != nil {
+		return errors.Wrapf(err, "error completing offline export %d", exportID)
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing offline export %d", exportID)
+}
+
+// visitPart adds the record of a part, after those of its sub-parts and the part comprising it, and before those of the parts it is related to,
+// which may be related to it in turn
+func (e *exporter) visitPart(id part.ID) error {
+	if e.visited[id.String()] {
+		return nil
+	}
+	e.visited[id.String()] = true
+
+	record, err := e.controller.partRecord(id)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range record.SubParts {
+		subPartID, err := uuid.Parse(v.PartID)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing sub-part id %s", v.PartID)
+		}
+		if err := e.visitPart(part.ID(subPartID)); err != nil {
+			return err
+		}
+	}
+	if record.Comprised != "" {
+		comprisedID, err := uuid.Parse(record.Comprised)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing comprised id %s", record.Comprised)
+		}
+		if err := e.visitPart(part.ID(comprisedID)); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range record.Files {
+		if v.Size < 1 { // empty files are never stored
+			continue
+		}
+		if _, ok := e.blobs[v.Sha256]; !ok {
+			sha, err := file.ParseSha256(v.Sha256)
+			if err != nil {
+				return err
+			}
+			e.blobs[v.Sha256] = blob{sha256: *sha, size: v.Size}
+		}
+	}
+	for _, v := range record.Archives {
+		sha, err := file.ParseSha256(v.Sha256)
+		if err != nil {
+			return err
+		}
+		e.blobs[v.Sha256] = blob{sha256: *sha, size: v.Size, archive: true}
+	}
+
+	e.parts = append(e.parts, *record)
+
+	for _, v := range record.Relationships {
+		relatedID, err := uuid.Parse(v.RelatedID)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing related id %s", v.RelatedID)
+		}
+		if err := e.visitPart(part.ID(relatedID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// visitPartList adds the record of a partlist, and the records of every part it lists and of the partlists beneath it
+func (e *exporter) visitPartList(id int64, parentID *int64, visitedLists map[int64]bool) error {
+	if visitedLists[id] {
+		return nil
+	}
+	visitedLists[id] = true
+
+	list, err := e.controller.PartListController.GetByID(id)
+	if err != nil {
+		return errors.Wrapf(err, "error getting partlist %d", id)
+	}
+
+	record := PartListRecord{ID: list.ID, Name: list.Name, ParentID: parentID, Parts: make([]string, 0)}
+	if err := e.controller.DB.Select(&record.Parts, "SELECT part_id::TEXT FROM partlist_has_part WHERE partlist_id=$1 ORDER BY part_id", id); err != nil {
+		return errors.Wrapf(err, "error selecting parts of partlist %d", id)
+	}
+	for _, v := range record.Parts {
+		partID, err := uuid.Parse(v)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing part id %s of partlist %d", v, id)
+		}
+		if err := e.visitPart(part.ID(partID)); err != nil {
+			return err
+		}
+	}
+	e.partlists = append(e.partlists, record)
+
+	children, err := e.controller.PartListController.GetByParentID(id)
+	if err != nil {
+		return errors.Wrapf(err, "error getting children of partlist %d", id)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+	for _, child := range children {
+		if err := e.visitPartList(child.ID, &list.ID, visitedLists); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nullString returns a pointer to the string, or nil if it is null
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+
+	return &s.String
+}
+
+// partRecord selects a part and everything that belongs to it, ordered so that unchanged parts have the same digest
+func (controller *OfflineController) partRecord(id part.ID) (*PartRecord, error) {
+	var p part.Part
+	if err := controller.DB.QueryRowx("SELECT * FROM part WHERE part_id=$1", id).StructScan(&p); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrapf(part.ErrNotFound, "part %s", id.String())
+		}
+
+		return nil, errors.Wrapf(err, "error selecting part %s", id.String())
+	}
+
+	ret := PartRecord{
+		PartID:           id.String(),
+		Type:             nullString(p.Type),
+		Name:             nullString(p.Name),
+		Version:          nullString(p.Version),
+		Label:            nullString(p.Label),
+		FamilyName:       nullString(p.FamilyName),
+		License:          nullString(p.License),
+		LicenseRationale: nullString(p.LicenseRationale),
+		Description:      nullString(p.Description),
+	}
+	if len(p.FileVerificationCode) > 0 {
+		ret.FileVerificationCode = hex.EncodeToString(p.FileVerificationCode)
+	}
+	if len(p.GitTreeSha1) > 0 {
+		ret.GitTreeSha1 = hex.EncodeToString(p.GitTreeSha1)
+	}
+	if p.Size.Valid {
+		ret.Size = &p.Size.Int64
+	}
+	if p.Comprised != part.ID(uuid.Nil) {
+		ret.Comprised = p.Comprised.String()
+	}
+
+	if err := controller.DB.Select(&ret.Aliases, "SELECT alias FROM part_alias WHERE part_id=$1 ORDER BY alias", id); err != nil {
+		return nil, errors.Wrapf(err, "error selecting aliases of part %s", id.String())
+	}
+
+	rows, err := controller.DB.Queryx(`SELECT key, NULL AS title, document FROM part_has_document WHERE part_id=$1
+	UNION ALL SELECT key, title, document FROM part_documents WHERE part_id=$1
+	ORDER BY key, title NULLS FIRST`, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting documents of part %s", id.String())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var document DocumentRecord
+		var title sql.NullString
+		if err := rows.Scan(&document.Key, &title, &document.Document); err != nil {
+			return nil, errors.Wrapf(err, "error scanning documents of part %s", id.String())
+		}
+		document.Title = nullString(title)
+
+		ret.Documents = append(ret.Documents, document)
+	}
+	rows.Close()
+
+	if err := controller.DB.Select(&ret.Identifiers, "SELECT type, value FROM part_identifier WHERE part_id=$1 ORDER BY type, value", id); err != nil {
+		return nil, errors.Wrapf(err, "error selecting identifiers of part %s", id.String())
+	}
+
+	if err := controller.DB.Select(&ret.SubParts, `SELECT child_id::TEXT AS part_id, path FROM part_has_part WHERE parent_id=$1 ORDER BY path, child_id`, id); err != nil {
+		return nil, errors.Wrapf(err, "error selecting sub-parts of part %s", id.String())
+	}
+
+	rows, err = controller.DB.Queryx(`SELECT type, related_id::TEXT, comment, metadata::TEXT FROM part_relationship WHERE part_id=$1 ORDER BY type, related_id`, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting relationships of part %s", id.String())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var relationship RelationshipRecord
+		var comment, metadata sql.NullString
+		if err := rows.Scan(&relationship.Type, &relationship.RelatedID, &comment, &metadata); err != nil {
+			return nil, errors.Wrapf(err, "error scanning relationships of part %s", id.String())
+		}
+		relationship.Comment = nullString(comment)
+		if metadata.Valid {
+			relationship.Metadata = json.RawMessage(metadata.String)
+		}
+
+		ret.Relationships = append(ret.Relationships, relationship)
+	}
+	rows.Close()
+
+	// names of files and archives, by sha256
+	names := make(map[string][]string)
+	rows, err = controller.DB.Queryx(`SELECT DISTINCT ENCODE(file_alias.file_sha256, 'hex'), file_alias.name FROM file_alias
+	INNER JOIN part_has_file ON part_has_file.file_sha256=file_alias.file_sha256
+	WHERE part_has_file.part_id=$1
+	UNION SELECT ENCODE(archive_alias.archive_sha256, 'hex'), archive_alias.name FROM archive_alias
+	INNER JOIN archive ON archive.sha256=archive_alias.archive_sha256
+	WHERE archive.part_id=$1
+	ORDER BY 2`, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting names of files of part %s", id.String())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sha, name string
+		if err := rows.Scan(&sha, &name); err != nil {
+			return nil, errors.Wrapf(err, "error scanning names of files of part %s", id.String())
+		}
+
+		names[sha] = append(names[sha], name)
+	}
+	rows.Close()
+
+	rows, err = controller.DB.Queryx(`SELECT ENCODE(file.sha256, 'hex'), part_has_file.path, file.file_size,
+	COALESCE(ENCODE(file.md5, 'hex'), ''), COALESCE(ENCODE(file.sha1, 'hex'), ''),
+	COALESCE(ENCODE(file.sha512, 'hex'), ''), COALESCE(ENCODE(file.gitoid_sha1, 'hex'), ''), COALESCE(ENCODE(file.gitoid_sha256, 'hex'), '')
+	FROM part_has_file INNER JOIN file ON file.sha256=part_has_file.file_sha256
+	WHERE part_has_file.part_id=$1
+	ORDER BY part_has_file.path, file.sha256`, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting files of part %s", id.String())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f FileRecord
+		if err := rows.Scan(&f.Sha256, &f.Path, &f.Size, &f.Md5, &f.Sha1, &f.Sha512, &f.GitoidSha1, &f.GitoidSha256); err != nil {
+			return nil, errors.Wrapf(err, "error scanning files of part %s", id.String())
+		}
+		f.Names = names[f.Sha256]
+
+		ret.Files = append(ret.Files, f)
+	}
+	rows.Close()
+
+	rows, err = controller.DB.Queryx(`SELECT ENCODE(sha256, 'hex'), archive_size,
+	COALESCE(ENCODE(md5, 'hex'), ''), COALESCE(ENCODE(sha1, 'hex'), ''),
+	COALESCE(ENCODE(sha512, 'hex'), ''), COALESCE(ENCODE(gitoid_sha1, 'hex'), ''), COALESCE(ENCODE(gitoid_sha256, 'hex'), '')
+	FROM archive WHERE part_id=$1 ORDER BY sha256`, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting archives of part %s", id.String())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a ArchiveRecord
+		if err := rows.Scan(&a.Sha256, &a.Size, &a.Md5, &a.Sha1, &a.Sha512, &a.GitoidSha1, &a.GitoidSha256); err != nil {
+			return nil, errors.Wrapf(err, "error scanning archives of part %s", id.String())
+		}
+		a.Names = names[a.Sha256]
+
+		ret.Archives = append(ret.Archives, a)
+	}
+
+	return &ret, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package offline
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+)
+
+// Format and FormatVersion identify offline bundles in their manifest
+const (
+	Format        = "tk-offline-bundle"
+	FormatVersion = 1
+)
+
+// Names of the members of an offline bundle, under its root directory
+const (
+	manifestName  = "manifest.json"
+	partsName     = "parts.ndjson"
+	partlistsName = "partlists.ndjson"
+	blobDirectory = "blobs"
+)
+
+// Manifest is the first member of an offline bundle
+// Parts, PartLists, and Blobs count the members actually in this bundle, which for an incremental export are only those changed since SinceID
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Instance  string    `json:"instance"`
+	ExportID  int64     `json:"export_id"`
+	SinceID   *int64    `json:"since_export_id,omitempty"`
+	Created   time.Time `json:"created"`
+	Parts     int       `json:"parts"`
+	PartLists int       `json:"partlists"`
+	Blobs     int       `json:"blobs"`
+}
+
+// PartRecord is a line of parts.ndjson, a part with its aliases, documents, identifiers, sub-parts, relationships, files, and archives.
+// Type is the raw ltree, and hashes are hex-encoded.
+type PartRecord struct {
+	PartID               string               `json:"part_id"`
+	Type                 *string              `json:"type,omitempty"`
+	Name                 *string              `json:"name,omitempty"`
+	Version              *string              `json:"version,omitempty"`
+	Label                *string              `json:"label,omitempty"`
+	FamilyName           *string              `json:"family_name,omitempty"`
+	FileVerificationCode string               `json:"file_verification_code,omitempty"`
+	GitTreeSha1          string               `json:"git_tree_sha1,omitempty"`
+	Size                 *int64               `json:"size,omitempty"`
+	License              *string              `json:"license,omitempty"`
+	LicenseRationale     *string              `json:"license_rationale,omitempty"`
+	Description          *string              `json:"description,omitempty"`
+	Comprised            string               `json:"comprised,omitempty"`
+	Aliases              []string             `json:"aliases,omitempty"`
+	Documents            []DocumentRecord     `json:"documents,omitempty"`
+	Identifiers          []IdentifierRecord   `json:"identifiers,omitempty"`
+	SubParts             []SubPartRecord      `json:"sub_parts,omitempty"`
+	Relationships        []RelationshipRecord `json:"relationships,omitempty"`
+	Files                []FileRecord         `json:"files,omitempty"`
+	Archives             []ArchiveRecord      `json:"archives,omitempty"`
+}
+
+// DocumentRecord is a part_has_document if Title is nil, otherwise a part_documents entry
+type DocumentRecord struct {
+	Key      string          `json:"key"`
+	Title    *string         `json:"title,omitempty"`
+	Document json.RawMessage `json:"document"`
+}
+
+type IdentifierRecord struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type SubPartRecord struct {
+	PartID string `json:"part_id"`
+	Path   string `json:"path"`
+}
+
+// RelationshipRecord is a typed relationship of the part to the related part, such as DEPENDS_ON
+type RelationshipRecord struct {
+	Type      string          `json:"type"`
+	RelatedID string          `json:"related_id"`
+	Comment   *string         `json:"comment,omitempty"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+}
+
+// FileRecord is a file of a part at a path, with every name the file is known by
+type FileRecord struct {
+	Sha256       string   `json:"sha256"`
+	Path         string   `json:"path"`
+	Size         int64    `json:"size"`
+	Md5          string   `json:"md5,omitempty"`
+	Sha1         string   `json:"sha1,omitempty"`
+	Sha512       string   `json:"sha512,omitempty"`
+	GitoidSha1   string   `json:"gitoid_sha1,omitempty"`
+	GitoidSha256 string   `json:"gitoid_sha256,omitempty"`
+	Names        []string `json:"names,omitempty"`
+}
+
+// ArchiveRecord is an archive of a part, with every name the archive is known by
+type ArchiveRecord struct {
+	Sha256       string   `json:"sha256"`
+	Size         int64    `json:"size"`
+	Md5          string   `json:"md5,omitempty"`
+	Sha1         string   `json:"sha1,omitempty"`
+	Sha512       string   `json:"sha512,omitempty"`
+	GitoidSha1   string   `json:"gitoid_sha1,omitempty"`
+	GitoidSha256 string   `json:"gitoid_sha256,omitempty"`
+	Names        []string `json:"names,omitempty"`
+}
+
+// PartListRecord is a line of partlists.ndjson, parents always come before their children
+// Parts are the ids of parts in the exporting instance, a partlist without a parent in the bundle is a root
+type PartListRecord struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	ParentID *int64   `json:"parent_id,omitempty"`
+	Parts    []string `json:"parts,omitempty"`
+}
+
+// digest identifies the content of a record, to find records that changed since an earlier export
+func digest(record any) ([]byte, error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+// memberName splits a tarball member's name into the name within the bundle, without its root directory
+func memberName(name string) string {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if _, rest, ok := strings.Cut(name, "/"); ok {
+		return rest
+	}
+
+	return name
+}
+
+// blobSha256 returns the hex-encoded sha256 of a blob member, or an empty string if the member is not a blob
+func blobSha256(name string) string {
+	dir, sha := path.Split(name)
+	if path.Clean(dir) != blobDirectory || len(sha) != 64 {
+		return ""
+	}
+
+	return strings.ToLower(sha)
+}
@@ -0,0 +1,807 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package offline
+
+import (
+	"archive/tar"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"wrs/tk/packages/blob/file"
+	"wrs/tk/packages/core/audit"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/review"
+	"wrs/tk/packages/core/similarity"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Conflict is a curated field whose value differs between this instance and an imported bundle, the local value is kept
+type Conflict struct {
+	PartID     string `json:"part_id,omitempty"`
+	PartListID *int64 `json:"partlist_id,omitempty"`
+	Field      string `json:"field"`
+	Local      string `json:"local"`
+	Incoming   string `json:"incoming"`
+}
+
+// Report is the outcome of importing an offline bundle
+type Report struct {
+	ID               int64       `json:"id"`
+	Instance         string      `json:"instance"`
+	ExportID         int64       `json:"export_id"`
+	SinceID          *int64      `json:"since_export_id,omitempty"`
+	PartsCreated     int         `json:"parts_created"`
+	PartsMatched     int         `json:"parts_matched"`
+	PartIDs          []uuid.UUID `json:"part_ids"`
+	PartListsCreated int         `json:"partlists_created"`
+	PartListsMatched int         `json:"partlists_matched"`
+	Blobs            int         `json:"blobs"`
+	Conflicts        []Conflict  `json:"conflicts"`
+	Warnings         []string    `json:"warnings"`
+}
+
+// fieldValue is a curated field of a part, as it is locally and in a bundle
+type fieldValue struct {
+	field    string
+	local    *string
+	incoming *string
+}
+
+// mergeFields returns the fields to fill in, those without a local value, and the fields whose values conflict
+func mergeFields(partID string, values []fieldValue) (map[string]string, []Conflict) {
+	updates := make(map[string]string)
+	conflicts := make([]Conflict, 0)
+	for _, v := range values {
+		if v.incoming == nil || *v.incoming == "" {
+			continue
+		}
+
+		if v.local == nil || *v.local == "" {
+			updates[v.field] = *v.incoming
+		} else if *v.local != *v.incoming {
+			conflicts = append(conflicts, Conflict{PartID: partID, Field: v.field, Local: *v.local, Incoming: *v.incoming})
+		}
+	}
+
+	return updates, conflicts
+}
+
+// importer merges the records of one bundle
+type importer struct {
+	controller *OfflineController
+	actor      string
+	manifest   *Manifest
+	report     *Report
+	attached   []part.ID // parts whose files and sub-parts were imported, to be indexed for similarity
+	related    []related // relationships, imported once every part they may relate to is
+}
+
+// related is a relationship of a part record to be imported
+type related struct {
+	partID       part.ID
+	sourcePartID string
+	relationship RelationshipRecord
+}
+
+// Import merges an offline bundle into this instance.
+// Parts are matched to local ones by their origin, file verification code, archive sha256, or part id, in that order, and created otherwise.
+// Curated fields are only filled in where a local part has no value; differing values are reported as conflicts and left as they are.
+// Importing the same bundle again changes nothing, and deletions are never propagated.
+// Parts created and curated fields filled in are recorded in the history of parts as changes made by actor.
+// The report lists the parts created, attached to, or with curated fields filled in, to be matched to vulnerabilities again.
+func (controller *OfflineController) Import(r io.Reader, actor string) (*Report, error) {
+	i := importer{
+		controller: controller,
+		actor:      actor,
+		report: &Report{
+			PartIDs:   make([]uuid.UUID, 0),
+			Conflicts: make([]Conflict, 0),
+			Warnings:  make([]string, 0),
+		},
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(ErrFormat, "error reading bundle: %s", err.Error())
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := memberName(hdr.Name)
+		if name != manifestName && i.manifest == nil {
+			return nil, errors.Wrapf(ErrFormat, "%s before %s", name, manifestName)
+		}
+
+		switch name {
+		case manifestName:
+			if err := i.readManifest(tr); err != nil {
+				return nil, err
+			}
+		case partsName:
+			decoder := json.NewDecoder(tr)
+			for decoder.More() {
+				var record PartRecord
+				if err := decoder.Decode(&record); err != nil {
+					return nil, errors.Wrapf(ErrFormat, "error decoding part: %s", err.Error())
+				}
+
+				if err := i.importPart(record); err != nil {
+					return nil, err
+				}
+			}
+		case partlistsName:
+			decoder := json.NewDecoder(tr)
+			for decoder.More() {
+				var record PartListRecord
+				if err := decoder.Decode(&record); err != nil {
+					return nil, errors.Wrapf(ErrFormat, "error decoding partlist: %s", err.Error())
+				}
+
+				if err := i.importPartList(record); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			if sha := blobSha256(name); sha != "" {
+				if err := i.importBlob(tr, sha, hdr.Size); err != nil {
+					return nil, err
+				}
+			} else {
+				i.warnf("ignored unknown member %s", name)
+			}
+		}
+	}
+	if i.manifest == nil {
+		return nil, errors.Wrapf(ErrFormat, "no %s", manifestName)
+	}
+
+	for _, r := range i.related {
+		if err := i.importRelationship(r); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, partID := range i.attached {
+		if err := (similarity.SimilarityController{DB: controller.DB}).Index(uuid.UUID(partID)); err != nil {
+			i.warnf("part %s similarity signature: %s", partID.String(), err.Error())
+		}
+	}
+
+	if err := controller.DB.QueryRow(`INSERT INTO offline_import (instance, export_id, since_id, report) VALUES ($1, $2, $3, '{}') RETURNING id`,
+		i.report.Instance, i.report.ExportID, i.report.SinceID).Scan(&i.report.ID); err != nil {
+		return nil, errors.Wrapf(err, "error inserting offline import")
+	}
+	report, err := json.Marshal(i.report)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling offline import report")
+	}
+	if _, err := controller.DB.Exec("UPDATE offline_import SET report=$1 WHERE id=$2", report, i.report.ID); err != nil {
+		return nil, errors.Wrapf(err, "error updating offline import %d", i.report.ID)
+	}
+
+	return i.report, nil
+}
+
+func (i *importer) warnf(format string, args ...any) {
+	i.report.Warnings = append(i.report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// readManifest checks the bundle is supported, and whether its base was imported
+func (i *importer) readManifest(r io.Reader) error {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return errors.Wrapf(ErrFormat, "error decoding %s: %s", manifestName, err.Error())
+	}
+	if manifest.Format != Format || manifest.Version != FormatVersion {
+		return errors.Wrapf(ErrFormat, "%s version %d", manifest.Format, manifest.Version)
+	}
+	if manifest.Instance == "" {
+		return errors.Wrapf(ErrFormat, "no instance in %s", manifestName)
+	}
+
+	i.manifest = &manifest
+	i.report.Instance = manifest.Instance
+	i.report.ExportID = manifest.ExportID
+	i.report.SinceID = manifest.SinceID
+
+	if manifest.SinceID != nil {
+		var imported bool
+		if err := i.controller.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM offline_import WHERE instance=$1 AND export_id=$2)",
+			manifest.Instance, *manifest.SinceID).Scan(&imported); err != nil {
+			return errors.Wrapf(err, "error selecting offline import")
+		}
+		if !imported {
+			i.warnf("bundle is incremental since export %d of %s, which was never imported", *manifest.SinceID, manifest.Instance)
+		}
+	}
+
+	return nil
+}
+
+// localPart returns the local part a part of the bundle's instance was imported as
+func (i *importer) localPart(sourceID string) (*part.ID, error) {
+	var ret part.ID
+	if err := i.controller.DB.QueryRow("SELECT part_id FROM offline_part_origin WHERE instance=$1 AND source_part_id=$2",
+		i.manifest.Instance, sourceID).Scan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "error selecting origin of part %s", sourceID)
+	}
+
+	return &ret, nil
+}
+
+// matchPart returns the local part matching a part record, if any
+func (i *importer) matchPart(record PartRecord, fileVerificationCode []byte) (*part.ID, error) {
+	if id, err := i.localPart(record.PartID); err != nil || id != nil {
+		return id, err
+	}
+
+	if len(fileVerificationCode) > 0 {
+		p, err := i.controller.PartController.GetByVerificationCode(fileVerificationCode)
+		if err == nil {
+			return &p.PartID, nil
+		} else if err != part.ErrNotFound {
+			return nil, err
+		}
+	}
+
+	for _, a := range record.Archives {
+		sha, err := hex.DecodeString(a.Sha256)
+		if err != nil {
+			return nil, errors.Wrapf(ErrFormat, "archive sha256 %s", a.Sha256)
+		}
+
+		var id part.ID
+		if err := i.controller.DB.QueryRow("SELECT part_id FROM archive WHERE sha256=$1 AND part_id IS NOT NULL", sha).Scan(&id); err == nil {
+			return &id, nil
+		} else if err != sql.ErrNoRows {
+			return nil, errors.Wrapf(err, "error selecting archive %s", a.Sha256)
+		}
+	}
+
+	var id part.ID
+	if err := i.controller.DB.QueryRow("SELECT part_id FROM part WHERE part_id=$1", record.PartID).Scan(&id); err == nil {
+		return &id, nil
+	} else if err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "error selecting part %s", record.PartID)
+	}
+
+	return nil, nil
+}
+
+// nilIfEmpty returns a pointer to the string, or nil if it is empty
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+// decodeHex decodes an optional hex field of a record, returning nil if it is empty
+func decodeHex(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrapf(ErrFormat, "invalid hex %s", s)
+	}
+
+	return b, nil
+}
+
+// decodeContentIdentifiers decodes the hex sha512, gitoid sha1, and gitoid sha256 of a file or archive, as query arguments
+func decodeContentIdentifiers(sha512, gitoidSha1, gitoidSha256 string) ([]any, error) {
+	ret := make([]any, 0, 3)
+	for _, s := range []string{sha512, gitoidSha1, gitoidSha256} {
+		b, err := decodeHex(s)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, b)
+	}
+
+	return ret, nil
+}
+
+// importPart merges a part record, whose sub-parts and comprising part were merged before it
+// The merged part has to follow its type, see part.CheckPartType
+func (i *importer) importPart(record PartRecord) error {
+	if _, err := uuid.Parse(record.PartID); err != nil {
+		return errors.Wrapf(ErrFormat, "part id %s", record.PartID)
+	}
+	fileVerificationCode, err := decodeHex(record.FileVerificationCode)
+	if err != nil {
+		return err
+	}
+
+	var comprised *string
+	if record.Comprised != "" {
+		id, err := i.localPart(record.Comprised)
+		if err != nil {
+			return err
+		}
+		if id == nil {
+			i.warnf("part %s is comprised by unknown part %s", record.PartID, record.Comprised)
+		} else {
+			s := id.String()
+			comprised = &s
+		}
+	}
+
+	localID, err := i.matchPart(record, fileVerificationCode)
+	if err != nil {
+		return err
+	}
+
+	// whether files and sub-parts are still to be attached
+	attach := localID == nil
+
+	tx, err := i.controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	var changes []audit.Change
+	action := audit.ActionUpdate
+	if localID == nil {
+		var id part.ID
+		if err := tx.QueryRow(`INSERT INTO part
+		(part_id, type, name, version, label, family_name, file_verification_code, size, license, license_rationale, description, comprised)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING part_id`,
+			record.PartID, record.Type, record.Name, record.Version, record.Label, record.FamilyName, fileVerificationCode, record.Size,
+			record.License, record.LicenseRationale, record.Description, comprised).Scan(&id); err != nil {
+			return errors.Wrapf(err, "error inserting part %s", record.PartID)
+		}
+
+		localID = &id
+		i.report.PartsCreated++
+
+		action = audit.ActionCreate
+		for _, v := range []fieldValue{
+			{"type", nil, record.Type}, {"name", nil, record.Name}, {"version", nil, record.Version}, {"label", nil, record.Label},
+			{"family_name", nil, record.FamilyName}, {"file_verification_code", nil, nilIfEmpty(record.FileVerificationCode)},
+			{"license", nil, record.License}, {"license_rationale", nil, record.LicenseRationale}, {"description", nil, record.Description},
+			{"comprised", nil, comprised},
+		} {
+			if change := audit.Diff(v.field, v.local, v.incoming); change != nil {
+				changes = append(changes, *change)
+			}
+		}
+	} else {
+		var local part.Part
+		if err := tx.QueryRowx("SELECT * FROM part WHERE part_id=$1 FOR UPDATE", *localID).StructScan(&local); err != nil {
+			return errors.Wrapf(err, "error selecting part %s", localID.String())
+		}
+		attach = len(local.FileVerificationCode) == 0
+
+		var localComprised, localFileVerificationCode *string
+		if local.Comprised != part.ID(uuid.Nil) {
+			s := local.Comprised.String()
+			localComprised = &s
+		}
+		if len(local.FileVerificationCode) > 0 {
+			s := hex.EncodeToString(local.FileVerificationCode)
+			localFileVerificationCode = &s
+		}
+		values := []fieldValue{
+			{"type", nullString(local.Type), record.Type},
+			{"name", nullString(local.Name), record.Name},
+			{"version", nullString(local.Version), record.Version},
+			{"label", nullString(local.Label), record.Label},
+			{"family_name", nullString(local.FamilyName), record.FamilyName},
+			{"license", nullString(local.License), record.License},
+			{"license_rationale", nullString(local.LicenseRationale), record.LicenseRationale},
+			{"description", nullString(local.Description), record.Description},
+			{"comprised", localComprised, comprised},
+			{"file_verification_code", localFileVerificationCode, nilIfEmpty(record.FileVerificationCode)},
+		}
+		updates, conflicts := mergeFields(localID.String(), values)
+		i.report.Conflicts = append(i.report.Conflicts, conflicts...)
+
+		if len(updates) > 0 {
+			query := "UPDATE part SET "
+			args := []any{*localID}
+			for field, value := range updates {
+				if len(args) > 1 {
+					query += ", "
+				}
+				args = append(args, value)
+				switch field {
+				case "comprised":
+					query += fmt.Sprintf("comprised=$%d::UUID", len(args))
+				case "file_verification_code":
+					query += fmt.Sprintf("file_verification_code=DECODE($%d, 'hex')", len(args))
+				default:
+					query += fmt.Sprintf("%s=$%d", field, len(args))
+				}
+			}
+			if _, err := tx.Exec(query+" WHERE part_id=$1", args...); err != nil {
+				return errors.Wrapf(err, "error updating part %s", localID.String())
+			}
+		}
+		for _, v := range values {
+			if value, ok := updates[v.field]; ok {
+				changes = append(changes, audit.Change{Field: v.field, Old: v.local, New: &value})
+			}
+		}
+
+		i.report.PartsMatched++
+	}
+
+	if err := part.CheckPartType(tx, *localID); err != nil {
+		return errors.Wrapf(err, "part %s", record.PartID)
+	}
+	if _, err := audit.RecordPart(tx, i.actor, uuid.UUID(*localID), action, changes); err != nil {
+		return err
+	}
+	if err := review.ResetApproval(tx, i.actor, uuid.UUID(*localID), changes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "error committing part %s", record.PartID)
+	}
+
+	if _, err := i.controller.DB.Exec(`INSERT INTO offline_part_origin (instance, source_part_id, part_id) VALUES ($1, $2, $3)
+	ON CONFLICT (instance, source_part_id) DO UPDATE SET part_id=EXCLUDED.part_id`,
+		i.manifest.Instance, record.PartID, *localID); err != nil {
+		return errors.Wrapf(err, "error upserting origin of part %s", record.PartID)
+	}
+
+	// a matched part keeps the directory it already has
+	if gitTreeSha1, err := decodeHex(record.GitTreeSha1); err != nil {
+		return err
+	} else if gitTreeSha1 != nil {
+		if _, err := i.controller.DB.Exec(`UPDATE part SET git_tree_sha1=$1 WHERE part_id=$2 AND git_tree_sha1 IS NULL`, gitTreeSha1, *localID); err != nil {
+			return errors.Wrapf(err, "error updating git_tree_sha1 of part %s", localID.String())
+		}
+	}
+
+	if attach {
+		if err := i.attach(*localID, record); err != nil {
+			return err
+		}
+		i.attached = append(i.attached, *localID)
+	}
+	// parts created, attached, or with curated fields filled in are to be matched to vulnerabilities again
+	if attach || len(changes) > 0 {
+		i.report.PartIDs = append(i.report.PartIDs, uuid.UUID(*localID))
+	}
+
+	for _, alias := range record.Aliases {
+		var aliasPartID part.ID
+		if err := i.controller.DB.QueryRow(`INSERT INTO part_alias (alias, part_id) VALUES ($1, $2)
+		ON CONFLICT (alias) DO UPDATE SET alias=EXCLUDED.alias
+		RETURNING part_id`, // meaningless update required for return
+			alias, *localID).Scan(&aliasPartID); err != nil {
+			return errors.Wrapf(err, "error upserting alias %s", alias)
+		}
+
+		if aliasPartID != *localID {
+			i.report.Conflicts = append(i.report.Conflicts, Conflict{
+				PartID:   localID.String(),
+				Field:    "alias",
+				Local:    fmt.Sprintf("%s of part %s", alias, aliasPartID.String()),
+				Incoming: alias,
+			})
+		}
+	}
+
+	for _, document := range record.Documents {
+		if err := i.importDocument(*localID, document); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range record.Relationships {
+		i.related = append(i.related, related{partID: *localID, sourcePartID: record.PartID, relationship: v})
+	}
+
+	for _, v := range record.Identifiers {
+		if _, err := i.controller.PartController.AddIdentifier(*localID, v.Type, v.Value); err != nil {
+			i.warnf("part %s identifier %s %s: %s", record.PartID, v.Type, v.Value, err.Error())
+		}
+	}
+
+	for _, a := range record.Archives {
+		sha, err := decodeHex(a.Sha256)
+		if err != nil {
+			return err
+		}
+		md5, err := decodeHex(a.Md5)
+		if err != nil {
+			return err
+		}
+		sha1, err := decodeHex(a.Sha1)
+		if err != nil {
+			return err
+		}
+		identifiers, err := decodeContentIdentifiers(a.Sha512, a.GitoidSha1, a.GitoidSha256)
+		if err != nil {
+			return err
+		}
+
+		if _, err := i.controller.DB.Exec(`INSERT INTO archive (sha256, archive_size, md5, sha1, part_id, sha512, gitoid_sha1, gitoid_sha256) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (sha256) DO UPDATE SET part_id=COALESCE(archive.part_id, EXCLUDED.part_id),
+		sha512=COALESCE(archive.sha512, EXCLUDED.sha512), gitoid_sha1=COALESCE(archive.gitoid_sha1, EXCLUDED.gitoid_sha1), gitoid_sha256=COALESCE(archive.gitoid_sha256, EXCLUDED.gitoid_sha256)`,
+			append([]any{sha, a.Size, md5, sha1, *localID}, identifiers...)...); err != nil {
+			return errors.Wrapf(err, "error upserting archive %s", a.Sha256)
+		}
+		for _, name := range a.Names {
+			if _, err := i.controller.DB.Exec(`INSERT INTO archive_alias (archive_sha256, name) VALUES ($1, $2) ON CONFLICT (archive_sha256, name) DO NOTHING`,
+				sha, name); err != nil {
+				return errors.Wrapf(err, "error upserting archive_alias")
+			}
+		}
+	}
+
+	return nil
+}
+
+// attach adds the files and sub-parts of a part record to a local part that has none of its own, in one transaction, see part.AddContentToPart
+func (i *importer) attach(partID part.ID, record PartRecord) error {
+	files := make([]part.PartFile, 0, len(record.Files))
+	for _, f := range record.Files {
+		sha, err := decodeHex(f.Sha256)
+		if err != nil {
+			return err
+		}
+		md5, err := decodeHex(f.Md5)
+		if err != nil {
+			return err
+		}
+		sha1, err := decodeHex(f.Sha1)
+		if err != nil {
+			return err
+		}
+		identifiers, err := decodeContentIdentifiers(f.Sha512, f.GitoidSha1, f.GitoidSha256)
+		if err != nil {
+			return err
+		}
+
+		if _, err := i.controller.DB.Exec(`INSERT INTO file (sha256, file_size, md5, sha1, sha512, gitoid_sha1, gitoid_sha256) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (sha256) DO UPDATE SET sha512=COALESCE(file.sha512, EXCLUDED.sha512),
+		gitoid_sha1=COALESCE(file.gitoid_sha1, EXCLUDED.gitoid_sha1), gitoid_sha256=COALESCE(file.gitoid_sha256, EXCLUDED.gitoid_sha256)`,
+			append([]any{sha, f.Size, md5, sha1}, identifiers...)...); err != nil {
+			return errors.Wrapf(err, "error upserting file %s", f.Sha256)
+		}
+		for _, name := range f.Names {
+			if _, err := i.controller.DB.Exec(`INSERT INTO file_alias (file_sha256, name) VALUES ($1, $2) ON CONFLICT (file_sha256, name) DO NOTHING`,
+				sha, name); err != nil {
+				return errors.Wrapf(err, "error upserting file_alias")
+			}
+		}
+		files = append(files, part.PartFile{Sha256: sha, Path: f.Path})
+	}
+
+	subParts := make([]part.SubPart, 0, len(record.SubParts))
+	for _, v := range record.SubParts {
+		childID, err := i.localPart(v.PartID)
+		if err != nil {
+			return err
+		}
+		if childID == nil {
+			i.warnf("part %s has unknown sub-part %s at %s", record.PartID, v.PartID, v.Path)
+			continue
+		}
+
+		subParts = append(subParts, part.SubPart{ID: *childID, Path: v.Path})
+	}
+
+	if err := i.controller.PartController.As(i.actor).AddContentToPart(partID, files, subParts); err != nil {
+		return errors.Wrapf(err, "part %s", record.PartID)
+	}
+
+	return part.SetVerificationCodeOne(i.controller.DB, partID)
+}
+
+// importRelationship adds a relationship a part does not have yet, leaving the comment and metadata of one it has as they are
+func (i *importer) importRelationship(r related) error {
+	relatedID, err := i.localPart(r.relationship.RelatedID)
+	if err != nil {
+		return err
+	}
+	if relatedID == nil {
+		i.warnf("part %s is %s unknown part %s", r.sourcePartID, r.relationship.Type, r.relationship.RelatedID)
+		return nil
+	}
+
+	var exists bool
+	if err := i.controller.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM part_relationship WHERE part_id=$1 AND type=$2 AND related_id=$3)",
+		r.partID, r.relationship.Type, *relatedID).Scan(&exists); err != nil {
+		return errors.Wrapf(err, "error selecting relationship of part %s", r.partID.String())
+	}
+	if exists {
+		return nil
+	}
+
+	if err := i.controller.PartController.As(i.actor).AddRelationship(r.partID, r.relationship.Type, *relatedID, r.relationship.Comment, r.relationship.Metadata); err != nil {
+		i.warnf("part %s relationship %s %s: %s", r.sourcePartID, r.relationship.Type, r.relationship.RelatedID, err.Error())
+	}
+
+	return nil
+}
+
+// importDocument adds a document a part does not have yet, and reports a conflict if it has a different one
+func (i *importer) importDocument(partID part.ID, document DocumentRecord) error {
+	field := "document " + document.Key
+	query := "SELECT document=$3::JSONB, document::TEXT FROM part_has_document WHERE part_id=$1 AND key=$2"
+	args := []any{partID, document.Key, []byte(document.Document)}
+	if document.Title != nil {
+		field += " " + *document.Title
+		query = "SELECT document=$3::JSONB, document::TEXT FROM part_documents WHERE part_id=$1 AND key=$2 AND title=$4"
+		args = append(args, *document.Title)
+	}
+
+	var same bool
+	var local string
+	if err := i.controller.DB.QueryRow(query, args...).Scan(&same, &local); err == nil {
+		if !same {
+			i.report.Conflicts = append(i.report.Conflicts, Conflict{
+				PartID:   partID.String(),
+				Field:    field,
+				Local:    local,
+				Incoming: string(document.Document),
+			})
+		}
+
+		return nil
+	} else if err != sql.ErrNoRows {
+		return errors.Wrapf(err, "error selecting %s of part %s", field, partID.String())
+	}
+
+	if document.Title == nil {
+		if _, err := i.controller.DB.Exec(`INSERT INTO part_has_document (part_id, key, document) VALUES ($1, $2, $3)`,
+			partID, document.Key, []byte(document.Document)); err != nil {
+			return errors.Wrapf(err, "error inserting into part_has_document")
+		}
+	} else {
+		if _, err := i.controller.DB.Exec(`INSERT INTO part_documents (part_id, key, title, document) VALUES ($1, $2, $3, $4)`,
+			partID, document.Key, *document.Title, []byte(document.Document)); err != nil {
+			return errors.Wrapf(err, "error inserting into part_documents")
+		}
+	}
+
+	return nil
+}
+
+// importPartList merges a partlist record, whose parent was merged before it
+func (i *importer) importPartList(record PartListRecord) error {
+	var parentID *int64
+	if record.ParentID != nil {
+		var id int64
+		if err := i.controller.DB.QueryRow("SELECT partlist_id FROM offline_partlist_origin WHERE instance=$1 AND source_partlist_id=$2",
+			i.manifest.Instance, *record.ParentID).Scan(&id); err == nil {
+			parentID = &id
+		} else if err != sql.ErrNoRows {
+			return errors.Wrapf(err, "error selecting origin of partlist %d", *record.ParentID)
+		} else {
+			i.warnf("partlist %d has unknown parent %d", record.ID, *record.ParentID)
+		}
+	}
+
+	var id int64
+	err := i.controller.DB.QueryRow("SELECT partlist_id FROM offline_partlist_origin WHERE instance=$1 AND source_partlist_id=$2",
+		i.manifest.Instance, record.ID).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = i.controller.DB.QueryRow("SELECT id FROM partlist WHERE name=$1 AND parent_id IS NOT DISTINCT FROM $2 ORDER BY id LIMIT 1",
+			record.Name, parentID).Scan(&id)
+	}
+	if err == nil {
+		i.report.PartListsMatched++
+	} else if err == sql.ErrNoRows {
+		if err := i.controller.DB.QueryRow("INSERT INTO partlist (name, parent_id) VALUES ($1, $2) RETURNING id",
+			record.Name, parentID).Scan(&id); err != nil {
+			return errors.Wrapf(err, "error inserting partlist %s", record.Name)
+		}
+
+		i.report.PartListsCreated++
+	} else {
+		return errors.Wrapf(err, "error selecting partlist %s", record.Name)
+	}
+
+	if _, err := i.controller.DB.Exec(`INSERT INTO offline_partlist_origin (instance, source_partlist_id, partlist_id) VALUES ($1, $2, $3)
+	ON CONFLICT (instance, source_partlist_id) DO UPDATE SET partlist_id=EXCLUDED.partlist_id`,
+		i.manifest.Instance, record.ID, id); err != nil {
+		return errors.Wrapf(err, "error upserting origin of partlist %d", record.ID)
+	}
+
+	for _, v := range record.Parts {
+		partID, err := i.localPart(v)
+		if err != nil {
+			return err
+		}
+		if partID == nil {
+			i.warnf("partlist %d lists unknown part %s", record.ID, v)
+			continue
+		}
+
+		if _, err := i.controller.DB.Exec("INSERT INTO partlist_has_part (partlist_id, part_id) VALUES ($1, $2) ON CONFLICT (partlist_id, part_id) DO NOTHING",
+			id, *partID); err != nil {
+			return errors.Wrapf(err, "error inserting part %s into partlist %d", partID.String(), id)
+		}
+	}
+
+	return nil
+}
+
+// importBlob verifies the content of a file or archive against its sha256, and stores it
+func (i *importer) importBlob(r io.Reader, sha string, size int64) error {
+	f, err := os.CreateTemp("", "offline-blob-*")
+	if err != nil {
+		return errors.Wrapf(err, "error creating temporary file")
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	sha256Hash := sha256.New()
+	sha1Hash := sha1.New()
+	if n, err := io.Copy(io.MultiWriter(f, sha256Hash, sha1Hash), r); err != nil {
+		return errors.Wrapf(err, "error spooling blob %s", sha)
+	} else if n != size {
+		return errors.Wrapf(ErrFormat, "blob %s is %d bytes, expected %d", sha, n, size)
+	}
+
+	info := file.FileInfo{Size: size}
+	copy(info.Sha256[:], sha256Hash.Sum(nil))
+	copy(info.Sha1[:], sha1Hash.Sum(nil))
+	if !strings.EqualFold(hex.EncodeToString(info.Sha256[:]), sha) {
+		return errors.Wrapf(ErrFormat, "blob %s has sha256 %x", sha, info.Sha256[:])
+	}
+
+	mimeType, err := mimetype.DetectFile(f.Name())
+	if err != nil {
+		return errors.Wrapf(err, "error detecting mimetype")
+	}
+	info.MimeType = mimeType.String()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "error seeking blob %s", sha)
+	}
+
+	var isArchive bool
+	if err := i.controller.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM archive WHERE sha256=$1)", info.Sha256[:]).Scan(&isArchive); err != nil {
+		return errors.Wrapf(err, "error selecting archive %s", sha)
+	}
+	if isArchive {
+		err = i.controller.ArchiveController.UploadArchive(f, &info)
+	} else {
+		err = i.controller.ArchiveController.UploadFile(f, &info)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error storing blob %s", sha)
+	}
+
+	i.report.Blobs++
+
+	return nil
+}
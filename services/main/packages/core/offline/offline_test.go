@@ -0,0 +1,51 @@
+package offline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeFields(t *testing.T) {
+	s := func(v string) *string { return &v }
+	updates, conflicts := mergeFields("p", []fieldValue{
+		{"name", nil, s("busybox")},
+		{"version", s(""), s("1.36.0")},
+		{"license", s("GPL-2.0-only"), s("GPL-2.0-or-later")},
+		{"label", s("same"), s("same")},
+		{"description", s("kept"), nil},
+	})
+
+	if want := map[string]string{"name": "busybox", "version": "1.36.0"}; !reflect.DeepEqual(updates, want) {
+		t.Errorf("mergeFields() updates = %v, want %v", updates, want)
+	}
+	if want := []Conflict{{PartID: "p", Field: "license", Local: "GPL-2.0-only", Incoming: "GPL-2.0-or-later"}}; !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("mergeFields() conflicts = %v, want %v", conflicts, want)
+	}
+}
+
+func TestMemberName(t *testing.T) {
+	sha := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	tests := []struct {
+		name   string
+		member string
+		want   string
+		blob   string
+	}{
+		{name: "manifest", member: "offline-export-1/manifest.json", want: manifestName},
+		{name: "dot prefix", member: "./offline-export-1/parts.ndjson", want: partsName},
+		{name: "blob", member: "offline-export-1/blobs/" + sha, want: "blobs/" + sha, blob: sha},
+		{name: "not a sha256", member: "offline-export-1/blobs/abc", want: "blobs/abc"},
+		{name: "nested blob", member: "offline-export-1/blobs/x/" + sha, want: "blobs/x/" + sha},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := memberName(tt.member)
+			if got != tt.want {
+				t.Errorf("memberName() = %s, want %s", got, tt.want)
+			}
+			if blob := blobSha256(got); blob != tt.blob {
+				t.Errorf("blobSha256() = %s, want %s", blob, tt.blob)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package offline
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+	"wrs/tk/packages/core/archive"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Export records an offline bundle written by this instance
+type Export struct {
+	ID         int64         `db:"id"`
+	SinceID    sql.NullInt64 `db:"since_id"`
+	PartIDs    PartIDs       `db:"part_ids"`
+	PartListID sql.NullInt64 `db:"partlist_id"`
+	Blobs      bool          `db:"blobs"`
+	Complete   bool          `db:"complete"`
+	InsertDate time.Time     `db:"insert_date"`
+}
+
+// PartIDs is stored as a JSONB array
+type PartIDs []string
+
+// Scan implements database/sql.scanner interface
+func (ids *PartIDs) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*ids = make(PartIDs, 0)
+		return nil
+	case []byte:
+		return json.Unmarshal(v, ids)
+	case string:
+		return json.Unmarshal([]byte(v), ids)
+	default:
+		return errors.Errorf("failed to scan part ids from %T", value)
+	}
+}
+
+type OfflineController struct {
+	DB                 *sqlx.DB
+	ArchiveController  *archive.ArchiveController
+	PartController     part.PartController
+	PartListController partlist.PartListController
+}
+
+// NewOfflineController creates a controller exporting and importing the parts of the part controller's catalog instance, with file and archive contents from the archive controller's storage
+// Imported parts are written by the part controller, with its enforcement of file verification codes
+func NewOfflineController(db *sqlx.DB, archiveController *archive.ArchiveController, partController part.PartController) *OfflineController {
+	return &OfflineController{
+		DB:                 db,
+		ArchiveController:  archiveController,
+		PartController:     partController,
+		PartListController: partlist.PartListController{DB: db},
+	}
+}
+
+// GetExport returns the export with the given id
+func (controller *OfflineController) GetExport(id int64) (*Export, error) {
+	var ret Export
+	if err := controller.DB.QueryRowx("SELECT * FROM offline_export WHERE id=$1", id).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting offline export %d", id)
+	}
+
+	return &ret, nil
+}
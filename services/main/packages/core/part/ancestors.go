@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Parent is a part containing a part, at the path of the part within it
+type Parent struct {
+	Part
+	Path string `db:"path"`
+}
+
+// Ancestor is a part containing a part, directly or through other parts, depth sub-parts above it
+// Path is the path of the part within the ancestor, the paths of every sub-part in between joined by /
+type Ancestor struct {
+	Part
+	Depth int    `db:"depth"`
+	Path  string `db:"path"`
+}
+
+// Parents returns the parts the given part is a sub-part of, with its path within each
+func (controller PartController) Parents(partID ID) ([]Parent, error) {
+	ret := make([]Parent, 0)
+	if err := controller.DB.Select(&ret, `SELECT part.*, php.path FROM part_has_part php
+		INNER JOIN part ON part.part_id=php.parent_id
+		WHERE php.child_id=$1 ORDER BY php.path, php.parent_id`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parents of part %s", partID.String())
+	}
+	for i := range ret {
+		if ret[i].Type.Valid {
+			ret[i].Type.String = "/" + strings.ReplaceAll(ret[i].Type.String, ".", "/")
+		}
+	}
+
+	return ret, nil
+}
+
+// Ancestors returns every part containing the given part, at most maxDepth sub-parts above it, or at any depth if maxDepth is less than 1.
+// Ancestors reached along several paths are returned once, at their nearest depth, closest first.
+func (controller PartController) Ancestors(partID ID, maxDepth int) ([]Ancestor, error) {
+	ret := make([]Ancestor, 0)
+	if err := controller.DB.Select(&ret, `SELECT part.*, nearest.depth, nearest.path FROM (
+		SELECT DISTINCT ON (ancestor_id) ancestor_id, depth, path FROM part_closure
+		WHERE descendant_id=$1 AND ($2 < 1 OR depth <= $2) ORDER BY ancestor_id, depth, path
+	) nearest INNER JOIN part ON part.part_id=nearest.ancestor_id ORDER BY nearest.depth, nearest.path`, partID, maxDepth); err != nil {
+		return nil, errors.Wrapf(err, "error selecting ancestors of part %s", partID.String())
+	}
+	for i := range ret {
+		if ret[i].Type.Valid {
+			ret[i].Type.String = "/" + strings.ReplaceAll(ret[i].Type.String, ".", "/")
+		}
+	}
+
+	return ret, nil
+}
+
+// Descendant is a part beneath a part, directly or through other parts, depth sub-parts below it
+// Path is the path of the descendant within the part, the paths of every sub-part in between joined by /
+type Descendant struct {
+	Part
+	Depth int    `db:"depth"`
+	Path  string `db:"path"`
+}
+
+// Descendants returns every part beneath the given part, at most maxDepth sub-parts below it, or at any depth if maxDepth is less than 1.
+// Descendants reached along several paths are returned once, at their nearest depth, closest first.
+func (controller PartController) Descendants(partID ID, maxDepth int) ([]Descendant, error) {
+	ret := make([]Descendant, 0)
+	if err := controller.DB.Select(&ret, `SELECT part.*, nearest.depth, nearest.path FROM (
+		SELECT DISTINCT ON (descendant_id) descendant_id, depth, path FROM part_closure
+		WHERE ancestor_id=$1 AND ($2 < 1 OR depth <= $2) ORDER BY descendant_id, depth, path
+	) nearest INNER JOIN part ON part.part_id=nearest.descendant_id ORDER BY nearest.depth, nearest.path`, partID, maxDepth); err != nil {
+		return nil, errors.Wrapf(err, "error selecting descendants of part %s", partID.String())
+	}
+	for i := range ret {
+		if ret[i].Type.Valid {
+			ret[i].Type.String = "/" + strings.ReplaceAll(ret[i].Type.String, ".", "/")
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,86 @@
+package part
+
+import "testing"
+
+func TestAncestors(t *testing.T) {
+	controller := PartController{DB: testDB(t)}
+	tree := createTestTree(t, controller.DB, 2, 3)
+	leaf := tree.Leaves[0]
+
+	parents, err := controller.Parents(leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parents) != 1 || parents[0].Path != "0" {
+		t.Fatalf("Parents() = %+v, want one at path 0", parents)
+	}
+	parent := parents[0].PartID
+	grandparents, err := controller.Parents(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grandparents) != 1 {
+		t.Fatalf("Parents() = %+v, want one", grandparents)
+	}
+	grandparent := grandparents[0].PartID
+
+	type want struct {
+		id    ID
+		depth int
+		path  string
+	}
+	check := func(maxDepth int, wants []want) {
+		t.Helper()
+		got, err := controller.Ancestors(leaf, maxDepth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(wants) {
+			t.Fatalf("Ancestors(%d) = %d ancestors, want %d", maxDepth, len(got), len(wants))
+		}
+		for i, w := range wants {
+			if got[i].PartID != w.id || got[i].Depth != w.depth || got[i].Path != w.path {
+				t.Errorf("Ancestors(%d)[%d] = %s at depth %d and path %s, want depth %d and path %s", maxDepth, i, got[i].PartID.String(), got[i].Depth, got[i].Path, w.depth, w.path)
+			}
+		}
+	}
+
+	check(0, []want{{parent, 1, "0"}, {grandparent, 2, "0/0"}, {tree.Root, 3, "0/0/0"}})
+	check(1, []want{{parent, 1, "0"}})
+	check(2, []want{{parent, 1, "0"}, {grandparent, 2, "0/0"}})
+
+	// with the parent also directly beneath the root, the root is reached at depths 2 and 3, and listed once at 2
+	if _, err := controller.DB.Exec("INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, 'shortcut')", tree.Root, parent); err != nil {
+		t.Fatal(err)
+	}
+	check(0, []want{{parent, 1, "0"}, {grandparent, 2, "0/0"}, {tree.Root, 2, "shortcut/0"}})
+	check(2, []want{{parent, 1, "0"}, {grandparent, 2, "0/0"}, {tree.Root, 2, "shortcut/0"}})
+	check(1, []want{{parent, 1, "0"}})
+
+	// the leaf is beneath the root twice, and listed once at its nearest depth
+	descendants, err := controller.Descendants(tree.Root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, v := range descendants {
+		if v.PartID == leaf {
+			count++
+			if v.Depth != 2 || v.Path != "shortcut/0" {
+				t.Errorf("Descendants() has the leaf at depth %d and path %s, want depth 2 and path shortcut/0", v.Depth, v.Path)
+			}
+		}
+	}
+	if count != 1 {
+		t.Errorf("Descendants() has the leaf %d times, want once", count)
+	}
+
+	// the parent now has two parents, ordered by path
+	parents, err = controller.Parents(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parents) != 2 || parents[0].PartID != grandparent || parents[0].Path != "0" || parents[1].PartID != tree.Root || parents[1].Path != "shortcut" {
+		t.Errorf("Parents() = %+v, want the grandparent at path 0 and the root at path shortcut", parents)
+	}
+}
@@ -0,0 +1,216 @@
+package part
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// testDB connects to TK_TEST_DB, the connection string of a catalog database migrated by goose, skipping without one
+func testDB(tb testing.TB) *sqlx.DB {
+	tb.Helper()
+	connStr := os.Getenv("TK_TEST_DB")
+	if connStr == "" {
+		tb.Skip("TK_TEST_DB is not set")
+	}
+
+	db, err := sqlx.Open("pgx", connStr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// testTree is a hierarchy of parts in the test database, each containing width parts, depth parts deep, with a file in every leaf.
+// The parts and files are named by a prefix of the tree, and deleted when the test or benchmark is done.
+type testTree struct {
+	Root   ID
+	Leaves []ID
+}
+
+func createTestTree(tb testing.TB, db *sqlx.DB, width int, depth int) testTree {
+	tb.Helper()
+	prefix := fmt.Sprintf("test-tree-%d", time.Now().UnixNano())
+	tb.Cleanup(func() {
+		for _, query := range []string{
+			"DELETE FROM part_has_file WHERE part_id IN (SELECT part_id FROM part WHERE name LIKE $1 || '/%')",
+			"DELETE FROM part_has_part WHERE parent_id IN (SELECT part_id FROM part WHERE name LIKE $1 || '/%')",
+			"DELETE FROM part WHERE name LIKE $1 || '/%'",
+			"DELETE FROM file WHERE label=$1",
+		} {
+			if _, err := db.Exec(query, prefix); err != nil {
+				tb.Errorf("error deleting test tree: %v", err)
+			}
+		}
+	})
+
+	insertPart := func(name string) ID {
+		var id ID
+		if err := db.QueryRow("INSERT INTO part (name) VALUES ($1) RETURNING part_id", prefix+"/"+name).Scan(&id); err != nil {
+			tb.Fatal(err)
+		}
+		return id
+	}
+
+	ret := testTree{Root: insertPart("root")}
+	level := []ID{ret.Root}
+	for d := 0; d < depth; d++ {
+		next := make([]ID, 0, len(level)*width)
+		for i, parent := range level {
+			for w := 0; w < width; w++ {
+				child := insertPart(fmt.Sprintf("%d.%d.%d", d, i, w))
+				if _, err := db.Exec("INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, $3)", parent, child, fmt.Sprint(w)); err != nil {
+					tb.Fatal(err)
+				}
+				next = append(next, child)
+			}
+		}
+		level = next
+	}
+
+	for i, leaf := range level {
+		name := fmt.Sprintf("%s/%d", prefix, i)
+		if _, err := db.Exec("INSERT INTO file (sha256, sha1, label) VALUES (DIGEST($1, 'sha256'), DIGEST($1, 'sha1'), $2)", name, prefix); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := db.Exec("INSERT INTO part_has_file (part_id, file_sha256, path) VALUES ($1, DIGEST($2, 'sha256'), 'file')", leaf, name); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	ret.Leaves = level
+
+	return ret
+}
+
+func TestPartClosureDiamond(t *testing.T) {
+	db := testDB(t)
+
+	// top contains left and right at the same path, and both contain bottom at the same path, so bottom is beneath top twice at lib/x
+	type edge struct {
+		parent string
+		child  string
+		path   string
+	}
+	topLeft, topRight, leftBottom, rightBottom := edge{"top", "left", "lib"}, edge{"top", "right", "lib"}, edge{"left", "bottom", "x"}, edge{"right", "bottom", "x"}
+	for _, tt := range []struct {
+		name   string
+		insert []edge
+		delete []edge
+	}{
+		{name: "top down", insert: []edge{topLeft, topRight, leftBottom, rightBottom}, delete: []edge{topLeft, rightBottom, topRight, leftBottom}},
+		{name: "bottom up", insert: []edge{leftBottom, rightBottom, topLeft, topRight}, delete: []edge{leftBottom, topRight, rightBottom, topLeft}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := db.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+
+			ids := make(map[string]ID)
+			for _, name := range []string{"top", "left", "right", "bottom"} {
+				var id ID
+				if err := tx.QueryRow("INSERT INTO part (name) VALUES ($1) RETURNING part_id", name).Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				ids[name] = id
+			}
+			routes := func() int {
+				var ret int
+				if err := tx.QueryRow("SELECT COALESCE(SUM(routes), 0) FROM part_closure WHERE ancestor_id=$1 AND descendant_id=$2 AND depth=2 AND path='lib/x'",
+					ids["top"], ids["bottom"]).Scan(&ret); err != nil {
+					t.Fatal(err)
+				}
+				return ret
+			}
+			drift := func() {
+				for _, query := range []string{closureDrift("down", "part_closure"), closureDrift("part_closure", "down")} {
+					keys := make([]string, 0)
+					if err := tx.Select(&keys, query); err != nil {
+						t.Fatal(err)
+					}
+					for _, key := range keys {
+						if strings.Contains(key, ids["top"].String()) || strings.Contains(key, ids["left"].String()) || strings.Contains(key, ids["right"].String()) {
+							t.Errorf("part_closure drifted from part_has_part: %s", key)
+						}
+					}
+				}
+			}
+
+			for _, e := range tt.insert {
+				if _, err := tx.Exec("INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, $3)", ids[e.parent], ids[e.child], e.path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := routes(); got != 2 {
+				t.Errorf("routes from top to bottom = %d, want 2", got)
+			}
+			drift()
+
+			for i, e := range tt.delete {
+				if _, err := tx.Exec("DELETE FROM part_has_part WHERE parent_id=$1 AND child_id=$2 AND path=$3", ids[e.parent], ids[e.child], e.path); err != nil {
+					t.Fatal(err)
+				}
+				want := 0
+				if i == 0 {
+					want = 1 // bottom is still beneath top by the other side of the diamond
+				}
+				if got := routes(); got != want {
+					t.Errorf("routes from top to bottom after deleting %s %s = %d, want %d", e.parent, e.child, got, want)
+				}
+				drift()
+			}
+		})
+	}
+}
+
+func TestPartHasPartAcyclic(t *testing.T) {
+	controller := PartController{DB: testDB(t)}
+	tree := createTestTree(t, controller.DB, 1, 2)
+	grandchild := tree.Leaves[0]
+
+	for _, tt := range []struct {
+		name   string
+		parent ID
+	}{
+		{name: "self edge", parent: tree.Root},
+		{name: "through grandchild", parent: grandchild},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := controller.DB.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+
+			_, err = tx.Exec("INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, 'back')", tt.parent, tree.Root)
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) || pgErr.Code != "23514" {
+				t.Fatalf("inserting a back-edge = %v, want a check_violation", err)
+			}
+			if err := subPartError(err); !errors.Is(err, ErrCycle) {
+				t.Errorf("subPartError() = %v, want ErrCycle", err)
+			}
+
+			if err := controller.AddPartToPart(tree.Root, tt.parent, "back"); !errors.Is(err, ErrCycle) {
+				t.Errorf("AddPartToPart() = %v, want ErrCycle", err)
+			}
+			var count int
+			if err := controller.DB.QueryRow("SELECT COUNT(*) FROM part_has_part WHERE child_id=$1", tree.Root).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Errorf("the root is a sub-part of %d parts, want none", count)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Embedded is a known part found as a directory of an uploaded archive, at Path within the part of the archive
+// Part is the other side of the match: the embedded part for GetEmbedded, the containing part for GetEmbeddedIn
+// Linked is whether the directory was added as the known part, rather than as its files
+type Embedded struct {
+	Part
+	Path   string `db:"embedded_path"`
+	Linked bool   `db:"embedded_linked"`
+}
+
+// GetEmbedded returns the known parts found embedded in the part, by path
+func (controller PartController) GetEmbedded(partID ID) ([]Embedded, error) {
+	ret := make([]Embedded, 0)
+	if err := controller.DB.Select(&ret, `SELECT part.*, e.path AS embedded_path, e.linked AS embedded_linked
+		FROM part_embedded e INNER JOIN part ON part.part_id=e.embedded_id
+		WHERE e.part_id=$1 ORDER BY e.path`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts embedded in part %s", partID.String())
+	}
+	for i := range ret {
+		if ret[i].Type.Valid {
+			ret[i].Type.String = "/" + strings.ReplaceAll(ret[i].Type.String, ".", "/")
+		}
+	}
+
+	return ret, nil
+}
+
+// GetEmbeddedIn returns the parts the part was found embedded in, with its path within each
+func (controller PartController) GetEmbeddedIn(partID ID) ([]Embedded, error) {
+	ret := make([]Embedded, 0)
+	if err := controller.DB.Select(&ret, `SELECT part.*, e.path AS embedded_path, e.linked AS embedded_linked
+		FROM part_embedded e INNER JOIN part ON part.part_id=e.part_id
+		WHERE e.embedded_id=$1 ORDER BY e.part_id, e.path`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts part %s is embedded in", partID.String())
+	}
+	for i := range ret {
+		if ret[i].Type.Valid {
+			ret[i].Type.String = "/" + strings.ReplaceAll(ret[i].Type.String, ".", "/")
+		}
+	}
+
+	return ret, nil
+}
@@ -13,3 +13,14 @@ package part
 import "fmt"
 
 var ErrNotFound error = fmt.Errorf("part not found")
+var ErrIdentifierNotFound error = fmt.Errorf("part identifier not found")
+var ErrForeignInstance error = fmt.Errorf("uri references another catalog instance")
+var ErrRevisionNotFound error = fmt.Errorf("part revision not found")
+var ErrMergeConflict error = fmt.Errorf("parts have conflicting values")
+var ErrUnknownType error = fmt.Errorf("part type not registered")
+var ErrInvalidType error = fmt.Errorf("invalid part type")
+var ErrMissingField error = fmt.Errorf("part is missing a field its type requires")
+var ErrSubPartsNotAllowed error = fmt.Errorf("part type does not allow sub-parts")
+var ErrInvalidRelationship error = fmt.Errorf("invalid part relationship")
+var ErrCycle error = fmt.Errorf("part would be its own ancestor")
+var ErrVerificationCodeMismatch error = fmt.Errorf("file verification code does not match the files of the part")
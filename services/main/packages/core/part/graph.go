@@ -25,60 +25,43 @@ type PartGraph struct {
 	Graph *generic.DirectedGraph[string, string]
 }
 
+// partEdge is a part_has_part edge of a part graph
+type partEdge struct {
+	ParentID string `db:"parent_id"`
+	ChildID  string `db:"child_id"`
+}
+
+// NewPartGraph loads the graph of a part and every part beneath it, in one query of part_has_part by part_closure
+// Only sub-parts are followed, relationships between parts are not, see Traverse for those
 func NewPartGraph(db *sqlx.DB, partID uuid.UUID) (*PartGraph, error) {
+	edges := make([]partEdge, 0)
+	if err := db.Select(&edges, `SELECT DISTINCT parent_id::TEXT, child_id::TEXT FROM part_has_part
+	WHERE parent_id=$1 OR parent_id IN (SELECT descendant_id FROM part_closure WHERE ancestor_id=$1)
+	ORDER BY 1, 2`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting part_has_part beneath part %s", partID.String())
+	}
+
+	return buildPartGraph(partID.String(), edges), nil
+}
+
+// buildPartGraph makes the graph of the root from the edges beneath it, the root's edges are only tracked by Edges
+func buildPartGraph(root string, edges []partEdge) *PartGraph {
 	pgraph := new(PartGraph)
-	pgraph.ID = partID.String()
+	pgraph.ID = root
 	pgraph.Edges = make([]string, 0)
 	pgraph.Graph = generic.NewDirectedGraph[string, string]()
 
-	// Add root's sub-parts to edges
-	rows, err := db.Queryx("SELECT child_id FROM part_has_part WHERE parent_id=$1", partID)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error selecting part's direct children")
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var tmp uuid.UUID
-		if err := rows.Scan(&tmp); err != nil {
-			return nil, errors.Wrapf(err, "error scanning part's direct children")
+	for _, edge := range edges {
+		child := pgraph.Graph.Insert(edge.ChildID, edge.ChildID)
+		if edge.ParentID == root {
+			pgraph.Edges = append(pgraph.Edges, edge.ChildID)
+			continue
 		}
 
-		pgraph.Edges = append(pgraph.Edges, tmp.String())
-		pgraph.Graph.Insert(tmp.String(), tmp.String())
-	}
-	rows.Close()
-
-	// Traverse edges and add their edges to the graph
-	if len(pgraph.Edges) > 0 {
-		if err := pgraph.Graph.TraverseUniqueEdges(func(id string) error {
-			if id == pgraph.ID { // skip root node
-				return nil
-			}
-
-			currentNode := pgraph.Graph.Get(id)
-			rows, err := db.Queryx("SELECT child_id FROM part_has_part WHERE parent_id=$1", id) // TODO do I need to parse the uuid string for this query?
-			if err != nil {
-				return errors.Wrapf(err, "error selecting part's childern")
-			}
-			defer rows.Close()
-
-			for rows.Next() {
-				var tmp uuid.UUID
-				if err := rows.Scan(&tmp); err != nil {
-					return errors.Wrapf(err, "error scanning part's children")
-				}
-
-				currentNode.Edges.Add(pgraph.Graph.Insert(tmp.String(), tmp.String()))
-			}
-
-			return nil
-		}, pgraph.Edges...); err != nil {
-			return nil, err
-		}
+		pgraph.Graph.Insert(edge.ParentID, edge.ParentID).Edges.Add(child)
 	}
 
-	return pgraph, nil
+	return pgraph
 }
 
 func (fcg PartGraph) TraverseUniqueEdges(visitor func(id string) error) error {
@@ -0,0 +1,160 @@
+package part
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildPartGraph(t *testing.T) {
+	edges := []partEdge{
+		{ParentID: "root", ChildID: "a"},
+		{ParentID: "root", ChildID: "b"},
+		{ParentID: "a", ChildID: "c"},
+		{ParentID: "b", ChildID: "c"},
+		{ParentID: "c", ChildID: "d"},
+	}
+
+	pgraph := buildPartGraph("root", edges)
+	if fmt.Sprint(pgraph.Edges) != "[a b]" {
+		t.Errorf("Edges = %v, want [a b]", pgraph.Edges)
+	}
+	if pgraph.Graph.Get("root") != nil {
+		t.Errorf("root is in the graph")
+	}
+
+	visited := make([]string, 0)
+	if err := pgraph.TraverseUniqueEdges(func(id string) error {
+		visited = append(visited, id)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(visited) != "[a b c c d]" { // once per edge, so c twice
+		t.Errorf("visited %v, want [a b c c d]", visited)
+	}
+}
+
+// chainEdges is a hierarchy of parts each containing the next, depth parts deep
+func chainEdges(depth int) []partEdge {
+	ret := make([]partEdge, 0, depth)
+	parent := "root"
+	for i := 0; i < depth; i++ {
+		child := fmt.Sprintf("part-%d", i)
+		ret = append(ret, partEdge{ParentID: parent, ChildID: child})
+		parent = child
+	}
+
+	return ret
+}
+
+// treeEdges is a hierarchy of parts each containing width parts, depth parts deep
+func treeEdges(width int, depth int) []partEdge {
+	ret := make([]partEdge, 0)
+	level := []string{"root"}
+	for d := 0; d < depth; d++ {
+		next := make([]string, 0, len(level)*width)
+		for _, parent := range level {
+			for w := 0; w < width; w++ {
+				child := fmt.Sprintf("%s.%d", parent, w)
+				ret = append(ret, partEdge{ParentID: parent, ChildID: child})
+				next = append(next, child)
+			}
+		}
+		level = next
+	}
+
+	return ret
+}
+
+func BenchmarkBuildPartGraph(b *testing.B) {
+	for _, bm := range []struct {
+		name  string
+		edges []partEdge
+	}{
+		{name: "chain of 1000", edges: chainEdges(1000)},
+		{name: "chain of 5000", edges: chainEdges(5000)},
+		{name: "4 wide 7 deep", edges: treeEdges(4, 7)},
+		{name: "10 wide 4 deep", edges: treeEdges(10, 4)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				pgraph := buildPartGraph("root", bm.edges)
+				if err := pgraph.TraverseUniqueEdges(func(string) error { return nil }); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// The benchmarks of the database paths below run against TK_TEST_DB, see testDB, on trees of parts created for them
+
+func BenchmarkPartClosure(b *testing.B) {
+	db := testDB(b)
+	for _, bm := range []struct {
+		name  string
+		width int
+		depth int
+	}{
+		{name: "chain of 200", width: 1, depth: 200},
+		{name: "4 wide 5 deep", width: 4, depth: 5},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			tree := createTestTree(b, db, bm.width, bm.depth)
+			parent := createTestTree(b, db, 1, 1).Leaves[0]
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// part_has_part_closure adds the routes to every part of the tree, and removes them again
+				if _, err := db.Exec("INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, 'tree')", parent, tree.Root); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := db.Exec("DELETE FROM part_has_part WHERE parent_id=$1 AND child_id=$2", parent, tree.Root); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCountFiles(b *testing.B) {
+	controller := PartController{DB: testDB(b)}
+	tree := createTestTree(b, controller.DB, 4, 6)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := controller.CountFiles(tree.Root); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewPartGraph(b *testing.B) {
+	db := testDB(b)
+	tree := createTestTree(b, db, 4, 6)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewPartGraph(db, uuid.UUID(tree.Root)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRefreshVerificationCodes(b *testing.B) {
+	controller := PartController{DB: testDB(b)}
+	tree := createTestTree(b, controller.DB, 1, 200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// the codes of the leaf and its 200 ancestors are computed, and rolled back
+		tx, err := controller.DB.Beginx()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := controller.refreshVerificationCodes(tx, tree.Leaves[0]); err != nil {
+			b.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"wrs/tk/packages/core/audit"
+	"wrs/tk/packages/core/review"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// curatedColumns are the columns of part whose changes are kept in its history
+var curatedColumns = []string{"type", "name", "version", "label", "family_name", "file_verification_code", "license", "license_rationale", "description", "comprised"}
+
+// As returns a copy of the controller whose changes are attributed to actor
+func (controller PartController) As(actor string) PartController {
+	controller.Actor = actor
+	return controller
+}
+
+// curatedValue returns the value of a curated column of a part as selected, the way its history shows it
+func curatedValue(p Part, column string) *string {
+	var v sql.NullString
+	switch column {
+	case "type":
+		if p.Type.Valid {
+			v = sql.NullString{String: "/" + strings.ReplaceAll(p.Type.String, ".", "/"), Valid: true}
+		}
+	case "name":
+		v = p.Name
+	case "version":
+		v = p.Version
+	case "label":
+		v = p.Label
+	case "family_name":
+		v = p.FamilyName
+	case "file_verification_code":
+		v = sql.NullString{String: hex.EncodeToString(p.FileVerificationCode), Valid: len(p.FileVerificationCode) > 0}
+	case "license":
+		v = p.License
+	case "license_rationale":
+		v = p.LicenseRationale
+	case "description":
+		v = p.Description
+	case "comprised":
+		v = sql.NullString{String: p.Comprised.String(), Valid: p.Comprised != ID(uuid.Nil)}
+	}
+	if !v.Valid {
+		return nil
+	}
+
+	return &v.String
+}
+
+// diffCurated returns the changes to the curated columns of a part between two selections of it
+func diffCurated(before Part, after Part) []audit.Change {
+	ret := make([]audit.Change, 0)
+	for _, column := range curatedColumns {
+		if change := audit.Diff(column, curatedValue(before, column), curatedValue(after, column)); change != nil {
+			ret = append(ret, *change)
+		}
+	}
+
+	return ret
+}
+
+// selectForUpdate selects a part as is, and locks it until the transaction ends
+func selectForUpdate(tx *sqlx.Tx, partID ID) (*Part, error) {
+	var ret Part
+	if err := tx.QueryRowx("SELECT * FROM part WHERE part_id=$1 FOR UPDATE", partID).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting part %s", partID.String())
+	}
+
+	return &ret, nil
+}
+
+// setAlias adds or removes an alias of a part, and returns the change, or nil if nothing changed
+func setAlias(tx *sqlx.Tx, partID ID, alias string, add bool) (*audit.Change, error) {
+	if !add {
+		res, err := tx.Exec("DELETE FROM part_alias WHERE alias=$1 AND part_id=$2", alias, partID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error deleting part_alias")
+		}
+		if count, _ := res.RowsAffected(); count < 1 {
+			return nil, nil
+		}
+
+		return &audit.Change{Field: audit.FieldAlias, Key: alias, Old: &alias}, nil
+	}
+
+	var aliasesPartID ID
+	var inserted bool
+	if err := tx.QueryRow(`INSERT INTO part_alias (alias, part_id) VALUES ($1, $2)
+	ON CONFLICT (alias) DO UPDATE SET alias=EXCLUDED.alias
+	RETURNING part_id, xmax=0`, // meaningless update required for return, xmax is only 0 for inserted rows
+		alias, partID).Scan(&aliasesPartID, &inserted); err != nil {
+		return nil, errors.Wrapf(err, "error inserting part_alias")
+	}
+
+	if aliasesPartID.String() != partID.String() {
+		return nil, errors.New("alias part id mismtach")
+	}
+	if !inserted {
+		return nil, nil
+	}
+
+	return &audit.Change{Field: audit.FieldAlias, Key: alias, New: &alias}, nil
+}
+
+// setDocument upserts or, if document is nil, deletes a document of a part, and returns the change, or nil if nothing changed
+func setDocument(tx *sqlx.Tx, partID ID, key string, title *string, document *string) (*audit.Change, error) {
+	table, where, args := "part_has_document", "part_id=$1 AND key=$2", []any{partID, key}
+	if title != nil {
+		table, where, args = "part_documents", "part_id=$1 AND key=$2 AND title=$3", append(args, *title)
+	}
+
+	var old, new *string
+	if err := tx.QueryRow("SELECT document::TEXT FROM "+table+" WHERE "+where, args...).Scan(&old); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "error selecting %s", table)
+	}
+
+	if document == nil {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE "+where, args...); err != nil {
+			return nil, errors.Wrapf(err, "error deleting from %s", table)
+		}
+	} else if title == nil {
+		if err := tx.QueryRow(`INSERT INTO part_has_document(part_id, key, document) VALUES ($1, $2, $3)
+		ON CONFLICT (part_id, key) DO UPDATE SET document=EXCLUDED.document
+		RETURNING document::TEXT`,
+			partID, key, *document).Scan(&new); err != nil {
+			return nil, errors.Wrapf(err, "error inserting into part_has_document")
+		}
+	} else {
+		if err := tx.QueryRow(`INSERT INTO part_documents(part_id, key, title, document) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (part_id, key, title) DO UPDATE SET document=EXCLUDED.document
+		RETURNING document::TEXT`,
+			partID, key, *title, *document).Scan(&new); err != nil {
+			return nil, errors.Wrapf(err, "error inserting into part_documents")
+		}
+	}
+
+	change := audit.Diff(audit.FieldDocument, old, new)
+	if change != nil {
+		change.Key = key
+		change.Title = title
+	}
+
+	return change, nil
+}
+
+// GetHistory returns the revisions of a part, oldest first
+func (controller PartController) GetHistory(partID ID) ([]audit.Entry, error) {
+	return audit.GetPartHistory(controller.DB, uuid.UUID(partID))
+}
+
+// RevertPart sets the curated fields, aliases, documents, and relationships of a part back to what they were at a revision, as a new revision.
+// Revision 0 is the part before its first revision. Nil is returned if nothing changed since the revision.
+// The file verification code is not reverted, as it follows the files of the part rather than being curated.
+// The reverted part has to follow its type, see CheckPartType.
+func (controller PartController) RevertPart(partID ID, revision int64) (*audit.Entry, error) {
+	entries, err := controller.GetHistory(partID)
+	if err != nil {
+		return nil, err
+	}
+	if revision < 0 || len(entries) == 0 && revision > 0 || len(entries) > 0 && revision > entries[len(entries)-1].Revision {
+		return nil, errors.Wrapf(ErrRevisionNotFound, "part %s revision %d", partID.String(), revision)
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	before, err := selectForUpdate(tx, partID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]audit.Change, 0)
+	setFragments := make([]string, 0)
+	args := []any{partID}
+	for _, change := range audit.Revert(entries, revision) {
+		switch change.Field {
+		case audit.FieldAlias:
+			c, err := setAlias(tx, partID, change.Key, change.New != nil)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reverting alias %s", change.Key)
+			}
+			if c != nil {
+				changes = append(changes, *c)
+			}
+		case audit.FieldDocument:
+			c, err := setDocument(tx, partID, change.Key, change.Title, change.New)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reverting document %s", change.Key)
+			}
+			if c != nil {
+				changes = append(changes, *c)
+			}
+		case audit.FieldRelation:
+			relType, relatedID, err := parseRelationKey(change.Key)
+			if err != nil {
+				return nil, err
+			}
+			c, err := setRelationship(tx, partID, relType, relatedID, change.New)
+			if errors.Is(err, ErrNotFound) {
+				continue // the related part was deleted since the revision
+			} else if err != nil {
+				return nil, errors.Wrapf(err, "error reverting relationship %s", change.Key)
+			}
+			if c != nil {
+				changes = append(changes, *c)
+			}
+		default:
+			value := fragmentValue(change.Field)
+			if value == "" || change.Field == "file_verification_code" { // the code is that of the files of the part, which a revert leaves as they are
+				continue
+			}
+
+			args = append(args, change.New)
+			if change.New != nil && change.Field == "type" {
+				args[len(args)-1] = strings.ReplaceAll(strings.Trim(*change.New, "/"), "/", ".")
+			}
+			setFragments = append(setFragments, fmt.Sprintf("%s=%s", change.Field, fmt.Sprintf(value, len(args))))
+		}
+	}
+
+	if len(setFragments) > 0 {
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE part SET %s WHERE part_id=$1", strings.Join(setFragments, ", ")), args...); err != nil {
+			return nil, errors.Wrapf(err, "error reverting part %s", partID.String())
+		}
+
+		var after Part
+		if err := tx.QueryRowx("SELECT * FROM part WHERE part_id=$1", partID).StructScan(&after); err != nil {
+			return nil, errors.Wrapf(err, "error selecting part %s", partID.String())
+		}
+		changes = append(diffCurated(*before, after), changes...)
+		if err := CheckPartType(tx, partID); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(partID), audit.ActionRevert, changes)
+	if err != nil {
+		return nil, err
+	}
+	if err := review.ResetApproval(tx, controller.Actor, uuid.UUID(partID), changes); err != nil {
+		return nil, err
+	}
+
+	return entry, errors.Wrapf(tx.Commit(), "error committing revert of part %s", partID.String())
+}
+
+// fragmentValue returns the placeholder format of a curated column's value, or an empty string if the column is not curated
+func fragmentValue(column string) string {
+	switch column {
+	case "file_verification_code":
+		return "DECODE($%d, 'hex')"
+	case "comprised":
+		return "$%d::UUID"
+	}
+
+	for _, v := range curatedColumns {
+		if v == column {
+			return "$%d"
+		}
+	}
+
+	return ""
+}
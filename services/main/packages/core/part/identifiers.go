@@ -0,0 +1,176 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"wrs/tk/packages/identifier"
+	"wrs/tk/packages/version"
+)
+
+// Identifier is an external identifier of a part, a purl, CPE, or SWID tag id
+type Identifier struct {
+	PartID  ID             `db:"part_id"`
+	Type    string         `db:"type"`
+	Value   string         `db:"value"`
+	Name    string         `db:"name"`
+	Version sql.NullString `db:"version"`
+}
+
+// AddIdentifier validates, normalizes, and adds an identifier to the given part
+// Adding an identifier the part already has changes nothing
+func (controller PartController) AddIdentifier(partID ID, idType string, value string) (*Identifier, error) {
+	id, err := identifier.Parse(idType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := Identifier{
+		PartID:  partID,
+		Type:    id.Type,
+		Value:   id.Value,
+		Name:    id.Name,
+		Version: sql.NullString{String: id.Version, Valid: id.Version != ""},
+	}
+	if _, err := controller.DB.Exec(`INSERT INTO part_identifier (part_id, type, value, name, version) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (part_id, type, value) DO NOTHING`,
+		partID, ret.Type, ret.Value, ret.Name, ret.Version); err != nil {
+		return nil, errors.Wrapf(err, "error inserting part_identifier")
+	}
+
+	return &ret, nil
+}
+
+// DeleteIdentifier removes an identifier from the given part
+func (controller PartController) DeleteIdentifier(partID ID, idType string, value string) error {
+	id, err := identifier.Parse(idType, value)
+	if err != nil {
+		return err
+	}
+
+	res, err := controller.DB.Exec("DELETE FROM part_identifier WHERE part_id=$1 AND type=$2 AND value=$3", partID, id.Type, id.Value)
+	if err != nil {
+		return errors.Wrapf(err, "error deleting part_identifier")
+	} else if count, _ := res.RowsAffected(); count < 1 {
+		return ErrIdentifierNotFound
+	}
+
+	return nil
+}
+
+// GetIdentifiers lists every identifier of the given part
+func (controller PartController) GetIdentifiers(partID ID) ([]Identifier, error) {
+	ret := make([]Identifier, 0)
+	if err := controller.DB.Select(&ret, "SELECT part_id, type, value, name, version FROM part_identifier WHERE part_id=$1 ORDER BY type, value",
+		partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting part_identifier")
+	}
+
+	return ret, nil
+}
+
+// GetByIdentifier gets the part with the given identifier.
+// An identifier with a version must match exactly, or else match on name and version, ignoring purl qualifiers and subpath or the other CPE attributes.
+// An identifier without a version gets the part with the highest version of that package.
+func (controller PartController) GetByIdentifier(idType string, value string) (*Part, error) {
+	id, err := identifier.Parse(idType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var identifiers []Identifier
+	if id.Version != "" {
+		if err := controller.DB.Select(&identifiers, `SELECT part_id, type, value, name, version FROM part_identifier
+		WHERE type=$1 AND (value=$2 OR (name=$3 AND version=$4))
+		ORDER BY value=$2 DESC, insert_date
+		LIMIT 1`,
+			id.Type, id.Value, id.Name, id.Version); err != nil {
+			return nil, errors.Wrapf(err, "error selecting part_identifier")
+		}
+	} else {
+		identifiers, err = controller.findIdentifiers(id, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(identifiers) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return controller.GetByID(identifiers[0].PartID)
+}
+
+// FindByIdentifier lists every part sharing the name of the given identifier, whose version is in the given range, highest version first.
+// versions is a vers range, e.g. vers:npm/>=1.0.0|<2.0.0, or >=1.0.0|<2.0.0, and every version matches if it is empty.
+func (controller PartController) FindByIdentifier(idType string, value string, versions string) ([]Part, error) {
+	id, err := identifier.Parse(idType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var versionRange *version.Range
+	if versions != "" {
+		if versionRange, err = version.ParseRange(versions); err != nil {
+			return nil, err
+		}
+	}
+
+	identifiers, err := controller.findIdentifiers(id, versionRange)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Part, 0, len(identifiers))
+	seen := make(map[ID]bool)
+	for _, v := range identifiers {
+		if seen[v.PartID] {
+			continue
+		}
+		seen[v.PartID] = true
+
+		tmp, err := controller.GetByID(v.PartID)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, *tmp)
+	}
+
+	return ret, nil
+}
+
+// findIdentifiers lists the identifiers sharing the name of id, whose version is in the range if one is given, highest version first
+func (controller PartController) findIdentifiers(id *identifier.Identifier, versionRange *version.Range) ([]Identifier, error) {
+	var identifiers []Identifier
+	if err := controller.DB.Select(&identifiers, "SELECT part_id, type, value, name, version FROM part_identifier WHERE type=$1 AND name=$2",
+		id.Type, id.Name); err != nil {
+		return nil, errors.Wrapf(err, "error selecting part_identifier by name")
+	}
+
+	ret := make([]Identifier, 0, len(identifiers))
+	for _, v := range identifiers {
+		if versionRange != nil && (!v.Version.Valid || !versionRange.Contains(v.Version.String)) {
+			continue
+		}
+
+		ret = append(ret, v)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return version.Compare(ret[i].Version.String, ret[j].Version.String) > 0
+	})
+
+	return ret, nil
+}
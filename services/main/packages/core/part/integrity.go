@@ -0,0 +1,213 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// IntegrityReport lists what is wrong with the relations of parts, see CheckIntegrity
+type IntegrityReport struct {
+	Cycles     [][]ID            // parts that are their own ancestors, each cycle's parts sorted
+	Relations  []RelationProblem // relations foreign keys do not keep consistent
+	Mismatches []CodeMismatch    // parts whose file verification code is not that of their files
+}
+
+// RelationProblem is a dangling or invalid relation, of a table, identified by key
+type RelationProblem struct {
+	Table   string
+	Key     string
+	Problem string
+}
+
+// CodeMismatch is a part whose stored file verification code 2 differs from the one computed from its files and those of its sub-parts
+type CodeMismatch struct {
+	PartID   ID
+	Stored   []byte
+	Computed []byte
+}
+
+// OK is whether nothing was found wrong
+func (r IntegrityReport) OK() bool {
+	return len(r.Cycles) == 0 && len(r.Relations) == 0 && len(r.Mismatches) == 0
+}
+
+// CheckIntegrity reports cycles of sub-parts, dangling or invalid relations, and parts whose file verification code disagrees with their files
+func (controller PartController) CheckIntegrity() (*IntegrityReport, error) {
+	ret := IntegrityReport{
+		Relations:  make([]RelationProblem, 0),
+		Mismatches: make([]CodeMismatch, 0),
+	}
+
+	edges := make([]partEdge, 0)
+	if err := controller.DB.Select(&edges, "SELECT DISTINCT parent_id::TEXT, child_id::TEXT FROM part_has_part"); err != nil {
+		return nil, errors.Wrapf(err, "error selecting part_has_part")
+	}
+	for _, cycle := range findCycles(edges) {
+		ids := make([]ID, 0, len(cycle))
+		for _, v := range cycle {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing part id %s", v)
+			}
+			ids = append(ids, ID(id))
+		}
+		ret.Cycles = append(ret.Cycles, ids)
+	}
+
+	for _, check := range []struct {
+		table   string
+		problem string
+		query   string
+	}{
+		{"part_redirect", "redirects a part that exists, which is unreachable by its id",
+			"SELECT part_redirect.part_id::TEXT FROM part_redirect INNER JOIN part ON part.part_id=part_redirect.part_id"},
+		{"part_alias", "references no part",
+			"SELECT alias FROM part_alias WHERE part_id IS NULL"},
+		{"part", "comprised by itself",
+			"SELECT part_id::TEXT FROM part WHERE comprised=part_id"},
+		{"part_closure", "missing, run SELECT rebuild_part_closure() to rebuild it", closureDrift("down", "part_closure")},
+		{"part_closure", "stale, run SELECT rebuild_part_closure() to rebuild it", closureDrift("part_closure", "down")},
+	} {
+		keys := make([]string, 0)
+		if err := controller.DB.Select(&keys, check.query); err != nil {
+			return nil, errors.Wrapf(err, "error checking %s", check.table)
+		}
+		for _, key := range keys {
+			ret.Relations = append(ret.Relations, RelationProblem{Table: check.table, Key: key, Problem: check.problem})
+		}
+	}
+
+	parents := make([]struct {
+		ID   string `db:"parent_id"`
+		Type string `db:"type"`
+	}, 0)
+	if err := controller.DB.Select(&parents, `SELECT DISTINCT part_has_part.parent_id::TEXT, part.type::TEXT FROM part_has_part
+	INNER JOIN part ON part.part_id=part_has_part.parent_id WHERE part.type IS NOT NULL ORDER BY 1`); err != nil {
+		return nil, errors.Wrapf(err, "error selecting types of parts with sub-parts")
+	}
+	for _, v := range parents {
+		if t, err := LookupType(v.Type); err == nil && !t.AllowsSubParts() {
+			ret.Relations = append(ret.Relations, RelationProblem{Table: "part_has_part", Key: v.ID, Problem: "sub-parts of a part of type " + v.Type + ", which has none"})
+		}
+	}
+
+	rows, err := controller.DB.Queryx(`SELECT part_id, stored, computed FROM (`+verificationCodes+`) codes WHERE stored<>computed ORDER BY part_id`, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error computing file verification codes")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var mismatch CodeMismatch
+		if err := rows.Scan(&mismatch.PartID, &mismatch.Stored, &mismatch.Computed); err != nil {
+			return nil, errors.Wrapf(err, "error scanning file verification codes")
+		}
+		ret.Mismatches = append(ret.Mismatches, mismatch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "error computing file verification codes")
+	}
+
+	return &ret, nil
+}
+
+// closureDrift selects the part_closure rows of from missing in to, as keys, where down is part_closure as rebuilt from part_has_part
+// Rows whose count of routes differs are missing from both sides
+func closureDrift(from string, to string) string {
+	return `WITH RECURSIVE walk(ancestor_id, descendant_id, depth, path, visited) AS (
+		SELECT parent_id, child_id, 1, path, ARRAY[parent_id, child_id] FROM part_has_part WHERE parent_id <> child_id
+		UNION ALL SELECT walk.ancestor_id, php.child_id, walk.depth + 1, CONCAT_WS('/', NULLIF(walk.path, ''), NULLIF(php.path, '')), walk.visited || php.child_id
+		FROM part_has_part php INNER JOIN walk ON php.parent_id=walk.descendant_id
+		WHERE php.child_id <> ALL(walk.visited)
+	), down AS (
+		SELECT ancestor_id, descendant_id, depth, path, COUNT(*)::INTEGER AS routes FROM walk GROUP BY 1, 2, 3, 4
+	)
+	SELECT ancestor_id::TEXT || ' ' || descendant_id::TEXT || ' ' || depth::TEXT || ' ' || path || ' x' || routes::TEXT FROM (
+		SELECT ancestor_id, descendant_id, depth, path, routes FROM ` + from + `
+		EXCEPT SELECT ancestor_id, descendant_id, depth, path, routes FROM ` + to + `
+	) drift ORDER BY 1`
+}
+
+// findCycles returns the cycles of the edges, the strongly connected components of more than one part or of a part containing itself,
+// each sorted, found by Tarjan's algorithm
+func findCycles(edges []partEdge) [][]string {
+	children := make(map[string][]string)
+	nodes := make([]string, 0)
+	selfLoops := make(map[string]bool)
+	for _, edge := range edges {
+		for _, v := range []string{edge.ParentID, edge.ChildID} {
+			if _, ok := children[v]; !ok {
+				children[v] = make([]string, 0)
+				nodes = append(nodes, v)
+			}
+		}
+		children[edge.ParentID] = append(children[edge.ParentID], edge.ChildID)
+		if edge.ParentID == edge.ChildID {
+			selfLoops[edge.ParentID] = true
+		}
+	}
+	sort.Strings(nodes)
+
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	ret := make([][]string, 0)
+
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		lowLink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range children[v] {
+			if _, ok := index[w]; !ok {
+				connect(w)
+				if lowLink[w] < lowLink[v] {
+					lowLink[v] = lowLink[w]
+				}
+			} else if onStack[w] && index[w] < lowLink[v] {
+				lowLink[v] = index[w]
+			}
+		}
+
+		if lowLink[v] != index[v] {
+			return
+		}
+
+		component := make([]string, 0)
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoops[v] {
+			sort.Strings(component)
+			ret = append(ret, component)
+		}
+	}
+	for _, v := range nodes {
+		if _, ok := index[v]; !ok {
+			connect(v)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i][0] < ret[j][0] })
+	return ret
+}
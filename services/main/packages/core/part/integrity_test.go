@@ -0,0 +1,100 @@
+package part
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestFindCycles(t *testing.T) {
+	edge := func(parent string, child string) partEdge {
+		return partEdge{ParentID: parent, ChildID: child}
+	}
+
+	tests := []struct {
+		name  string
+		edges []partEdge
+		want  string
+	}{
+		{name: "tree", edges: []partEdge{edge("a", "b"), edge("a", "c"), edge("b", "d")}, want: "[]"},
+		{name: "diamond", edges: []partEdge{edge("a", "b"), edge("a", "c"), edge("b", "d"), edge("c", "d")}, want: "[]"},
+		{name: "own sub-part", edges: []partEdge{edge("a", "a"), edge("a", "b")}, want: "[[a]]"},
+		{name: "own ancestor", edges: []partEdge{edge("a", "b"), edge("b", "c"), edge("c", "a"), edge("c", "d")}, want: "[[a b c]]"},
+		{name: "two cycles", edges: []partEdge{edge("x", "y"), edge("y", "x"), edge("a", "b"), edge("b", "a"), edge("b", "x")}, want: "[[a b] [x y]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(findCycles(tt.edges)); got != tt.want {
+				t.Errorf("findCycles() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	controller := PartController{DB: testDB(t)}
+	tree := createTestTree(t, controller.DB, 2, 1)
+	alias := fmt.Sprintf("test-integrity-%s", tree.Root.String())
+	t.Cleanup(func() {
+		if _, err := controller.DB.Exec("DELETE FROM part_alias WHERE alias=$1", alias); err != nil {
+			t.Errorf("error deleting alias: %v", err)
+		}
+		if _, err := controller.DB.Exec("UPDATE part SET comprised=NULL WHERE part_id=$1", tree.Leaves[0]); err != nil {
+			t.Errorf("error unsetting comprised: %v", err)
+		}
+	})
+
+	// a dangling alias, a part comprised by itself, and a root whose stored code is not that of its files
+	if _, err := controller.DB.Exec("INSERT INTO part_alias (alias, part_id) VALUES ($1, NULL)", alias); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := controller.DB.Exec("UPDATE part SET comprised=part_id WHERE part_id=$1", tree.Leaves[0]); err != nil {
+		t.Fatal(err)
+	}
+	var stored []byte
+	if err := controller.DB.QueryRow("UPDATE part SET file_verification_code='\\x4656433200'::BYTEA || DIGEST(name, 'sha256') WHERE part_id=$1 RETURNING file_verification_code",
+		tree.Root).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	computed, _, err := calculateVerificationCode(controller.DB, tree.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := controller.CheckIntegrity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Fatal("CheckIntegrity() found nothing wrong")
+	}
+	for _, want := range []RelationProblem{
+		{Table: "part_alias", Key: alias, Problem: "references no part"},
+		{Table: "part", Key: tree.Leaves[0].String(), Problem: "comprised by itself"},
+	} {
+		found := false
+		for _, v := range report.Relations {
+			found = found || v == want
+		}
+		if !found {
+			t.Errorf("CheckIntegrity() did not report %+v", want)
+		}
+	}
+	found := false
+	for _, v := range report.Mismatches {
+		if v.PartID == tree.Root {
+			found = true
+			if !bytes.Equal(v.Stored, stored) || !bytes.Equal(v.Computed, computed) {
+				t.Errorf("CheckIntegrity() mismatch = %x, %x, want %x, %x", v.Stored, v.Computed, stored, computed)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("CheckIntegrity() did not report the file verification code of the root")
+	}
+	for _, v := range report.Mismatches {
+		if v.PartID == tree.Leaves[0] || v.PartID == tree.Leaves[1] {
+			t.Errorf("CheckIntegrity() reported leaf %s without a stored code", v.PartID.String())
+		}
+	}
+}
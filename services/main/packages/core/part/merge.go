@@ -0,0 +1,353 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"wrs/tk/packages/core/audit"
+	"wrs/tk/packages/core/review"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Strategies of merging the curated fields and documents of parts, where both parts have differing values
+const (
+	MergeKeep   = "keep"   // the kept part's values stay, the removed part's only fill in what it has none of
+	MergeRemove = "remove" // the removed part's values replace the kept part's
+	MergeFail   = "fail"   // the merge fails with ErrMergeConflict
+)
+
+// mergeColumns are the curated columns merged from the removed part.
+// file_verification_code is the part's content, and is only moved with its files to a kept part without content.
+var mergeColumns = []string{"type", "name", "version", "label", "family_name", "license", "license_rationale", "description", "comprised"}
+
+// mergeDocument is a document of the removed part, to be set on the kept part
+type mergeDocument struct {
+	Key      string  `db:"key"`
+	Title    *string `db:"title"`
+	Document string  `db:"document"`
+}
+
+// mergeValue returns the value a field of the kept part has after a merge, and whether the parts' values conflicted
+func mergeValue(keep *string, remove *string, strategy string) (*string, bool) {
+	if remove == nil || keep != nil && *keep == *remove {
+		return keep, false
+	} else if keep == nil {
+		return remove, false
+	} else if strategy == MergeRemove {
+		return remove, true
+	}
+
+	return keep, true
+}
+
+// MergeParts merges a duplicate part into the part kept, and deletes it, leaving a redirect for its id to keep resolving to the kept part.
+// Archives, aliases, documents, identifiers, VEX statements, partlist memberships, comprised references, and parents are moved to the kept part.
+// Files and sub-parts, with the file verification code, are only moved to a kept part without any of its own.
+// Curated fields and documents both parts have are merged by strategy, MergeKeep if empty.
+// The merge is recorded in the history of both parts, and of the partlists listing the removed part.
+// The merged part has to follow its type, see CheckPartType.
+// The file verification codes of the kept part and its ancestors, those of the removed part included, are refreshed, see refreshVerificationCodes.
+func (controller PartController) MergeParts(keepID ID, removeID ID, strategy string) (*audit.Entry, error) {
+	switch strategy {
+	case "":
+		strategy = MergeKeep
+	case MergeKeep, MergeRemove, MergeFail:
+	default:
+		return nil, errors.Errorf("unknown merge strategy %s", strategy)
+	}
+	if keepID == removeID {
+		return nil, errors.New("a part can't be merged into itself")
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	// part_has_part is locked before the parts, as AddPartToPart does, since moving sub-parts and parents takes its lock
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('part_has_part'))"); err != nil {
+		return nil, errors.Wrapf(err, "error locking part_has_part")
+	}
+	// lock in the same order whichever part is kept, so concurrent merges of the same parts can't deadlock
+	locked := make(map[ID]*Part)
+	order := []ID{keepID, removeID}
+	if keepID.String() > removeID.String() {
+		order = []ID{removeID, keepID}
+	}
+	for _, id := range order {
+		p, err := selectForUpdate(tx, id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "part %s", id.String())
+		}
+		locked[id] = p
+	}
+	keep, remove := locked[keepID], locked[removeID]
+
+	for _, ids := range [][2]ID{{keepID, removeID}, {removeID, keepID}} {
+		if contains, err := containsPart(tx, ids[0], ids[1]); err != nil {
+			return nil, err
+		} else if contains {
+			return nil, errors.Errorf("part %s is a sub-part of %s, and can't be merged with it", ids[1].String(), ids[0].String())
+		}
+	}
+
+	conflicts := make([]string, 0)
+	setFragments := make([]string, 0)
+	args := []any{keepID}
+	for _, column := range mergeColumns {
+		old, candidate := curatedValue(*keep, column), curatedValue(*remove, column)
+		if column == "comprised" && candidate != nil && *candidate == keepID.String() {
+			continue // the removed part was built by the part kept
+		}
+
+		value, conflict := mergeValue(old, candidate, strategy)
+		if conflict {
+			conflicts = append(conflicts, column)
+		}
+		if value == old {
+			continue
+		}
+
+		args = append(args, *value)
+		if column == "type" {
+			args[len(args)-1] = strings.ReplaceAll(strings.Trim(*value, "/"), "/", ".")
+		}
+		setFragments = append(setFragments, fmt.Sprintf("%s=%s", column, fmt.Sprintf(fragmentValue(column), len(args))))
+	}
+
+	documents := make([]mergeDocument, 0)
+	if err := tx.Select(&documents, `SELECT key, NULL::TEXT AS title, document::TEXT FROM part_has_document WHERE part_id=$1
+	UNION ALL SELECT key, title, document::TEXT FROM part_documents WHERE part_id=$1`, removeID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting documents of part %s", removeID.String())
+	}
+	moved := make([]mergeDocument, 0, len(documents))
+	for _, d := range documents {
+		query, queryArgs := "SELECT document::TEXT FROM part_has_document WHERE part_id=$1 AND key=$2", []any{keepID, d.Key}
+		if d.Title != nil {
+			query, queryArgs = "SELECT document::TEXT FROM part_documents WHERE part_id=$1 AND key=$2 AND title=$3", append(queryArgs, *d.Title)
+		}
+
+		var existing *string
+		if err := tx.QueryRow(query, queryArgs...).Scan(&existing); err != nil && err != sql.ErrNoRows {
+			return nil, errors.Wrapf(err, "error selecting document %s of part %s", d.Key, keepID.String())
+		}
+		value, conflict := mergeValue(existing, &d.Document, strategy)
+		if conflict {
+			conflicts = append(conflicts, "document "+d.Key)
+		}
+		if value != existing {
+			moved = append(moved, d)
+		}
+	}
+
+	if strategy == MergeFail && len(conflicts) > 0 {
+		return nil, errors.Wrapf(ErrMergeConflict, "%s", strings.Join(conflicts, ", "))
+	}
+
+	if err := mergeContent(tx, keep, remove); err != nil {
+		return nil, err
+	}
+
+	if len(setFragments) > 0 {
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE part SET %s WHERE part_id=$1", strings.Join(setFragments, ", ")), args...); err != nil {
+			return nil, errors.Wrapf(err, "error merging fields of part %s", removeID.String())
+		}
+	}
+
+	changes := make([]audit.Change, 0)
+	removeChanges := []audit.Change{{Field: audit.FieldMerge, Key: keepID.String(), New: strPtr(keepID.String())}}
+
+	if _, err := tx.Exec("UPDATE archive SET part_id=$1 WHERE part_id=$2", keepID, removeID); err != nil {
+		return nil, errors.Wrapf(err, "error moving archives of part %s", removeID.String())
+	}
+
+	aliases := make([]string, 0)
+	if err := tx.Select(&aliases, "UPDATE part_alias SET part_id=$1 WHERE part_id=$2 RETURNING alias", keepID, removeID); err != nil {
+		return nil, errors.Wrapf(err, "error moving aliases of part %s", removeID.String())
+	}
+	for _, alias := range aliases {
+		alias := alias
+		changes = append(changes, audit.Change{Field: audit.FieldAlias, Key: alias, New: &alias})
+		removeChanges = append(removeChanges, audit.Change{Field: audit.FieldAlias, Key: alias, Old: &alias})
+	}
+
+	for _, d := range moved {
+		document := d.Document
+		change, err := setDocument(tx, keepID, d.Key, d.Title, &document)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error moving document %s", d.Key)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	for _, table := range []string{"part_has_document", "part_documents"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE part_id=$1", removeID); err != nil {
+			return nil, errors.Wrapf(err, "error deleting %s of part %s", table, removeID.String())
+		}
+	}
+
+	for _, query := range []struct {
+		sql  string
+		args []any
+	}{
+		// identifiers and VEX statements the kept part already has are left to be deleted with the removed part
+		{`UPDATE part_identifier SET part_id=$1 WHERE part_id=$2
+		AND NOT EXISTS (SELECT 1 FROM part_identifier k WHERE k.part_id=$1 AND k.type=part_identifier.type AND k.value=part_identifier.value)`, []any{keepID, removeID}},
+		{`UPDATE vex_statement SET part_id=$1, update_date=NOW() WHERE part_id=$2
+		AND NOT EXISTS (SELECT 1 FROM vex_statement k WHERE k.part_id=$1 AND k.vulnerability_id=vex_statement.vulnerability_id)`, []any{keepID, removeID}},
+		{"UPDATE offline_part_origin SET part_id=$1 WHERE part_id=$2", []any{keepID, removeID}},
+		{"INSERT INTO part_has_part (parent_id, child_id, path) SELECT parent_id, $1, path FROM part_has_part WHERE child_id=$2 ON CONFLICT DO NOTHING", []any{keepID, removeID}},
+		{"DELETE FROM part_has_part WHERE child_id=$1", []any{removeID}},
+		// embedded parts the kept part already has at a path, or that are the kept part itself, are left to be deleted
+		{`INSERT INTO part_embedded (part_id, path, embedded_id, linked, insert_date)
+		SELECT $1, path, embedded_id, linked, insert_date FROM part_embedded WHERE part_id=$2 AND embedded_id<>$1 ON CONFLICT DO NOTHING`, []any{keepID, removeID}},
+		{"UPDATE part_embedded SET embedded_id=$1 WHERE embedded_id=$2 AND part_id<>$1", []any{keepID, removeID}},
+		{"UPDATE part SET comprised=$1 WHERE comprised=$2 AND part_id<>$1", []any{keepID, removeID}},
+		{"UPDATE part SET comprised=NULL WHERE part_id=$1 AND comprised=$2", []any{keepID, removeID}},
+		// relationships between the merged parts would relate the kept part to itself, and are left to be deleted
+		{`INSERT INTO part_relationship (part_id, type, related_id, comment, metadata, insert_date)
+		SELECT CASE WHEN part_id=$2 THEN $1 ELSE part_id END, type, CASE WHEN related_id=$2 THEN $1 ELSE related_id END, comment, metadata, insert_date
+		FROM part_relationship WHERE (part_id=$2 OR related_id=$2) AND part_id<>$1 AND related_id<>$1 ON CONFLICT DO NOTHING`, []any{keepID, removeID}},
+		{"UPDATE part_redirect SET target_id=$1 WHERE target_id=$2", []any{keepID, removeID}},
+		{"INSERT INTO part_redirect (part_id, target_id) VALUES ($1, $2)", []any{removeID, keepID}},
+	} {
+		if _, err := tx.Exec(query.sql, query.args...); err != nil {
+			return nil, errors.Wrapf(err, "error merging part %s into %s", removeID.String(), keepID.String())
+		}
+	}
+
+	if err := mergePartLists(tx, controller.Actor, keepID, removeID); err != nil {
+		return nil, err
+	}
+
+	if err := CheckPartType(tx, keepID); err != nil {
+		return nil, err
+	}
+
+	var after Part
+	if err := tx.QueryRowx("SELECT * FROM part WHERE part_id=$1", keepID).StructScan(&after); err != nil {
+		return nil, errors.Wrapf(err, "error selecting part %s", keepID.String())
+	}
+	changes = append(append(diffCurated(*keep, after), audit.Change{Field: audit.FieldMerge, Key: removeID.String(), New: strPtr(removeID.String())}), changes...)
+
+	if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(removeID), audit.ActionMerge, removeChanges); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM part WHERE part_id=$1", removeID); err != nil {
+		return nil, errors.Wrapf(err, "error deleting part %s", removeID.String())
+	}
+	// the parents of the removed part now contain the kept part, and the kept part may have taken the files of the removed part
+	if err := controller.refreshVerificationCodes(tx, keepID); err != nil {
+		return nil, err
+	}
+
+	entry, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(keepID), audit.ActionMerge, changes)
+	if err != nil {
+		return nil, err
+	}
+	if err := review.ResetApproval(tx, controller.Actor, uuid.UUID(keepID), changes); err != nil {
+		return nil, err
+	}
+
+	return entry, errors.Wrapf(tx.Commit(), "error committing merge of part %s into %s", removeID.String(), keepID.String())
+}
+
+// containsPart returns whether child is a sub-part of parent, at any depth
+func containsPart(tx *sqlx.Tx, parentID ID, childID ID) (bool, error) {
+	var ret bool
+	if err := tx.QueryRow(`WITH RECURSIVE tree(part_id) AS (
+		SELECT $1::UUID
+		UNION
+		SELECT php.child_id FROM part_has_part php INNER JOIN tree ON tree.part_id=php.parent_id
+	) SELECT EXISTS(SELECT 1 FROM tree WHERE part_id=$2)`, parentID, childID).Scan(&ret); err != nil {
+		return false, errors.Wrapf(err, "error selecting sub-parts of part %s", parentID.String())
+	}
+
+	return ret, nil
+}
+
+// mergeContent moves the files, sub-parts, and file verification code of the removed part to a kept part without any of its own, or drops them
+func mergeContent(tx *sqlx.Tx, keep *Part, remove *Part) error {
+	hasContent := len(keep.FileVerificationCode) > 0
+	if !hasContent {
+		if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM part_has_file WHERE part_id=$1) OR EXISTS(SELECT 1 FROM part_has_part WHERE parent_id=$1)`,
+			keep.PartID).Scan(&hasContent); err != nil {
+			return errors.Wrapf(err, "error selecting content of part %s", keep.PartID.String())
+		}
+	}
+
+	if hasContent {
+		if _, err := tx.Exec("DELETE FROM part_has_file WHERE part_id=$1", remove.PartID); err != nil {
+			return errors.Wrapf(err, "error deleting files of part %s", remove.PartID.String())
+		}
+		if _, err := tx.Exec("DELETE FROM part_has_part WHERE parent_id=$1", remove.PartID); err != nil {
+			return errors.Wrapf(err, "error deleting sub-parts of part %s", remove.PartID.String())
+		}
+
+		return nil
+	}
+
+	if _, err := tx.Exec("UPDATE part_has_file SET part_id=$1 WHERE part_id=$2", keep.PartID, remove.PartID); err != nil {
+		return errors.Wrapf(err, "error moving files of part %s", remove.PartID.String())
+	}
+	if _, err := tx.Exec("UPDATE part_has_part SET parent_id=$1 WHERE parent_id=$2", keep.PartID, remove.PartID); err != nil {
+		return errors.Wrapf(err, "error moving sub-parts of part %s", remove.PartID.String())
+	}
+	if len(remove.FileVerificationCode) > 0 {
+		// file_verification_code is unique, so it has to be unset before it is moved
+		if _, err := tx.Exec("UPDATE part SET file_verification_code=NULL, file_verification_code_one=NULL WHERE part_id=$1", remove.PartID); err != nil {
+			return errors.Wrapf(err, "error unsetting file_verification_code of part %s", remove.PartID.String())
+		}
+		if _, err := tx.Exec("UPDATE part SET file_verification_code=$2, file_verification_code_one=$3, size=$4, git_tree_sha1=COALESCE(git_tree_sha1, $5) WHERE part_id=$1",
+			keep.PartID, remove.FileVerificationCode, remove.FileVerificationCodeOne, remove.Size, remove.GitTreeSha1); err != nil {
+			return errors.Wrapf(err, "error moving file_verification_code to part %s", keep.PartID.String())
+		}
+	}
+
+	return nil
+}
+
+// mergePartLists lists the kept part in place of the removed part in every partlist listing it, recording the change in the partlists' history
+func mergePartLists(tx *sqlx.Tx, actor string, keepID ID, removeID ID) error {
+	partlistIDs := make([]int64, 0)
+	if err := tx.Select(&partlistIDs, "DELETE FROM partlist_has_part WHERE part_id=$1 RETURNING partlist_id", removeID); err != nil {
+		return errors.Wrapf(err, "error deleting part %s from partlists", removeID.String())
+	}
+
+	for _, partlistID := range partlistIDs {
+		changes := []audit.Change{{Field: audit.FieldPart, Key: removeID.String(), Old: strPtr(removeID.String())}}
+		res, err := tx.Exec("INSERT INTO partlist_has_part (partlist_id, part_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", partlistID, keepID)
+		if err != nil {
+			return errors.Wrapf(err, "error adding part %s to partlist %d", keepID.String(), partlistID)
+		}
+		if count, _ := res.RowsAffected(); count > 0 {
+			changes = append(changes, audit.Change{Field: audit.FieldPart, Key: keepID.String(), New: strPtr(keepID.String())})
+		}
+
+		if _, err := audit.RecordPartList(tx, actor, partlistID, audit.ActionMerge, changes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
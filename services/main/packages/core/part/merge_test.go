@@ -0,0 +1,33 @@
+package part
+
+import "testing"
+
+func TestMergeValue(t *testing.T) {
+	s := func(v string) *string { return &v }
+	tests := []struct {
+		name     string
+		keep     *string
+		remove   *string
+		strategy string
+		want     *string
+		conflict bool
+	}{
+		{"both empty", nil, nil, MergeKeep, nil, false},
+		{"removed empty", s("MIT"), nil, MergeRemove, s("MIT"), false},
+		{"kept empty", nil, s("MIT"), MergeKeep, s("MIT"), false},
+		{"equal", s("MIT"), s("MIT"), MergeFail, s("MIT"), false},
+		{"keep", s("MIT"), s("BSD-3-Clause"), MergeKeep, s("MIT"), true},
+		{"remove", s("MIT"), s("BSD-3-Clause"), MergeRemove, s("BSD-3-Clause"), true},
+		{"fail", s("MIT"), s("BSD-3-Clause"), MergeFail, s("MIT"), true},
+	}
+
+	for _, test := range tests {
+		got, conflict := mergeValue(test.keep, test.remove, test.strategy)
+		if conflict != test.conflict {
+			t.Errorf("%s: expected conflict %v, got %v", test.name, test.conflict, conflict)
+		}
+		if (got == nil) != (test.want == nil) || got != nil && *got != *test.want {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+		}
+	}
+}
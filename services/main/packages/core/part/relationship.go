@@ -0,0 +1,306 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"wrs/tk/packages/core/audit"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Relationship types between parts, the SPDX relationship types beyond the containment of part_has_part
+const (
+	RelationshipDependsOn         = "DEPENDS_ON"
+	RelationshipBuildDependencyOf = "BUILD_DEPENDENCY_OF"
+	RelationshipGeneratedFrom     = "GENERATED_FROM"
+	RelationshipPatchFor          = "PATCH_FOR"
+	RelationshipVariantOf         = "VARIANT_OF"
+	RelationshipDynamicLink       = "DYNAMIC_LINK"
+	RelationshipDescendantOf      = "DESCENDANT_OF"
+)
+
+// RelationshipTypes lists the relationship types parts may have
+var RelationshipTypes = []string{
+	RelationshipDependsOn,
+	RelationshipBuildDependencyOf,
+	RelationshipGeneratedFrom,
+	RelationshipPatchFor,
+	RelationshipVariantOf,
+	RelationshipDynamicLink,
+	RelationshipDescendantOf,
+}
+
+// EdgeContains is the type of the edges of graph traversals between a part and its sub-parts
+const EdgeContains = "CONTAINS"
+
+// Relationship is a typed relationship between parts, read as part type related, e.g. a DEPENDS_ON b
+type Relationship struct {
+	PartID     ID             `db:"part_id"`
+	Type       string         `db:"type"`
+	RelatedID  ID             `db:"related_id"`
+	Comment    sql.NullString `db:"comment"`
+	Metadata   sql.NullString `db:"metadata"` // json
+	InsertDate time.Time      `db:"insert_date"`
+}
+
+// Edge is an edge of a graph traversal, from a part to another, of a relationship type or EdgeContains
+type Edge struct {
+	From  ID
+	To    ID
+	Type  string
+	Path  string // path of a sub-part within its part, only for EdgeContains
+	Depth int    // edges away from the part the traversal started at, starting at 1
+}
+
+// relationValue is the value of a relationship in the history of a part
+type relationValue struct {
+	Comment  *string         `json:"comment,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// CheckRelationshipType returns an error if the relationship type is not one of RelationshipTypes
+func CheckRelationshipType(relType string) error {
+	for _, t := range RelationshipTypes {
+		if t == relType {
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrInvalidRelationship, "unknown type %s", relType)
+}
+
+// relationKey is the key of the change of a relationship in the history of a part
+func relationKey(relType string, relatedID ID) string {
+	return relType + " " + relatedID.String()
+}
+
+// parseRelationKey parses the key of the change of a relationship
+func parseRelationKey(key string) (string, ID, error) {
+	relType, related, ok := strings.Cut(key, " ")
+	if !ok {
+		return "", ID{}, errors.Wrapf(ErrInvalidRelationship, "malformed key %s", key)
+	}
+
+	relatedID, err := uuid.Parse(related)
+	if err != nil {
+		return "", ID{}, errors.Wrapf(ErrInvalidRelationship, "malformed key %s", key)
+	}
+
+	return relType, ID(relatedID), nil
+}
+
+// setRelationship upserts or, if value is nil, deletes a relationship of a part, and returns the change, or nil if nothing changed
+func setRelationship(tx *sqlx.Tx, partID ID, relType string, relatedID ID, value *string) (*audit.Change, error) {
+	if err := CheckRelationshipType(relType); err != nil {
+		return nil, err
+	}
+	if partID == relatedID {
+		return nil, errors.Wrapf(ErrInvalidRelationship, "part %s related to itself", partID.String())
+	}
+
+	var old, new *string
+	if err := tx.QueryRow(`SELECT JSONB_STRIP_NULLS(JSONB_BUILD_OBJECT('comment', comment, 'metadata', metadata))::TEXT
+	FROM part_relationship WHERE part_id=$1 AND type=$2 AND related_id=$3`, partID, relType, relatedID).Scan(&old); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "error selecting part_relationship")
+	}
+
+	if value == nil {
+		if _, err := tx.Exec("DELETE FROM part_relationship WHERE part_id=$1 AND type=$2 AND related_id=$3", partID, relType, relatedID); err != nil {
+			return nil, errors.Wrapf(err, "error deleting part_relationship")
+		}
+	} else {
+		var v relationValue
+		if err := json.Unmarshal([]byte(*value), &v); err != nil {
+			return nil, errors.Wrapf(ErrInvalidRelationship, "malformed value %s", *value)
+		}
+		var metadata *string
+		if len(v.Metadata) > 0 && string(v.Metadata) != "null" {
+			metadata = strPtr(string(v.Metadata))
+		}
+
+		if err := tx.QueryRow(`INSERT INTO part_relationship (part_id, type, related_id, comment, metadata) VALUES ($1, $2, $3, $4, $5::JSONB)
+		ON CONFLICT (part_id, type, related_id) DO UPDATE SET comment=EXCLUDED.comment, metadata=EXCLUDED.metadata
+		RETURNING JSONB_STRIP_NULLS(JSONB_BUILD_OBJECT('comment', comment, 'metadata', metadata))::TEXT`,
+			partID, relType, relatedID, v.Comment, metadata).Scan(&new); err != nil {
+			if strings.Contains(err.Error(), "part_relationship_related_id_fkey") {
+				return nil, errors.Wrapf(ErrNotFound, "related part %s", relatedID.String())
+			}
+			return nil, errors.Wrapf(err, "error inserting part_relationship")
+		}
+	}
+
+	change := audit.Diff(audit.FieldRelation, old, new)
+	if change != nil {
+		change.Key = relationKey(relType, relatedID)
+	}
+
+	return change, nil
+}
+
+// AddRelationship upserts a typed relationship of a part to a related part, with an optional comment and metadata
+func (controller PartController) AddRelationship(partID ID, relType string, relatedID ID, comment *string, metadata json.RawMessage) error {
+	if metadata != nil && !json.Valid(metadata) {
+		return errors.Wrapf(ErrInvalidRelationship, "metadata is not valid json")
+	}
+
+	value, err := json.Marshal(relationValue{Comment: comment, Metadata: metadata})
+	if err != nil {
+		return errors.Wrapf(err, "error marshalling relationship")
+	}
+
+	return controller.changeRelationship(partID, relType, relatedID, strPtr(string(value)))
+}
+
+// RemoveRelationship deletes a typed relationship of a part to a related part
+func (controller PartController) RemoveRelationship(partID ID, relType string, relatedID ID) error {
+	return controller.changeRelationship(partID, relType, relatedID, nil)
+}
+
+// changeRelationship sets a relationship and records the change in the history of the part
+func (controller PartController) changeRelationship(partID ID, relType string, relatedID ID, value *string) error {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	change, err := setRelationship(tx, partID, relType, relatedID, value)
+	if err != nil {
+		return err
+	}
+	if change != nil {
+		if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(partID), audit.ActionRelate, []audit.Change{*change}); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing part_relationship")
+}
+
+// Relationships returns the relationships of a part to other parts, or if incoming, of other parts to the part, optionally only of the given types
+func (controller PartController) Relationships(partID ID, incoming bool, types []string) ([]Relationship, error) {
+	column := "part_id"
+	if incoming {
+		column = "related_id"
+	}
+
+	query, args := "SELECT part_id, type, related_id, comment, metadata::TEXT AS metadata, insert_date FROM part_relationship WHERE "+column+"=$1", []any{partID}
+	if len(types) > 0 {
+		placeholders := make([]string, 0, len(types))
+		for _, t := range types {
+			args = append(args, t)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		query += " AND type IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	ret := make([]Relationship, 0)
+	if err := controller.DB.Select(&ret, query+" ORDER BY type, insert_date", args...); err != nil {
+		return nil, errors.Wrapf(err, "error selecting relationships of part %s", partID.String())
+	}
+
+	return ret, nil
+}
+
+// Traverse walks the graph of parts from a part, breadth first, along sub-parts and relationships of the given types,
+// EdgeContains for sub-parts, or every type if none are given, and returns the edges walked.
+// Outgoing edges lead from parts to their sub-parts and related parts, incoming edges the other way.
+// Parts are visited once, and a max depth less than 1 is unlimited.
+func (controller PartController) Traverse(partID ID, types []string, incoming bool, maxDepth int) ([]Edge, error) {
+	contains, relTypes := len(types) == 0, make([]string, 0, len(types))
+	for _, t := range types {
+		if t == EdgeContains {
+			contains = true
+			continue
+		}
+		if err := CheckRelationshipType(t); err != nil {
+			return nil, err
+		}
+		relTypes = append(relTypes, t)
+	}
+	relations := len(types) == 0 || len(relTypes) > 0
+
+	visited := map[ID]bool{partID: true}
+	frontier := []ID{partID}
+	ret := make([]Edge, 0)
+	for depth := 1; len(frontier) > 0 && (maxDepth < 1 || depth <= maxDepth); depth++ {
+		next := make([]ID, 0)
+		for _, id := range frontier {
+			edges := make([]Edge, 0)
+			if contains {
+				subParts, err := controller.containsEdges(id, incoming)
+				if err != nil {
+					return nil, err
+				}
+				edges = append(edges, subParts...)
+			}
+			if relations {
+				relationships, err := controller.Relationships(id, incoming, relTypes)
+				if err != nil {
+					return nil, err
+				}
+				for _, r := range relationships {
+					edges = append(edges, Edge{From: r.PartID, To: r.RelatedID, Type: r.Type})
+				}
+			}
+
+			for _, edge := range edges {
+				edge.Depth = depth
+				ret = append(ret, edge)
+
+				other := edge.To
+				if incoming {
+					other = edge.From
+				}
+				if !visited[other] {
+					visited[other] = true
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	return ret, nil
+}
+
+// containsEdges returns the edges between a part and its sub-parts, or if incoming, the parts it is a sub-part of
+func (controller PartController) containsEdges(partID ID, incoming bool) ([]Edge, error) {
+	column := "parent_id"
+	if incoming {
+		column = "child_id"
+	}
+
+	rows, err := controller.DB.Query("SELECT parent_id, child_id, path FROM part_has_part WHERE "+column+"=$1 ORDER BY path", partID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting part_has_part of part %s", partID.String())
+	}
+	defer rows.Close()
+
+	ret := make([]Edge, 0)
+	for rows.Next() {
+		edge := Edge{Type: EdgeContains}
+		if err := rows.Scan(&edge.From, &edge.To, &edge.Path); err != nil {
+			return nil, errors.Wrapf(err, "error scanning part_has_part")
+		}
+		ret = append(ret, edge)
+	}
+
+	return ret, rows.Err()
+}
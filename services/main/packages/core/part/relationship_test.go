@@ -0,0 +1,47 @@
+package part
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRelationKey(t *testing.T) {
+	relatedID := ID(uuid.MustParse("3c6a1c04-9f5f-4c87-9b0a-1b51d3ab8c1e"))
+
+	tests := []struct {
+		name     string
+		key      string
+		wantType string
+		wantErr  error
+	}{
+		{name: "round trip", key: relationKey(RelationshipDependsOn, relatedID), wantType: RelationshipDependsOn},
+		{name: "no id", key: RelationshipPatchFor, wantErr: ErrInvalidRelationship},
+		{name: "malformed id", key: RelationshipPatchFor + " 3c6a1c04", wantErr: ErrInvalidRelationship},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relType, id, err := parseRelationKey(tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseRelationKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (relType != tt.wantType || id != relatedID) {
+				t.Errorf("parseRelationKey() = %s %s, want %s %s", relType, id.String(), tt.wantType, relatedID.String())
+			}
+		})
+	}
+}
+
+func TestCheckRelationshipType(t *testing.T) {
+	for _, relType := range RelationshipTypes {
+		if err := CheckRelationshipType(relType); err != nil {
+			t.Errorf("CheckRelationshipType(%s) error = %v", relType, err)
+		}
+	}
+	for _, relType := range []string{"", EdgeContains, "depends_on", "CONTAINED_BY"} {
+		if err := CheckRelationshipType(relType); !errors.Is(err, ErrInvalidRelationship) {
+			t.Errorf("CheckRelationshipType(%s) error = %v, want %v", relType, err, ErrInvalidRelationship)
+		}
+	}
+}
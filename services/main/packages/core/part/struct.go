@@ -11,13 +11,18 @@
 package part
 
 import (
+	"bytes"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"wrs/tk/packages/checksum/gitoid"
+	"wrs/tk/packages/core/audit"
+	"wrs/tk/packages/core/review"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -58,22 +63,28 @@ func (id *ID) Scan(value interface{}) error {
 }
 
 type Part struct {
-	PartID               ID             `db:"part_id"`
-	Type                 sql.NullString `db:"type"`
-	Name                 sql.NullString `db:"name"`
-	Version              sql.NullString `db:"version"`
-	Label                sql.NullString `db:"label"`
-	FamilyName           sql.NullString `db:"family_name"`
-	FileVerificationCode []byte         `db:"file_verification_code"`
-	Size                 sql.NullInt64  `db:"size"`
-	License              sql.NullString `db:"license"`
-	LicenseRationale     sql.NullString `db:"license_rationale"`
-	Description          sql.NullString `db:"description"`
-	Comprised            ID             `db:"comprised"`
+	PartID                  ID             `db:"part_id"`
+	Type                    sql.NullString `db:"type"`
+	Name                    sql.NullString `db:"name"`
+	Version                 sql.NullString `db:"version"`
+	Label                   sql.NullString `db:"label"`
+	FamilyName              sql.NullString `db:"family_name"`
+	FileVerificationCode    []byte         `db:"file_verification_code"`
+	FileVerificationCodeOne []byte         `db:"file_verification_code_one"` // FVC1, whose payload is the SPDX package verification code
+	GitTreeSha1             []byte         `db:"git_tree_sha1"`              // git tree id of the archive the part was extracted from, that of its swh:1:dir SWHID
+	Size                    sql.NullInt64  `db:"size"`
+	License                 sql.NullString `db:"license"`
+	LicenseRationale        sql.NullString `db:"license_rationale"`
+	Description             sql.NullString `db:"description"`
+	Comprised               ID             `db:"comprised"`
 }
 
 type PartController struct {
-	DB *sqlx.DB
+	DB       *sqlx.DB
+	Instance string // catalog instance identity, [domain-name]/[catalog-instance-id], of catalog URIs
+	Actor    string // who changes are attributed to in the history of parts, see As
+
+	EnforceVerificationCodes bool // refuse writes leaving a file verification code that does not match the files of its part
 }
 
 func (controller PartController) GetBy(verificationCode []byte, partID *ID) (*Part, error) {
@@ -97,18 +108,32 @@ func (controller PartController) GetBy(verificationCode []byte, partID *ID) (*Pa
 	return ret, ErrNotFound
 }
 
+// GetByVerificationCode returns the part with a file verification code, of version 2 or 1,
+// or a version 0 code, which is the SPDX package verification code, as the code 1 with its payload
 func (controller PartController) GetByVerificationCode(verificationCode []byte) (*Part, error) {
 	if len(verificationCode) == 0 {
 		return nil, ErrNotFound
 	}
 
-	// check verification code version
-	if version, _ := code.VersionOf(verificationCode); version != nil && *version != code.VERSION_TWO {
-		return nil, errors.New("expected FVC2")
+	// check verification code version, no part has a code of none
+	if len(verificationCode) < 5 {
+		return nil, ErrNotFound
+	}
+	version, err := code.VersionOf(verificationCode)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	column := "file_verification_code"
+	switch *version {
+	case code.VERSION_ZERO:
+		column = "file_verification_code_one"
+		verificationCode = append([]byte("FVC1\000"), verificationCode...)
+	case code.VERSION_ONE:
+		column = "file_verification_code_one"
 	}
 
 	var ret Part
-	if err := controller.DB.QueryRowx("SELECT * FROM part WHERE file_verification_code=$1", verificationCode).StructScan(&ret); err != nil {
+	if err := controller.DB.QueryRowx("SELECT * FROM part WHERE "+column+"=$1 ORDER BY part_id LIMIT 1", verificationCode).StructScan(&ret); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -122,9 +147,37 @@ func (controller PartController) GetByVerificationCode(verificationCode []byte)
 	return &ret, nil
 }
 
+// GetBySWHID returns the part extracted from the directory of a swh:1:dir SWHID, or from the archive of a swh:1:cnt SWHID
+func (controller PartController) GetBySWHID(swhid string) (*Part, error) {
+	objectType, id, err := gitoid.ParseSWHID(swhid)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM part WHERE git_tree_sha1=$1 ORDER BY part_id LIMIT 1"
+	if objectType == gitoid.TypeContent {
+		query = "SELECT part.* FROM part INNER JOIN archive ON archive.part_id=part.part_id WHERE archive.gitoid_sha1=$1 ORDER BY archive.sha256 LIMIT 1"
+	}
+
+	var ret Part
+	if err := controller.DB.QueryRowx(query, id).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting part by SWHID %s", swhid)
+	}
+	if ret.Type.Valid {
+		ret.Type.String = "/" + strings.ReplaceAll(ret.Type.String, ".", "/")
+	}
+
+	return &ret, nil
+}
+
+// GetByID returns the part with the id, or the part it was merged into
 func (controller PartController) GetByID(partID ID) (*Part, error) {
 	var ret Part
-	if err := controller.DB.QueryRowx("SELECT * FROM part WHERE part_id=$1",
+	if err := controller.DB.QueryRowx("SELECT * FROM part WHERE part_id=COALESCE((SELECT target_id FROM part_redirect WHERE part_id=$1), $1)",
 		partID).StructScan(&ret); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -184,38 +237,13 @@ func (controller PartController) ShallowCountFiles(partID ID) (int64, error) {
 	return ret, nil
 }
 
-// CountFiles recursively counts files from the given part and any sub-parts
+// CountFiles counts the files of the given part and of its sub-parts, those of a sub-part once per path it is found at
 func (controller PartController) CountFiles(partID ID) (int64, error) {
-	ret, err := controller.ShallowCountFiles(partID)
-	if err != nil {
-		return ret, nil
-	}
-
-	subPartIDs := make([]ID, 0)
-	rows, err := controller.DB.Queryx(`SELECT child_id FROM part_has_part WHERE parent_id=$1`,
-		partID)
-	if err != nil && err != sql.ErrNoRows {
-		return ret, errors.Wrapf(err, "error selecting sub-parts of %s", partID.String())
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var tmp ID
-		if err := rows.Scan(&tmp); err != nil {
-			return ret, errors.Wrapf(err, "error scanning sub-part of %s", partID.String())
-		}
-
-		subPartIDs = append(subPartIDs, tmp)
-	}
-	rows.Close()
-
-	for _, v := range subPartIDs {
-		subCount, err := controller.CountFiles(v)
-		if err != nil {
-			return ret, errors.Wrapf(err, "error counting files of sub-part %s of %s", v.String(), partID.String())
-		}
-
-		ret += subCount
+	var ret int64
+	if err := controller.DB.QueryRow(`SELECT (SELECT COUNT(*) FROM part_has_file WHERE part_id=$1)
+	+ (SELECT COUNT(*) FROM part_closure INNER JOIN part_has_file ON part_has_file.part_id=part_closure.descendant_id WHERE part_closure.ancestor_id=$1)`,
+		partID).Scan(&ret); err != nil {
+		return ret, errors.Wrapf(err, "error counting files of %s", partID.String())
 	}
 
 	return ret, nil
@@ -252,6 +280,7 @@ func appendFragment[T any](column string,
 }
 
 // UpdateTribalKnowledge takes optional values we are receiving from GraphQL and updates them in the database iff they are not nil and not their zero value
+// The updated part has to follow its type, see CheckPartType
 // TODO, should this function be updated to allow to nil or zero values, or should that be a different function we add?
 func (controller PartController) UpdateTribalKnowledge(partID ID, partType *string, name *string, version *string, label *string, familyName *string, fileVerificationCode []byte, license *string, licenseRationale *string, description *string, comprised *ID) error {
 	valueMap := make(map[string]interface{}) // construct key -> value map to pass to query
@@ -275,7 +304,20 @@ func (controller PartController) UpdateTribalKnowledge(partID ID, partType *stri
 	sql := fmt.Sprintf("UPDATE part SET %s WHERE part_id=:pid", strings.Join(setFragments, ", "))
 	log.Debug().Str("sql", sql).Interface("value_map", valueMap).Msg("Updating file_collection")
 
-	res, err := controller.DB.NamedExec(sql, valueMap)
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	before, err := selectForUpdate(tx, partID)
+	if err == ErrNotFound {
+		return errors.New("update had no affect")
+	} else if err != nil {
+		return err
+	}
+
+	res, err := tx.NamedExec(sql, valueMap)
 	if err != nil {
 		log.Error().Err(err).Interface("value_map", valueMap).Str("sql", sql).Msg("error updating file_collection")
 		return err
@@ -284,47 +326,88 @@ func (controller PartController) UpdateTribalKnowledge(partID ID, partType *stri
 		return errors.New("update had no affect")
 	}
 
-	return nil
+	var after Part
+	if err := tx.QueryRowx("SELECT * FROM part WHERE part_id=$1", partID).StructScan(&after); err != nil {
+		return errors.Wrapf(err, "error selecting updated part")
+	}
+	if err := CheckPartType(tx, partID); err != nil {
+		return err
+	}
+	if controller.EnforceVerificationCodes && len(fileVerificationCode) > 0 {
+		computed, _, err := calculateVerificationCode(tx, partID)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(computed, fileVerificationCode) {
+			return errors.Wrapf(ErrVerificationCodeMismatch, "declared %x, calculated %x", fileVerificationCode, computed)
+		}
+	}
+	if len(fileVerificationCode) > 0 {
+		if err := SetVerificationCodeOne(tx, partID); err != nil {
+			return err
+		}
+	}
+	changes := diffCurated(*before, after)
+	if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(partID), audit.ActionUpdate, changes); err != nil {
+		return err
+	}
+	if err := review.ResetApproval(tx, controller.Actor, uuid.UUID(partID), changes); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing part update")
 }
 
 // CreateAlias upserts an alias to a given part
 // If the alias already exists for the given part
 // If the alias exists and is associated with a different part, an error is returned
 func (controller PartController) CreateAlias(partId ID, alias string) (*ID, error) {
-	var aliasesPartID ID
-	if err := controller.DB.QueryRow(`INSERT INTO part_alias (alias, part_id) VALUES ($1, $2)
-	ON CONFLICT (alias) DO UPDATE SET alias=EXCLUDED.alias
-	RETURNING part_id`, // meaningless update required for return
-		alias, partId).Scan(&aliasesPartID); err != nil {
-		return nil, errors.Wrapf(err, "error inserting part_alias")
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
 	}
+	defer tx.Rollback()
 
-	if aliasesPartID.String() != partId.String() {
-		return nil, errors.New("alias part id mismtach")
+	change, err := setAlias(tx, partId, alias, true)
+	if err != nil {
+		return nil, err
+	}
+	if change != nil {
+		if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(partId), audit.ActionAlias, []audit.Change{*change}); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing part_alias")
 	}
 
-	return &aliasesPartID, nil
+	return &partId, nil
 }
 
 // AttachDocument upserts a document into part_has_document or part_documents, depending on if a title is given
 func (controller PartController) AttachDocument(partId ID, key string, title *string, document json.RawMessage) error {
-	if title == nil || *title == "" {
-		// no title, so insert into part_has_document
-		if _, err := controller.DB.Exec(`INSERT INTO part_has_document(part_id, key, document) VALUES ($1, $2, $3)
-		ON CONFLICT (part_id, key) DO UPDATE SET document=EXCLUDED.document`,
-			partId, key, document); err != nil {
-			return errors.Wrapf(err, "error inserting into part_has_document")
-		}
+	if title != nil && *title == "" {
+		title = nil // no title, so insert into part_has_document
+	}
 
-		return nil
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
 	}
+	defer tx.Rollback()
 
-	if _, err := controller.DB.Exec(`INSERT INTO part_documents(part_id, key, title, document) VALUES ($1, $2, $3, $4)
-	ON CONFLICT (part_id, key, title) DO UPDATE SET document=EXCLUDED.document`); err != nil {
-		return errors.Wrapf(err, "error inserting into part_documents")
+	value := string(document)
+	change, err := setDocument(tx, partId, key, title, &value)
+	if err != nil {
+		return err
+	}
+	if change != nil {
+		if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(partId), audit.ActionUpdate, []audit.Change{*change}); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return errors.Wrapf(tx.Commit(), "error committing document")
 }
 
 type SubPart struct {
@@ -360,22 +443,128 @@ func (controller PartController) SubParts(partID ID) ([]SubPart, error) {
 }
 
 // AddPartToPart adds a sub-part to a part at a path
-// If the relationship already exists, nothing changes
+// If the relationship already exists, nothing changes, and ErrCycle is returned if the part is the sub-part or beneath it
+// ErrSubPartsNotAllowed is returned if the type of the part does not allow sub-parts
+// The file verification codes of the part and its ancestors are refreshed, see refreshVerificationCodes
 func (controller PartController) AddPartToPart(childID ID, parentID ID, path string) error {
-	if _, err := controller.DB.Exec("INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, $3) ON CONFLICT (parent_id, child_id, path) DO NOTHING", // TODO this probably shouldn't catch the conflict, to make sure users didn't accidentally set the same path twice
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if err := insertSubPart(tx, childID, parentID, path); err != nil {
+		return err
+	}
+	if err := controller.refreshVerificationCodes(tx, parentID); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing part_has_part")
+}
+
+// insertSubPart adds a sub-part to a part at a path within the transaction, see AddPartToPart
+func insertSubPart(tx *sqlx.Tx, childID ID, parentID ID, path string) error {
+	// the same lock part_has_part_acyclic takes, so no other sub-part is added between the check and the insert
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('part_has_part'))"); err != nil {
+		return errors.Wrapf(err, "error locking part_has_part")
+	}
+
+	var cycle bool
+	if err := tx.QueryRow("SELECT $1::UUID=$2::UUID OR EXISTS (SELECT 1 FROM part_closure WHERE ancestor_id=$1 AND descendant_id=$2)",
+		childID, parentID).Scan(&cycle); err != nil {
+		return errors.Wrapf(err, "error selecting ancestors of part %s", parentID.String())
+	}
+	if cycle {
+		return errors.Wrapf(ErrCycle, "%s is beneath %s", parentID.String(), childID.String())
+	}
+
+	if _, err := tx.Exec("INSERT INTO part_has_part (parent_id, child_id, path) VALUES ($1, $2, $3) ON CONFLICT (parent_id, child_id, path) DO NOTHING", // TODO this probably shouldn't catch the conflict, to make sure users didn't accidentally set the same path twice
 		parentID, childID, path); err != nil {
-		return errors.Wrapf(err, "error inserting part_has_part")
+		return errors.Wrapf(subPartError(err), "error inserting part_has_part")
+	}
+	var parentType sql.NullString
+	if err := tx.QueryRow("SELECT type FROM part WHERE part_id=$1", parentID).Scan(&parentType); err != nil {
+		return errors.Wrapf(err, "error selecting type of part %s", parentID.String())
 	}
 
-	return nil
+	return checkSubParts(tx, parentID, parentType)
+}
+
+// subPartError returns ErrCycle for the check_violation part_has_part_acyclic raises when a sub-part would be its own ancestor, or else err
+func subPartError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+		return errors.Wrapf(ErrCycle, "%s", pgErr.Message)
+	}
+
+	return err
+}
+
+// PartFile is a file of a part, at its path within the part
+type PartFile struct {
+	Sha256 []byte
+	Path   string
+}
+
+// AddContentToPart adds files and sub-parts to a part in one transaction, as AddFileToPart and AddPartToPart do one at a time
+// The file verification codes of the part and its ancestors are refreshed once all of them are added, see refreshVerificationCodes
+func (controller PartController) AddContentToPart(partID ID, files []PartFile, subParts []SubPart) error {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	for _, f := range files {
+		if _, err := tx.Exec("INSERT INTO part_has_file (part_id, file_sha256, path) VALUES ($1, $2, $3) ON CONFLICT (part_id, file_sha256, path) DO NOTHING",
+			partID, f.Sha256, f.Path); err != nil {
+			return errors.Wrapf(err, "error inserting part_has_file")
+		}
+	}
+	for _, subPart := range subParts {
+		if err := insertSubPart(tx, subPart.ID, partID, subPart.Path); err != nil {
+			return err
+		}
+	}
+	if err := controller.refreshVerificationCodes(tx, partID); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing content of part %s", partID.String())
+}
+
+// AddFileToPart adds a file to a part at a path
+// If the file is already at the path, nothing changes
+// The file verification codes of the part and its ancestors are refreshed, see refreshVerificationCodes
+func (controller PartController) AddFileToPart(partID ID, fileSha256 []byte, path string) error {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO part_has_file (part_id, file_sha256, path) VALUES ($1, $2, $3) ON CONFLICT (part_id, file_sha256, path) DO NOTHING",
+		partID, fileSha256, path); err != nil {
+		return errors.Wrapf(err, "error inserting part_has_file")
+	}
+	if err := controller.refreshVerificationCodes(tx, partID); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing part_has_file")
 }
 
 // CreatePart creates a new part with the given info, and returns the newly created part
 // The newly created part should have the same fields as the input, with the additional of a real part UUID
+// The part has to follow its type, see CheckType
 func (controller PartController) CreatePart(part Part) (*Part, error) {
 	if part.PartID != ID(uuid.Nil) {
 		return nil, errors.New("CreatePart was given a part with an ID")
 	}
+	if err := CheckType(&part); err != nil {
+		return nil, err
+	}
 
 	var comprised sql.NullString
 	if part.Comprised != ID(uuid.Nil) {
@@ -383,8 +572,14 @@ func (controller PartController) CreatePart(part Part) (*Part, error) {
 		comprised.String = part.Comprised.String()
 	}
 
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
 	var newPart Part
-	if err := controller.DB.QueryRowx(`INSERT INTO part 
+	if err := tx.QueryRowx(`INSERT INTO part 
 	(type, name, version, label, family_name, license, license_rationale, description, comprised) 
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
 	RETURNING *`,
@@ -393,6 +588,12 @@ func (controller PartController) CreatePart(part Part) (*Part, error) {
 		StructScan(&newPart); err != nil {
 		return nil, errors.Wrapf(err, "error inserting part")
 	}
+	if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(newPart.PartID), audit.ActionCreate, diffCurated(Part{}, newPart)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing part")
+	}
 	if newPart.Type.Valid {
 		newPart.Type.String = "/" + strings.ReplaceAll(newPart.Type.String, ".", "/")
 	}
@@ -400,57 +601,93 @@ func (controller PartController) CreatePart(part Part) (*Part, error) {
 	return &newPart, nil
 }
 
-// DeletePart deletes the given part
+// DeletePart deletes the given part, and its sub-parts, in one transaction
 // Any existing archive or comprised relationships will be set t onull
+// The deletion of each part is recorded in its history, with the curated values it had
 func (controller PartController) DeletePart(partID ID) error {
 	if partID == ID(uuid.Nil) {
 		return errors.New("DeletePart was given a nil ID")
 	}
 
 	// Get part to check that it exists
-	if _, err := controller.GetByID(partID); err != nil {
+	if p, err := controller.GetByID(partID); err != nil {
+		return err
+	} else if p.PartID != partID {
+		return errors.Wrapf(ErrNotFound, "%s was merged into %s", partID.String(), p.PartID.String())
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if err := controller.deletePart(tx, partID); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing deletion of part %s", partID.String())
+}
+
+// deletePart deletes a part and its sub-parts within the transaction, see DeletePart
+// Sub-parts already deleted, beneath the part by another path, are skipped
+func (controller PartController) deletePart(tx *sqlx.Tx, partID ID) error {
+	before, err := selectForUpdate(tx, partID)
+	if err != nil {
 		return err
 	}
 
 	// Remove archive relationship if any
-	if _, err := controller.DB.Exec(`UPDATE archive SET part_id=NULL WHERE part_id=$1`, partID); err != nil {
+	if _, err := tx.Exec(`UPDATE archive SET part_id=NULL WHERE part_id=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error unsetting archive part_id %s", partID)
 	}
 	// Delete part_alias
-	if _, err := controller.DB.Exec(`DELETE FROM part_alias WHERE part_id=$1`, partID); err != nil {
+	if _, err := tx.Exec(`DELETE FROM part_alias WHERE part_id=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error deleting aliases of part %s", partID)
 	}
 	// Unset comprised
-	if _, err := controller.DB.Exec(`UPDATE part SET comprised=NULL WHERE comprised=$1`, partID); err != nil {
+	if _, err := tx.Exec(`UPDATE part SET comprised=NULL WHERE comprised=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error removing %s from comprised fields", partID)
 	}
 	// Delete documents
-	if _, err := controller.DB.Exec(`DELETE FROM part_has_document WHERE part_id=$1`, partID); err != nil {
+	if _, err := tx.Exec(`DELETE FROM part_has_document WHERE part_id=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error deleting part_has_document of %s", partID)
 	}
-	if _, err := controller.DB.Exec(`DELETE FROM part_documents WHERE part_id=$1`, partID); err != nil {
+	if _, err := tx.Exec(`DELETE FROM part_documents WHERE part_id=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error deleting part_has_documents of %s", partID)
 	}
 	// Delete file relations
-	if _, err := controller.DB.Exec(`DELETE FROM part_has_file WHERE part_id=$1`, partID); err != nil {
+	if _, err := tx.Exec(`DELETE FROM part_has_file WHERE part_id=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error deleting files from %s", partID)
 	}
 	// Remove from partlist // TOOD should this be a call to partlistcontroller?
-	if _, err := controller.DB.Exec(`DELETE FROM partlist_has_part WHERE part_id=$1`, partID); err != nil {
+	if _, err := tx.Exec(`DELETE FROM partlist_has_part WHERE part_id=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error deleting part %s from partlists", partID)
 	}
 	// Delete sub-parts
-	subParts, err := controller.SubParts(partID)
-	if err != nil {
-		return err
+	subParts := make([]SubPart, 0)
+	if err := tx.Select(&subParts, "SELECT child_id, path FROM part_has_part WHERE parent_id=$1", partID); err != nil {
+		return errors.Wrapf(err, "error selecting sub-parts of part %s", partID)
 	}
 	for _, subPart := range subParts {
-		if err := controller.DeletePart(subPart.ID); err != nil {
+		if err := controller.deletePart(tx, subPart.ID); err != nil && !errors.Is(err, ErrNotFound) {
 			return err
 		}
 	}
+	if _, err := tx.Exec(`DELETE FROM part_has_part WHERE parent_id=$1 OR child_id=$1`, partID); err != nil {
+		return errors.Wrapf(err, "error deleting part_has_part of part %s", partID)
+	}
+
+	changes := diffCurated(*before, Part{})
+	if len(changes) == 0 { // a part without curated values, recorded by its id
+		id := partID.String()
+		changes = append(changes, audit.Change{Field: "part_id", Old: &id})
+	}
+	if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(partID), audit.ActionDelete, changes); err != nil {
+		return err
+	}
 	// Delete part
-	if _, err := controller.DB.Exec(`DELETE FROM part WHERE part_id=$1`, partID); err != nil {
+	if _, err := tx.Exec(`DELETE FROM part WHERE part_id=$1`, partID); err != nil {
 		return errors.Wrapf(err, "error deleting part %s", partID)
 	}
 
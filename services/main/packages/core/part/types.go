@@ -0,0 +1,181 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// How parts of a type are comprised of other parts, the comprised of column of the part types of SoftwarePartsDataModel.md
+const (
+	ComprisedOfNone    = "n/a"     // parts of the type have no sub-parts
+	ComprisedOfLink    = "link"    // parts of the type may contain sub-parts
+	ComprisedOfLogical = "logical" // parts of the type are a logical structure of sub-parts, such as the contents of a product
+)
+
+// Type is a registered type of part
+type Type struct {
+	Type        string // ltree of the type, such as part.file.src
+	Description string
+	ComprisedOf string   // whether parts of the type may have sub-parts
+	Required    []string // columns parts of the type must have values of
+	Custom      bool     // whether any type beneath the type may be used, with its rules, though not the type itself
+}
+
+// Types is the registry of part types, the types of SoftwarePartsDataModel.md, and the types parts were given before it
+var Types = []Type{
+	{Type: "part.file.src", Description: "single source file, such as main.c", ComprisedOf: ComprisedOfNone, Required: []string{"name"}},
+	{Type: "part.file.binary.app", Description: "single application binary, such as app.exe", ComprisedOf: ComprisedOfLink, Required: []string{"name"}},
+	{Type: "part.file.binary.library", Description: "single library binary, such as libdb.so", ComprisedOf: ComprisedOfLink, Required: []string{"name"}},
+	{Type: "part.file.binary.runtime", Description: "runtime binary, such as a linux runtime binary", ComprisedOf: ComprisedOfLink, Required: []string{"name"}},
+	{Type: "part.file.binary.container", Description: "container image file", ComprisedOf: ComprisedOfLink, Required: []string{"name"}},
+	{Type: "part.file.collection", Description: "archive of files and other parts, such as busybox.1.31.2.tar.gz", ComprisedOf: ComprisedOfLink, Required: []string{"name"}},
+	{Type: "part.collection.contents", Description: "composite product, such as vxworks7-22.09", ComprisedOf: ComprisedOfLogical, Required: []string{"name", "version"}},
+	{Type: "archive", Description: "uploaded archive", ComprisedOf: ComprisedOfLink},
+	{Type: "archive.custom", ComprisedOf: ComprisedOfLink, Custom: true},
+	{Type: "file.source", Description: "source file", ComprisedOf: ComprisedOfNone},
+	{Type: "file.binary", Description: "binary file", ComprisedOf: ComprisedOfLink},
+	{Type: "file.custom", ComprisedOf: ComprisedOfLink, Custom: true},
+	{Type: "container.image", Description: "container image", ComprisedOf: ComprisedOfLink},
+	{Type: "container.source", Description: "source of a container image", ComprisedOf: ComprisedOfLink},
+	{Type: "container.custom", ComprisedOf: ComprisedOfLink, Custom: true},
+	{Type: "logical", Description: "logical structure of parts", ComprisedOf: ComprisedOfLogical},
+	{Type: "logical.custom", ComprisedOf: ComprisedOfLogical, Custom: true},
+}
+
+// typeLTreePattern matches the ltree of a part type, labels of letters, digits, and underscores joined by dots
+var typeLTreePattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+// TypeLTree converts a part type styled like a file path, /part/file/src, into an ltree, part.file.src, leaving ltrees as they are
+func TypeLTree(partType string) string {
+	return strings.ReplaceAll(strings.Trim(partType, "/"), "/", ".")
+}
+
+// LookupType returns the registered type of a part type, the type itself or the custom type it is beneath
+func LookupType(partType string) (*Type, error) {
+	lTree := TypeLTree(partType)
+	for i, t := range Types {
+		if !t.Custom && t.Type == lTree || t.Custom && strings.HasPrefix(lTree, t.Type+".") {
+			return &Types[i], nil
+		}
+	}
+
+	return nil, errors.Wrapf(ErrUnknownType, "%s", partType)
+}
+
+// Check returns an error if the part is missing a column the type requires
+func (t Type) Check(p *Part) error {
+	for _, column := range t.Required {
+		var ok bool
+		switch column {
+		case "name":
+			ok = p.Name.String != ""
+		case "version":
+			ok = p.Version.String != ""
+		case "label":
+			ok = p.Label.String != ""
+		case "family_name":
+			ok = p.FamilyName.String != ""
+		case "license":
+			ok = p.License.String != ""
+		case "description":
+			ok = p.Description.String != ""
+		}
+		if !ok {
+			return errors.Wrapf(ErrMissingField, "%s parts require %s", t.Type, column)
+		}
+	}
+
+	return nil
+}
+
+// AllowsSubParts returns whether parts of the type may have sub-parts
+func (t Type) AllowsSubParts() bool {
+	return t.ComprisedOf != ComprisedOfNone
+}
+
+// CheckType returns an error if the type of a part is not registered, or the part is missing a column its type requires.
+// Parts without a type are not checked.
+func CheckType(p *Part) error {
+	if !p.Type.Valid || p.Type.String == "" {
+		return nil
+	}
+
+	t, err := LookupType(p.Type.String)
+	if err != nil {
+		return err
+	}
+
+	return t.Check(p)
+}
+
+// CheckPartType returns an error if a part, as it is within the transaction, does not follow its type, see CheckType,
+// or has sub-parts though its type does not allow them
+func CheckPartType(q sqlx.Queryer, partID ID) error {
+	var p Part
+	if err := q.QueryRowx("SELECT * FROM part WHERE part_id=$1", partID).StructScan(&p); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+
+		return errors.Wrapf(err, "error selecting part %s", partID.String())
+	}
+	if err := CheckType(&p); err != nil {
+		return errors.Wrapf(err, "part %s", partID.String())
+	}
+
+	return checkSubParts(q, partID, p.Type)
+}
+
+// checkSubParts returns ErrSubPartsNotAllowed if a part has sub-parts though its type does not allow them.
+// Parts without a registered type are not checked.
+func checkSubParts(q sqlx.Queryer, partID ID, partType sql.NullString) error {
+	if !partType.Valid || partType.String == "" {
+		return nil
+	}
+	t, err := LookupType(partType.String)
+	if err != nil || t.AllowsSubParts() {
+		return nil
+	}
+
+	var count int64
+	if err := q.QueryRowx("SELECT COUNT(*) FROM part_has_part WHERE parent_id=$1", partID).Scan(&count); err != nil {
+		return errors.Wrapf(err, "error counting sub-parts of part %s", partID.String())
+	}
+	if count > 0 {
+		return errors.Wrapf(ErrSubPartsNotAllowed, "part %s of type %s has %d sub-parts", partID.String(), t.Type, count)
+	}
+
+	return nil
+}
+
+// GetByTypeUnder returns the parts of a type or of any type beneath it, such as /part/file/binary for applications, libraries, and so on
+// ErrInvalidType is returned if the type is not a well-formed type, rather than an error of the query
+func (controller PartController) GetByTypeUnder(partType string) ([]Part, error) {
+	if !typeLTreePattern.MatchString(TypeLTree(partType)) {
+		return nil, errors.Wrapf(ErrInvalidType, "%s", partType)
+	}
+
+	ret := make([]Part, 0)
+	if err := controller.DB.Select(&ret, "SELECT * FROM part WHERE type <@ $1::ltree ORDER BY type, name, version", TypeLTree(partType)); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts of type %s", partType)
+	}
+	for i := range ret {
+		ret[i].Type.String = "/" + strings.ReplaceAll(ret[i].Type.String, ".", "/")
+	}
+
+	return ret, nil
+}
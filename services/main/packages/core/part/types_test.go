@@ -0,0 +1,53 @@
+package part
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestCheckType(t *testing.T) {
+	named := func(partType string, name string, version string) *Part {
+		return &Part{
+			Type:    sql.NullString{String: partType, Valid: true},
+			Name:    sql.NullString{String: name, Valid: name != ""},
+			Version: sql.NullString{String: version, Valid: version != ""},
+		}
+	}
+
+	tests := []struct {
+		name string
+		part *Part
+		want error
+	}{
+		{name: "registered", part: named("/part/file/src", "main.c", ""), want: nil},
+		{name: "ltree", part: named("part.file.binary.library", "libdb.so", ""), want: nil},
+		{name: "unregistered", part: named("/part/file/firmware", "boot.bin", ""), want: ErrUnknownType},
+		{name: "beneath a registered type", part: named("/part/file/src/c", "main.c", ""), want: ErrUnknownType},
+		{name: "missing name", part: named("/part/file/src", "", ""), want: ErrMissingField},
+		{name: "missing version", part: named("/part/collection/contents", "vxworks7", ""), want: ErrMissingField},
+		{name: "custom", part: named("/archive/custom/firmware", "", ""), want: nil},
+		{name: "custom itself", part: named("/archive/custom", "", ""), want: ErrUnknownType},
+		{name: "untyped", part: &Part{}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckType(tt.part); !errors.Is(err, tt.want) {
+				t.Errorf("CheckType() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if src, _ := LookupType("/part/file/src"); src.AllowsSubParts() {
+		t.Errorf("source files allow sub-parts")
+	}
+}
+
+func TestGetByTypeUnderInvalid(t *testing.T) {
+	// rejected before querying, so no database is needed
+	for _, partType := range []string{"", "/", "/part//file", "part.file.src'; --", "/part/file-src", "part..file"} {
+		if _, err := (PartController{}).GetByTypeUnder(partType); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("GetByTypeUnder(%q) error = %v, want %v", partType, err, ErrInvalidType)
+		}
+	}
+}
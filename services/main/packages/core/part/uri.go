@@ -0,0 +1,150 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Schemes of catalog URIs, [scheme]://[domain-name]/[catalog-instance-id]/[id]
+const (
+	SchemePartID  = "partid"
+	SchemeFVCID   = "fvcid"
+	SchemeAliasID = "aliasid"
+)
+
+// schemeSpellings maps other spellings found in the data model document to their scheme
+var schemeSpellings = map[string]string{
+	SchemePartID:  SchemePartID,
+	SchemeFVCID:   SchemeFVCID,
+	"fvc":         SchemeFVCID,
+	SchemeAliasID: SchemeAliasID,
+	"alaisid":     SchemeAliasID,
+	"partalias":   SchemeAliasID,
+}
+
+// URI is an externally referenceable id of a part in a catalog instance
+// Instance is the catalog instance identity, [domain-name]/[catalog-instance-id], and ID is a part id, file verification code, or alias depending on the scheme
+type URI struct {
+	Scheme   string
+	Instance string
+	ID       string
+}
+
+// ParseURI parses a partid://, fvcid://, or aliasid:// URI
+// The id of a partid must be a UUID, and that of an fvcid hex-encoded
+func ParseURI(s string) (*URI, error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(s), "://")
+	if !ok {
+		return nil, errors.Errorf("catalog uri \"%s\" has no scheme", s)
+	}
+
+	ret := new(URI)
+	if ret.Scheme, ok = schemeSpellings[strings.ToLower(scheme)]; !ok {
+		return nil, errors.Errorf("unknown catalog uri scheme \"%s\", expected one of partid, fvcid, or aliasid", scheme)
+	}
+
+	components := strings.SplitN(rest, "/", 3)
+	if len(components) != 3 || components[0] == "" || components[1] == "" || components[2] == "" {
+		return nil, errors.Errorf("catalog uri \"%s\" is not [scheme]://[domain-name]/[catalog-instance-id]/[id]", s)
+	}
+	ret.Instance = strings.ToLower(components[0]) + "/" + components[1]
+
+	id, err := url.PathUnescape(components[2])
+	if err != nil {
+		return nil, errors.Wrapf(err, "error unescaping id of catalog uri \"%s\"", s)
+	}
+	ret.ID = id
+
+	switch ret.Scheme {
+	case SchemePartID:
+		if _, err := uuid.Parse(ret.ID); err != nil {
+			return nil, errors.Wrapf(err, "error parsing part id of \"%s\"", s)
+		}
+	case SchemeFVCID:
+		if _, err := hex.DecodeString(ret.ID); err != nil {
+			return nil, errors.Wrapf(err, "error decoding file verification code of \"%s\"", s)
+		}
+	}
+
+	return ret, nil
+}
+
+func (uri URI) String() string {
+	return uri.Scheme + "://" + uri.Instance + "/" + url.PathEscape(uri.ID)
+}
+
+// URI returns the catalog URI of the given scheme and id in this catalog instance
+func (controller PartController) URI(scheme string, id string) string {
+	return URI{Scheme: scheme, Instance: controller.Instance, ID: id}.String()
+}
+
+// GetURIs lists every catalog URI of the given part, its partid, its fvcid if it has a file verification code, and an aliasid per alias
+func (controller PartController) GetURIs(partID ID, fileVerificationCode []byte) ([]string, error) {
+	ret := []string{controller.URI(SchemePartID, partID.String())}
+	if len(fileVerificationCode) > 0 {
+		ret = append(ret, controller.URI(SchemeFVCID, hex.EncodeToString(fileVerificationCode)))
+	}
+
+	aliases, err := controller.GetAliases(partID)
+	if err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		ret = append(ret, controller.URI(SchemeAliasID, alias))
+	}
+
+	return ret, nil
+}
+
+// Resolve gets the part referenced by a partid://, fvcid://, or aliasid:// URI of this catalog instance
+// ErrForeignInstance is returned for URIs of other catalog instances
+func (controller PartController) Resolve(s string) (*Part, error) {
+	uri, err := ParseURI(s)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(uri.Instance, controller.Instance) {
+		return nil, errors.Wrapf(ErrForeignInstance, "\"%s\" is not %s", uri.Instance, controller.Instance)
+	}
+
+	switch uri.Scheme {
+	case SchemePartID:
+		partUUID, err := uuid.Parse(uri.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing part id of \"%s\"", s)
+		}
+
+		return controller.GetByID(ID(partUUID))
+	case SchemeFVCID:
+		fvc, err := hex.DecodeString(uri.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decoding file verification code of \"%s\"", s)
+		}
+
+		return controller.GetByVerificationCode(fvc)
+	default:
+		var partID ID
+		if err := controller.DB.QueryRowx("SELECT part_id FROM part_alias WHERE alias=$1", uri.ID).Scan(&partID); err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "error selecting part alias \"%s\"", uri.ID)
+		}
+
+		return controller.GetByID(partID)
+	}
+}
@@ -0,0 +1,49 @@
+package part
+
+import "testing"
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		want      URI
+		wantError bool
+	}{
+		{
+			name: "partid",
+			uri:  "partid://WindRiver.com/spc/2b1b0f2c-1f7e-4c4b-9a53-53d0a5f6bd0e",
+			want: URI{Scheme: SchemePartID, Instance: "windriver.com/spc", ID: "2b1b0f2c-1f7e-4c4b-9a53-53d0a5f6bd0e"},
+		},
+		{
+			name: "fvc spelling",
+			uri:  "fvc://tesla.com/public-1/4656433200e3b0c4",
+			want: URI{Scheme: SchemeFVCID, Instance: "tesla.com/public-1", ID: "4656433200e3b0c4"},
+		},
+		{
+			name: "escaped alias",
+			uri:  "partalias://windriver.com/spc/vxworks%2F22.09",
+			want: URI{Scheme: SchemeAliasID, Instance: "windriver.com/spc", ID: "vxworks/22.09"},
+		},
+		{name: "unknown scheme", uri: "http://windriver.com/spc/x", wantError: true},
+		{name: "no instance", uri: "partid://windriver.com/x", wantError: true},
+		{name: "partid not a uuid", uri: "partid://tesla.com/public-1/2503451233", wantError: true},
+		{name: "no scheme", uri: "windriver.com/spc/x", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseURI(tt.uri)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseURI() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err != nil {
+				return
+			}
+			if *got != tt.want {
+				t.Errorf("ParseURI() = %#v, want %#v", *got, tt.want)
+			}
+			if roundTrip, err := ParseURI(got.String()); err != nil || *roundTrip != *got {
+				t.Errorf("ParseURI(%s) = %#v, %v, want %#v", got.String(), roundTrip, err, *got)
+			}
+		})
+	}
+}
@@ -1,72 +1,200 @@
 package part
 
 import (
+	"bytes"
+	"wrs/tk/packages/core/audit"
+
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"gitlab.devstar.cloud/ip-systems/verification-code.git/code"
 )
 
-// processFileCollection catalogs files found at parentDirectory as children of this archive, and recursively processes any sub-packages.
-// if any file is missing a sha256, the resulting file verification code 2 will be nil
+// verificationCodes selects the stored and computed file verification code 2 of the parts with one stored, of only the part $1 unless it is NULL
+const verificationCodes = `WITH coded AS (
+		SELECT part_id, file_verification_code FROM part WHERE SUBSTRING(file_verification_code FROM 1 FOR 5)='\x4656433200'::BYTEA
+		AND ($1::UUID IS NULL OR part_id=$1::UUID)
+	), files AS (
+		SELECT coded.part_id, part_has_file.file_sha256 AS sha256 FROM coded
+		INNER JOIN part_has_file ON part_has_file.part_id=coded.part_id
+		UNION ALL SELECT coded.part_id, part_has_file.file_sha256 FROM coded
+		INNER JOIN part_closure ON part_closure.ancestor_id=coded.part_id
+		INNER JOIN part_has_file ON part_has_file.part_id=part_closure.descendant_id
+	)
+	SELECT coded.part_id, coded.file_verification_code AS stored,
+	'\x4656433200'::BYTEA || DIGEST(COALESCE(STRING_AGG(files.sha256::BYTEA, ''::BYTEA ORDER BY files.sha256), ''::BYTEA), 'sha256') AS computed
+	FROM coded LEFT JOIN files ON files.part_id=coded.part_id
+	GROUP BY coded.part_id, coded.file_verification_code`
+
+// CalculateFileCollectionVerificationCode calculates the file verification code 2 of the files of a part and of every part beneath it,
+// the files of a sub-part counted once per path it is found at, as they are when an archive is extracted
 func (p *PartController) CalculateFileCollectionVerificationCode(partID uuid.UUID) (vcodeTwo []byte, err error) {
+	vcodeTwo, _, err = calculateVerificationCode(p.DB, ID(partID))
+	return vcodeTwo, err
+}
+
+// calculateVerificationCode calculates the file verification code 2 of a part, and counts the files it was calculated from
+func calculateVerificationCode(q sqlx.Queryer, partID ID) (vcodeTwo []byte, files int64, err error) {
 	vcoderTwo := code.NewVersionTwo().(*code.VersionTwoHasher)
 
-	// Select all files and feed to verification code
-	rows, err := p.DB.Queryx("SELECT f.checksum_sha256 "+
-		"FROM part_has_file phf "+
-		"INNER JOIN file f ON f.sha256=phf.phf.file_sha256 "+
-		"WHERE phf.part_id=$1", partID)
+	rows, err := q.Queryx(`SELECT file_sha256 FROM part_has_file WHERE part_id=$1
+	UNION ALL SELECT part_has_file.file_sha256 FROM part_closure
+	INNER JOIN part_has_file ON part_has_file.part_id=part_closure.descendant_id
+	WHERE part_closure.ancestor_id=$1`, partID)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error selecting files of part %s", partID)
+		return nil, 0, errors.Wrapf(err, "error selecting files of part %s", partID)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var tmpSha256 []byte
 		if err := rows.Scan(&tmpSha256); err != nil {
-			return nil, errors.Wrapf(err, "error scanning checksum of files of part %s", partID)
+			return nil, 0, errors.Wrapf(err, "error scanning checksums of files of part %s", partID)
 		}
 
 		if err := vcoderTwo.AddSha256(tmpSha256); err != nil {
+			return nil, 0, err
+		}
+		files++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.Wrapf(err, "error selecting files of part %s", partID)
+	}
+
+	vcodeTwo = vcoderTwo.Sum()
+	return vcodeTwo, files, nil
+}
+
+// SetVerificationCodeOne sets the file verification code 1 of a part from the sha1s of its files and those of its sub-parts,
+// or unsets it if the part has no file verification code 2, no files, or a file without a sha1
+func SetVerificationCodeOne(tx sqlx.Execer, partID ID) error {
+	if _, err := tx.Exec(`UPDATE part SET file_verification_code_one=CASE WHEN file_verification_code IS NULL THEN NULL ELSE calculate_part_verification_code_v1(part_id) END
+	WHERE part_id=$1`, partID); err != nil {
+		return errors.Wrapf(err, "error updating file_verification_code_one of part %s", partID.String())
+	}
+
+	return nil
+}
+
+// VerifyVerificationCodes recomputes the file verification code 2 of the given parts, or of every part when none are given,
+// records the codes computed in part_verification, and returns the parts whose stored code differs
+// Parts without a stored code 2 are not verified
+func (controller PartController) VerifyVerificationCodes(partIDs ...ID) ([]CodeMismatch, error) {
+	filters := make([]interface{}, 0, len(partIDs))
+	for _, partID := range partIDs {
+		filters = append(filters, partID)
+	}
+	if len(filters) == 0 {
+		filters = append(filters, nil)
+	}
+
+	ret := make([]CodeMismatch, 0)
+	for _, filter := range filters {
+		if _, err := controller.DB.Exec(`INSERT INTO part_verification (part_id, computed, check_date)
+		SELECT part_id, computed, NOW() FROM (`+verificationCodes+`) codes
+		ON CONFLICT (part_id) DO UPDATE SET computed=EXCLUDED.computed, check_date=EXCLUDED.check_date`, filter); err != nil {
+			return nil, errors.Wrapf(err, "error verifying file verification codes")
+		}
+
+		mismatches, err := verificationMismatches(controller.DB, filter)
+		if err != nil {
 			return nil, err
 		}
+		ret = append(ret, mismatches...)
 	}
-	rows.Close()
 
-	pgraph, err := NewPartGraph(p.DB, partID)
+	return ret, nil
+}
+
+// VerificationMismatches returns the parts whose stored file verification code differed from the one computed when they were last verified
+func (controller PartController) VerificationMismatches() ([]CodeMismatch, error) {
+	return verificationMismatches(controller.DB, nil)
+}
+
+// verificationMismatches selects the recorded mismatches, of only the part filter unless it is nil
+func verificationMismatches(q sqlx.Queryer, filter interface{}) ([]CodeMismatch, error) {
+	rows, err := q.Queryx(`SELECT part.part_id, part.file_verification_code, part_verification.computed FROM part_verification
+	INNER JOIN part ON part.part_id=part_verification.part_id
+	WHERE part.file_verification_code<>part_verification.computed AND ($1::UUID IS NULL OR part.part_id=$1::UUID)
+	ORDER BY part.part_id`, filter)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "error selecting file verification code mismatches")
 	}
+	defer rows.Close()
 
-	if len(pgraph.Edges) > 0 {
-		if err := pgraph.TraverseUniqueEdges(func(partID string) error {
-			// Select all files and feed to verification code
-			rows, err = p.DB.Queryx("SELECT f.sha256 "+
-				"FROM part_has_file phf "+
-				"INNER JOIN file f ON f.sha256=phf.file_sha256 "+
-				"WHERE phf.part_id=$1", partID)
-			if err != nil {
-				return errors.Wrapf(err, "error selecting files of part %s", partID)
-			}
-			defer rows.Close()
+	ret := make([]CodeMismatch, 0)
+	for rows.Next() {
+		var mismatch CodeMismatch
+		if err := rows.Scan(&mismatch.PartID, &mismatch.Stored, &mismatch.Computed); err != nil {
+			return nil, errors.Wrapf(err, "error scanning file verification code mismatches")
+		}
+		ret = append(ret, mismatch)
+	}
+
+	return ret, errors.Wrapf(rows.Err(), "error selecting file verification code mismatches")
+}
 
-			for rows.Next() {
-				var tmpSha256 []byte
-				if err := rows.Scan(&tmpSha256); err != nil {
-					return errors.Wrapf(err, "error scanning checksums of files of part %s", partID)
-				}
+// refreshVerificationCodes brings the file verification codes of a part and its ancestors in line with their files, after the files beneath the part changed
+// Parts built by hand, from no archive, have their code computed once they have files, unless another part already has it.
+// Parts of archives keep the code of their archive, and with EnforceVerificationCodes the change is refused with ErrVerificationCodeMismatch when it no longer matches.
+func (controller PartController) refreshVerificationCodes(tx *sqlx.Tx, partID ID) error {
+	parts := make([]struct {
+		ID       ID     `db:"part_id"`
+		Code     []byte `db:"file_verification_code"`
+		Archived bool   `db:"archived"`
+	}, 0)
+	if err := tx.Select(&parts, `SELECT part_id, file_verification_code, EXISTS (SELECT 1 FROM archive WHERE archive.part_id=part.part_id) AS archived FROM part
+	WHERE part_id=$1 OR part_id IN (SELECT ancestor_id FROM part_closure WHERE descendant_id=$1)`, partID); err != nil {
+		return errors.Wrapf(err, "error selecting ancestors of part %s", partID.String())
+	}
 
-				if err := vcoderTwo.AddSha256(tmpSha256); err != nil {
-					return err
-				}
-			}
+	for _, p := range parts {
+		if p.Archived && (len(p.Code) == 0 || !controller.EnforceVerificationCodes) {
+			continue
+		}
 
-			return nil
-		}); err != nil {
-			return nil, err
+		computed, files, err := calculateVerificationCode(tx, p.ID)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(computed, p.Code) {
+			continue
+		}
+		if p.Archived {
+			return errors.Wrapf(ErrVerificationCodeMismatch, "part %s of an archive would have the files of %x rather than %x", p.ID.String(), computed, p.Code)
+		}
+		if files == 0 {
+			continue
+		}
+
+		var taken bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM part WHERE file_verification_code=$1 AND part_id<>$2)", computed, p.ID).Scan(&taken); err != nil {
+			return errors.Wrapf(err, "error selecting part by file verification code")
+		}
+		if taken {
+			log.Warn().Str("part_id", p.ID.String()).Hex("file_verification_code", computed).Msg("another part has the files of part, its file verification code is cleared")
+			computed = nil
+		}
+
+		before, err := selectForUpdate(tx, p.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE part SET file_verification_code=$1 WHERE part_id=$2", computed, p.ID); err != nil {
+			return errors.Wrapf(err, "error updating file_verification_code of part %s", p.ID.String())
+		}
+		if err := SetVerificationCodeOne(tx, p.ID); err != nil {
+			return err
+		}
+		after := *before
+		after.FileVerificationCode = computed
+		if changes := diffCurated(*before, after); len(changes) > 0 {
+			if _, err := audit.RecordPart(tx, controller.Actor, uuid.UUID(p.ID), audit.ActionUpdate, changes); err != nil {
+				return err
+			}
 		}
 	}
 
-	vcodeTwo = vcoderTwo.Sum()
-	return vcodeTwo, nil
+	return nil
 }
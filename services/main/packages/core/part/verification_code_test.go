@@ -0,0 +1,193 @@
+package part
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// verificationFixture inserts parts and files within a transaction that is rolled back when the test is done
+type verificationFixture struct {
+	t      *testing.T
+	tx     *sqlx.Tx
+	prefix string
+}
+
+func newVerificationFixture(t *testing.T, controller PartController) verificationFixture {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+
+	return verificationFixture{t: t, tx: tx, prefix: fmt.Sprintf("test-verification-%d", time.Now().UnixNano())}
+}
+
+func (f verificationFixture) part(name string) ID {
+	var id ID
+	if err := f.tx.QueryRow("INSERT INTO part (name) VALUES ($1) RETURNING part_id", f.prefix+"/"+name).Scan(&id); err != nil {
+		f.t.Fatal(err)
+	}
+	return id
+}
+
+// file adds the file named name to the part, inserting the file the first time
+func (f verificationFixture) file(partID ID, name string) {
+	if _, err := f.tx.Exec("INSERT INTO file (sha256, sha1, label) VALUES (DIGEST($1, 'sha256'), DIGEST($1, 'sha1'), $2) ON CONFLICT DO NOTHING",
+		f.prefix+"/"+name, f.prefix); err != nil {
+		f.t.Fatal(err)
+	}
+	if _, err := f.tx.Exec("INSERT INTO part_has_file (part_id, file_sha256, path) VALUES ($1, DIGEST($2, 'sha256'), $3)", partID, f.prefix+"/"+name, name); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f verificationFixture) codes(partID ID) (fvc2 []byte, fvc1 []byte) {
+	if err := f.tx.QueryRow("SELECT file_verification_code, file_verification_code_one FROM part WHERE part_id=$1", partID).Scan(&fvc2, &fvc1); err != nil {
+		f.t.Fatal(err)
+	}
+	return fvc2, fvc1
+}
+
+func TestRefreshVerificationCodesHandBuilt(t *testing.T) {
+	controller := PartController{DB: testDB(t)}
+	f := newVerificationFixture(t, controller)
+
+	p := f.part("hand-built")
+	if err := controller.refreshVerificationCodes(f.tx, p); err != nil {
+		t.Fatal(err)
+	}
+	if fvc2, fvc1 := f.codes(p); fvc2 != nil || fvc1 != nil {
+		t.Errorf("part without files has codes %x and %x, want none", fvc2, fvc1)
+	}
+
+	f.file(p, "a")
+	f.file(p, "b")
+	if err := controller.refreshVerificationCodes(f.tx, p); err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := calculateVerificationCode(f.tx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fvc2, fvc1 := f.codes(p)
+	if !bytes.Equal(fvc2, want) {
+		t.Errorf("file_verification_code = %x, want %x", fvc2, want)
+	}
+	if len(fvc1) == 0 {
+		t.Errorf("file_verification_code_one is not set")
+	}
+}
+
+func TestRefreshVerificationCodesTaken(t *testing.T) {
+	controller := PartController{DB: testDB(t)}
+	f := newVerificationFixture(t, controller)
+
+	// first has the files a and b, second has a, then b too, the files of first
+	first, second := f.part("first"), f.part("second")
+	f.file(first, "a")
+	f.file(first, "b")
+	f.file(second, "a")
+	for _, p := range []ID{first, second} {
+		if err := controller.refreshVerificationCodes(f.tx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fvc2, _ := f.codes(second); fvc2 == nil {
+		t.Fatal("second part has no file verification code")
+	}
+
+	f.file(second, "b")
+	if err := controller.refreshVerificationCodes(f.tx, second); err != nil {
+		t.Fatal(err)
+	}
+	if fvc2, fvc1 := f.codes(second); fvc2 != nil || fvc1 != nil {
+		t.Errorf("second part has codes %x and %x with the files of the first, want them cleared", fvc2, fvc1)
+	}
+	if fvc2, _ := f.codes(first); fvc2 == nil {
+		t.Errorf("first part lost its file verification code")
+	}
+}
+
+func TestRefreshVerificationCodesArchived(t *testing.T) {
+	for _, enforce := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enforce %v", enforce), func(t *testing.T) {
+			controller := PartController{DB: testDB(t), EnforceVerificationCodes: enforce}
+			f := newVerificationFixture(t, controller)
+
+			p := f.part("archived")
+			f.file(p, "a")
+			if err := controller.refreshVerificationCodes(f.tx, p); err != nil {
+				t.Fatal(err)
+			}
+			code, _ := f.codes(p)
+			if _, err := f.tx.Exec("INSERT INTO archive (sha256, part_id) VALUES (DIGEST($1, 'sha256'), $2)", f.prefix+"/archive", p); err != nil {
+				t.Fatal(err)
+			}
+
+			f.file(p, "b")
+			err := controller.refreshVerificationCodes(f.tx, p)
+			if enforce && !errors.Is(err, ErrVerificationCodeMismatch) {
+				t.Errorf("refreshVerificationCodes() = %v, want ErrVerificationCodeMismatch", err)
+			} else if !enforce && err != nil {
+				t.Errorf("refreshVerificationCodes() = %v, want the code of the archive kept", err)
+			}
+			if fvc2, _ := f.codes(p); !bytes.Equal(fvc2, code) {
+				t.Errorf("file_verification_code = %x, want the code of the archive %x", fvc2, code)
+			}
+		})
+	}
+}
+
+func TestVerifyVerificationCodes(t *testing.T) {
+	controller := PartController{DB: testDB(t)}
+	tree := createTestTree(t, controller.DB, 2, 2)
+
+	computed, _, err := calculateVerificationCode(controller.DB, tree.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored []byte
+	if err := controller.DB.QueryRow("UPDATE part SET file_verification_code='\\x4656433200'::BYTEA || DIGEST(name, 'sha256') WHERE part_id=$1 RETURNING file_verification_code",
+		tree.Root).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, err := controller.VerifyVerificationCodes(tree.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].PartID != tree.Root || !bytes.Equal(mismatches[0].Stored, stored) || !bytes.Equal(mismatches[0].Computed, computed) {
+		t.Fatalf("VerifyVerificationCodes() = %+v, want the root stored as %x and computed as %x", mismatches, stored, computed)
+	}
+
+	var recorded []byte
+	if err := controller.DB.QueryRow("SELECT computed FROM part_verification WHERE part_id=$1", tree.Root).Scan(&recorded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(recorded, computed) {
+		t.Errorf("part_verification computed = %x, want %x", recorded, computed)
+	}
+
+	mismatches, err = verificationMismatches(controller.DB, tree.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].PartID != tree.Root {
+		t.Errorf("verificationMismatches() = %+v, want the root", mismatches)
+	}
+
+	// once the stored code is right again, nothing is mismatched
+	if _, err := controller.DB.Exec("UPDATE part SET file_verification_code=$1 WHERE part_id=$2", computed, tree.Root); err != nil {
+		t.Fatal(err)
+	}
+	if mismatches, err := controller.VerifyVerificationCodes(tree.Root); err != nil {
+		t.Fatal(err)
+	} else if len(mismatches) != 0 {
+		t.Errorf("VerifyVerificationCodes() = %+v after correcting the code, want none", mismatches)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package part
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+// Verifier re-validates the file verification codes of parts in the background, see VerifyVerificationCodes
+type Verifier struct {
+	controller PartController
+	queue      chan []ID
+}
+
+// NewVerifier creates a verifier, and starts its background job
+func NewVerifier(controller PartController) *Verifier {
+	verifier := &Verifier{
+		controller: controller,
+		queue:      make(chan []ID, 64),
+	}
+	go verifier.verifyWorker()
+
+	return verifier
+}
+
+// QueueVerify queues parts to be verified in the background.
+// With no parts given, every part is verified.
+func (verifier *Verifier) QueueVerify(partIDs ...ID) {
+	go func() {
+		verifier.queue <- partIDs
+	}()
+}
+
+func (verifier *Verifier) verifyWorker() {
+	for partIDs := range verifier.queue {
+		mismatches, err := verifier.controller.VerifyVerificationCodes(partIDs...)
+		if err != nil {
+			log.Error().Err(err).Msg("error verifying file verification codes")
+			continue
+		}
+		for _, mismatch := range mismatches {
+			log.Warn().Str("part_id", mismatch.PartID.String()).Hex("stored", mismatch.Stored).Hex("computed", mismatch.Computed).Msg("file verification code does not match the files of part")
+		}
+		log.Info().Int("parts", len(partIDs)).Int("mismatches", len(mismatches)).Msg("verified file verification codes")
+	}
+}
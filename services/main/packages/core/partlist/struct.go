@@ -12,6 +12,8 @@ package partlist
 
 import (
 	"database/sql"
+	"fmt"
+	"wrs/tk/packages/core/audit"
 	"wrs/tk/packages/core/part"
 
 	"github.com/google/uuid"
@@ -26,106 +28,158 @@ type PartList struct {
 }
 
 type PartListController struct {
-	DB *sqlx.DB
+	DB    *sqlx.DB
+	Actor string // who changes are attributed to in the history of partlists, see As
+}
+
+// As returns a copy of the controller whose changes are attributed to actor
+func (controller PartListController) As(actor string) PartListController {
+	controller.Actor = actor
+	return controller
+}
+
+// GetHistory returns the revisions of a partlist, oldest first
+func (controller PartListController) GetHistory(id int64) ([]audit.Entry, error) {
+	return audit.GetPartListHistory(controller.DB, id)
 }
 
 func (controller PartListController) AddParts(id int64, parts []*uuid.UUID) (*PartList, error) {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	changes := make([]audit.Change, 0)
 	for _, v := range parts {
 		var exists bool
-		err := controller.DB.QueryRowx("SELECT EXISTS(SELECT FROM partlist_has_part WHERE partlist_id=$1 AND part_id=$2)", id, *v).Scan(&exists)
+		err := tx.QueryRowx("SELECT EXISTS(SELECT FROM partlist_has_part WHERE partlist_id=$1 AND part_id=$2)", id, *v).Scan(&exists)
 		if err != nil && err != sql.ErrNoRows {
 			return nil, err
 		}
 		if exists {
 			continue
 		}
-		result, err := controller.DB.Exec("INSERT INTO partlist_has_part(partlist_id, part_id) VALUES ($1, $2)", id, *v)
+		result, err := tx.Exec("INSERT INTO partlist_has_part(partlist_id, part_id) VALUES ($1, $2)", id, *v)
 		if err != nil {
 			return nil, err
 		}
 		if count, _ := result.RowsAffected(); count < 1 {
 			return nil, errors.New("unable to insert part, no rows affected")
 		}
+
+		partID := v.String()
+		changes = append(changes, audit.Change{Field: audit.FieldPart, Key: partID, New: &partID})
 	}
 	var ret PartList
-	if err := controller.DB.QueryRowx("SELECT * FROM partlist WHERE id=$1", id).StructScan(&ret); err != nil {
+	if err := tx.QueryRowx("SELECT * FROM partlist WHERE id=$1", id).StructScan(&ret); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-	return &ret, nil
-}
-
-func (controller PartListController) AddPartList(name string) (*PartList, error) {
-	var ret PartList
-	result, err := controller.DB.Exec("INSERT INTO partlist(name) VALUES ($1)", name)
-	if err != nil {
+	if _, err := audit.RecordPartList(tx, controller.Actor, id, audit.ActionUpdate, changes); err != nil {
 		return nil, err
 	}
-	if count, _ := result.RowsAffected(); count < 1 {
-		return nil, errors.New("unable to insert partlist, no rows affected")
-	}
-	if err := controller.DB.QueryRowx("SELECT * FROM partlist WHERE name=$1", name).StructScan(&ret); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
-		return nil, err
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing partlist parts")
 	}
 	return &ret, nil
 }
 
+func (controller PartListController) AddPartList(name string) (*PartList, error) {
+	return controller.addPartList(name, sql.NullInt64{})
+}
+
 func (controller PartListController) AddPartListWithParent(name string, parentID int64) (*PartList, error) {
-	var ret PartList
-	result, err := controller.DB.Exec("INSERT INTO partlist(name, parent_id) VALUES ($1, $2)", name, parentID)
+	return controller.addPartList(name, sql.NullInt64{Int64: parentID, Valid: true})
+}
+
+// addPartList inserts a partlist, recording its name and parent as its first revision
+func (controller PartListController) addPartList(name string, parentID sql.NullInt64) (*PartList, error) {
+	tx, err := controller.DB.Beginx()
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "error beginning transaction")
 	}
-	if count, _ := result.RowsAffected(); count < 1 {
-		return nil, errors.New("unable to insert partlist, no rows affected")
+	defer tx.Rollback()
+
+	var ret PartList
+	if err := tx.QueryRowx("INSERT INTO partlist(name, parent_id) VALUES ($1, $2) RETURNING *", name, parentID).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "unable to insert partlist")
 	}
-	if err := controller.DB.QueryRowx("SELECT * FROM partlist WHERE name=$1", name).StructScan(&ret); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
+
+	changes := []audit.Change{{Field: "name", New: &name}}
+	if parentID.Valid {
+		parent := fmt.Sprint(parentID.Int64)
+		changes = append(changes, audit.Change{Field: "parent_id", New: &parent})
+	}
+	if _, err := audit.RecordPartList(tx, controller.Actor, ret.ID, audit.ActionCreate, changes); err != nil {
 		return nil, err
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing partlist")
+	}
 	return &ret, nil
 }
 
 func (controller PartListController) DeletePartList(id int64) (*PartList, error) {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
 	var ret PartList
-	if err := controller.DB.QueryRowx("SELECT * FROM partlist WHERE id=$1", id).StructScan(&ret); err != nil {
+	if err := tx.QueryRowx("SELECT * FROM partlist WHERE id=$1", id).StructScan(&ret); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-	result, err := controller.DB.Exec("DELETE FROM partlist WHERE id=$1", id)
+	if _, err := audit.RecordPartList(tx, controller.Actor, id, audit.ActionDelete, []audit.Change{{Field: "name", Old: &ret.Name}}); err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec("DELETE FROM partlist WHERE id=$1", id)
 	if err != nil {
 		return nil, err
 	}
 	if count, _ := result.RowsAffected(); count < 1 {
 		return nil, errors.New("unable to delete partlist, no rows affected")
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing partlist deletion")
+	}
 	return &ret, nil
 }
 
 func (controller PartListController) DeletePartFromList(list_id int64, part_id uuid.UUID) (*PartList, error) {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
 	var ret PartList
-	if err := controller.DB.QueryRowx("SELECT * FROM partlist WHERE id=$1", list_id).StructScan(&ret); err != nil {
+	if err := tx.QueryRowx("SELECT * FROM partlist WHERE id=$1", list_id).StructScan(&ret); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-	result, err := controller.DB.Exec("DELETE FROM partlist_has_part WHERE partlist_id=$1 AND part_id=$2", list_id, part_id)
+	result, err := tx.Exec("DELETE FROM partlist_has_part WHERE partlist_id=$1 AND part_id=$2", list_id, part_id)
 	if err != nil {
 		return nil, err
 	}
 	if count, _ := result.RowsAffected(); count < 1 {
 		return nil, errors.New("unable to delete partlist, no rows affected")
 	}
+	partID := part_id.String()
+	if _, err := audit.RecordPartList(tx, controller.Actor, list_id, audit.ActionUpdate, []audit.Change{{Field: audit.FieldPart, Key: partID, Old: &partID}}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing partlist part deletion")
+	}
 	return &ret, nil
 }
 
@@ -187,6 +241,18 @@ func (controller PartListController) GetByParentID(parentID int64) ([]PartList,
 	return ret, nil
 }
 
+// GetByPart returns the partlists listing the given part
+func (controller PartListController) GetByPart(partID part.ID) ([]PartList, error) {
+	ret := make([]PartList, 0)
+	if err := controller.DB.Select(&ret, `SELECT partlist.* FROM partlist
+	INNER JOIN partlist_has_part ON partlist_has_part.partlist_id=partlist.id
+	WHERE partlist_has_part.part_id=$1 ORDER BY partlist.id`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting partlists of part %s", partID.String())
+	}
+
+	return ret, nil
+}
+
 type PartListHasPart struct {
 	Partlist_id int64     `db:"partlist_id"`
 	Part_id     uuid.UUID `db:"part_id"`
@@ -0,0 +1,2 @@
+// policy contains the controller for storing license policies, and evaluating them against a part and its sub-parts, or a part list.
+package policy
@@ -0,0 +1,329 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package policy
+
+import (
+	"database/sql"
+	"strings"
+	"wrs/tk/packages/core/license"
+	"wrs/tk/packages/core/part"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Violation is a part whose license breaks a rule of the policy
+type Violation struct {
+	Rule    Rule
+	PartID  uuid.UUID
+	License string
+	// Path is the chain of part ids through part_has_part, from the checked part to the offending part
+	Path []uuid.UUID
+	// ParentID is the containing part whose license the offending part conflicts with, only set for deny_within
+	ParentID *uuid.UUID
+}
+
+// Result is the outcome of checking a policy
+type Result struct {
+	Policy     Policy
+	Violations []Violation
+}
+
+// Passed returns true if no violations were found
+func (result Result) Passed() bool {
+	return len(result.Violations) == 0
+}
+
+// partTree holds the licenses and part_has_part edges of every part reachable from the checked parts
+type partTree struct {
+	Children map[uuid.UUID][]uuid.UUID
+	Licenses map[uuid.UUID]string
+	// Aliases maps lower-cased license aliases to their internal license identifier
+	Aliases map[string]string
+
+	expressions map[uuid.UUID]*license.Expression
+}
+
+func newPartTree() *partTree {
+	return &partTree{
+		Children:    make(map[uuid.UUID][]uuid.UUID),
+		Licenses:    make(map[uuid.UUID]string),
+		Aliases:     make(map[string]string),
+		expressions: make(map[uuid.UUID]*license.Expression),
+	}
+}
+
+// expression returns the parsed license expression of a part, or nil if the part has no license.
+// A license that cannot be parsed is treated as a single identifier, so it may still be matched as a whole.
+func (tree *partTree) expression(id uuid.UUID) *license.Expression {
+	if expression, ok := tree.expressions[id]; ok {
+		return expression
+	}
+
+	var expression *license.Expression
+	if raw := strings.TrimSpace(tree.Licenses[id]); raw != "" {
+		var err error
+		if expression, err = license.ParseExpression(raw); err != nil {
+			expression = &license.Expression{Identifier: raw}
+		}
+	}
+	tree.expressions[id] = expression
+
+	return expression
+}
+
+// canonical resolves license aliases, then converts the identifier into its canonical form
+func (tree *partTree) canonical(identifier string) string {
+	if licenseID, ok := tree.Aliases[strings.ToLower(identifier)]; ok {
+		return licenseID
+	}
+
+	return license.CanonicalID(identifier)
+}
+
+// matcher returns a function reporting if a license identifier matches any of the patterns
+func (tree *partTree) matcher(patterns []string) func(identifier string) bool {
+	return func(identifier string) bool {
+		id := tree.canonical(identifier)
+		for _, v := range patterns {
+			if prefix, ok := strings.CutSuffix(v, "*"); ok {
+				if license.MatchLicense(tree.canonical(prefix)+"*", id) {
+					return true
+				}
+			} else if license.MatchLicense(tree.canonical(v), id) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// requires returns true if every way of satisfying the expression uses a license that matches
+func requires(expression *license.Expression, match func(identifier string) bool) bool {
+	if expression == nil {
+		return false
+	}
+
+	switch expression.Operator {
+	case license.OperatorOr:
+		return requires(expression.Left, match) && requires(expression.Right, match)
+	case license.OperatorAnd:
+		return requires(expression.Left, match) || requires(expression.Right, match)
+	case license.OperatorWith:
+		return requires(expression.Left, match)
+	default:
+		return match(expression.Identifier)
+	}
+}
+
+// evaluate checks every rule of the policy against the roots and everything beneath them
+func (policy Policy) evaluate(tree *partTree, roots []uuid.UUID) []Violation {
+	ret := make([]Violation, 0)
+	for _, rule := range policy.Rules {
+		ret = append(ret, rule.evaluate(tree, roots)...)
+	}
+
+	return ret
+}
+
+// evaluate walks the tree depth first, reporting each offending part once along the first path it was found by
+func (rule Rule) evaluate(tree *partTree, roots []uuid.UUID) []Violation {
+	ret := make([]Violation, 0)
+	denied := tree.matcher(rule.Licenses)
+	parents := tree.matcher(rule.ParentLicenses)
+
+	type visit struct {
+		id     uuid.UUID
+		within bool
+	}
+	visited := make(map[visit]bool)
+	reported := make(map[uuid.UUID]bool)
+
+	var walk func(id uuid.UUID, path []uuid.UUID, parent *uuid.UUID)
+	walk = func(id uuid.UUID, path []uuid.UUID, parent *uuid.UUID) {
+		key := visit{id: id, within: parent != nil}
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+
+		path = append(path[:len(path):len(path)], id)
+		expression := tree.expression(id)
+
+		violated := false
+		switch rule.Type {
+		case RuleDeny:
+			violated = requires(expression, denied)
+		case RuleDenyWithin:
+			if parent != nil {
+				violated = requires(expression, denied)
+			} else if requires(expression, parents) {
+				current := id
+				parent = &current
+			}
+		}
+
+		if violated && !reported[id] {
+			reported[id] = true
+			ret = append(ret, Violation{
+				Rule:     rule,
+				PartID:   id,
+				License:  expression.String(),
+				Path:     path,
+				ParentID: parent,
+			})
+		}
+
+		for _, child := range tree.Children[id] {
+			walk(child, path, parent)
+		}
+	}
+
+	for _, root := range roots {
+		walk(root, nil, nil)
+	}
+
+	return ret
+}
+
+// CheckPart evaluates the policy against the part and all of its sub-parts
+func (controller PolicyController) CheckPart(policy Policy, partID uuid.UUID) (*Result, error) {
+	tree, err := controller.loadTree([]uuid.UUID{partID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Policy:     policy,
+		Violations: policy.evaluate(tree, []uuid.UUID{partID}),
+	}, nil
+}
+
+// CheckPartList evaluates the policy against every part in the part list and the part lists beneath it, and all of their sub-parts
+func (controller PolicyController) CheckPartList(policy Policy, partlistID int64) (*Result, error) {
+	roots := make([]uuid.UUID, 0)
+
+	rows, err := controller.DB.Queryx(`WITH RECURSIVE lists AS (
+		SELECT id FROM partlist WHERE id=$1
+		UNION SELECT partlist.id FROM partlist INNER JOIN lists ON partlist.parent_id=lists.id
+	) SELECT DISTINCT part_id FROM partlist_has_part WHERE partlist_id IN (SELECT id FROM lists) ORDER BY part_id`, partlistID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts of partlist %d", partlistID)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tmp uuid.UUID
+		if err := rows.Scan(&tmp); err != nil {
+			return nil, errors.Wrapf(err, "error scanning parts of partlist %d", partlistID)
+		}
+
+		roots = append(roots, tmp)
+	}
+	rows.Close()
+
+	tree, err := controller.loadTree(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Policy:     policy,
+		Violations: policy.evaluate(tree, roots),
+	}, nil
+}
+
+// loadTree loads the PartGraph of each root, the license of every part found, and the license aliases
+func (controller PolicyController) loadTree(roots []uuid.UUID) (*partTree, error) {
+	tree := newPartTree()
+
+	for _, root := range roots {
+		if _, ok := tree.Children[root]; ok {
+			continue // already loaded as a sub-part of a previous root
+		}
+
+		graph, err := part.NewPartGraph(controller.DB, root)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error loading part graph of %s", root.String())
+		}
+
+		children, err := parseIDs(graph.Edges)
+		if err != nil {
+			return nil, err
+		}
+		tree.Children[root] = children
+
+		for id, node := range *graph.Graph {
+			if id == graph.ID {
+				continue // root's edges are only tracked by graph.Edges
+			}
+
+			edges := make([]string, 0, len(node.Edges))
+			for _, v := range node.Edges {
+				edges = append(edges, v.ID)
+			}
+
+			parsedID, err := uuid.Parse(id)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing part id %s", id)
+			}
+			if tree.Children[parsedID], err = parseIDs(edges); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for id := range tree.Children {
+		var partLicense sql.NullString
+		if err := controller.DB.QueryRowx("SELECT license FROM part WHERE part_id=$1", id).Scan(&partLicense); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.Wrapf(part.ErrNotFound, "%s", id.String())
+			}
+
+			return nil, errors.Wrapf(err, "error selecting license of part %s", id.String())
+		}
+
+		tree.Licenses[id] = partLicense.String
+	}
+
+	rows, err := controller.DB.Queryx("SELECT alias, license_id FROM license_alias")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting license aliases")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var alias, licenseID string
+		if err := rows.Scan(&alias, &licenseID); err != nil {
+			return nil, errors.Wrapf(err, "error scanning license alias")
+		}
+
+		tree.Aliases[strings.ToLower(alias)] = licenseID
+	}
+	rows.Close()
+
+	return tree, nil
+}
+
+func parseIDs(ids []string) ([]uuid.UUID, error) {
+	ret := make([]uuid.UUID, 0, len(ids))
+	for _, v := range ids {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing part id %s", v)
+		}
+
+		ret = append(ret, id)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRuleEvaluate(t *testing.T) {
+	product := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	library := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	gpl := uuid.MustParse("00000000-0000-0000-0000-000000000003")
+	agpl := uuid.MustParse("00000000-0000-0000-0000-000000000004")
+	dual := uuid.MustParse("00000000-0000-0000-0000-000000000005")
+
+	// product -> library -> gpl
+	// product -> agpl
+	// library -> dual
+	tree := newPartTree()
+	tree.Children[product] = []uuid.UUID{library, agpl}
+	tree.Children[library] = []uuid.UUID{gpl, dual}
+	tree.Licenses[product] = "CUSTOM[Wind River Proprietary]"
+	tree.Licenses[library] = "_mit"
+	tree.Licenses[gpl] = "GPLv3"
+	tree.Licenses[agpl] = "AGPL-3.0-or-later AND _mit"
+	tree.Licenses[dual] = "_gpl-3.0 OR _bsd-3-clause"
+	tree.Aliases["gplv3"] = "_gpl-3.0"
+
+	tests := []struct {
+		name  string
+		rule  Rule
+		roots []uuid.UUID
+		want  []Violation
+	}{
+		{
+			name:  "deny with wildcard and alias",
+			rule:  Rule{Type: RuleDeny, Licenses: []string{"GPL-3.0", "AGPL*"}},
+			roots: []uuid.UUID{product},
+			want: []Violation{
+				{PartID: gpl, License: "GPLv3", Path: []uuid.UUID{product, library, gpl}},
+				{PartID: agpl, License: "AGPL-3.0-or-later AND _mit", Path: []uuid.UUID{product, agpl}},
+			},
+		},
+		{
+			name:  "deny from a sub-part",
+			rule:  Rule{Type: RuleDeny, Licenses: []string{"_gpl-3.0"}},
+			roots: []uuid.UUID{library},
+			want: []Violation{
+				{PartID: gpl, License: "GPLv3", Path: []uuid.UUID{library, gpl}},
+			},
+		},
+		{
+			name:  "deny within proprietary parent",
+			rule:  Rule{Type: RuleDenyWithin, Licenses: []string{"_gpl*", "_agpl*"}, ParentLicenses: []string{"CUSTOM[Wind River Proprietary]"}},
+			roots: []uuid.UUID{product},
+			want: []Violation{
+				{PartID: gpl, License: "GPLv3", Path: []uuid.UUID{product, library, gpl}, ParentID: &product},
+				{PartID: agpl, License: "AGPL-3.0-or-later AND _mit", Path: []uuid.UUID{product, agpl}, ParentID: &product},
+			},
+		},
+		{
+			name:  "deny within without proprietary parent",
+			rule:  Rule{Type: RuleDenyWithin, Licenses: []string{"_gpl*"}, ParentLicenses: []string{"CUSTOM[Wind River Proprietary]"}},
+			roots: []uuid.UUID{library},
+			want:  []Violation{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.want {
+				tt.want[i].Rule = tt.rule
+			}
+
+			if got := tt.rule.evaluate(tree, tt.roots); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rule.evaluate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "deny", rule: Rule{Type: RuleDeny, Licenses: []string{"_gpl-3.0"}}},
+		{name: "deny without licenses", rule: Rule{Type: RuleDeny}, wantErr: true},
+		{name: "deny within without parents", rule: Rule{Type: RuleDenyWithin, Licenses: []string{"_gpl-3.0"}}, wantErr: true},
+		{name: "unknown type", rule: Rule{Type: "allow", Licenses: []string{"_mit"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Rule.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package policy
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Rule types a policy may contain
+const (
+	// RuleDeny flags any part whose license expression cannot be satisfied without one of Licenses
+	RuleDeny = "deny"
+	// RuleDenyWithin flags any part whose license expression requires one of Licenses,
+	// when it is contained by a part whose license expression requires one of ParentLicenses
+	RuleDenyWithin = "deny_within"
+)
+
+// Rule is a single check of a policy.
+// Licenses and ParentLicenses are license identifiers in either SPDX or internal form, or aliases.
+// An identifier ending with * matches every license starting with it, e.g. AGPL* matches _agpl-3.0 and _agpl-3.0+
+type Rule struct {
+	Type           string   `json:"type"`
+	Licenses       []string `json:"licenses"`
+	ParentLicenses []string `json:"parent_licenses,omitempty"`
+	Message        string   `json:"message,omitempty"`
+}
+
+// Validate checks that the rule has a known type and the licenses that type requires
+func (rule Rule) Validate() error {
+	switch rule.Type {
+	case RuleDeny:
+	case RuleDenyWithin:
+		if len(rule.ParentLicenses) == 0 {
+			return errors.Errorf("%s rule requires parent_licenses", rule.Type)
+		}
+	default:
+		return errors.Errorf("unknown rule type \"%s\"", rule.Type)
+	}
+
+	if len(rule.Licenses) == 0 {
+		return errors.Errorf("%s rule requires licenses", rule.Type)
+	}
+
+	return nil
+}
+
+// Rules is stored as a JSONB array
+type Rules []Rule
+
+// Scan implements database/sql.scanner interface
+func (rules *Rules) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*rules = make(Rules, 0)
+		return nil
+	case []byte:
+		return json.Unmarshal(v, rules)
+	case string:
+		return json.Unmarshal([]byte(v), rules)
+	default:
+		return errors.Errorf("failed to scan rules from %T", value)
+	}
+}
+
+// Value implements database/sql/driver.Valuer interface
+func (rules Rules) Value() (driver.Value, error) {
+	if rules == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(rules)
+}
+
+// Policy is a named set of license rules that parts are checked against
+type Policy struct {
+	ID          int64          `json:"id" db:"id"`
+	Name        string         `json:"name" db:"name"`
+	Description sql.NullString `json:"description" db:"description"`
+	Rules       Rules          `json:"rules" db:"rules"`
+	InsertDate  time.Time      `json:"insert_date" db:"insert_date"`
+}
+
+type PolicyController struct {
+	DB *sqlx.DB
+}
+
+// GetByID returns the policy with the given id
+func (controller PolicyController) GetByID(id int64) (*Policy, error) {
+	var ret Policy
+	if err := controller.DB.QueryRowx("SELECT * FROM license_policy WHERE id=$1", id).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting policy %d", id)
+	}
+
+	return &ret, nil
+}
+
+// GetByName returns the policy with the given name
+func (controller PolicyController) GetByName(name string) (*Policy, error) {
+	var ret Policy
+	if err := controller.DB.QueryRowx("SELECT * FROM license_policy WHERE name=$1", name).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting policy \"%s\"", name)
+	}
+
+	return &ret, nil
+}
+
+// List returns every policy ordered by name
+func (controller PolicyController) List() ([]Policy, error) {
+	ret := make([]Policy, 0)
+
+	rows, err := controller.DB.Queryx("SELECT * FROM license_policy ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting policies")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Policy
+		if err := rows.StructScan(&p); err != nil {
+			return nil, errors.Wrapf(err, "error scanning policy")
+		}
+
+		ret = append(ret, p)
+	}
+	rows.Close()
+
+	return ret, nil
+}
+
+// CreatePolicy validates and stores a new policy
+func (controller PolicyController) CreatePolicy(name string, description string, rules Rules) (*Policy, error) {
+	if name == "" {
+		return nil, errors.New("policy requires a name")
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("policy requires at least one rule")
+	}
+	for i, v := range rules {
+		if err := v.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid rule %d", i)
+		}
+	}
+
+	var ret Policy
+	if err := controller.DB.QueryRowx("INSERT INTO license_policy (name, description, rules) VALUES ($1, $2, $3) RETURNING *",
+		name, sql.NullString{Valid: description != "", String: description}, rules).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "error inserting policy \"%s\"", name)
+	}
+
+	return &ret, nil
+}
+
+// DeletePolicy deletes the policy with the given id, returning what was deleted
+func (controller PolicyController) DeletePolicy(id int64) (*Policy, error) {
+	var ret Policy
+	if err := controller.DB.QueryRowx("DELETE FROM license_policy WHERE id=$1 RETURNING *", id).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error deleting policy %d", id)
+	}
+
+	return &ret, nil
+}
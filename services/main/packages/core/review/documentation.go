@@ -0,0 +1,3 @@
+// review keeps the review state of parts, unreviewed, in_review, approved, or rejected, with who requested and gave the review, when, and every transition of it.
+// Approval is reset when the license of an approved part changes, in the same transaction as the change.
+package review
@@ -0,0 +1,242 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package review
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"wrs/tk/packages/core/audit"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// States of reviews
+const (
+	StateUnreviewed = "unreviewed"
+	StateInReview   = "in_review"
+	StateApproved   = "approved"
+	StateRejected   = "rejected"
+)
+
+// Review is the review state of a part, parts never reviewed are unreviewed
+type Review struct {
+	PartID      uuid.UUID      `db:"part_id"`
+	State       string         `db:"state"`
+	RequestedBy sql.NullString `db:"requested_by"` // who last requested the review of the part
+	RequestDate sql.NullTime   `db:"request_date"`
+	Reviewer    sql.NullString `db:"reviewer"` // who last approved or rejected the part
+	ReviewDate  sql.NullTime   `db:"review_date"`
+	Comment     sql.NullString `db:"comment"` // comment of the last transition
+	UpdateDate  sql.NullTime   `db:"update_date"`
+}
+
+// Event is a transition of the review state of a part
+type Event struct {
+	ID         int64          `db:"id"`
+	PartID     uuid.UUID      `db:"part_id"`
+	State      string         `db:"state"`
+	Actor      string         `db:"actor"`
+	Comment    sql.NullString `db:"comment"`
+	InsertDate time.Time      `db:"insert_date"`
+}
+
+type ReviewController struct {
+	DB    *sqlx.DB
+	Actor string // who transitions are attributed to, see As
+}
+
+// As returns a copy of the controller whose transitions are attributed to actor
+func (controller ReviewController) As(actor string) ReviewController {
+	controller.Actor = actor
+	return controller
+}
+
+// Get returns the review of a part
+func (controller ReviewController) Get(partID uuid.UUID) (*Review, error) {
+	ret := Review{PartID: partID, State: StateUnreviewed}
+	if err := controller.DB.QueryRowx("SELECT * FROM part_review WHERE part_id=$1", partID).StructScan(&ret); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "error selecting review of part %s", partID.String())
+	}
+
+	return &ret, nil
+}
+
+// GetEvents returns the transitions of the review of a part, oldest first
+func (controller ReviewController) GetEvents(partID uuid.UUID) ([]Event, error) {
+	ret := make([]Event, 0)
+	if err := controller.DB.Select(&ret, "SELECT * FROM part_review_event WHERE part_id=$1 ORDER BY id", partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting review events of part %s", partID.String())
+	}
+
+	return ret, nil
+}
+
+// RequestReview puts a part in review, unless it already is
+func (controller ReviewController) RequestReview(partID uuid.UUID, comment *string) (*Review, error) {
+	return controller.transition(partID, StateInReview, comment)
+}
+
+// Approve approves a part in review
+func (controller ReviewController) Approve(partID uuid.UUID, comment *string) (*Review, error) {
+	return controller.transition(partID, StateApproved, comment)
+}
+
+// Reject rejects a part in review, a comment giving the reason is required
+func (controller ReviewController) Reject(partID uuid.UUID, comment *string) (*Review, error) {
+	if comment == nil || *comment == "" {
+		return nil, ErrCommentRequired
+	}
+
+	return controller.transition(partID, StateRejected, comment)
+}
+
+// Queue returns the reviews of parts in a state, in review by default, oldest request first.
+// If partlistID is given, only the parts listed by the partlist and the partlists beneath it are returned.
+func (controller ReviewController) Queue(partlistID *int64, state string) ([]Review, error) {
+	if state == "" {
+		state = StateInReview
+	}
+	if !isState(state) {
+		return nil, errors.Errorf("unknown review state %s", state)
+	}
+
+	query := `SELECT p.part_id, COALESCE(r.state, 'unreviewed') AS state, r.requested_by, r.request_date, r.reviewer, r.review_date, r.comment, r.update_date
+	FROM part p LEFT JOIN part_review r ON r.part_id=p.part_id WHERE COALESCE(r.state, 'unreviewed')=$1`
+	args := []any{state}
+	if partlistID != nil {
+		query = `WITH RECURSIVE lists AS (
+			SELECT id FROM partlist WHERE id=$2
+			UNION SELECT partlist.id FROM partlist INNER JOIN lists ON partlist.parent_id=lists.id
+		) ` + query + ` AND p.part_id IN (SELECT part_id FROM partlist_has_part WHERE partlist_id IN (SELECT id FROM lists))`
+		args = append(args, *partlistID)
+	}
+
+	ret := make([]Review, 0)
+	if err := controller.DB.Select(&ret, query+" ORDER BY r.request_date NULLS LAST, p.part_id", args...); err != nil {
+		return nil, errors.Wrapf(err, "error selecting %s review queue", state)
+	}
+
+	return ret, nil
+}
+
+// ResetApproval returns an approved part to unreviewed if its license is among the changes, in the transaction making them
+func ResetApproval(tx *sqlx.Tx, actor string, partID uuid.UUID, changes []audit.Change) error {
+	for _, change := range changes {
+		if change.Field != "license" {
+			continue
+		}
+
+		comment := fmt.Sprintf("license changed from %s to %s", orNone(change.Old), orNone(change.New))
+		res, err := tx.Exec(`UPDATE part_review SET state=$2, comment=$3, update_date=NOW() WHERE part_id=$1 AND state=$4`,
+			partID, StateUnreviewed, comment, StateApproved)
+		if err != nil {
+			return errors.Wrapf(err, "error resetting review of part %s", partID.String())
+		}
+		if count, _ := res.RowsAffected(); count > 0 {
+			return insertEvent(tx, actor, partID, StateUnreviewed, &comment)
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// transition moves the review of a part to a state, recording who did it
+func (controller ReviewController) transition(partID uuid.UUID, state string, comment *string) (*Review, error) {
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT part_id FROM part WHERE part_id=$1 FOR UPDATE", partID).Scan(&partID); err == sql.ErrNoRows {
+		return nil, errors.Wrapf(ErrPartNotFound, "%s", partID.String())
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "error locking part %s", partID.String())
+	}
+
+	current := Review{PartID: partID, State: StateUnreviewed}
+	if err := tx.QueryRowx("SELECT * FROM part_review WHERE part_id=$1", partID).StructScan(&current); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "error selecting review of part %s", partID.String())
+	}
+	if err := checkTransition(current.State, state); err != nil {
+		return nil, err
+	}
+
+	actor := controller.Actor
+	if actor == "" {
+		actor = audit.System
+	}
+
+	// parts never reviewed are inserted, which only requesting their review can do
+	set := "requested_by=EXCLUDED.requested_by, request_date=EXCLUDED.request_date"
+	if state != StateInReview {
+		set = "reviewer=$3, review_date=NOW()"
+	}
+
+	var ret Review
+	if err := tx.QueryRowx(`INSERT INTO part_review (part_id, state, requested_by, request_date, comment) VALUES ($1, $2, $3, NOW(), $4)
+	ON CONFLICT (part_id) DO UPDATE SET state=EXCLUDED.state, `+set+`, comment=EXCLUDED.comment, update_date=NOW()
+	RETURNING *`,
+		partID, state, actor, comment).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "error updating review of part %s", partID.String())
+	}
+
+	if err := insertEvent(tx, actor, partID, state, comment); err != nil {
+		return nil, err
+	}
+
+	return &ret, errors.Wrapf(tx.Commit(), "error committing review of part %s", partID.String())
+}
+
+// checkTransition returns ErrInvalidTransition unless a review may move from one state to another.
+// Any part not in review may be put in review, and only parts in review may be approved or rejected.
+func checkTransition(from string, to string) error {
+	switch {
+	case to == StateInReview && from != StateInReview:
+	case (to == StateApproved || to == StateRejected) && from == StateInReview:
+	default:
+		return errors.Wrapf(ErrInvalidTransition, "%s to %s", from, to)
+	}
+
+	return nil
+}
+
+func insertEvent(tx *sqlx.Tx, actor string, partID uuid.UUID, state string, comment *string) error {
+	if _, err := tx.Exec("INSERT INTO part_review_event (part_id, state, actor, comment) VALUES ($1, $2, $3, $4)",
+		partID, state, actor, comment); err != nil {
+		return errors.Wrapf(err, "error inserting review event of part %s", partID.String())
+	}
+
+	return nil
+}
+
+func isState(state string) bool {
+	switch state {
+	case StateUnreviewed, StateInReview, StateApproved, StateRejected:
+		return true
+	}
+
+	return false
+}
+
+func orNone(s *string) string {
+	if s == nil {
+		return "none"
+	}
+
+	return *s
+}
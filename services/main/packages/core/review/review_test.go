@@ -0,0 +1,34 @@
+package review
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from  string
+		to    string
+		valid bool
+	}{
+		{StateUnreviewed, StateInReview, true},
+		{StateApproved, StateInReview, true},
+		{StateRejected, StateInReview, true},
+		{StateInReview, StateInReview, false},
+		{StateInReview, StateApproved, true},
+		{StateInReview, StateRejected, true},
+		{StateUnreviewed, StateApproved, false},
+		{StateRejected, StateApproved, false},
+		{StateApproved, StateRejected, false},
+		{StateInReview, StateUnreviewed, false},
+	}
+
+	for _, test := range tests {
+		err := checkTransition(test.from, test.to)
+		if test.valid && err != nil {
+			t.Errorf("%s to %s: unexpected error %v", test.from, test.to, err)
+		} else if !test.valid && !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("%s to %s: expected ErrInvalidTransition, got %v", test.from, test.to, err)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// similarity finds parts with similar sets of files, such as vendored or lightly patched copies of upstream code, which never share a verification code.
+// Parts are given MinHash signatures of the sha256 set of their files and sub-parts' files at ingestion, and candidates are found by locality-sensitive hashing of signature bands.
+package similarity
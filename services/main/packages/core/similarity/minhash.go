@@ -0,0 +1,145 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package similarity
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Signature sizes, a signature of numHashes minimums is split into numBands bands of bandRows rows.
+// Parts sharing a band are candidates, which finds about half the parts with a Jaccard similarity of 0.1, and nearly every part above 0.3.
+const (
+	numHashes = 128
+	bandRows  = 2
+	numBands  = numHashes / bandRows
+)
+
+// seeds of the hash functions, generated once from a fixed seed so signatures stay comparable
+var seeds = func() []uint64 {
+	ret := make([]uint64, numHashes)
+	state := uint64(0x5350432d4d696e48) // "SPC-MinH"
+	for i := range ret {
+		state += 0x9e3779b97f4a7c15
+		ret[i] = mix(state)
+	}
+
+	return ret
+}()
+
+// mix is the splitmix64 finalizer
+func mix(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Signature is the MinHash signature of a set
+type Signature []uint64
+
+// NewSignature returns the signature of an empty set, which any set's signature is merged into
+func NewSignature() Signature {
+	ret := make(Signature, numHashes)
+	for i := range ret {
+		ret[i] = math.MaxUint64
+	}
+
+	return ret
+}
+
+// Add adds the sha256 of a file to the set
+func (s Signature) Add(sha256 []byte) {
+	if len(sha256) < 8 {
+		return
+	}
+
+	x := binary.LittleEndian.Uint64(sha256)
+	for i, seed := range seeds {
+		if h := mix(x ^ seed); h < s[i] {
+			s[i] = h
+		}
+	}
+}
+
+// Merge merges the signature of another set into the signature of this set, making it the signature of their union
+func (s Signature) Merge(other Signature) {
+	for i := range s {
+		if i < len(other) && other[i] < s[i] {
+			s[i] = other[i]
+		}
+	}
+}
+
+// IsEmpty returns whether the signature is of an empty set
+func (s Signature) IsEmpty() bool {
+	for _, v := range s {
+		if v != math.MaxUint64 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Jaccard estimates the Jaccard similarity of the sets of two signatures, the share of their union both have
+func (s Signature) Jaccard(other Signature) float64 {
+	if len(s) != len(other) || len(s) == 0 {
+		return 0
+	}
+
+	same := 0
+	for i := range s {
+		if s[i] == other[i] {
+			same++
+		}
+	}
+
+	return float64(same) / float64(len(s))
+}
+
+// Bands returns the hash of each band of the signature
+func (s Signature) Bands() []int64 {
+	ret := make([]int64, 0, numBands)
+	for band := 0; band+bandRows <= len(s); band += bandRows {
+		h := uint64(band)
+		for _, v := range s[band : band+bandRows] {
+			h = mix(h ^ v)
+		}
+		ret = append(ret, int64(h))
+	}
+
+	return ret
+}
+
+// Bytes encodes the signature to be stored, as big-endian 64 bit integers
+func (s Signature) Bytes() []byte {
+	ret := make([]byte, 8*len(s))
+	for i, v := range s {
+		binary.BigEndian.PutUint64(ret[8*i:], v)
+	}
+
+	return ret
+}
+
+// ParseSignature decodes a stored signature
+func ParseSignature(b []byte) Signature {
+	ret := make(Signature, len(b)/8)
+	for i := range ret {
+		ret[i] = binary.BigEndian.Uint64(b[8*i:])
+	}
+
+	return ret
+}
+
+// estimateShared estimates the size of the intersection of two sets from their Jaccard similarity and sizes
+func estimateShared(jaccard float64, a int64, b int64) float64 {
+	return jaccard / (1 + jaccard) * float64(a+b)
+}
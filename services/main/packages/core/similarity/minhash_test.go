@@ -0,0 +1,50 @@
+package similarity
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	file := func(i int) []byte {
+		sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+		return sum[:]
+	}
+	signature := func(from int, to int) Signature {
+		ret := NewSignature()
+		for i := from; i < to; i++ {
+			ret.Add(file(i))
+		}
+		return ret
+	}
+
+	// 600 of 1000 files in common
+	a, b := signature(0, 800), signature(200, 1000)
+	if jaccard := a.Jaccard(b); math.Abs(jaccard-0.6) > 0.15 {
+		t.Errorf("expected a Jaccard similarity near 0.6, got %f", jaccard)
+	}
+	if shared := estimateShared(a.Jaccard(b), 800, 800); math.Abs(shared-600) > 100 {
+		t.Errorf("expected near 600 shared files, got %f", shared)
+	}
+	if jaccard := a.Jaccard(signature(1000, 2000)); jaccard > 0.1 {
+		t.Errorf("expected disjoint sets to be dissimilar, got %f", jaccard)
+	}
+
+	union := signature(0, 400)
+	union.Merge(signature(400, 800))
+	if !reflect.DeepEqual(union, a) {
+		t.Errorf("expected merged signatures to be the signature of the union")
+	}
+	if !reflect.DeepEqual(ParseSignature(a.Bytes()), a) {
+		t.Errorf("expected signature to survive encoding")
+	}
+	if !NewSignature().IsEmpty() || a.IsEmpty() {
+		t.Errorf("expected only the signature of the empty set to be empty")
+	}
+	if len(a.Bands()) != numBands {
+		t.Errorf("expected %d bands, got %d", numBands, len(a.Bands()))
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package similarity
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Defaults of Similar
+const (
+	DefaultMinSimilarity = 0.5
+	DefaultLimit         = 20
+)
+
+// estimateSlack is how far below the minimum similarity an estimate may be for the exact similarity to be computed
+const estimateSlack = 0.15
+
+// Match is a part similar to the part compared, by the files of the parts and their sub-parts
+type Match struct {
+	PartID             uuid.UUID
+	Jaccard            float64 // share of the files of both parts that they have in common
+	Containment        float64 // share of the files of the part compared that the match has
+	ReverseContainment float64 // share of the files of the match that the part compared has
+	Shared             int64   // files both parts have
+	Unique             int64   // files only the part compared has
+	MatchUnique        int64   // files only the match has
+}
+
+// Score is the highest similarity of the match, either way
+func (m Match) Score() float64 {
+	ret := m.Jaccard
+	if m.Containment > ret {
+		ret = m.Containment
+	}
+	if m.ReverseContainment > ret {
+		ret = m.ReverseContainment
+	}
+
+	return ret
+}
+
+type SimilarityController struct {
+	DB *sqlx.DB
+}
+
+// Index computes the signatures of a part and its sub-parts, and updates the signatures of the parts containing it
+func (controller SimilarityController) Index(partID uuid.UUID) error {
+	edges := make([]struct {
+		Parent uuid.UUID `db:"parent_id"`
+		Child  uuid.UUID `db:"child_id"`
+	}, 0)
+	if err := controller.DB.Select(&edges, `WITH RECURSIVE tree(part_id) AS (
+		SELECT $1::UUID
+		UNION SELECT php.child_id FROM part_has_part php INNER JOIN tree ON php.parent_id=tree.part_id
+	) SELECT DISTINCT parent_id, child_id FROM part_has_part WHERE parent_id IN (SELECT part_id FROM tree)`, partID); err != nil {
+		return errors.Wrapf(err, "error selecting sub-parts of part %s", partID.String())
+	}
+
+	children := make(map[uuid.UUID][]uuid.UUID)
+	for _, e := range edges {
+		children[e.Parent] = append(children[e.Parent], e.Child)
+	}
+
+	// sub-parts are composed before the parts containing them
+	visited := make(map[uuid.UUID]bool)
+	var visit func(id uuid.UUID) error
+	visit = func(id uuid.UUID) error {
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		for _, child := range children[id] {
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+
+		return controller.compose(id)
+	}
+	if err := visit(partID); err != nil {
+		return err
+	}
+
+	return controller.updateAncestors(partID)
+}
+
+// Update recomputes the signatures of a part and the parts containing it, after its files or sub-parts changed
+func (controller SimilarityController) Update(partID uuid.UUID) error {
+	if err := controller.compose(partID); err != nil {
+		return err
+	}
+
+	return controller.updateAncestors(partID)
+}
+
+// IndexAll computes the signatures of every part, returning the count of parts indexed from
+func (controller SimilarityController) IndexAll() (int, error) {
+	roots := make([]uuid.UUID, 0)
+	if err := controller.DB.Select(&roots, "SELECT part_id FROM part WHERE part_id NOT IN (SELECT child_id FROM part_has_part)"); err != nil {
+		return 0, errors.Wrapf(err, "error selecting parts")
+	}
+
+	for i, id := range roots {
+		if err := controller.Index(id); err != nil {
+			return i, err
+		}
+	}
+
+	return len(roots), nil
+}
+
+// updateAncestors recomputes the signatures of the parts containing a part, each after every part beneath it
+func (controller SimilarityController) updateAncestors(partID uuid.UUID) error {
+	ancestors := make([]uuid.UUID, 0)
+	if err := controller.DB.Select(&ancestors, `WITH RECURSIVE up(part_id, depth) AS (
+		SELECT $1::UUID, 0
+		UNION SELECT php.parent_id, up.depth+1 FROM part_has_part php INNER JOIN up ON php.child_id=up.part_id WHERE up.depth < 100
+	) SELECT part_id FROM up WHERE depth > 0 GROUP BY part_id ORDER BY MAX(depth)`, partID); err != nil {
+		return errors.Wrapf(err, "error selecting parts containing part %s", partID.String())
+	}
+
+	for _, id := range ancestors {
+		if err := controller.compose(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compose computes the signature of a part from its files and the stored signatures of its sub-parts
+func (controller SimilarityController) compose(partID uuid.UUID) error {
+	signature := NewSignature()
+
+	files := make([][]byte, 0)
+	if err := controller.DB.Select(&files, "SELECT file_sha256 FROM part_has_file WHERE part_id=$1", partID); err != nil {
+		return errors.Wrapf(err, "error selecting files of part %s", partID.String())
+	}
+	for _, sha256 := range files {
+		signature.Add(sha256)
+	}
+
+	subParts := make([][]byte, 0)
+	if err := controller.DB.Select(&subParts, `SELECT signature FROM part_minhash
+	WHERE part_id IN (SELECT child_id FROM part_has_part WHERE parent_id=$1)`, partID); err != nil {
+		return errors.Wrapf(err, "error selecting signatures of sub-parts of part %s", partID.String())
+	}
+	for _, b := range subParts {
+		signature.Merge(ParseSignature(b))
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM part_minhash_band WHERE part_id=$1", partID); err != nil {
+		return errors.Wrapf(err, "error deleting signature bands of part %s", partID.String())
+	}
+
+	if signature.IsEmpty() {
+		if _, err := tx.Exec("DELETE FROM part_minhash WHERE part_id=$1", partID); err != nil {
+			return errors.Wrapf(err, "error deleting signature of part %s", partID.String())
+		}
+
+		return errors.Wrapf(tx.Commit(), "error committing signature of part %s", partID.String())
+	}
+
+	if _, err := tx.Exec(`INSERT INTO part_minhash (part_id, signature, file_count)
+	SELECT $1, $2, COUNT(DISTINCT file_sha256) FROM part_has_file WHERE part_id IN (
+		WITH RECURSIVE tree(part_id) AS (
+			SELECT $1::UUID
+			UNION SELECT php.child_id FROM part_has_part php INNER JOIN tree ON php.parent_id=tree.part_id
+		) SELECT part_id FROM tree
+	)
+	ON CONFLICT (part_id) DO UPDATE SET signature=EXCLUDED.signature, file_count=EXCLUDED.file_count, update_date=NOW()`,
+		partID, signature.Bytes()); err != nil {
+		return errors.Wrapf(err, "error upserting signature of part %s", partID.String())
+	}
+
+	for band, hash := range signature.Bands() {
+		if _, err := tx.Exec("INSERT INTO part_minhash_band (part_id, band, hash) VALUES ($1, $2, $3)", partID, band, hash); err != nil {
+			return errors.Wrapf(err, "error inserting signature band of part %s", partID.String())
+		}
+	}
+
+	return errors.Wrapf(tx.Commit(), "error committing signature of part %s", partID.String())
+}
+
+// Similar returns the parts with files most similar to those of a part, other than its sub-parts and the parts containing it, most similar first.
+// Matches have a Jaccard similarity or containment either way of at least minSimilarity.
+// Candidates are the parts sharing a band of the part's signature, so parts with few files in common may be missed.
+func (controller SimilarityController) Similar(partID uuid.UUID, minSimilarity float64, limit int) ([]Match, error) {
+	if minSimilarity <= 0 {
+		minSimilarity = DefaultMinSimilarity
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	var stored struct {
+		Signature []byte `db:"signature"`
+		FileCount int64  `db:"file_count"`
+	}
+	query := "SELECT signature, file_count FROM part_minhash WHERE part_id=$1"
+	if err := controller.DB.Get(&stored, query, partID); err == sql.ErrNoRows {
+		// parts created before ingestion computed signatures
+		if err := controller.Index(partID); err != nil {
+			return nil, err
+		}
+		if err := controller.DB.Get(&stored, query, partID); err == sql.ErrNoRows {
+			return []Match{}, nil // the part has no files
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "error selecting signature of part %s", partID.String())
+		}
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "error selecting signature of part %s", partID.String())
+	}
+	signature := ParseSignature(stored.Signature)
+
+	candidates := make([]struct {
+		PartID    uuid.UUID `db:"part_id"`
+		Signature []byte    `db:"signature"`
+		FileCount int64     `db:"file_count"`
+	}, 0)
+	if err := controller.DB.Select(&candidates, `WITH RECURSIVE down(part_id) AS (
+		SELECT $1::UUID
+		UNION SELECT php.child_id FROM part_has_part php INNER JOIN down ON php.parent_id=down.part_id
+	), up(part_id) AS (
+		SELECT $1::UUID
+		UNION SELECT php.parent_id FROM part_has_part php INNER JOIN up ON php.child_id=up.part_id
+	) SELECT m.part_id, m.signature, m.file_count FROM part_minhash m WHERE m.part_id IN (
+		SELECT b2.part_id FROM part_minhash_band b1
+		INNER JOIN part_minhash_band b2 ON b2.band=b1.band AND b2.hash=b1.hash
+		WHERE b1.part_id=$1
+	) AND m.part_id NOT IN (SELECT part_id FROM down) AND m.part_id NOT IN (SELECT part_id FROM up)`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts similar to part %s", partID.String())
+	}
+
+	type estimate struct {
+		partID uuid.UUID
+		score  float64
+	}
+	estimates := make([]estimate, 0, len(candidates))
+	for _, c := range candidates {
+		jaccard := signature.Jaccard(ParseSignature(c.Signature))
+		shared := estimateShared(jaccard, stored.FileCount, c.FileCount)
+		score := jaccard
+		for _, count := range []int64{stored.FileCount, c.FileCount} {
+			if count > 0 && shared/float64(count) > score {
+				score = shared / float64(count)
+			}
+		}
+		if score >= minSimilarity-estimateSlack {
+			estimates = append(estimates, estimate{c.PartID, score})
+		}
+	}
+	sort.Slice(estimates, func(i, j int) bool { return estimates[i].score > estimates[j].score })
+
+	ret := make([]Match, 0)
+	for i := 0; i < len(estimates) && len(ret) < limit && i < 4*limit; i++ {
+		match, err := controller.compare(partID, estimates[i].partID)
+		if err != nil {
+			return nil, err
+		}
+		if match.Score() >= minSimilarity {
+			ret = append(ret, *match)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Score() > ret[j].Score() })
+
+	return ret, nil
+}
+
+// compare counts the files two parts and their sub-parts have in common, and only one of them has
+func (controller SimilarityController) compare(partID uuid.UUID, otherID uuid.UUID) (*Match, error) {
+	var counts struct {
+		A      int64 `db:"a"`
+		B      int64 `db:"b"`
+		Shared int64 `db:"shared"`
+	}
+	if err := controller.DB.Get(&counts, `WITH RECURSIVE a_tree(part_id) AS (
+		SELECT $1::UUID
+		UNION SELECT php.child_id FROM part_has_part php INNER JOIN a_tree ON php.parent_id=a_tree.part_id
+	), b_tree(part_id) AS (
+		SELECT $2::UUID
+		UNION SELECT php.child_id FROM part_has_part php INNER JOIN b_tree ON php.parent_id=b_tree.part_id
+	),
+	a_files AS (SELECT DISTINCT file_sha256 FROM part_has_file WHERE part_id IN (SELECT part_id FROM a_tree)),
+	b_files AS (SELECT DISTINCT file_sha256 FROM part_has_file WHERE part_id IN (SELECT part_id FROM b_tree))
+	SELECT (SELECT COUNT(*) FROM a_files) AS a, (SELECT COUNT(*) FROM b_files) AS b,
+	(SELECT COUNT(*) FROM a_files INNER JOIN b_files USING (file_sha256)) AS shared`, partID, otherID); err != nil {
+		return nil, errors.Wrapf(err, "error comparing files of parts %s and %s", partID.String(), otherID.String())
+	}
+
+	ret := Match{PartID: otherID, Shared: counts.Shared, Unique: counts.A - counts.Shared, MatchUnique: counts.B - counts.Shared}
+	if union := counts.A + counts.B - counts.Shared; union > 0 {
+		ret.Jaccard = float64(counts.Shared) / float64(union)
+	}
+	if counts.A > 0 {
+		ret.Containment = float64(counts.Shared) / float64(counts.A)
+	}
+	if counts.B > 0 {
+		ret.ReverseContainment = float64(counts.Shared) / float64(counts.B)
+	}
+
+	return &ret, nil
+}
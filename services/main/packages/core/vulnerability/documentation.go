@@ -0,0 +1,3 @@
+// vulnerability contains the controller for importing OSV vulnerability records, and matching them to parts.
+// Records are matched to parts by the purls of the parts, or by package name for parts without one, and affected version ranges, and by the sha256 of archives and files when an advisory lists them.
+package vulnerability
@@ -0,0 +1,263 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package vulnerability
+
+import (
+	"database/sql"
+	"encoding/json"
+	"wrs/tk/packages/core/part"
+	pkgid "wrs/tk/packages/identifier"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type match struct {
+	vulnerabilityID string
+	match           string
+	detail          string
+}
+
+// QueueMatch queues parts and their sub-parts to be re-matched in the background.
+// With no parts given, every part is re-matched.
+func (controller *VulnerabilityController) QueueMatch(partIDs ...uuid.UUID) {
+	go func() {
+		controller.queue <- partIDs
+	}()
+}
+
+func (controller *VulnerabilityController) matchWorker() {
+	for partIDs := range controller.queue {
+		var err error
+		if len(partIDs) == 0 {
+			err = controller.MatchAll()
+		} else {
+			err = controller.MatchTree(partIDs...)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("error matching vulnerabilities to parts")
+		}
+	}
+}
+
+// MatchAll re-matches every part
+func (controller *VulnerabilityController) MatchAll() error {
+	partIDs := make([]uuid.UUID, 0)
+	if err := controller.DB.Select(&partIDs, "SELECT part_id FROM part"); err != nil {
+		return errors.Wrapf(err, "error selecting parts")
+	}
+
+	for _, partID := range partIDs {
+		if err := controller.MatchPart(part.ID(partID)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("parts", len(partIDs)).Msg("matched vulnerabilities to all parts")
+
+	return nil
+}
+
+// MatchTree re-matches the given parts and all of their sub-parts
+func (controller *VulnerabilityController) MatchTree(partIDs ...uuid.UUID) error {
+	seen := make(map[uuid.UUID]bool)
+	for _, root := range partIDs {
+		tree := make([]uuid.UUID, 0)
+		if err := controller.DB.Select(&tree, `WITH RECURSIVE tree(part_id) AS (
+			SELECT $1::UUID
+			UNION
+			SELECT php.child_id FROM part_has_part php INNER JOIN tree ON tree.part_id=php.parent_id
+		) SELECT part_id FROM tree`, root); err != nil {
+			return errors.Wrapf(err, "error selecting sub-parts of %s", root)
+		}
+
+		for _, partID := range tree {
+			if seen[partID] {
+				continue
+			}
+			seen[partID] = true
+
+			if err := controller.MatchPart(part.ID(partID)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// MatchPart replaces the vulnerabilities matched to a part.
+// A part matches a vulnerability when its archive or one of its files has a hash listed by the vulnerability,
+// or when one of its purls names an affected package, by the purl or the ecosystem and name of the package, and its version is affected.
+// Parts without a purl match affected packages by name alone.
+// Withdrawn vulnerabilities are never matched.
+func (controller *VulnerabilityController) MatchPart(partID part.ID) error {
+	var p part.Part
+	if err := controller.DB.QueryRowx("SELECT * FROM part WHERE part_id=$1", partID).StructScan(&p); err != nil {
+		if err == sql.ErrNoRows {
+			return part.ErrNotFound
+		}
+
+		return errors.Wrapf(err, "error selecting part %s", partID)
+	}
+
+	matches := make([]match, 0)
+	found := make(map[string]bool)
+	add := func(m match) {
+		if !found[m.vulnerabilityID] {
+			found[m.vulnerabilityID] = true
+			matches = append(matches, m)
+		}
+	}
+
+	// Archive hashes
+	rows, err := controller.DB.Queryx(`SELECT DISTINCT vh.vulnerability_id, encode(a.sha256, 'hex') FROM vulnerability_hash vh 
+	INNER JOIN archive a ON a.sha256=vh.sha256 
+	INNER JOIN vulnerability v ON v.vulnerability_id=vh.vulnerability_id 
+	WHERE a.part_id=$1 AND v.withdrawn IS NULL`, partID)
+	if err != nil {
+		return errors.Wrapf(err, "error selecting archive hash matches of %s", partID)
+	}
+	for rows.Next() {
+		var id, sha256 string
+		if err := rows.Scan(&id, &sha256); err != nil {
+			rows.Close()
+			return errors.Wrapf(err, "error scanning archive hash matches of %s", partID)
+		}
+		add(match{vulnerabilityID: id, match: MatchArchive, detail: "archive sha256 " + sha256})
+	}
+	rows.Close()
+
+	// File hashes
+	rows, err = controller.DB.Queryx(`SELECT DISTINCT ON (vh.vulnerability_id) vh.vulnerability_id, phf.path FROM vulnerability_hash vh 
+	INNER JOIN part_has_file phf ON phf.file_sha256=vh.sha256 
+	INNER JOIN vulnerability v ON v.vulnerability_id=vh.vulnerability_id 
+	WHERE phf.part_id=$1 AND v.withdrawn IS NULL 
+	ORDER BY vh.vulnerability_id, phf.path`, partID)
+	if err != nil {
+		return errors.Wrapf(err, "error selecting file hash matches of %s", partID)
+	}
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			rows.Close()
+			return errors.Wrapf(err, "error scanning file hash matches of %s", partID)
+		}
+		add(match{vulnerabilityID: id, match: MatchFile, detail: "file " + path})
+	}
+	rows.Close()
+
+	// Package purls and versions
+	purls := make([]struct {
+		Value   string         `db:"value"`
+		Version sql.NullString `db:"version"`
+	}, 0)
+	if err := controller.DB.Select(&purls, "SELECT value, version FROM part_identifier WHERE part_id=$1 AND type=$2 ORDER BY value",
+		partID, pkgid.TypePurl); err != nil {
+		return errors.Wrapf(err, "error selecting purls of %s", partID)
+	}
+	for _, v := range purls {
+		purl, err := pkgid.ParsePurl(v.Value)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing purl %s of %s", v.Value, partID)
+		}
+		partVersion := v.Version.String
+		if partVersion == "" {
+			partVersion = p.Version.String
+		}
+		if partVersion == "" {
+			continue
+		}
+
+		packages, err := controller.affectedPackages(matchName(purl.Name))
+		if err != nil {
+			return err
+		}
+		for _, pkg := range packages {
+			if affectedPurl, ok := packagePurl(pkg.affected.Package); !ok || !samePackage(*purl, *affectedPurl) {
+				continue
+			}
+			if ok, detail := pkg.affected.Affects(partVersion); ok {
+				add(match{vulnerabilityID: pkg.vulnerabilityID, match: MatchPackage, detail: purl.Base() + ": " + detail})
+			}
+		}
+	}
+
+	// Package name and version, for parts without a purl
+	if len(purls) == 0 && p.Name.Valid && p.Version.Valid && p.Name.String != "" && p.Version.String != "" {
+		packages, err := controller.affectedPackages(matchName(p.Name.String))
+		if err != nil {
+			return err
+		}
+		for _, pkg := range packages {
+			if ok, detail := pkg.affected.Affects(p.Version.String); ok {
+				add(match{vulnerabilityID: pkg.vulnerabilityID, match: MatchPackage, detail: pkg.affected.Package.Name + ": " + detail})
+			}
+		}
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return errors.Wrapf(err, "error starting transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM part_has_vulnerability WHERE part_id=$1", partID); err != nil {
+		return errors.Wrapf(err, "error deleting vulnerabilities of %s", partID)
+	}
+	for _, m := range matches {
+		if _, err := tx.Exec("INSERT INTO part_has_vulnerability (part_id, vulnerability_id, match, detail) VALUES ($1, $2, $3, $4)",
+			partID, m.vulnerabilityID, m.match, m.detail); err != nil {
+			return errors.Wrapf(err, "error inserting vulnerability %s of %s", m.vulnerabilityID, partID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "error committing vulnerabilities of %s", partID)
+	}
+
+	return nil
+}
+
+type affectedPackage struct {
+	vulnerabilityID string
+	affected        Affected
+}
+
+// affectedPackages lists the affected packages with the given match name, of vulnerabilities that are not withdrawn
+func (controller *VulnerabilityController) affectedPackages(name string) ([]affectedPackage, error) {
+	rows, err := controller.DB.Queryx(`SELECT vp.vulnerability_id, vp.affected FROM vulnerability_package vp 
+	INNER JOIN vulnerability v ON v.vulnerability_id=vp.vulnerability_id 
+	WHERE vp.match_name=$1 AND v.withdrawn IS NULL 
+	ORDER BY vp.vulnerability_id`, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error selecting affected packages named %s", name)
+	}
+	defer rows.Close()
+
+	ret := make([]affectedPackage, 0)
+	for rows.Next() {
+		var id string
+		var entry []byte
+		if err := rows.Scan(&id, &entry); err != nil {
+			return nil, errors.Wrapf(err, "error scanning affected packages named %s", name)
+		}
+
+		pkg := affectedPackage{vulnerabilityID: id}
+		if err := json.Unmarshal(entry, &pkg.affected); err != nil {
+			return nil, errors.Wrapf(err, "error parsing affected package of %s", id)
+		}
+		ret = append(ret, pkg)
+	}
+
+	return ret, errors.Wrapf(rows.Err(), "error reading affected packages named %s", name)
+}
@@ -0,0 +1,412 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package vulnerability
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	pkgid "wrs/tk/packages/identifier"
+	"wrs/tk/packages/version"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// OSV is the subset of an OSV record used for matching; the whole record is stored as is
+type OSV struct {
+	ID               string                 `json:"id"`
+	Modified         *time.Time             `json:"modified"`
+	Published        *time.Time             `json:"published"`
+	Withdrawn        *time.Time             `json:"withdrawn"`
+	Aliases          []string               `json:"aliases"`
+	Summary          string                 `json:"summary"`
+	Details          string                 `json:"details"`
+	Severity         json.RawMessage        `json:"severity"`
+	Affected         []Affected             `json:"affected"`
+	DatabaseSpecific map[string]interface{} `json:"database_specific"`
+}
+
+// Package is an OSV affected package
+type Package struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Purl      string `json:"purl"`
+}
+
+// Affected is an OSV affected entry
+type Affected struct {
+	Package           Package                `json:"package"`
+	Ranges            []Range                `json:"ranges"`
+	Versions          []string               `json:"versions"`
+	EcosystemSpecific map[string]interface{} `json:"ecosystem_specific"`
+	DatabaseSpecific  map[string]interface{} `json:"database_specific"`
+}
+
+// Affects returns true if the version is listed, or within an evaluable range
+func (affected Affected) Affects(partVersion string) (bool, string) {
+	for _, v := range affected.Versions {
+		if v == partVersion || version.Compare(v, partVersion) == 0 {
+			return true, "version " + partVersion + " is listed as affected"
+		}
+	}
+	for _, r := range affected.Ranges {
+		if r.Evaluable() && r.Affects(partVersion) {
+			return true, "version " + partVersion + " is within an affected " + r.Type + " range"
+		}
+	}
+
+	return false, ""
+}
+
+// matchName returns the lower case last component of a package name, so that "github.com/madler/zlib" and "zlib" compare equal
+func matchName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 && i < len(name)-1 {
+		name = name[i+1:]
+	}
+
+	return name
+}
+
+// ecosystemPurls are the purl type, and namespace if the ecosystem implies one, of the OSV ecosystems
+var ecosystemPurls = map[string][2]string{
+	"npm":         {"npm", ""},
+	"pypi":        {"pypi", ""},
+	"go":          {"golang", ""},
+	"crates.io":   {"cargo", ""},
+	"maven":       {"maven", ""},
+	"nuget":       {"nuget", ""},
+	"rubygems":    {"gem", ""},
+	"packagist":   {"composer", ""},
+	"hex":         {"hex", ""},
+	"pub":         {"pub", ""},
+	"swifturl":    {"swift", ""},
+	"debian":      {"deb", "debian"},
+	"ubuntu":      {"deb", "ubuntu"},
+	"alpine":      {"apk", "alpine"},
+	"almalinux":   {"rpm", "almalinux"},
+	"rocky linux": {"rpm", "rocky-linux"},
+	"red hat":     {"rpm", "redhat"},
+}
+
+// packagePurl returns the purl of an affected package, without version, from its purl if it has one, or else from its ecosystem and name.
+// It returns false if the package has neither, or its ecosystem has no purl type.
+func packagePurl(pkg Package) (*pkgid.Purl, bool) {
+	if pkg.Purl != "" {
+		if purl, err := pkgid.ParsePurl(pkg.Purl); err == nil {
+			return purl, true
+		}
+	}
+
+	// Ecosystems may carry a release, e.g. Debian:11
+	ecosystem, _, _ := strings.Cut(strings.ToLower(pkg.Ecosystem), ":")
+	typeNamespace, ok := ecosystemPurls[ecosystem]
+	if !ok || pkg.Name == "" {
+		return nil, false
+	}
+
+	namespace, name := typeNamespace[1], pkg.Name
+	if typeNamespace[0] == "maven" {
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			namespace, name = name[:i], name[i+1:]
+		}
+	} else if i := strings.LastIndex(name, "/"); i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+	purl := pkgid.NewPurl(typeNamespace[0], namespace, name, "")
+
+	return &purl, true
+}
+
+// samePackage returns true if two purls name the same package, comparing type, namespace, and name
+func samePackage(a pkgid.Purl, b pkgid.Purl) bool {
+	return a.Type == b.Type && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+// hashes collects the sha256 listed under "sha256" or "hashes" of database or ecosystem specific fields.
+// OSV has no standard field for these, so both a string, a list of strings, and a list of {"algorithm", "value"} objects are accepted.
+func hashes(specific map[string]interface{}) [][]byte {
+	ret := make([][]byte, 0)
+	add := func(value string) {
+		if sha256, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(value), "sha256:")); err == nil && len(sha256) == 32 {
+			ret = append(ret, sha256)
+		}
+	}
+
+	var walk func(value interface{}, algorithm string)
+	walk = func(value interface{}, algorithm string) {
+		switch v := value.(type) {
+		case string:
+			if algorithm == "" || strings.EqualFold(algorithm, "sha256") || strings.EqualFold(algorithm, "sha-256") {
+				add(v)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item, algorithm)
+			}
+		case map[string]interface{}:
+			if value, ok := v["value"]; ok {
+				algorithm, _ := v["algorithm"].(string)
+				walk(value, algorithm)
+			} else if value, ok := v["sha256"]; ok {
+				walk(value, "sha256")
+			}
+		}
+	}
+
+	if value, ok := specific["sha256"]; ok {
+		walk(value, "sha256")
+	}
+	if value, ok := specific["hashes"]; ok {
+		walk(value, "")
+	}
+
+	return ret
+}
+
+// Import stores an OSV record, replacing an older copy of it.
+// Returns false if the stored copy is newer, and the record was not imported.
+func (controller *VulnerabilityController) Import(record []byte) (bool, error) {
+	var osv OSV
+	if err := json.Unmarshal(record, &osv); err != nil {
+		return false, errors.Wrapf(err, "error parsing OSV record")
+	}
+	if osv.ID == "" {
+		return false, errors.New("OSV record has no id")
+	}
+	if len(osv.Severity) == 0 || string(osv.Severity) == "null" {
+		osv.Severity = json.RawMessage("[]")
+	}
+
+	tx, err := controller.DB.Beginx()
+	if err != nil {
+		return false, errors.Wrapf(err, "error starting transaction")
+	}
+	defer tx.Rollback()
+
+	var id string
+	if err := tx.QueryRowx(`INSERT INTO vulnerability (vulnerability_id, summary, details, severity, published, modified, withdrawn, record) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+	ON CONFLICT (vulnerability_id) DO UPDATE SET summary=EXCLUDED.summary, details=EXCLUDED.details, severity=EXCLUDED.severity, 
+	published=EXCLUDED.published, modified=EXCLUDED.modified, withdrawn=EXCLUDED.withdrawn, record=EXCLUDED.record 
+	WHERE vulnerability.modified IS NULL OR EXCLUDED.modified IS NULL OR EXCLUDED.modified >= vulnerability.modified 
+	RETURNING vulnerability_id`,
+		osv.ID, toNullString(osv.Summary), toNullString(osv.Details), []byte(osv.Severity), osv.Published, osv.Modified, osv.Withdrawn, record).
+		Scan(&id); err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "error inserting vulnerability %s", osv.ID)
+	}
+
+	for _, table := range []string{"vulnerability_alias", "vulnerability_package", "vulnerability_hash"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE vulnerability_id=$1", id); err != nil {
+			return false, errors.Wrapf(err, "error deleting %s of %s", table, id)
+		}
+	}
+
+	for _, alias := range osv.Aliases {
+		if _, err := tx.Exec("INSERT INTO vulnerability_alias (vulnerability_id, alias) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, alias); err != nil {
+			return false, errors.Wrapf(err, "error inserting alias %s of %s", alias, id)
+		}
+	}
+
+	sha256s := hashes(osv.DatabaseSpecific)
+	for _, affected := range osv.Affected {
+		sha256s = append(sha256s, hashes(affected.DatabaseSpecific)...)
+		sha256s = append(sha256s, hashes(affected.EcosystemSpecific)...)
+		if affected.Package.Name == "" {
+			continue
+		}
+
+		entry, err := json.Marshal(affected)
+		if err != nil {
+			return false, errors.Wrapf(err, "error encoding affected package %s of %s", affected.Package.Name, id)
+		}
+		if _, err := tx.Exec(`INSERT INTO vulnerability_package (vulnerability_id, ecosystem, name, match_name, purl, affected) 
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+			id, toNullString(affected.Package.Ecosystem), affected.Package.Name, matchName(affected.Package.Name),
+			toNullString(affected.Package.Purl), entry); err != nil {
+			return false, errors.Wrapf(err, "error inserting affected package %s of %s", affected.Package.Name, id)
+		}
+	}
+	for _, sha256 := range sha256s {
+		if _, err := tx.Exec("INSERT INTO vulnerability_hash (vulnerability_id, sha256) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, sha256); err != nil {
+			return false, errors.Wrapf(err, "error inserting hash of %s", id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrapf(err, "error committing vulnerability %s", id)
+	}
+
+	return true, nil
+}
+
+// importJSON imports a file holding one OSV record, or an array of them
+func (controller *VulnerabilityController) importJSON(data []byte) (int64, error) {
+	data = bytes.TrimSpace(data)
+	records := []json.RawMessage{data}
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return 0, errors.Wrapf(err, "error parsing OSV records")
+		}
+	}
+
+	var count int64
+	for _, record := range records {
+		imported, err := controller.Import(record)
+		if err != nil {
+			return count, err
+		}
+		if imported {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// importZip imports every .json member of an OSV zip dump, such as the all.zip of an ecosystem
+func (controller *VulnerabilityController) importZip(r io.ReaderAt, size int64) (int64, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error opening zip")
+	}
+
+	var count int64
+	for _, member := range reader.File {
+		if member.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(member.Name), ".json") {
+			continue
+		}
+
+		f, err := member.Open()
+		if err != nil {
+			return count, errors.Wrapf(err, "error opening %s", member.Name)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return count, errors.Wrapf(err, "error reading %s", member.Name)
+		}
+
+		imported, err := controller.importJSON(data)
+		count += imported
+		if err != nil {
+			return count, errors.Wrapf(err, "error importing %s", member.Name)
+		}
+	}
+
+	return count, nil
+}
+
+// ImportFile imports an OSV zip dump, or a JSON file of one or many records, and queues every part to be re-matched.
+// Returns the number of records imported.
+func (controller *VulnerabilityController) ImportFile(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	magic, _ := reader.Peek(4)
+
+	var count int64
+	var err error
+	if bytes.Equal(magic, []byte("PK\x03\x04")) {
+		// zip needs random access, so spool the upload to disk rather than memory
+		tmp, tmpErr := os.CreateTemp("", "osv-*.zip")
+		if tmpErr != nil {
+			return 0, errors.Wrapf(tmpErr, "error creating temporary file")
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		size, copyErr := io.Copy(tmp, reader)
+		if copyErr != nil {
+			return 0, errors.Wrapf(copyErr, "error writing temporary file")
+		}
+		count, err = controller.importZip(tmp, size)
+	} else {
+		data, readErr := io.ReadAll(reader)
+		if readErr != nil {
+			return 0, errors.Wrapf(readErr, "error reading OSV file")
+		}
+		count, err = controller.importJSON(data)
+	}
+
+	if count > 0 {
+		controller.QueueMatch()
+	}
+
+	return count, err
+}
+
+// ImportDirectory imports every .json and .zip file under a local directory, such as an offline mirror of the OSV bucket, and queues every part to be re-matched.
+// Returns the number of records imported.
+func (controller *VulnerabilityController) ImportDirectory(directory string) (int64, error) {
+	var count int64
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		var imported int64
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "error reading %s", path)
+			}
+			imported, err = controller.importJSON(data)
+			if err != nil {
+				return errors.Wrapf(err, "error importing %s", path)
+			}
+		case ".zip":
+			f, err := os.Open(path)
+			if err != nil {
+				return errors.Wrapf(err, "error opening %s", path)
+			}
+			defer f.Close()
+
+			info, err := f.Stat()
+			if err != nil {
+				return errors.Wrapf(err, "error reading %s", path)
+			}
+			imported, err = controller.importZip(f, info.Size())
+			if err != nil {
+				return errors.Wrapf(err, "error importing %s", path)
+			}
+		}
+
+		count += imported
+		log.Debug().Str("path", path).Int64("imported", imported).Msg("imported OSV records")
+
+		return nil
+	})
+
+	if count > 0 {
+		controller.QueueMatch()
+	}
+
+	return count, err
+}
+
+func toNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
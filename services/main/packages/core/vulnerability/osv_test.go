@@ -0,0 +1,81 @@
+package vulnerability
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestMatchName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "zlib", want: "zlib"},
+		{name: "github.com/madler/zlib", want: "zlib"},
+		{name: "org.apache.logging.log4j:log4j-core", want: "log4j-core"},
+		{name: " OpenSSL ", want: "openssl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchName(tt.name); got != tt.want {
+				t.Errorf("matchName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackagePurl(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  Package
+		want string
+		ok   bool
+	}{
+		{name: "purl", pkg: Package{Ecosystem: "npm", Name: "lodash", Purl: "pkg:npm/lodash"}, want: "pkg:npm/lodash", ok: true},
+		{name: "go module", pkg: Package{Ecosystem: "Go", Name: "github.com/madler/zlib"}, want: "pkg:golang/github.com/madler/zlib", ok: true},
+		{name: "maven", pkg: Package{Ecosystem: "Maven", Name: "org.apache.logging.log4j:log4j-core"}, want: "pkg:maven/org.apache.logging.log4j/log4j-core", ok: true},
+		{name: "debian release", pkg: Package{Ecosystem: "Debian:11", Name: "openssl"}, want: "pkg:deb/debian/openssl", ok: true},
+		{name: "pypi", pkg: Package{Ecosystem: "PyPI", Name: "Foo_Bar"}, want: "pkg:pypi/foo-bar", ok: true},
+		{name: "unknown ecosystem", pkg: Package{Ecosystem: "OSS-Fuzz", Name: "zlib"}, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := packagePurl(tt.pkg)
+			if ok != tt.ok {
+				t.Fatalf("packagePurl() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got.Base() != tt.want {
+				t.Errorf("packagePurl() = %v, want %v", got.Base(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHashes(t *testing.T) {
+	sha := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	tests := []struct {
+		name     string
+		specific map[string]interface{}
+		want     int
+	}{
+		{name: "sha256 string", specific: map[string]interface{}{"sha256": sha}, want: 1},
+		{name: "prefixed hashes list", specific: map[string]interface{}{"hashes": []interface{}{"sha256:" + sha}}, want: 1},
+		{name: "algorithm objects", specific: map[string]interface{}{"hashes": []interface{}{
+			map[string]interface{}{"algorithm": "SHA-256", "value": sha},
+			map[string]interface{}{"algorithm": "md5", "value": sha},
+		}}, want: 1},
+		{name: "not a sha256", specific: map[string]interface{}{"sha256": "abcd"}, want: 0},
+		{name: "none", specific: nil, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hashes(tt.specific)
+			if len(got) != tt.want {
+				t.Fatalf("hashes() = %d hashes, want %d", len(got), tt.want)
+			}
+			if len(got) > 0 && hex.EncodeToString(got[0]) != sha {
+				t.Errorf("hashes() = %x, want %s", got[0], sha)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package vulnerability
+
+import (
+	"sort"
+	"wrs/tk/packages/version"
+)
+
+// Range event types from the OSV schema
+const (
+	EventIntroduced   = "introduced"
+	EventFixed        = "fixed"
+	EventLastAffected = "last_affected"
+	EventLimit        = "limit"
+)
+
+// Range is an OSV affected range
+type Range struct {
+	Type   string              `json:"type"`
+	Events []map[string]string `json:"events"`
+}
+
+// Evaluable returns true if the range can be evaluated against a version string.
+// GIT ranges are commit hashes and cannot be.
+func (r Range) Evaluable() bool {
+	return r.Type == "SEMVER" || r.Type == "ECOSYSTEM"
+}
+
+// Affects returns true if the version is within the range
+func (r Range) Affects(partVersion string) bool {
+	type event struct {
+		kind    string
+		version string
+	}
+
+	events := make([]event, 0, len(r.Events))
+	for _, v := range r.Events {
+		for kind, eventVersion := range v {
+			events = append(events, event{kind: kind, version: eventVersion})
+		}
+	}
+
+	// introduced "0" means every version since the beginning
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].version == "0" || events[j].version == "0" {
+			return events[i].version == "0" && events[j].version != "0"
+		}
+		return version.Compare(events[i].version, events[j].version) < 0
+	})
+
+	affected := false
+	for _, v := range events {
+		switch v.kind {
+		case EventIntroduced:
+			if v.version == "0" || version.Compare(partVersion, v.version) >= 0 {
+				affected = true
+			}
+		case EventFixed, EventLimit:
+			if version.Compare(partVersion, v.version) >= 0 {
+				affected = false
+			}
+		case EventLastAffected:
+			if version.Compare(partVersion, v.version) > 0 {
+				affected = false
+			}
+		}
+	}
+
+	return affected
+}
@@ -0,0 +1,69 @@
+package vulnerability
+
+import "testing"
+
+func TestRangeAffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []map[string]string
+		version string
+		want    bool
+	}{
+		{
+			name:    "introduced and fixed, inside",
+			events:  []map[string]string{{"introduced": "1.0.0"}, {"fixed": "1.2.0"}},
+			version: "1.1.5",
+			want:    true,
+		},
+		{
+			name:    "introduced and fixed, at fix",
+			events:  []map[string]string{{"introduced": "1.0.0"}, {"fixed": "1.2.0"}},
+			version: "1.2.0",
+			want:    false,
+		},
+		{
+			name:    "introduced and fixed, before",
+			events:  []map[string]string{{"introduced": "1.0.0"}, {"fixed": "1.2.0"}},
+			version: "0.9",
+			want:    false,
+		},
+		{
+			name:    "introduced zero",
+			events:  []map[string]string{{"introduced": "0"}, {"fixed": "3.0.8"}},
+			version: "1.0.2",
+			want:    true,
+		},
+		{
+			name:    "last affected is inclusive",
+			events:  []map[string]string{{"introduced": "0"}, {"last_affected": "2.4"}},
+			version: "2.4",
+			want:    true,
+		},
+		{
+			name:    "multiple intervals, between them",
+			events:  []map[string]string{{"introduced": "1.0"}, {"fixed": "1.1"}, {"introduced": "2.0"}, {"fixed": "2.3"}},
+			version: "1.5",
+			want:    false,
+		},
+		{
+			name:    "multiple intervals, in second",
+			events:  []map[string]string{{"introduced": "1.0"}, {"fixed": "1.1"}, {"introduced": "2.0"}, {"fixed": "2.3"}},
+			version: "2.1",
+			want:    true,
+		},
+		{
+			name:    "introduced without fix",
+			events:  []map[string]string{{"introduced": "4.0"}},
+			version: "5.1",
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Range{Type: "ECOSYSTEM", Events: tt.events}
+			if got := r.Affects(tt.version); got != tt.want {
+				t.Errorf("Range.Affects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package vulnerability
+
+import (
+	"database/sql"
+	"wrs/tk/packages/core/part"
+
+	"github.com/pkg/errors"
+)
+
+// Finding is a vulnerability matched to a part found under a part list, or under a part, with the VEX statement applying to it there
+type Finding struct {
+	PartVulnerability
+	PartListID sql.NullInt64
+	// Path is the part ids from the part in the part list, or the part the report is of, down to the vulnerable part
+	Path []part.ID
+}
+
+// reporter walks part lists and parts, caching what is loaded so each part and vulnerability is only queried once
+type reporter struct {
+	controller      *VulnerabilityController
+	includeResolved bool
+
+	children        map[part.ID][]part.ID
+	vulnerabilities map[part.ID][]PartVulnerability
+	statements      map[string][]Statement
+	seen            map[string]bool
+	findings        []Finding
+}
+
+func (controller *VulnerabilityController) newReporter(includeResolved bool) *reporter {
+	return &reporter{
+		controller:      controller,
+		includeResolved: includeResolved,
+		children:        make(map[part.ID][]part.ID),
+		vulnerabilities: make(map[part.ID][]PartVulnerability),
+		statements:      make(map[string][]Statement),
+		seen:            make(map[string]bool),
+		findings:        make([]Finding, 0),
+	}
+}
+
+// walkPart reports the vulnerabilities of the last part of path, then walks its sub-parts
+func (r *reporter) walkPart(partlists []int64, path []part.ID) error {
+	id := path[len(path)-1]
+
+	vulnerabilities, ok := r.vulnerabilities[id]
+	if !ok {
+		var err error
+		if vulnerabilities, err = r.controller.GetByPart(id); err != nil {
+			return err
+		}
+		r.vulnerabilities[id] = vulnerabilities
+	}
+
+	var partlistID sql.NullInt64
+	if len(partlists) > 0 {
+		partlistID = sql.NullInt64{Int64: partlists[len(partlists)-1], Valid: true}
+	}
+
+	for _, v := range vulnerabilities {
+		key := v.ID + "/" + id.String()
+		if partlistID.Valid {
+			key += "/" + PartListProductID(partlistID.Int64)
+		}
+		if r.seen[key] {
+			continue // already reported through another path
+		}
+		r.seen[key] = true
+
+		statements, ok := r.statements[v.ID]
+		if !ok {
+			var err error
+			if statements, err = r.controller.GetStatementsByVulnerability(v.ID); err != nil {
+				return err
+			}
+			r.statements[v.ID] = statements
+		}
+
+		v.Statement = Resolve(statements, partlists, path)
+		if r.includeResolved || Actionable(v.Statement) {
+			r.findings = append(r.findings, Finding{
+				PartVulnerability: v,
+				PartListID:        partlistID,
+				Path:              append([]part.ID{}, path...),
+			})
+		}
+	}
+
+	children, ok := r.children[id]
+	if !ok {
+		subParts, err := part.PartController{DB: r.controller.DB}.SubParts(id)
+		if err != nil {
+			return err
+		}
+
+		children = make([]part.ID, 0, len(subParts))
+		for _, v := range subParts {
+			children = append(children, v.ID)
+		}
+		r.children[id] = children
+	}
+
+	for _, child := range children {
+		cycle := false
+		for _, v := range path {
+			cycle = cycle || v == child
+		}
+		if cycle {
+			continue
+		}
+
+		if err := r.walkPart(partlists, append(path, child)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkPartList walks the parts of the last part list of partlists, then the part lists beneath it
+func (r *reporter) walkPartList(partlists []int64) error {
+	id := partlists[len(partlists)-1]
+
+	parts := make([]part.ID, 0)
+	if err := r.controller.DB.Select(&parts, "SELECT part_id FROM partlist_has_part WHERE partlist_id=$1 ORDER BY part_id", id); err != nil {
+		return errors.Wrapf(err, "error selecting parts of partlist %d", id)
+	}
+	for _, v := range parts {
+		if err := r.walkPart(partlists, []part.ID{v}); err != nil {
+			return err
+		}
+	}
+
+	children := make([]int64, 0)
+	if err := r.controller.DB.Select(&children, "SELECT id FROM partlist WHERE parent_id=$1 ORDER BY id", id); err != nil {
+		return errors.Wrapf(err, "error selecting partlists of partlist %d", id)
+	}
+	for _, child := range children {
+		if err := r.walkPartList(append(append([]int64{}, partlists...), child)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReportPart returns the vulnerabilities of the part and all of its sub-parts, with the VEX statements applying to each.
+// Vulnerabilities resolved as not_affected or fixed are left out unless includeResolved.
+func (controller *VulnerabilityController) ReportPart(partID part.ID, includeResolved bool) ([]Finding, error) {
+	r := controller.newReporter(includeResolved)
+	if err := r.walkPart(nil, []part.ID{partID}); err != nil {
+		return nil, err
+	}
+
+	return r.findings, nil
+}
+
+// ReportPartList returns the vulnerabilities of every part in the part list and the part lists beneath it, and all of their sub-parts,
+// with the VEX statements applying to each. Statements on the part list's parents apply too.
+// Vulnerabilities resolved as not_affected or fixed are left out unless includeResolved.
+func (controller *VulnerabilityController) ReportPartList(partlistID int64, includeResolved bool) ([]Finding, error) {
+	partlists, err := controller.partlistChain(partlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := controller.newReporter(includeResolved)
+	if err := r.walkPartList(partlists); err != nil {
+		return nil, err
+	}
+
+	return r.findings, nil
+}
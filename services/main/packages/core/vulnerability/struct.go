@@ -0,0 +1,174 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package vulnerability
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+	"wrs/tk/packages/core/part"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Ways a vulnerability is matched to a part
+const (
+	MatchPackage = "package"
+	MatchArchive = "archive"
+	MatchFile    = "file"
+)
+
+// Vulnerability is an imported OSV record
+type Vulnerability struct {
+	ID         string          `db:"vulnerability_id"`
+	Summary    sql.NullString  `db:"summary"`
+	Details    sql.NullString  `db:"details"`
+	Severity   json.RawMessage `db:"severity"`
+	Published  sql.NullTime    `db:"published"`
+	Modified   sql.NullTime    `db:"modified"`
+	Withdrawn  sql.NullTime    `db:"withdrawn"`
+	Record     json.RawMessage `db:"record"`
+	InsertDate time.Time       `db:"insert_date"`
+}
+
+// PartVulnerability is a vulnerability matched to a part, with how it was matched, and the VEX statement applying to it if any
+type PartVulnerability struct {
+	Vulnerability
+	PartID    part.ID        `db:"part_id"`
+	Match     string         `db:"match"`
+	Detail    sql.NullString `db:"detail"`
+	Statement *Statement     `db:"-"`
+}
+
+type VulnerabilityController struct {
+	DB *sqlx.DB
+
+	queue chan []uuid.UUID
+}
+
+// NewVulnerabilityController creates a controller, and starts the background job re-matching parts
+func NewVulnerabilityController(db *sqlx.DB) *VulnerabilityController {
+	controller := &VulnerabilityController{
+		DB:    db,
+		queue: make(chan []uuid.UUID, 64),
+	}
+	go controller.matchWorker()
+
+	return controller
+}
+
+// GetByID returns the vulnerability with the given id, or with the given alias such as a CVE id
+func (controller *VulnerabilityController) GetByID(id string) (*Vulnerability, error) {
+	var ret Vulnerability
+	if err := controller.DB.QueryRowx(`SELECT * FROM vulnerability WHERE vulnerability_id=$1 
+	OR vulnerability_id IN (SELECT vulnerability_id FROM vulnerability_alias WHERE alias=$1) 
+	ORDER BY vulnerability_id=$1 DESC LIMIT 1`, id).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting vulnerability %s", id)
+	}
+
+	return &ret, nil
+}
+
+// GetAliases returns the other identifiers of a vulnerability
+func (controller *VulnerabilityController) GetAliases(id string) ([]string, error) {
+	ret := make([]string, 0)
+	if err := controller.DB.Select(&ret, "SELECT alias FROM vulnerability_alias WHERE vulnerability_id=$1 ORDER BY alias", id); err != nil {
+		return nil, errors.Wrapf(err, "error selecting aliases of vulnerability %s", id)
+	}
+
+	return ret, nil
+}
+
+// GetByPart returns the vulnerabilities matched to a part
+func (controller *VulnerabilityController) GetByPart(partID part.ID) ([]PartVulnerability, error) {
+	ret := make([]PartVulnerability, 0)
+	if err := controller.DB.Select(&ret, `SELECT v.*, phv.part_id, phv.match, phv.detail FROM part_has_vulnerability phv 
+	INNER JOIN vulnerability v ON v.vulnerability_id=phv.vulnerability_id 
+	WHERE phv.part_id=$1 ORDER BY v.vulnerability_id`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting vulnerabilities of part %s", partID)
+	}
+
+	return ret, nil
+}
+
+// GetByPartInScope returns the vulnerabilities matched to a part, with the VEX statement applying to each when the part is in the given part list.
+// Vulnerabilities resolved as not_affected or fixed are left out unless includeResolved.
+func (controller *VulnerabilityController) GetByPartInScope(partID part.ID, partlistID *int64, includeResolved bool) ([]PartVulnerability, error) {
+	partlists := make([]int64, 0)
+	if partlistID != nil {
+		var err error
+		if partlists, err = controller.partlistChain(*partlistID); err != nil {
+			return nil, err
+		}
+	}
+
+	vulnerabilities, err := controller.GetByPart(partID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]PartVulnerability, 0, len(vulnerabilities))
+	for _, v := range vulnerabilities {
+		statements, err := controller.GetStatementsByVulnerability(v.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Statement = Resolve(statements, partlists, []part.ID{partID})
+		if includeResolved || Actionable(v.Statement) {
+			ret = append(ret, v)
+		}
+	}
+
+	return ret, nil
+}
+
+// GetParts returns the parts matched to a vulnerability, which may be given by one of its aliases.
+// Parts with a VEX statement resolving the vulnerability as not_affected or fixed are left out unless includeResolved.
+func (controller *VulnerabilityController) GetParts(id string, includeResolved bool) ([]part.Part, error) {
+	vulnerability, err := controller.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]part.Part, 0)
+	if err := controller.DB.Select(&parts, `SELECT p.* FROM part p 
+	INNER JOIN part_has_vulnerability phv ON phv.part_id=p.part_id 
+	WHERE phv.vulnerability_id=$1 ORDER BY p.name, p.version`, vulnerability.ID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting parts of vulnerability %s", vulnerability.ID)
+	}
+
+	statements, err := controller.GetStatementsByVulnerability(vulnerability.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]part.Part, 0, len(parts))
+	for _, p := range parts {
+		if !includeResolved && !Actionable(Resolve(statements, nil, []part.ID{p.PartID})) {
+			continue
+		}
+		if p.Type.Valid {
+			p.Type.String = "/" + strings.ReplaceAll(p.Type.String, ".", "/")
+		}
+
+		ret = append(ret, p)
+	}
+
+	return ret, nil
+}
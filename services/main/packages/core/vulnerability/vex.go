@@ -0,0 +1,283 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package vulnerability
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// VEX statuses
+const (
+	StatusNotAffected        = "not_affected"
+	StatusAffected           = "affected"
+	StatusFixed              = "fixed"
+	StatusUnderInvestigation = "under_investigation"
+)
+
+// Statuses lists every VEX status
+var Statuses = []string{StatusNotAffected, StatusAffected, StatusFixed, StatusUnderInvestigation}
+
+// VEX justifications for not_affected, from the OpenVEX vocabulary
+const (
+	JustificationComponentNotPresent            = "component_not_present"
+	JustificationVulnerableCodeNotPresent       = "vulnerable_code_not_present"
+	JustificationVulnerableCodeNotInExecutePath = "vulnerable_code_not_in_execute_path"
+	JustificationCannotBeControlledByAdversary  = "vulnerable_code_cannot_be_controlled_by_adversary"
+	JustificationInlineMitigationsAlreadyExist  = "inline_mitigations_already_exist"
+)
+
+// Justifications lists every VEX justification
+var Justifications = []string{JustificationComponentNotPresent, JustificationVulnerableCodeNotPresent,
+	JustificationVulnerableCodeNotInExecutePath, JustificationCannotBeControlledByAdversary, JustificationInlineMitigationsAlreadyExist}
+
+// Statement is a VEX statement on the exploitability of a vulnerability in a part, or in every part of a part list.
+// Exactly one of PartID and PartListID is set.
+type Statement struct {
+	ID              int64          `db:"id"`
+	VulnerabilityID string         `db:"vulnerability_id"`
+	PartID          part.ID        `db:"part_id"`
+	PartListID      sql.NullInt64  `db:"partlist_id"`
+	Status          string         `db:"status"`
+	Justification   sql.NullString `db:"justification"`
+	ImpactStatement sql.NullString `db:"impact_statement"`
+	ActionStatement sql.NullString `db:"action_statement"`
+	InsertDate      time.Time      `db:"insert_date"`
+	UpdateDate      time.Time      `db:"update_date"`
+}
+
+// Actionable returns true if a vulnerability with the statement, or without one, still needs attention
+func Actionable(statement *Statement) bool {
+	return statement == nil || (statement.Status != StatusNotAffected && statement.Status != StatusFixed)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate checks the status and justification, and that the statement has exactly one scope
+func (statement Statement) validate() error {
+	if statement.VulnerabilityID == "" {
+		return errors.New("VEX statement has no vulnerability")
+	}
+	if !contains(Statuses, statement.Status) {
+		return errors.Errorf("invalid VEX status \"%s\", expected one of %s", statement.Status, strings.Join(Statuses, ", "))
+	}
+	if statement.Justification.Valid && !contains(Justifications, statement.Justification.String) {
+		return errors.Errorf("invalid VEX justification \"%s\", expected one of %s", statement.Justification.String, strings.Join(Justifications, ", "))
+	}
+	if (statement.PartID == part.ID(uuid.Nil)) == !statement.PartListID.Valid {
+		return errors.New("VEX statement must be scoped to exactly one of a part or a part list")
+	}
+
+	return nil
+}
+
+// Scope returns the product identifier of the statement's part or part list, as used in exported documents
+func (statement Statement) Scope() string {
+	if statement.PartListID.Valid {
+		return PartListProductID(statement.PartListID.Int64)
+	}
+
+	return PartProductID(statement.PartID)
+}
+
+// PartProductID is the product identifier of a part in VEX documents
+func PartProductID(id part.ID) string {
+	return "urn:uuid:" + id.String()
+}
+
+// PartListProductID is the product identifier of a part list in VEX documents
+func PartListProductID(id int64) string {
+	return fmt.Sprintf("urn:tk:partlist:%d", id)
+}
+
+// Resolve returns the statement that applies to a vulnerability found under the given part lists and parts, or nil if none do.
+// partlists and parts are ordered from the product down: the outermost part list to the one holding the part,
+// then the outermost part down to the part the vulnerability was found in.
+// Statements nearest the product win, so the innermost part list is considered first, then its parents,
+// then parts from the outermost down. Ties go to the latest update.
+func Resolve(statements []Statement, partlists []int64, parts []part.ID) *Statement {
+	rank := make(map[string]int)
+	for i := range partlists {
+		rank[PartListProductID(partlists[i])] = len(partlists) - i
+	}
+	for i := range parts {
+		if _, ok := rank[PartProductID(parts[i])]; !ok {
+			rank[PartProductID(parts[i])] = len(partlists) + i + 1
+		}
+	}
+
+	candidates := make([]*Statement, 0)
+	for i := range statements {
+		if _, ok := rank[statements[i].Scope()]; ok {
+			candidates = append(candidates, &statements[i])
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := rank[candidates[i].Scope()], rank[candidates[j].Scope()]
+		if ri != rj {
+			return ri < rj
+		}
+		return candidates[i].UpdateDate.After(candidates[j].UpdateDate)
+	})
+
+	return candidates[0]
+}
+
+// SetStatement stores a VEX statement, replacing the statement on the same vulnerability and scope if there was one
+func (controller *VulnerabilityController) SetStatement(statement Statement) (*Statement, error) {
+	if err := statement.validate(); err != nil {
+		return nil, err
+	}
+
+	var partID interface{}
+	conflict := "(vulnerability_id, partlist_id) WHERE partlist_id IS NOT NULL"
+	if !statement.PartListID.Valid {
+		partID = statement.PartID
+		conflict = "(vulnerability_id, part_id) WHERE part_id IS NOT NULL"
+	}
+
+	var ret Statement
+	if err := controller.DB.QueryRowx(`INSERT INTO vex_statement 
+	(vulnerability_id, part_id, partlist_id, status, justification, impact_statement, action_statement) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7) 
+	ON CONFLICT `+conflict+` DO UPDATE SET status=EXCLUDED.status, justification=EXCLUDED.justification, 
+	impact_statement=EXCLUDED.impact_statement, action_statement=EXCLUDED.action_statement, update_date=NOW() 
+	RETURNING *`,
+		statement.VulnerabilityID, partID, statement.PartListID, statement.Status, statement.Justification,
+		statement.ImpactStatement, statement.ActionStatement).StructScan(&ret); err != nil {
+		return nil, errors.Wrapf(err, "error inserting VEX statement on %s for %s", statement.VulnerabilityID, statement.Scope())
+	}
+
+	return &ret, nil
+}
+
+// DeleteStatement deletes the VEX statement with the given id
+func (controller *VulnerabilityController) DeleteStatement(id int64) (*Statement, error) {
+	var ret Statement
+	if err := controller.DB.QueryRowx("DELETE FROM vex_statement WHERE id=$1 RETURNING *", id).StructScan(&ret); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStatementNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error deleting VEX statement %d", id)
+	}
+
+	return &ret, nil
+}
+
+// GetStatementsByPart returns the VEX statements scoped to the part
+func (controller *VulnerabilityController) GetStatementsByPart(partID part.ID) ([]Statement, error) {
+	ret := make([]Statement, 0)
+	if err := controller.DB.Select(&ret, "SELECT * FROM vex_statement WHERE part_id=$1 ORDER BY vulnerability_id", partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting VEX statements of part %s", partID)
+	}
+
+	return ret, nil
+}
+
+// GetStatementsByPartList returns the VEX statements scoped to the part list
+func (controller *VulnerabilityController) GetStatementsByPartList(partlistID int64) ([]Statement, error) {
+	ret := make([]Statement, 0)
+	if err := controller.DB.Select(&ret, "SELECT * FROM vex_statement WHERE partlist_id=$1 ORDER BY vulnerability_id", partlistID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting VEX statements of partlist %d", partlistID)
+	}
+
+	return ret, nil
+}
+
+// GetStatementsByVulnerability returns the VEX statements on the vulnerability, made by its id or by any of its aliases
+func (controller *VulnerabilityController) GetStatementsByVulnerability(vulnerabilityID string) ([]Statement, error) {
+	ret := make([]Statement, 0)
+	if err := controller.DB.Select(&ret, `SELECT * FROM vex_statement WHERE vulnerability_id=$1 
+	OR vulnerability_id IN (SELECT alias FROM vulnerability_alias WHERE vulnerability_id=$1) 
+	ORDER BY id`, vulnerabilityID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting VEX statements of %s", vulnerabilityID)
+	}
+
+	return ret, nil
+}
+
+// partlistChain returns the part list and its parents, from the outermost down
+func (controller *VulnerabilityController) partlistChain(partlistID int64) ([]int64, error) {
+	ret := make([]int64, 0)
+	seen := make(map[int64]bool)
+	id := sql.NullInt64{Int64: partlistID, Valid: true}
+	for id.Valid && !seen[id.Int64] {
+		seen[id.Int64] = true
+		ret = append([]int64{id.Int64}, ret...)
+
+		var parent sql.NullInt64
+		if err := controller.DB.QueryRowx("SELECT parent_id FROM partlist WHERE id=$1", id.Int64).Scan(&parent); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.Wrapf(partlist.ErrNotFound, "%d", id.Int64)
+			}
+
+			return nil, errors.Wrapf(err, "error selecting parent of partlist %d", id.Int64)
+		}
+		id = parent
+	}
+
+	return ret, nil
+}
+
+// parseProductID returns the part or part list identified by a product identifier.
+// Besides the identifiers produced by PartProductID and PartListProductID, part aliases are accepted.
+func (controller *VulnerabilityController) parseProductID(productID string) (part.ID, sql.NullInt64, error) {
+	if strings.HasPrefix(productID, "urn:tk:partlist:") {
+		id, err := strconv.ParseInt(strings.TrimPrefix(productID, "urn:tk:partlist:"), 10, 64)
+		if err != nil {
+			return part.ID(uuid.Nil), sql.NullInt64{}, errors.Wrapf(err, "error parsing partlist id of \"%s\"", productID)
+		}
+
+		return part.ID(uuid.Nil), sql.NullInt64{Int64: id, Valid: true}, nil
+	}
+
+	if strings.HasPrefix(productID, "urn:uuid:") {
+		id, err := uuid.Parse(strings.TrimPrefix(productID, "urn:uuid:"))
+		if err != nil {
+			return part.ID(uuid.Nil), sql.NullInt64{}, errors.Wrapf(err, "error parsing part id of \"%s\"", productID)
+		}
+
+		return part.ID(id), sql.NullInt64{}, nil
+	}
+
+	var id part.ID
+	if err := controller.DB.QueryRowx("SELECT part_id FROM part_alias WHERE alias=$1", productID).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return part.ID(uuid.Nil), sql.NullInt64{}, errors.Wrapf(part.ErrNotFound, "no part identified by \"%s\"", productID)
+		}
+
+		return part.ID(uuid.Nil), sql.NullInt64{}, errors.Wrapf(err, "error selecting part alias \"%s\"", productID)
+	}
+
+	return id, sql.NullInt64{}, nil
+}
@@ -0,0 +1,451 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package vulnerability
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+	"wrs/tk/packages/core/part"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// VEX document formats
+const (
+	FormatOpenVEX   = "openvex"
+	FormatCycloneDX = "cyclonedx"
+)
+
+const openVEXContext = "https://openvex.dev/ns/v0.2.0"
+
+type openVEXDocument struct {
+	Context    string             `json:"@context"`
+	ID         string             `json:"@id"`
+	Author     string             `json:"author"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Version    int                `json:"version"`
+	Statements []openVEXStatement `json:"statements"`
+}
+
+// openVEXStatement holds vulnerability and products as interface{}, as older documents give them as plain strings
+type openVEXStatement struct {
+	Vulnerability   interface{}   `json:"vulnerability"`
+	Products        []interface{} `json:"products"`
+	Status          string        `json:"status"`
+	Justification   string        `json:"justification,omitempty"`
+	ImpactStatement string        `json:"impact_statement,omitempty"`
+	ActionStatement string        `json:"action_statement,omitempty"`
+	Timestamp       *time.Time    `json:"timestamp,omitempty"`
+}
+
+type cycloneDXDocument struct {
+	BOMFormat       string                   `json:"bomFormat"`
+	SpecVersion     string                   `json:"specVersion"`
+	SerialNumber    string                   `json:"serialNumber,omitempty"`
+	Version         int                      `json:"version"`
+	Metadata        *cycloneDXMetadata       `json:"metadata,omitempty"`
+	Components      []cycloneDXComponent     `json:"components,omitempty"`
+	Vulnerabilities []cycloneDXVulnerability `json:"vulnerabilities"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type cycloneDXComponent struct {
+	Type    string `json:"type"`
+	BOMRef  string `json:"bom-ref"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Purl    string `json:"purl,omitempty"`
+}
+
+type cycloneDXVulnerability struct {
+	ID       string             `json:"id"`
+	Analysis *cycloneDXAnalysis `json:"analysis,omitempty"`
+	Affects  []cycloneDXAffects `json:"affects"`
+}
+
+type cycloneDXAnalysis struct {
+	State         string   `json:"state"`
+	Justification string   `json:"justification,omitempty"`
+	Response      []string `json:"response,omitempty"`
+	Detail        string   `json:"detail,omitempty"`
+}
+
+type cycloneDXAffects struct {
+	Ref string `json:"ref"`
+}
+
+// CycloneDX analysis states by VEX status, and VEX statuses by CycloneDX analysis state
+var (
+	cycloneDXStates = map[string]string{
+		StatusNotAffected:        "not_affected",
+		StatusAffected:           "exploitable",
+		StatusFixed:              "resolved",
+		StatusUnderInvestigation: "in_triage",
+	}
+	cycloneDXStatuses = map[string]string{
+		"not_affected":           StatusNotAffected,
+		"false_positive":         StatusNotAffected,
+		"exploitable":            StatusAffected,
+		"resolved":               StatusFixed,
+		"resolved_with_pedigree": StatusFixed,
+		"in_triage":              StatusUnderInvestigation,
+	}
+)
+
+// CycloneDX justifications by VEX justification, and VEX justifications by CycloneDX justification.
+// The vocabularies differ, so the closest equivalent is used each way.
+var (
+	cycloneDXJustifications = map[string]string{
+		JustificationComponentNotPresent:            "requires_dependency",
+		JustificationVulnerableCodeNotPresent:       "code_not_present",
+		JustificationVulnerableCodeNotInExecutePath: "code_not_reachable",
+		JustificationCannotBeControlledByAdversary:  "requires_environment",
+		JustificationInlineMitigationsAlreadyExist:  "protected_by_mitigating_control",
+	}
+	vexJustifications = map[string]string{
+		"code_not_present":                JustificationVulnerableCodeNotPresent,
+		"code_not_reachable":              JustificationVulnerableCodeNotInExecutePath,
+		"requires_configuration":          JustificationCannotBeControlledByAdversary,
+		"requires_dependency":             JustificationComponentNotPresent,
+		"requires_environment":            JustificationCannotBeControlledByAdversary,
+		"protected_by_compiler":           JustificationInlineMitigationsAlreadyExist,
+		"protected_at_runtime":            JustificationInlineMitigationsAlreadyExist,
+		"protected_at_perimeter":          JustificationInlineMitigationsAlreadyExist,
+		"protected_by_mitigating_control": JustificationInlineMitigationsAlreadyExist,
+	}
+)
+
+// parsedStatement is a statement read from a VEX document, with the product identifiers it applies to
+type parsedStatement struct {
+	Statement
+	Products []string
+}
+
+// identifier returns an identifier given as a plain string, or as an object with one of the given keys
+func identifier(value interface{}, keys ...string) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case map[string]interface{}:
+		ret := make([]string, 0)
+		for _, key := range keys {
+			if s, ok := v[key].(string); ok && s != "" {
+				ret = append(ret, s)
+			}
+		}
+		// OpenVEX v0.2.0 products may list other identifiers, such as a purl
+		if identifiers, ok := v["identifiers"].(map[string]interface{}); ok {
+			for _, value := range identifiers {
+				if s, ok := value.(string); ok && s != "" {
+					ret = append(ret, s)
+				}
+			}
+		}
+		return ret
+	default:
+		return nil
+	}
+}
+
+// parseVEX reads the statements of an OpenVEX or CycloneDX VEX document, returning the format it was in
+func parseVEX(data []byte) ([]parsedStatement, string, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, "", errors.Wrapf(err, "error parsing VEX document")
+	}
+
+	ret := make([]parsedStatement, 0)
+	if _, ok := probe["bomFormat"]; ok {
+		var document cycloneDXDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, "", errors.Wrapf(err, "error parsing CycloneDX document")
+		}
+
+		purls := make(map[string]string)
+		for _, v := range document.Components {
+			if v.Purl != "" {
+				purls[v.BOMRef] = v.Purl
+			}
+		}
+
+		for _, v := range document.Vulnerabilities {
+			if v.Analysis == nil || v.Analysis.State == "" {
+				continue // not a VEX entry
+			}
+
+			status, ok := cycloneDXStatuses[v.Analysis.State]
+			if !ok {
+				return nil, "", errors.Errorf("unknown CycloneDX analysis state \"%s\" of %s", v.Analysis.State, v.ID)
+			}
+
+			statement := parsedStatement{
+				Statement: Statement{
+					VulnerabilityID: v.ID,
+					Status:          status,
+				},
+				Products: make([]string, 0),
+			}
+			if justification, ok := vexJustifications[v.Analysis.Justification]; ok {
+				statement.Justification = toNullString(justification)
+			}
+			if status == StatusNotAffected {
+				statement.ImpactStatement = toNullString(v.Analysis.Detail)
+			} else {
+				statement.ActionStatement = toNullString(v.Analysis.Detail)
+			}
+
+			for _, affects := range v.Affects {
+				ref := affects.Ref
+				if i := strings.LastIndexByte(ref, '#'); strings.HasPrefix(ref, "urn:cdx:") && i >= 0 {
+					ref = ref[i+1:] // BOM-Link to a component of another BOM
+				}
+				statement.Products = append(statement.Products, ref)
+				if purl, ok := purls[ref]; ok {
+					statement.Products = append(statement.Products, purl)
+				}
+			}
+
+			ret = append(ret, statement)
+		}
+
+		return ret, FormatCycloneDX, nil
+	}
+
+	if _, ok := probe["statements"]; !ok {
+		return nil, "", errors.New("VEX document is neither OpenVEX nor CycloneDX")
+	}
+
+	var document openVEXDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, "", errors.Wrapf(err, "error parsing OpenVEX document")
+	}
+
+	for _, v := range document.Statements {
+		ids := identifier(v.Vulnerability, "name", "@id")
+		if len(ids) == 0 {
+			return nil, "", errors.New("OpenVEX statement has no vulnerability")
+		}
+
+		statement := parsedStatement{
+			Statement: Statement{
+				VulnerabilityID: ids[0],
+				Status:          v.Status,
+				Justification:   toNullString(v.Justification),
+				ImpactStatement: toNullString(v.ImpactStatement),
+				ActionStatement: toNullString(v.ActionStatement),
+			},
+			Products: make([]string, 0),
+		}
+		for _, product := range v.Products {
+			statement.Products = append(statement.Products, identifier(product, "@id")...)
+		}
+
+		ret = append(ret, statement)
+	}
+
+	return ret, FormatOpenVEX, nil
+}
+
+// ImportVEX stores the statements of an OpenVEX or CycloneDX VEX document, returning the number stored.
+// If partID or partlistID is given, every statement is scoped to it. Otherwise each statement is scoped to the parts and
+// part lists its products identify, and statements without a known product are skipped.
+func (controller *VulnerabilityController) ImportVEX(r io.Reader, partID part.ID, partlistID sql.NullInt64) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error reading VEX document")
+	}
+
+	statements, format, err := parseVEX(data)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, v := range statements {
+		scoped := make([]Statement, 0)
+		if partID != part.ID(uuid.Nil) || partlistID.Valid {
+			v.Statement.PartID, v.Statement.PartListID = partID, partlistID
+			scoped = append(scoped, v.Statement)
+		} else {
+			for _, product := range v.Products {
+				productPartID, productPartListID, err := controller.parseProductID(product)
+				if err != nil {
+					log.Debug().Err(err).Str("product", product).Msg("skipping unknown VEX product")
+					continue
+				}
+
+				statement := v.Statement
+				statement.PartID, statement.PartListID = productPartID, productPartListID
+				scoped = append(scoped, statement)
+			}
+		}
+		if len(scoped) == 0 {
+			log.Warn().Str("format", format).Str("vulnerability", v.VulnerabilityID).Strs("products", v.Products).
+				Msg("skipping VEX statement without a known product")
+			continue
+		}
+
+		for _, statement := range scoped {
+			if _, err := controller.SetStatement(statement); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ExportStatements returns the VEX statements on the part and its sub-parts
+func (controller *VulnerabilityController) ExportStatements(partID part.ID) ([]Statement, error) {
+	ret := make([]Statement, 0)
+	if err := controller.DB.Select(&ret, `WITH RECURSIVE tree(part_id) AS (
+		SELECT $1::UUID
+		UNION
+		SELECT php.child_id FROM part_has_part php INNER JOIN tree ON tree.part_id=php.parent_id
+	) SELECT * FROM vex_statement WHERE part_id IN (SELECT part_id FROM tree) ORDER BY vulnerability_id, id`, partID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting VEX statements of part %s", partID)
+	}
+
+	return ret, nil
+}
+
+// ExportPartListStatements returns the VEX statements on the part list, the part lists beneath it, and the parts they list
+func (controller *VulnerabilityController) ExportPartListStatements(partlistID int64) ([]Statement, error) {
+	ret := make([]Statement, 0)
+	if err := controller.DB.Select(&ret, `WITH RECURSIVE lists AS (
+		SELECT id FROM partlist WHERE id=$1
+		UNION SELECT partlist.id FROM partlist INNER JOIN lists ON partlist.parent_id=lists.id
+	) SELECT * FROM vex_statement WHERE partlist_id IN (SELECT id FROM lists) 
+	OR part_id IN (SELECT part_id FROM partlist_has_part WHERE partlist_id IN (SELECT id FROM lists)) 
+	ORDER BY vulnerability_id, id`, partlistID); err != nil {
+		return nil, errors.Wrapf(err, "error selecting VEX statements of partlist %d", partlistID)
+	}
+
+	return ret, nil
+}
+
+// WriteOpenVEX writes the statements as an OpenVEX document
+func WriteOpenVEX(w io.Writer, author string, statements []Statement) error {
+	document := openVEXDocument{
+		Context:    openVEXContext,
+		ID:         "urn:uuid:" + uuid.New().String(),
+		Author:     author,
+		Timestamp:  time.Now().UTC(),
+		Version:    1,
+		Statements: make([]openVEXStatement, 0, len(statements)),
+	}
+
+	for _, v := range statements {
+		timestamp := v.UpdateDate.UTC()
+		document.Statements = append(document.Statements, openVEXStatement{
+			Vulnerability:   map[string]string{"name": v.VulnerabilityID},
+			Products:        []interface{}{map[string]string{"@id": v.Scope()}},
+			Status:          v.Status,
+			Justification:   v.Justification.String,
+			ImpactStatement: v.ImpactStatement.String,
+			ActionStatement: v.ActionStatement.String,
+			Timestamp:       &timestamp,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// WriteCycloneDX writes the statements as a CycloneDX VEX document, with a component for each part or part list they are scoped to.
+// names gives the component name of each product identifier.
+func WriteCycloneDX(w io.Writer, names map[string]string, statements []Statement) error {
+	document := cycloneDXDocument{
+		BOMFormat:       "CycloneDX",
+		SpecVersion:     "1.4",
+		SerialNumber:    "urn:uuid:" + uuid.New().String(),
+		Version:         1,
+		Metadata:        &cycloneDXMetadata{Timestamp: time.Now().UTC()},
+		Components:      make([]cycloneDXComponent, 0),
+		Vulnerabilities: make([]cycloneDXVulnerability, 0, len(statements)),
+	}
+
+	components := make(map[string]bool)
+	for _, v := range statements {
+		scope := v.Scope()
+		if !components[scope] {
+			components[scope] = true
+
+			componentType := "library"
+			if v.PartListID.Valid {
+				componentType = "application"
+			}
+			document.Components = append(document.Components, cycloneDXComponent{
+				Type:   componentType,
+				BOMRef: scope,
+				Name:   names[scope],
+			})
+		}
+
+		analysis := cycloneDXAnalysis{
+			State:         cycloneDXStates[v.Status],
+			Justification: cycloneDXJustifications[v.Justification.String],
+			Detail:        v.ActionStatement.String,
+		}
+		if v.Status == StatusNotAffected {
+			analysis.Detail = v.ImpactStatement.String
+		}
+
+		document.Vulnerabilities = append(document.Vulnerabilities, cycloneDXVulnerability{
+			ID:       v.VulnerabilityID,
+			Analysis: &analysis,
+			Affects:  []cycloneDXAffects{{Ref: scope}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// ProductNames returns the name of every part or part list the statements are scoped to, by product identifier
+func (controller *VulnerabilityController) ProductNames(statements []Statement) (map[string]string, error) {
+	ret := make(map[string]string)
+	for _, v := range statements {
+		scope := v.Scope()
+		if _, ok := ret[scope]; ok {
+			continue
+		}
+
+		var name sql.NullString
+		var err error
+		if v.PartListID.Valid {
+			err = controller.DB.QueryRowx("SELECT name FROM partlist WHERE id=$1", v.PartListID.Int64).Scan(&name)
+		} else {
+			err = controller.DB.QueryRowx("SELECT name FROM part WHERE part_id=$1", v.PartID).Scan(&name)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return nil, errors.Wrapf(err, "error selecting name of %s", scope)
+		}
+
+		ret[scope] = name.String
+		if !name.Valid || name.String == "" {
+			ret[scope] = scope
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,170 @@
+package vulnerability
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+	"wrs/tk/packages/core/part"
+
+	"github.com/google/uuid"
+)
+
+func TestResolve(t *testing.T) {
+	product, component := part.ID(uuid.New()), part.ID(uuid.New())
+	older, newer := time.Now().Add(-time.Hour), time.Now()
+
+	onPart := func(id part.ID, status string, updated time.Time) Statement {
+		return Statement{PartID: id, Status: status, UpdateDate: updated}
+	}
+	onPartList := func(id int64, status string) Statement {
+		return Statement{PartListID: sql.NullInt64{Int64: id, Valid: true}, Status: status, UpdateDate: older}
+	}
+
+	tests := []struct {
+		name       string
+		statements []Statement
+		partlists  []int64
+		parts      []part.ID
+		want       string
+	}{
+		{
+			name:       "no statements",
+			statements: nil,
+			parts:      []part.ID{component},
+			want:       "",
+		},
+		{
+			name:       "statement on another part",
+			statements: []Statement{onPart(product, StatusNotAffected, older)},
+			parts:      []part.ID{component},
+			want:       "",
+		},
+		{
+			name:       "statement on the vulnerable part",
+			statements: []Statement{onPart(component, StatusFixed, older)},
+			parts:      []part.ID{product, component},
+			want:       StatusFixed,
+		},
+		{
+			name:       "outer part wins over the vulnerable part",
+			statements: []Statement{onPart(component, StatusAffected, newer), onPart(product, StatusNotAffected, older)},
+			parts:      []part.ID{product, component},
+			want:       StatusNotAffected,
+		},
+		{
+			name:       "part list wins over parts",
+			statements: []Statement{onPart(product, StatusNotAffected, newer), onPartList(2, StatusAffected)},
+			partlists:  []int64{1, 2},
+			parts:      []part.ID{product, component},
+			want:       StatusAffected,
+		},
+		{
+			name:       "innermost part list wins",
+			statements: []Statement{onPartList(1, StatusNotAffected), onPartList(2, StatusUnderInvestigation)},
+			partlists:  []int64{1, 2},
+			parts:      []part.ID{component},
+			want:       StatusUnderInvestigation,
+		},
+		{
+			name:       "parent part list applies",
+			statements: []Statement{onPartList(1, StatusNotAffected)},
+			partlists:  []int64{1, 2},
+			parts:      []part.ID{component},
+			want:       StatusNotAffected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Resolve(tt.statements, tt.partlists, tt.parts)
+			var status string
+			if got != nil {
+				status = got.Status
+			}
+			if status != tt.want {
+				t.Errorf("Resolve() = %v, want %v", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVEX(t *testing.T) {
+	tests := []struct {
+		name          string
+		document      string
+		format        string
+		vulnerability string
+		status        string
+		justification string
+		products      []string
+	}{
+		{
+			name: "openvex",
+			document: `{"@context": "https://openvex.dev/ns/v0.2.0", "@id": "urn:uuid:1", "author": "a", "version": 1,
+				"statements": [{"vulnerability": {"name": "CVE-2023-0001"}, "products": [{"@id": "urn:uuid:8c0b1f6e-9a4a-4f6e-8b8e-0d0f5a1f2b3c", "identifiers": {"purl": "pkg:generic/zlib@1.2.13"}}],
+				"status": "not_affected", "justification": "vulnerable_code_not_in_execute_path"}]}`,
+			format:        FormatOpenVEX,
+			vulnerability: "CVE-2023-0001",
+			status:        StatusNotAffected,
+			justification: JustificationVulnerableCodeNotInExecutePath,
+			products:      []string{"urn:uuid:8c0b1f6e-9a4a-4f6e-8b8e-0d0f5a1f2b3c", "pkg:generic/zlib@1.2.13"},
+		},
+		{
+			name: "openvex v0.0 strings",
+			document: `{"@context": "https://openvex.dev/ns", "statements": [{"vulnerability": "CVE-2023-0002",
+				"products": ["urn:tk:partlist:4"], "status": "fixed"}]}`,
+			format:        FormatOpenVEX,
+			vulnerability: "CVE-2023-0002",
+			status:        StatusFixed,
+			products:      []string{"urn:tk:partlist:4"},
+		},
+		{
+			name: "cyclonedx",
+			document: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1,
+				"components": [{"type": "library", "bom-ref": "zlib", "name": "zlib", "purl": "pkg:generic/zlib@1.2.13"}],
+				"vulnerabilities": [{"id": "CVE-2023-0003", "analysis": {"state": "not_affected", "justification": "code_not_present", "detail": "no inflate"},
+				"affects": [{"ref": "urn:cdx:3e671687-395b-41f5-a30f-a58921a69b79/1#zlib"}]}]}`,
+			format:        FormatCycloneDX,
+			vulnerability: "CVE-2023-0003",
+			status:        StatusNotAffected,
+			justification: JustificationVulnerableCodeNotPresent,
+			products:      []string{"zlib", "pkg:generic/zlib@1.2.13"},
+		},
+		{
+			name: "cyclonedx exploitable",
+			document: `{"bomFormat": "CycloneDX", "specVersion": "1.4", "version": 1,
+				"vulnerabilities": [{"id": "CVE-2023-0004", "analysis": {"state": "exploitable"}, "affects": [{"ref": "urn:uuid:8c0b1f6e-9a4a-4f6e-8b8e-0d0f5a1f2b3c"}]}]}`,
+			format:        FormatCycloneDX,
+			vulnerability: "CVE-2023-0004",
+			status:        StatusAffected,
+			products:      []string{"urn:uuid:8c0b1f6e-9a4a-4f6e-8b8e-0d0f5a1f2b3c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, format, err := parseVEX([]byte(tt.document))
+			if err != nil {
+				t.Fatalf("parseVEX() error = %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("parseVEX() format = %v, want %v", format, tt.format)
+			}
+			if len(statements) != 1 {
+				t.Fatalf("parseVEX() = %d statements, want 1", len(statements))
+			}
+
+			got := statements[0]
+			if got.VulnerabilityID != tt.vulnerability || got.Status != tt.status || got.Justification.String != tt.justification {
+				t.Errorf("parseVEX() = %s %s %s, want %s %s %s", got.VulnerabilityID, got.Status, got.Justification.String,
+					tt.vulnerability, tt.status, tt.justification)
+			}
+			if len(got.Products) != len(tt.products) {
+				t.Fatalf("parseVEX() products = %v, want %v", got.Products, tt.products)
+			}
+			for i := range got.Products {
+				if got.Products[i] != tt.products[i] {
+					t.Errorf("parseVEX() products = %v, want %v", got.Products, tt.products)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package graphql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	errWrapper "github.com/pkg/errors"
+
+	"wrs/tk/packages/graphql/model"
+	"wrs/tk/packages/middleware"
+)
+
+// HasRole implements the @hasRole directive, resolving the field only when
+// the identity of the request holds the role
+func HasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role model.Role) (interface{}, error) {
+	name := strings.ToLower(role.String())
+	if identity := middleware.GetIdentity(ctx); identity == nil {
+		return nil, errWrapper.Errorf("%s role required", name)
+	} else if !identity.HasRole(name) {
+		return nil, errWrapper.Errorf("%s does not have the %s role", identity.Name, name)
+	}
+	return next(ctx)
+}
@@ -15,6 +15,9 @@ type Archive struct {
 	Name       string    `json:"name"`
 	InsertDate time.Time `json:"insert_date"`
 	// Extracted  bool      `json:"extract_status"`
+	Sha512       []byte `json:"sha512"`
+	GitoidSha1   []byte `json:"gitoid_sha1"`
+	GitoidSha256 []byte `json:"gitoid_sha256"`
 }
 
 func ToArchive(a *archive.Archive) Archive {
@@ -26,6 +29,9 @@ func ToArchive(a *archive.Archive) Archive {
 		Sha1:       a.Sha1,
 		InsertDate: a.InsertDate,
 		// Extracted?
+		Sha512:       a.Sha512,
+		GitoidSha1:   a.GitoidSha1,
+		GitoidSha256: a.GitoidSha256,
 	}
 
 	if len(a.Aliases) > 0 {
@@ -34,3 +40,32 @@ func ToArchive(a *archive.Archive) Archive {
 
 	return ret
 }
+
+type File struct {
+	Sha256       [32]byte `json:"sha256"`
+	Size         int64    `json:"size"`
+	Md5          []byte   `json:"md5"`
+	Sha1         []byte   `json:"sha1"`
+	Sha512       []byte   `json:"sha512"`
+	GitoidSha1   []byte   `json:"gitoid_sha1"`
+	GitoidSha256 []byte   `json:"gitoid_sha256"`
+	Names        []string `json:"names"`
+}
+
+func ToFile(f *archive.CatalogedFile) File {
+	ret := File{
+		Sha256:       f.Sha256,
+		Size:         f.Size,
+		Md5:          f.Md5,
+		Sha1:         f.Sha1,
+		Sha512:       f.Sha512,
+		GitoidSha1:   f.GitoidSha1,
+		GitoidSha256: f.GitoidSha256,
+		Names:        f.Names,
+	}
+	if ret.Names == nil {
+		ret.Names = make([]string, 0)
+	}
+
+	return ret
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+	"wrs/tk/packages/core/bundle"
+)
+
+type SourceBundle struct {
+	ID         int64     `json:"id"`
+	PartListID *int64    `json:"partlist_id"`
+	Licenses   []string  `json:"licenses"`
+	Status     string    `json:"status"`
+	Size       int64     `json:"size"`
+	Error      *string   `json:"error"`
+	InsertDate time.Time `json:"insert_date"`
+	UpdateDate time.Time `json:"update_date"`
+}
+
+func ToSourceBundle(b *bundle.Bundle) SourceBundle {
+	ret := SourceBundle{
+		ID:         b.ID,
+		Licenses:   b.Licenses,
+		Status:     b.Status,
+		Size:       b.Size,
+		InsertDate: b.InsertDate,
+		UpdateDate: b.UpdateDate,
+	}
+
+	if b.PartListID.Valid {
+		ret.PartListID = &b.PartListID.Int64
+	}
+	if ret.Licenses == nil {
+		ret.Licenses = make([]string, 0)
+	}
+	if b.Error.Valid {
+		ret.Error = &b.Error.String
+	}
+
+	return ret
+}
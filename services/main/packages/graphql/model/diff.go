@@ -0,0 +1,76 @@
+package model
+
+import (
+	"encoding/hex"
+	"wrs/tk/packages/core/diff"
+)
+
+type PartDiff struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Added     int64          `json:"added"`
+	Removed   int64          `json:"removed"`
+	Modified  int64          `json:"modified"`
+	Renamed   int64          `json:"renamed"`
+	Unchanged int64          `json:"unchanged"`
+	Files     []*FileDiff    `json:"files"`
+	Licenses  []*LicenseDiff `json:"licenses"`
+}
+
+type FileDiff struct {
+	Status    string  `json:"status"`
+	Path      string  `json:"path"`
+	OldPath   *string `json:"old_path"`
+	OldSha256 *string `json:"old_sha256"`
+	NewSha256 *string `json:"new_sha256"`
+	OldSize   *int64  `json:"old_size"`
+	NewSize   *int64  `json:"new_size"`
+	Diff      *string `json:"diff"`
+}
+
+type LicenseDiff struct {
+	Path string  `json:"path"`
+	Old  *string `json:"old"`
+	New  *string `json:"new"`
+}
+
+func ToPartDiff(d *diff.Diff) PartDiff {
+	ret := PartDiff{
+		From:      d.From.String(),
+		To:        d.To.String(),
+		Unchanged: d.Unchanged,
+		Files:     make([]*FileDiff, 0, len(d.Files)),
+		Licenses:  make([]*LicenseDiff, 0, len(d.Licenses)),
+	}
+
+	for _, f := range d.Files {
+		switch f.Status {
+		case diff.StatusAdded:
+			ret.Added++
+		case diff.StatusRemoved:
+			ret.Removed++
+		case diff.StatusModified:
+			ret.Modified++
+		case diff.StatusRenamed:
+			ret.Renamed++
+		}
+
+		file := FileDiff{Status: f.Status, Path: f.Path, Diff: f.Text}
+		if f.Old != nil {
+			path, sha256, size := f.Old.Path, hex.EncodeToString(f.Old.Sha256), f.Old.Size
+			file.OldPath, file.OldSha256, file.OldSize = &path, &sha256, &size
+		}
+		if f.New != nil {
+			sha256, size := hex.EncodeToString(f.New.Sha256), f.New.Size
+			file.NewSha256, file.NewSize = &sha256, &size
+		}
+
+		ret.Files = append(ret.Files, &file)
+	}
+
+	for _, l := range d.Licenses {
+		ret.Licenses = append(ret.Licenses, &LicenseDiff{Path: l.Path, Old: l.Old, New: l.New})
+	}
+
+	return ret
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"wrs/tk/packages/core/family"
+)
+
+type VersionFamily struct {
+	Name           string  `json:"name"`
+	Scheme         string  `json:"scheme"`
+	SchemeInferred bool    `json:"scheme_inferred"`
+	Versions       []*Part `json:"versions"`
+	Latest         *Part   `json:"latest"`
+}
+
+func ToVersionFamily(f *family.Family) VersionFamily {
+	ret := VersionFamily{
+		Name:           f.Name,
+		Scheme:         f.Scheme,
+		SchemeInferred: !f.SchemeIsSet,
+		Versions:       make([]*Part, 0, len(f.Parts)),
+	}
+	for i := range f.Parts {
+		p := ToPart(&f.Parts[i])
+		ret.Versions = append(ret.Versions, &p)
+	}
+	if len(ret.Versions) > 0 {
+		ret.Latest = ret.Versions[len(ret.Versions)-1]
+	}
+
+	return ret
+}
+
+type OutdatedPart struct {
+	PartList   *PartList `json:"partlist"`
+	Part       *Part     `json:"part"`
+	FamilyName string    `json:"family_name"`
+	Latest     *Part     `json:"latest"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+	"wrs/tk/packages/core/audit"
+)
+
+type Revision struct {
+	Revision int64          `json:"revision"`
+	Action   string         `json:"action"`
+	Actor    string         `json:"actor"`
+	Date     time.Time      `json:"date"`
+	Changes  []*FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	Field string  `json:"field"`
+	Key   *string `json:"key"`
+	Title *string `json:"title"`
+	Old   *string `json:"old"`
+	New   *string `json:"new"`
+}
+
+func ToRevision(e *audit.Entry) Revision {
+	ret := Revision{
+		Revision: e.Revision,
+		Action:   e.Action,
+		Actor:    e.Actor,
+		Date:     e.InsertDate,
+		Changes:  make([]*FieldChange, 0, len(e.Changes)),
+	}
+
+	for _, c := range e.Changes {
+		change := FieldChange{Field: c.Field, Title: c.Title, Old: c.Old, New: c.New}
+		if c.Key != "" {
+			key := c.Key
+			change.Key = &key
+		}
+
+		ret.Changes = append(ret.Changes, &change)
+	}
+
+	return ret
+}
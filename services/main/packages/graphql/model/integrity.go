@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/hex"
+
+	"wrs/tk/packages/core/part"
+)
+
+type IntegrityReport struct {
+	Cycles     [][]string         `json:"cycles"`
+	Relations  []*RelationProblem `json:"relations"`
+	Mismatches []*CodeMismatch    `json:"mismatches"`
+}
+
+type RelationProblem struct {
+	Table   string `json:"table"`
+	Key     string `json:"key"`
+	Problem string `json:"problem"`
+}
+
+type CodeMismatch struct {
+	PartID   string `json:"part_id"`
+	Stored   string `json:"stored"`
+	Computed string `json:"computed"`
+}
+
+func ToIntegrityReport(r *part.IntegrityReport) IntegrityReport {
+	ret := IntegrityReport{
+		Cycles:     make([][]string, 0, len(r.Cycles)),
+		Relations:  make([]*RelationProblem, 0, len(r.Relations)),
+		Mismatches: make([]*CodeMismatch, 0, len(r.Mismatches)),
+	}
+	for _, cycle := range r.Cycles {
+		ids := make([]string, 0, len(cycle))
+		for _, id := range cycle {
+			ids = append(ids, id.String())
+		}
+		ret.Cycles = append(ret.Cycles, ids)
+	}
+	for _, v := range r.Relations {
+		ret.Relations = append(ret.Relations, &RelationProblem{Table: v.Table, Key: v.Key, Problem: v.Problem})
+	}
+	for _, v := range r.Mismatches {
+		mismatch := ToCodeMismatch(v)
+		ret.Mismatches = append(ret.Mismatches, &mismatch)
+	}
+
+	return ret
+}
+
+func ToCodeMismatch(m part.CodeMismatch) CodeMismatch {
+	return CodeMismatch{
+		PartID:   m.PartID.String(),
+		Stored:   hex.EncodeToString(m.Stored),
+		Computed: hex.EncodeToString(m.Computed),
+	}
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"wrs/tk/packages/core/license"
+)
+
+type License struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	SpdxID *string `json:"spdx_id"`
+	Custom bool    `json:"custom"`
+	Record Json    `json:"record"`
+	Text   *string `json:"text"`
+}
+
+func ToLicense(l *license.License) License {
+	ret := License{
+		ID:     l.LicenseID,
+		Name:   l.Name,
+		Custom: l.Custom,
+		Record: Json(l.Record),
+	}
+
+	if l.SpdxID.Valid {
+		ret.SpdxID = &l.SpdxID.String
+	}
+	if l.Text.Valid {
+		ret.Text = &l.Text.String
+	}
+	if len(ret.Record) == 0 {
+		ret.Record = Json("{}")
+	}
+
+	return ret
+}
+
+type LicenseObligation struct {
+	Obligation  string  `json:"obligation"`
+	Description *string `json:"description"`
+}
+
+func ToLicenseObligation(o *license.Obligation) LicenseObligation {
+	ret := LicenseObligation{
+		Obligation: o.Obligation,
+	}
+
+	if o.Description.Valid {
+		ret.Description = &o.Description.String
+	}
+
+	return ret
+}
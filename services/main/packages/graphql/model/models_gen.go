@@ -2,6 +2,18 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type AncestorPart struct {
+	Part  *Part  `json:"part"`
+	Depth int    `json:"depth"`
+	Path  string `json:"path"`
+}
+
 type ArchiveDistance struct {
 	Distance int64    `json:"distance"`
 	Archive  *Archive `json:"archive"`
@@ -12,6 +24,26 @@ type Document struct {
 	Document Json    `json:"document"`
 }
 
+type EmbeddedPart struct {
+	Path   string `json:"path"`
+	Part   *Part  `json:"part"`
+	Linked bool   `json:"linked"`
+}
+
+type FilePart struct {
+	Path string `json:"path"`
+	Part *Part  `json:"part"`
+}
+
+type NewLicenseInput struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	SpdxID  *string  `json:"spdx_id"`
+	Record  Json     `json:"record"`
+	Text    *string  `json:"text"`
+	Aliases []string `json:"aliases"`
+}
+
 type NewPartInput struct {
 	Type             *string `json:"type"`
 	Name             *string `json:"name"`
@@ -24,6 +56,17 @@ type NewPartInput struct {
 	Comprised        *string `json:"comprised"`
 }
 
+type NewPolicyInput struct {
+	Name        string             `json:"name"`
+	Description *string            `json:"description"`
+	Rules       []*PolicyRuleInput `json:"rules"`
+}
+
+type ParentPart struct {
+	Path string `json:"path"`
+	Part *Part  `json:"part"`
+}
+
 type PartInput struct {
 	ID                   string  `json:"id"`
 	Type                 *string `json:"type"`
@@ -38,6 +81,13 @@ type PartInput struct {
 	Comprised            *string `json:"comprised"`
 }
 
+type PolicyRuleInput struct {
+	Type           string   `json:"type"`
+	Licenses       []string `json:"licenses"`
+	ParentLicenses []string `json:"parent_licenses"`
+	Message        *string  `json:"message"`
+}
+
 type Profile struct {
 	Key       string      `json:"key"`
 	Documents []*Document `json:"documents"`
@@ -59,3 +109,58 @@ type UploadedArchive struct {
 	Extracted bool     `json:"extracted"`
 	Archive   *Archive `json:"archive"`
 }
+
+type VexStatementInput struct {
+	VulnerabilityID string  `json:"vulnerability_id"`
+	PartID          *string `json:"part_id"`
+	PartlistID      *int64  `json:"partlist_id"`
+	Status          string  `json:"status"`
+	Justification   *string `json:"justification"`
+	ImpactStatement *string `json:"impact_statement"`
+	ActionStatement *string `json:"action_statement"`
+}
+
+type Role string
+
+const (
+	RoleViewer   Role = "VIEWER"
+	RoleCurator  Role = "CURATOR"
+	RoleUploader Role = "UPLOADER"
+	RoleAdmin    Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleViewer,
+	RoleCurator,
+	RoleUploader,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleViewer, RoleCurator, RoleUploader, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -1,8 +1,6 @@
 package model
 
 import (
-	"fmt"
-	"reflect"
 	"strings"
 	"unicode"
 	"wrs/tk/packages/core/part"
@@ -18,6 +16,8 @@ type Part struct {
 	Label                      string   `json:"label"`
 	FamilyName                 string   `json:"family_name"`
 	FileVerificationCode       []byte   `json:"file_verification_code"`
+	FileVerificationCodeOne    []byte   `json:"file_verification_code_one"`
+	GitTreeSha1                []byte   `json:"git_tree_sha1"`
 	Size                       int64    `json:"size"`
 	License                    *string  `json:"license"`
 	LicenseRationale           *string  `json:"license_rationale"`
@@ -30,18 +30,20 @@ type Part struct {
 
 func ToPart(p *part.Part) Part {
 	ret := Part{
-		ID:                   p.PartID,
-		Type:                 p.Type.String,
-		Name:                 p.Name.String,
-		Version:              p.Version.String,
-		Label:                p.Label.String,
-		FamilyName:           p.FamilyName.String,
-		FileVerificationCode: p.FileVerificationCode,
-		Size:                 p.Size.Int64,
-		License:              &p.License.String,
-		LicenseRationale:     &p.LicenseRationale.String,
-		Description:          p.Description.String,
-		Comprised:            &p.Comprised,
+		ID:                      p.PartID,
+		Type:                    p.Type.String,
+		Name:                    p.Name.String,
+		Version:                 p.Version.String,
+		Label:                   p.Label.String,
+		FamilyName:              p.FamilyName.String,
+		FileVerificationCode:    p.FileVerificationCode,
+		FileVerificationCodeOne: p.FileVerificationCodeOne,
+		GitTreeSha1:             p.GitTreeSha1,
+		Size:                    p.Size.Int64,
+		License:                 &p.License.String,
+		LicenseRationale:        &p.LicenseRationale.String,
+		Description:             p.Description.String,
+		Comprised:               &p.Comprised,
 	}
 
 	if p.License.Valid {
@@ -51,8 +53,29 @@ func ToPart(p *part.Part) Part {
 	return ret
 }
 
+type PartIdentifier struct {
+	Type    string  `json:"type"`
+	Value   string  `json:"value"`
+	Name    string  `json:"name"`
+	Version *string `json:"version"`
+}
+
+func ToPartIdentifier(i *part.Identifier) PartIdentifier {
+	ret := PartIdentifier{
+		Type:  i.Type,
+		Value: i.Value,
+		Name:  i.Name,
+	}
+
+	if i.Version.Valid {
+		ret.Version = &i.Version.String
+	}
+
+	return ret
+}
+
 // TypeToLTree converts a part type, which is styled like a file path, into a PostgreSQL ltree
-// It also validates to make sure it is an accepted type.
+// It also validates to make sure it is a registered type, see part.Types.
 func TypeToLTree(partType string) (string, error) {
 	if partType == "" {
 		return "", errors.New("empty part type")
@@ -87,53 +110,40 @@ func TypeToLTree(partType string) (string, error) {
 		return "", errors.New("empty part type")
 	}
 
-	// validate components
-	root := components[0]
-	subTypes := components[1:]
-	switch root {
-	case "file":
-		switch {
-		case len(subTypes) == 0: // file
-			return "", errors.New("file expects a sub-type")
-		case reflect.DeepEqual(subTypes, []string{"source"}): // file/source
-		case reflect.DeepEqual(subTypes, []string{"binary"}): // file/binary
-		case subTypes[0] == "custom" && len(subTypes) > 1: // file/custom/*
-		default:
-			return "", errors.New(fmt.Sprintf("unsupported sub-type for file: %s", strings.Join(subTypes, "/")))
-		}
-	case "archive":
-		switch {
-		case len(subTypes) == 0: // archive
-		case subTypes[0] == "custom" && len(subTypes) > 1: // archive/custom/*
-			fmt.Printf("subTypes: %#v\n", subTypes)
-		default:
-			return "", errors.New(fmt.Sprintf("unsupported sub-type for archive: %s", strings.Join(subTypes, "/")))
-		}
-	case "container":
-		switch {
-		case len(subTypes) == 0: // container
-			return "", errors.New("container expects a sub-type")
-		case reflect.DeepEqual(subTypes, []string{"image"}): // container/image
-		case reflect.DeepEqual(subTypes, []string{"source"}): // container/source
-		case subTypes[0] == "custom" && len(subTypes) > 1: // container/custom/*
-		default:
-			return "", errors.New(fmt.Sprintf("unsupported sub-type for container: %s", strings.Join(subTypes, "/")))
-		}
-	case "logical":
-		switch {
-		case len(subTypes) == 0: // logical
-		case subTypes[0] == "custom" && len(subTypes) > 1: // logical/custom/*
-		default:
-			return "", errors.New(fmt.Sprintf("unsupported sub-type for logical: %s", strings.Join(subTypes, "/")))
-		}
-	default:
-		return "", errors.New(fmt.Sprintf("unxpected root %s", components[0]))
+	lTree := strings.Join(components, ".")
+	if _, err := part.LookupType(lTree); err != nil {
+		return "", err
 	}
 
-	return strings.Join(components, "."), nil
+	return lTree, nil
 }
 
 // Re-stylizes an ltree like a filepath
 func LTreeToPath(lTree string) string {
 	return "/" + strings.ReplaceAll(lTree, ".", "/")
 }
+
+type PartType struct {
+	Type           string   `json:"type"`
+	Description    *string  `json:"description"`
+	ComprisedOf    string   `json:"comprised_of"`
+	RequiredFields []string `json:"required_fields"`
+	Custom         bool     `json:"custom"`
+}
+
+func ToPartType(t part.Type) PartType {
+	ret := PartType{
+		Type:           LTreeToPath(t.Type),
+		ComprisedOf:    t.ComprisedOf,
+		RequiredFields: t.Required,
+		Custom:         t.Custom,
+	}
+	if ret.RequiredFields == nil {
+		ret.RequiredFields = []string{}
+	}
+	if t.Description != "" {
+		ret.Description = &t.Description
+	}
+
+	return ret
+}
@@ -84,6 +84,18 @@ func TestTypeToLTree(t *testing.T) {
 			want:    "",
 			wantErr: true,
 		},
+		{
+			name:    "part type",
+			args:    args{partType: "/part/file/binary/library"},
+			want:    "part.file.binary.library",
+			wantErr: false,
+		},
+		{
+			name:    "part type beneath a registered type",
+			args:    args{partType: "/part/file/binary/library/static"},
+			want:    "",
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
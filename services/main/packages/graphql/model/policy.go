@@ -0,0 +1,96 @@
+package model
+
+import (
+	"wrs/tk/packages/core/policy"
+)
+
+type LicensePolicy struct {
+	ID          int64                `json:"id"`
+	Name        string               `json:"name"`
+	Description *string              `json:"description"`
+	Rules       []*LicensePolicyRule `json:"rules"`
+}
+
+type LicensePolicyRule struct {
+	Type           string   `json:"type"`
+	Licenses       []string `json:"licenses"`
+	ParentLicenses []string `json:"parent_licenses"`
+	Message        *string  `json:"message"`
+}
+
+type PolicyViolation struct {
+	Rule     *LicensePolicyRule `json:"rule"`
+	PartID   string             `json:"part_id"`
+	License  string             `json:"license"`
+	Path     []string           `json:"path"`
+	ParentID *string            `json:"parent_id"`
+}
+
+type PolicyCheck struct {
+	Policy     *LicensePolicy     `json:"policy"`
+	Passed     bool               `json:"passed"`
+	Violations []*PolicyViolation `json:"violations"`
+}
+
+func ToLicensePolicyRule(r policy.Rule) *LicensePolicyRule {
+	ret := LicensePolicyRule{
+		Type:           r.Type,
+		Licenses:       r.Licenses,
+		ParentLicenses: r.ParentLicenses,
+	}
+
+	if ret.Licenses == nil {
+		ret.Licenses = make([]string, 0)
+	}
+	if r.Message != "" {
+		ret.Message = &r.Message
+	}
+
+	return &ret
+}
+
+func ToLicensePolicy(p *policy.Policy) LicensePolicy {
+	ret := LicensePolicy{
+		ID:    p.ID,
+		Name:  p.Name,
+		Rules: make([]*LicensePolicyRule, 0, len(p.Rules)),
+	}
+
+	if p.Description.Valid {
+		ret.Description = &p.Description.String
+	}
+	for _, v := range p.Rules {
+		ret.Rules = append(ret.Rules, ToLicensePolicyRule(v))
+	}
+
+	return ret
+}
+
+func ToPolicyCheck(r *policy.Result) PolicyCheck {
+	p := ToLicensePolicy(&r.Policy)
+	ret := PolicyCheck{
+		Policy:     &p,
+		Passed:     r.Passed(),
+		Violations: make([]*PolicyViolation, 0, len(r.Violations)),
+	}
+
+	for _, v := range r.Violations {
+		violation := PolicyViolation{
+			Rule:    ToLicensePolicyRule(v.Rule),
+			PartID:  v.PartID.String(),
+			License: v.License,
+			Path:    make([]string, 0, len(v.Path)),
+		}
+		for _, id := range v.Path {
+			violation.Path = append(violation.Path, id.String())
+		}
+		if v.ParentID != nil {
+			parentID := v.ParentID.String()
+			violation.ParentID = &parentID
+		}
+
+		ret.Violations = append(ret.Violations, &violation)
+	}
+
+	return ret
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"wrs/tk/packages/core/part"
+
+	"github.com/pkg/errors"
+)
+
+type PartRelationship struct {
+	PartID     part.ID
+	Type       string `json:"type"`
+	RelatedID  part.ID
+	Comment    *string   `json:"comment"`
+	Metadata   Json      `json:"metadata"`
+	InsertDate time.Time `json:"insert_date"`
+}
+
+func ToPartRelationship(r part.Relationship) PartRelationship {
+	ret := PartRelationship{
+		PartID:     r.PartID,
+		Type:       r.Type,
+		RelatedID:  r.RelatedID,
+		InsertDate: r.InsertDate,
+	}
+	if r.Comment.Valid {
+		ret.Comment = &r.Comment.String
+	}
+	if r.Metadata.Valid {
+		ret.Metadata = Json(r.Metadata.String)
+	}
+
+	return ret
+}
+
+type PartEdge struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Type  string  `json:"type"`
+	Path  *string `json:"path"`
+	Depth int     `json:"depth"`
+}
+
+func ToPartEdge(e part.Edge) PartEdge {
+	ret := PartEdge{
+		From:  e.From.String(),
+		To:    e.To.String(),
+		Type:  e.Type,
+		Depth: e.Depth,
+	}
+	if e.Type == part.EdgeContains {
+		ret.Path = &e.Path
+	}
+
+	return ret
+}
+
+// IsIncoming parses the direction of relationships and graph traversals, outgoing if not given
+func IsIncoming(direction *string) (bool, error) {
+	if direction == nil {
+		return false, nil
+	}
+
+	switch *direction {
+	case "outgoing":
+		return false, nil
+	case "incoming":
+		return true, nil
+	}
+
+	return false, errors.Errorf("invalid direction %s, expected outgoing or incoming", *direction)
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+	"wrs/tk/packages/core/review"
+
+	"github.com/google/uuid"
+)
+
+type Review struct {
+	PartID      uuid.UUID  `json:"-"`
+	State       string     `json:"state"`
+	RequestedBy *string    `json:"requested_by"`
+	RequestDate *time.Time `json:"request_date"`
+	Reviewer    *string    `json:"reviewer"`
+	ReviewDate  *time.Time `json:"review_date"`
+	Comment     *string    `json:"comment"`
+}
+
+type ReviewEvent struct {
+	State   string    `json:"state"`
+	Actor   string    `json:"actor"`
+	Comment *string   `json:"comment"`
+	Date    time.Time `json:"date"`
+}
+
+func ToReview(r *review.Review) Review {
+	ret := Review{PartID: r.PartID, State: r.State}
+	if r.RequestedBy.Valid {
+		ret.RequestedBy = &r.RequestedBy.String
+	}
+	if r.RequestDate.Valid {
+		ret.RequestDate = &r.RequestDate.Time
+	}
+	if r.Reviewer.Valid {
+		ret.Reviewer = &r.Reviewer.String
+	}
+	if r.ReviewDate.Valid {
+		ret.ReviewDate = &r.ReviewDate.Time
+	}
+	if r.Comment.Valid {
+		ret.Comment = &r.Comment.String
+	}
+
+	return ret
+}
+
+func ToReviewEvent(e *review.Event) ReviewEvent {
+	ret := ReviewEvent{State: e.State, Actor: e.Actor, Date: e.InsertDate}
+	if e.Comment.Valid {
+		ret.Comment = &e.Comment.String
+	}
+
+	return ret
+}
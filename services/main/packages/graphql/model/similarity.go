@@ -0,0 +1,27 @@
+package model
+
+import (
+	"wrs/tk/packages/core/similarity"
+)
+
+type SimilarPart struct {
+	Part               *Part   `json:"part"`
+	Similarity         float64 `json:"similarity"`
+	Containment        float64 `json:"containment"`
+	ReverseContainment float64 `json:"reverse_containment"`
+	SharedFiles        int64   `json:"shared_files"`
+	UniqueFiles        int64   `json:"unique_files"`
+	SimilarUniqueFiles int64   `json:"similar_unique_files"`
+}
+
+func ToSimilarPart(m similarity.Match, p *Part) SimilarPart {
+	return SimilarPart{
+		Part:               p,
+		Similarity:         m.Jaccard,
+		Containment:        m.Containment,
+		ReverseContainment: m.ReverseContainment,
+		SharedFiles:        m.Shared,
+		UniqueFiles:        m.Unique,
+		SimilarUniqueFiles: m.MatchUnique,
+	}
+}
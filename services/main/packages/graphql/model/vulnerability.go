@@ -0,0 +1,139 @@
+package model
+
+import (
+	"time"
+	"wrs/tk/packages/core/vulnerability"
+)
+
+type Vulnerability struct {
+	ID        string     `json:"id"`
+	Summary   *string    `json:"summary"`
+	Details   *string    `json:"details"`
+	Severity  Json       `json:"severity"`
+	Published *time.Time `json:"published"`
+	Modified  *time.Time `json:"modified"`
+	Withdrawn *time.Time `json:"withdrawn"`
+	Record    Json       `json:"record"`
+}
+
+type PartVulnerability struct {
+	Vulnerability Vulnerability `json:"vulnerability"`
+	Match         string        `json:"match"`
+	Detail        *string       `json:"detail"`
+	Statement     *VexStatement `json:"statement"`
+}
+
+type VexStatement struct {
+	ID              int64     `json:"id"`
+	VulnerabilityID string    `json:"vulnerability_id"`
+	PartID          *string   `json:"part_id"`
+	PartListID      *int64    `json:"partlist_id"`
+	Status          string    `json:"status"`
+	Justification   *string   `json:"justification"`
+	ImpactStatement *string   `json:"impact_statement"`
+	ActionStatement *string   `json:"action_statement"`
+	InsertDate      time.Time `json:"insert_date"`
+	UpdateDate      time.Time `json:"update_date"`
+}
+
+type VulnerabilityFinding struct {
+	PartID        string        `json:"part_id"`
+	PartListID    *int64        `json:"partlist_id"`
+	Path          []string      `json:"path"`
+	Vulnerability Vulnerability `json:"vulnerability"`
+	Match         string        `json:"match"`
+	Detail        *string       `json:"detail"`
+	Statement     *VexStatement `json:"statement"`
+}
+
+func ToVulnerability(v *vulnerability.Vulnerability) Vulnerability {
+	ret := Vulnerability{
+		ID:       v.ID,
+		Severity: Json(v.Severity),
+		Record:   Json(v.Record),
+	}
+
+	if v.Summary.Valid {
+		ret.Summary = &v.Summary.String
+	}
+	if v.Details.Valid {
+		ret.Details = &v.Details.String
+	}
+	if v.Published.Valid {
+		ret.Published = &v.Published.Time
+	}
+	if v.Modified.Valid {
+		ret.Modified = &v.Modified.Time
+	}
+	if v.Withdrawn.Valid {
+		ret.Withdrawn = &v.Withdrawn.Time
+	}
+
+	return ret
+}
+
+func ToPartVulnerability(v *vulnerability.PartVulnerability) PartVulnerability {
+	ret := PartVulnerability{
+		Vulnerability: ToVulnerability(&v.Vulnerability),
+		Match:         v.Match,
+	}
+
+	if v.Detail.Valid {
+		ret.Detail = &v.Detail.String
+	}
+	if v.Statement != nil {
+		statement := ToVexStatement(v.Statement)
+		ret.Statement = &statement
+	}
+
+	return ret
+}
+
+func ToVexStatement(s *vulnerability.Statement) VexStatement {
+	ret := VexStatement{
+		ID:              s.ID,
+		VulnerabilityID: s.VulnerabilityID,
+		Status:          s.Status,
+		InsertDate:      s.InsertDate,
+		UpdateDate:      s.UpdateDate,
+	}
+
+	if s.PartListID.Valid {
+		ret.PartListID = &s.PartListID.Int64
+	} else {
+		partID := s.PartID.String()
+		ret.PartID = &partID
+	}
+	if s.Justification.Valid {
+		ret.Justification = &s.Justification.String
+	}
+	if s.ImpactStatement.Valid {
+		ret.ImpactStatement = &s.ImpactStatement.String
+	}
+	if s.ActionStatement.Valid {
+		ret.ActionStatement = &s.ActionStatement.String
+	}
+
+	return ret
+}
+
+func ToVulnerabilityFinding(f *vulnerability.Finding) VulnerabilityFinding {
+	partVulnerability := ToPartVulnerability(&f.PartVulnerability)
+	ret := VulnerabilityFinding{
+		PartID:        f.PartID.String(),
+		Path:          make([]string, 0, len(f.Path)),
+		Vulnerability: partVulnerability.Vulnerability,
+		Match:         partVulnerability.Match,
+		Detail:        partVulnerability.Detail,
+		Statement:     partVulnerability.Statement,
+	}
+
+	if f.PartListID.Valid {
+		ret.PartListID = &f.PartListID.Int64
+	}
+	for _, id := range f.Path {
+		ret.Path = append(ret.Path, id.String())
+	}
+
+	return ret
+}
@@ -4,9 +4,16 @@ package graphql
 
 import (
 	"wrs/tk/packages/core/archive"
+	"wrs/tk/packages/core/bundle"
+	"wrs/tk/packages/core/diff"
+	"wrs/tk/packages/core/family"
 	"wrs/tk/packages/core/license"
 	"wrs/tk/packages/core/part"
 	"wrs/tk/packages/core/partlist"
+	"wrs/tk/packages/core/policy"
+	"wrs/tk/packages/core/review"
+	"wrs/tk/packages/core/similarity"
+	"wrs/tk/packages/core/vulnerability"
 )
 
 // This file will not be regenerated automatically.
@@ -14,8 +21,16 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	ArchiveController  *archive.ArchiveController
-	PartController     *part.PartController
-	LicenseController  *license.LicenseController
-	PartListController *partlist.PartListController
+	ArchiveController       *archive.ArchiveController
+	PartController          *part.PartController
+	Verifier                *part.Verifier
+	LicenseController       *license.LicenseController
+	PartListController      *partlist.PartListController
+	PolicyController        *policy.PolicyController
+	BundleController        *bundle.BundleController
+	VulnerabilityController *vulnerability.VulnerabilityController
+	ReviewController        *review.ReviewController
+	DiffController          *diff.DiffController
+	SimilarityController    *similarity.SimilarityController
+	FamilyController        *family.FamilyController
 }
@@ -8,15 +8,25 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"io"
 	"os"
+	"wrs/tk/packages/checksum/gitoid"
 	"wrs/tk/packages/core/archive"
+	"wrs/tk/packages/core/audit"
+	"wrs/tk/packages/core/bundle"
+	"wrs/tk/packages/core/family"
+	"wrs/tk/packages/core/license"
 	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
+	"wrs/tk/packages/core/policy"
+	"wrs/tk/packages/core/review"
+	"wrs/tk/packages/core/similarity"
+	"wrs/tk/packages/core/vulnerability"
 	"wrs/tk/packages/editDistance"
 	"wrs/tk/packages/generics"
 	"wrs/tk/packages/graphql/generated"
 	"wrs/tk/packages/graphql/model"
+	"wrs/tk/packages/identifier"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/google/uuid"
@@ -67,10 +77,166 @@ func (r *archiveResolver) Sha1(ctx context.Context, obj *model.Archive) (*string
 	return &ret, nil
 }
 
+// Sha512 is the resolver for the sha512 field.
+func (r *archiveResolver) Sha512(ctx context.Context, obj *model.Archive) (*string, error) {
+	if len(obj.Sha512) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.Sha512)
+	return &ret, nil
+}
+
+// GitoidSha1 is the resolver for the gitoid_sha1 field.
+func (r *archiveResolver) GitoidSha1(ctx context.Context, obj *model.Archive) (*string, error) {
+	if len(obj.GitoidSha1) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.GitoidSha1)
+	return &ret, nil
+}
+
+// GitoidSha256 is the resolver for the gitoid_sha256 field.
+func (r *archiveResolver) GitoidSha256(ctx context.Context, obj *model.Archive) (*string, error) {
+	if len(obj.GitoidSha256) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.GitoidSha256)
+	return &ret, nil
+}
+
+// Swhid is the resolver for the swhid field.
+func (r *archiveResolver) Swhid(ctx context.Context, obj *model.Archive) (*string, error) {
+	if len(obj.GitoidSha1) == 0 {
+		return nil, nil
+	}
+
+	ret := gitoid.SWHID(gitoid.TypeContent, obj.GitoidSha1)
+	return &ret, nil
+}
+
+// Sha256 is the resolver for the sha256 field.
+func (r *fileResolver) Sha256(ctx context.Context, obj *model.File) (string, error) {
+	return hex.EncodeToString(obj.Sha256[:]), nil
+}
+
+// Md5 is the resolver for the md5 field.
+func (r *fileResolver) Md5(ctx context.Context, obj *model.File) (*string, error) {
+	if len(obj.Md5) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.Md5)
+	return &ret, nil
+}
+
+// Sha1 is the resolver for the sha1 field.
+func (r *fileResolver) Sha1(ctx context.Context, obj *model.File) (*string, error) {
+	if len(obj.Sha1) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.Sha1)
+	return &ret, nil
+}
+
+// Sha512 is the resolver for the sha512 field.
+func (r *fileResolver) Sha512(ctx context.Context, obj *model.File) (*string, error) {
+	if len(obj.Sha512) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.Sha512)
+	return &ret, nil
+}
+
+// GitoidSha1 is the resolver for the gitoid_sha1 field.
+func (r *fileResolver) GitoidSha1(ctx context.Context, obj *model.File) (*string, error) {
+	if len(obj.GitoidSha1) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.GitoidSha1)
+	return &ret, nil
+}
+
+// GitoidSha256 is the resolver for the gitoid_sha256 field.
+func (r *fileResolver) GitoidSha256(ctx context.Context, obj *model.File) (*string, error) {
+	if len(obj.GitoidSha256) == 0 {
+		return nil, nil
+	}
+
+	ret := hex.EncodeToString(obj.GitoidSha256)
+	return &ret, nil
+}
+
+// Swhid is the resolver for the swhid field.
+func (r *fileResolver) Swhid(ctx context.Context, obj *model.File) (*string, error) {
+	if len(obj.GitoidSha1) == 0 {
+		return nil, nil
+	}
+
+	ret := gitoid.SWHID(gitoid.TypeContent, obj.GitoidSha1)
+	return &ret, nil
+}
+
+// Parts is the resolver for the parts field.
+func (r *fileResolver) Parts(ctx context.Context, obj *model.File) ([]*model.FilePart, error) {
+	fileParts, err := r.ArchiveController.GetFileParts(obj.Sha256[:])
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[archive.FilePart, *model.FilePart](fileParts, func(fp archive.FilePart) (*model.FilePart, error) {
+		p, err := r.PartController.GetByID(fp.PartID)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part %s", fp.PartID.String())
+		}
+
+		prt := model.ToPart(p)
+		return &model.FilePart{Path: fp.Path, Part: &prt}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Aliases is the resolver for the aliases field.
+func (r *licenseResolver) Aliases(ctx context.Context, obj *model.License) ([]string, error) {
+	aliases, err := r.LicenseController.GetAliases(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// Obligations is the resolver for the obligations field.
+func (r *licenseResolver) Obligations(ctx context.Context, obj *model.License) ([]*model.LicenseObligation, error) {
+	obligations, err := r.LicenseController.GetObligations(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[license.Obligation, *model.LicenseObligation](obligations, func(o license.Obligation) (*model.LicenseObligation, error) {
+		ret := model.ToLicenseObligation(&o)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal obligations to model obligations")
+	}
+
+	return ret, nil
+}
+
 // AddPartList is the resolver for the addPartList field.
 func (r *mutationResolver) AddPartList(ctx context.Context, name string, parentID *int64) (*model.PartList, error) {
 	if parentID != nil && *parentID != 0 {
-		pl, err := r.PartListController.AddPartListWithParent(name, *parentID)
+		pl, err := r.PartListController.As(audit.GetActor(ctx)).AddPartListWithParent(name, *parentID)
 		if err != nil {
 			return nil, err
 		}
@@ -78,7 +244,7 @@ func (r *mutationResolver) AddPartList(ctx context.Context, name string, parentI
 		return &ret, nil
 	}
 	if name != "" {
-		pl, err := r.PartListController.AddPartList(name)
+		pl, err := r.PartListController.As(audit.GetActor(ctx)).AddPartList(name)
 		if err != nil {
 			return nil, err
 		}
@@ -91,7 +257,7 @@ func (r *mutationResolver) AddPartList(ctx context.Context, name string, parentI
 // DeletePartList is the resolver for the deletePartList field.
 func (r *mutationResolver) DeletePartList(ctx context.Context, id int64) (*model.PartList, error) {
 	if id != 0 {
-		pl, err := r.PartListController.DeletePartList(id)
+		pl, err := r.PartListController.As(audit.GetActor(ctx)).DeletePartList(id)
 		if err != nil {
 			return nil, err
 		}
@@ -108,7 +274,7 @@ func (r *mutationResolver) DeletePartFromList(ctx context.Context, listID int64,
 		return nil, errWrapper.Wrapf(err, "error parsing part_id \"%s\"", partUUID)
 	}
 	if listID != 0 {
-		pl, err := r.PartListController.DeletePartFromList(listID, partUUID)
+		pl, err := r.PartListController.As(audit.GetActor(ctx)).DeletePartFromList(listID, partUUID)
 		if err != nil {
 			return nil, err
 		}
@@ -171,7 +337,7 @@ func (r *mutationResolver) UploadArchive(ctx context.Context, file graphql.Uploa
 	log.Debug().Str(zerolog.CallerFieldName, "mutationResolver.UploadArchive").
 		Interface("arch", arch).Msg("processing archive in background")
 	tmpHandOff = true
-	go func(arch *archive.Archive, archiveController *archive.ArchiveController) error {
+	go func(arch *archive.Archive, archiveController *archive.ArchiveController, vulnerabilityController *vulnerability.VulnerabilityController) error {
 		defer os.Remove(arch.StoragePath.String)
 
 		if err := archiveController.Process(arch); err != nil {
@@ -179,8 +345,13 @@ func (r *mutationResolver) UploadArchive(ctx context.Context, file graphql.Uploa
 			return err
 		}
 
+		// Match the new part and its sub-parts to known vulnerabilities
+		if processed, err := archiveController.GetBySha256(arch.Sha256[:]); err == nil && processed.PartID != nil {
+			vulnerabilityController.QueueMatch(uuid.UUID(*processed.PartID))
+		}
+
 		return nil
-	}(arch, r.ArchiveController)
+	}(arch, r.ArchiveController, r.VulnerabilityController)
 
 	// Format response
 	// ret.Extracted = arch.ExtractStatus > 0
@@ -216,7 +387,7 @@ func (r *mutationResolver) UpdateArchive(ctx context.Context, sha256 string, lic
 		return nil, errWrapper.New("error getting part")
 	}
 
-	if err := r.PartController.UpdateTribalKnowledge(part.PartID, nil, nil, nil, nil, familyString, nil, license, licenseRationale, nil, nil); err != nil {
+	if err := r.PartController.As(audit.GetActor(ctx)).UpdateTribalKnowledge(part.PartID, nil, nil, nil, nil, familyString, nil, license, licenseRationale, nil, nil); err != nil {
 		return nil, errWrapper.Wrapf(err, "error updating file_collection")
 	}
 
@@ -241,7 +412,7 @@ func (r *mutationResolver) UpdatePartList(ctx context.Context, id int64, name *s
 			}
 			partIDS[i] = &partID
 		}
-		pl, err := r.PartListController.AddParts(id, partIDS)
+		pl, err := r.PartListController.As(audit.GetActor(ctx)).AddParts(id, partIDS)
 		if err != nil {
 			return nil, err
 		}
@@ -291,7 +462,7 @@ func (r *mutationResolver) UpdatePart(ctx context.Context, partInput *model.Part
 		partType = &lTree
 	}
 
-	if err := r.PartController.UpdateTribalKnowledge(p.PartID,
+	if err := r.PartController.As(audit.GetActor(ctx)).UpdateTribalKnowledge(p.PartID,
 		partType, partInput.Name, partInput.Version, partInput.Label, partInput.FamilyName,
 		rawVerificationCode, partInput.License, partInput.LicenseRationale, partInput.Description, comprised); err != nil {
 		return nil, errWrapper.Wrapf(err, "error updating part")
@@ -301,6 +472,7 @@ func (r *mutationResolver) UpdatePart(ctx context.Context, partInput *model.Part
 	if err != nil {
 		return nil, errWrapper.Wrapf(err, "error getting updated part")
 	}
+	r.VulnerabilityController.QueueMatch(uuid.UUID(p.PartID))
 
 	ret := model.ToPart(p)
 
@@ -314,7 +486,7 @@ func (r *mutationResolver) CreateAlias(ctx context.Context, id string, alias str
 		return id, errWrapper.Wrapf(err, "error parsing id")
 	}
 
-	partID, err := r.PartController.CreateAlias(part.ID(partUUID), alias)
+	partID, err := r.PartController.As(audit.GetActor(ctx)).CreateAlias(part.ID(partUUID), alias)
 	if err != nil {
 		return partID.String(), err
 	}
@@ -329,7 +501,7 @@ func (r *mutationResolver) AttachDocument(ctx context.Context, id string, key st
 		return false, errWrapper.Wrapf(err, "error parsing id")
 	}
 
-	if err := r.PartController.AttachDocument(part.ID(partUUID), key, title, json.RawMessage(document)); err != nil {
+	if err := r.PartController.As(audit.GetActor(ctx)).AttachDocument(part.ID(partUUID), key, title, json.RawMessage(document)); err != nil {
 		return false, errWrapper.Wrapf(err, "error attaching document")
 	}
 
@@ -350,13 +522,122 @@ func (r *mutationResolver) PartHasPart(ctx context.Context, parent string, child
 	if err := r.PartController.AddPartToPart(part.ID(childUUID), part.ID(parentUUID), path); err != nil {
 		return false, err
 	}
+	r.VulnerabilityController.QueueMatch(childUUID)
+	if err := r.SimilarityController.Update(parentUUID); err != nil {
+		log.Error().Str(zerolog.CallerFieldName, "mutationResolver.PartHasPart").Err(err).Msg("error updating similarity signature")
+	}
+
+	return true, nil
+}
+
+// AddRelationship is the resolver for the addRelationship field.
+func (r *mutationResolver) AddRelationship(ctx context.Context, partID string, typeArg string, relatedID string, comment *string, metadata model.Json) (*model.PartRelationship, error) {
+	partUUID, err := uuid.Parse(partID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing part_id")
+	}
+	relatedUUID, err := uuid.Parse(relatedID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing related_id")
+	}
+
+	if err := r.PartController.As(audit.GetActor(ctx)).AddRelationship(part.ID(partUUID), typeArg, part.ID(relatedUUID), comment, json.RawMessage(metadata)); err != nil {
+		return nil, errWrapper.Wrapf(err, "error adding relationship")
+	}
+
+	relationships, err := r.PartController.Relationships(part.ID(partUUID), false, []string{typeArg})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting relationship")
+	}
+	for _, relationship := range relationships {
+		if relationship.RelatedID == part.ID(relatedUUID) {
+			ret := model.ToPartRelationship(relationship)
+			return &ret, nil
+		}
+	}
+
+	return nil, errWrapper.Errorf("relationship %s %s %s not found", partID, typeArg, relatedID)
+}
+
+// RemoveRelationship is the resolver for the removeRelationship field.
+func (r *mutationResolver) RemoveRelationship(ctx context.Context, partID string, typeArg string, relatedID string) (bool, error) {
+	partUUID, err := uuid.Parse(partID)
+	if err != nil {
+		return false, errWrapper.Wrapf(err, "error parsing part_id")
+	}
+	relatedUUID, err := uuid.Parse(relatedID)
+	if err != nil {
+		return false, errWrapper.Wrapf(err, "error parsing related_id")
+	}
+
+	if err := r.PartController.As(audit.GetActor(ctx)).RemoveRelationship(part.ID(partUUID), typeArg, part.ID(relatedUUID)); err != nil {
+		return false, errWrapper.Wrapf(err, "error removing relationship")
+	}
 
 	return true, nil
 }
 
 // PartHasFile is the resolver for the partHasFile field.
 func (r *mutationResolver) PartHasFile(ctx context.Context, id string, fileSha256 string, path *string) (bool, error) {
-	panic(fmt.Errorf("not implemented: PartHasFile - partHasFile"))
+	partUUID, err := uuid.Parse(id)
+	if err != nil {
+		return false, errWrapper.Wrapf(err, "error parsing id")
+	}
+	rawSha256, err := hex.DecodeString(fileSha256)
+	if err != nil {
+		return false, errWrapper.Wrapf(err, "error decoding file_sha256 \"%s\"", fileSha256)
+	}
+	var filePath string
+	if path != nil {
+		filePath = *path
+	}
+
+	p, err := r.PartController.GetByID(part.ID(partUUID))
+	if err != nil {
+		return false, errWrapper.Wrapf(err, "error getting part")
+	}
+	if err := r.PartController.As(audit.GetActor(ctx)).AddFileToPart(p.PartID, rawSha256, filePath); err != nil {
+		return false, errWrapper.Wrapf(err, "error adding file to part")
+	}
+	r.VulnerabilityController.QueueMatch(uuid.UUID(p.PartID))
+
+	return true, nil
+}
+
+// VerifyParts is the resolver for the verifyParts field.
+func (r *mutationResolver) VerifyParts(ctx context.Context, ids []string) ([]*model.CodeMismatch, error) {
+	partIDs := make([]part.ID, 0, len(ids))
+	for _, id := range ids {
+		partUUID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error parsing id \"%s\"", id)
+		}
+		p, err := r.PartController.GetByID(part.ID(partUUID))
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part %s", id)
+		}
+		partIDs = append(partIDs, p.PartID)
+	}
+	if len(partIDs) == 0 {
+		return []*model.CodeMismatch{}, nil
+	}
+
+	mismatches, err := r.PartController.VerifyVerificationCodes(partIDs...)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error verifying parts")
+	}
+
+	return generics.Map(mismatches, func(m part.CodeMismatch) (*model.CodeMismatch, error) {
+		ret := model.ToCodeMismatch(m)
+		return &ret, nil
+	})
+}
+
+// VerifyCatalog is the resolver for the verifyCatalog field.
+func (r *mutationResolver) VerifyCatalog(ctx context.Context) (bool, error) {
+	r.Verifier.QueueVerify()
+
+	return true, nil
 }
 
 // CreatePart is the resolver for the createPart field.
@@ -392,7 +673,7 @@ func (r *mutationResolver) CreatePart(ctx context.Context, partInput model.NewPa
 		partType.String = lTree
 	}
 
-	p, err := r.PartController.CreatePart(part.Part{
+	newPart := part.Part{
 		Type:             partType,
 		Name:             toNullString(partInput.Name),
 		Version:          toNullString(partInput.Version),
@@ -402,10 +683,12 @@ func (r *mutationResolver) CreatePart(ctx context.Context, partInput model.NewPa
 		LicenseRationale: toNullString(partInput.LicenseRationale),
 		Description:      toNullString(partInput.Description),
 		Comprised:        part.ID(comprised),
-	})
+	}
+	p, err := r.PartController.As(audit.GetActor(ctx)).CreatePart(newPart)
 	if err != nil {
 		return nil, err
 	}
+	r.VulnerabilityController.QueueMatch(uuid.UUID(p.PartID))
 
 	ret := model.ToPart(p)
 
@@ -433,491 +716,1911 @@ func (r *mutationResolver) DeletePart(ctx context.Context, partID string) (bool,
 
 	log.Debug().Str("UUID", uuid.String()).Msg("Deleting Part")
 	// Delete Part
-	if err := r.PartController.DeletePart(part.ID(uuid)); err != nil {
+	if err := r.PartController.As(audit.GetActor(ctx)).DeletePart(part.ID(uuid)); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
-// ID is the resolver for the id field.
-func (r *partResolver) ID(ctx context.Context, obj *model.Part) (string, error) {
-	return obj.ID.String(), nil
-}
+// CreateLicense is the resolver for the createLicense field.
+func (r *mutationResolver) CreateLicense(ctx context.Context, licenseInput model.NewLicenseInput) (*model.License, error) {
+	toNullString := func(s *string) sql.NullString {
+		if s == nil || *s == "" {
+			return sql.NullString{}
+		}
 
-// FileVerificationCode is the resolver for the file_verification_code field.
-func (r *partResolver) FileVerificationCode(ctx context.Context, obj *model.Part) (*string, error) {
-	if len(obj.FileVerificationCode) == 0 {
-		return nil, nil
+		return sql.NullString{
+			Valid:  true,
+			String: *s,
+		}
 	}
 
-	encoded := hex.EncodeToString(obj.FileVerificationCode)
-	return &encoded, nil
+	l, err := r.LicenseController.CreateLicense(license.License{
+		LicenseID: licenseInput.ID,
+		Name:      licenseInput.Name,
+		SpdxID:    toNullString(licenseInput.SpdxID),
+		Record:    json.RawMessage(licenseInput.Record),
+		Text:      toNullString(licenseInput.Text),
+	}, licenseInput.Aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToLicense(l)
+	return &ret, nil
 }
 
-// License is the resolver for the license field.
-func (r *partResolver) License(ctx context.Context, obj *model.Part) (*string, error) {
-	return obj.License, nil
+// AttachLicenseText is the resolver for the attachLicenseText field.
+func (r *mutationResolver) AttachLicenseText(ctx context.Context, id string, text string) (*model.License, error) {
+	l, err := r.LicenseController.GetByIdentifier(id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting license \"%s\"", id)
+	}
+
+	l, err = r.LicenseController.AttachText(l.LicenseID, text)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error attaching text to license \"%s\"", id)
+	}
+
+	ret := model.ToLicense(l)
+	return &ret, nil
 }
 
-// Comprised is the resolver for the comprised field.
-func (r *partResolver) Comprised(ctx context.Context, obj *model.Part) (*string, error) {
-	if obj.Comprised == nil {
-		return nil, nil
+// ImportLicenseList is the resolver for the importLicenseList field.
+func (r *mutationResolver) ImportLicenseList(ctx context.Context, file graphql.Upload) (int64, error) {
+	count, err := r.LicenseController.ImportSPDXLicenseList(file.File)
+	if err != nil {
+		return count, errWrapper.Wrapf(err, "error importing license list %s", file.Filename)
 	}
 
-	comprisedID := obj.Comprised.String()
-	return &comprisedID, nil
+	return count, nil
 }
 
-// Aliases is the resolver for the aliases field.
-func (r *partResolver) Aliases(ctx context.Context, obj *model.Part) ([]string, error) {
-	aliases, err := r.PartController.GetAliases(obj.ID)
+// SetLicenseObligation is the resolver for the setLicenseObligation field.
+func (r *mutationResolver) SetLicenseObligation(ctx context.Context, id string, obligation string, description *string) (*model.LicenseObligation, error) {
+	l, err := r.LicenseController.GetByIdentifier(id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting license \"%s\"", id)
+	}
+
+	var descriptionValue string
+	if description != nil {
+		descriptionValue = *description
+	}
+
+	o, err := r.LicenseController.SetObligation(l.LicenseID, obligation, descriptionValue)
 	if err != nil {
 		return nil, err
 	}
 
-	return aliases, nil
+	ret := model.ToLicenseObligation(o)
+	return &ret, nil
 }
 
-// Profiles is the resolver for the profiles field.
-func (r *partResolver) Profiles(ctx context.Context, obj *model.Part) ([]*model.Profile, error) {
-	profiles, err := r.PartController.GetProfiles(obj.ID)
+// DeleteLicenseObligation is the resolver for the deleteLicenseObligation field.
+func (r *mutationResolver) DeleteLicenseObligation(ctx context.Context, id string, obligation string) (bool, error) {
+	l, err := r.LicenseController.GetByIdentifier(id)
 	if err != nil {
-		return nil, err
+		return false, errWrapper.Wrapf(err, "error getting license \"%s\"", id)
 	}
 
-	ret, err := generics.Map[part.Profile, *model.Profile](profiles, func(p part.Profile) (*model.Profile, error) {
-		ret := new(model.Profile)
-		ret.Key = p.Key
+	if err := r.LicenseController.DeleteObligation(l.LicenseID, obligation); err != nil {
+		return false, err
+	}
 
-		documents, err := generics.Map[part.Document, *model.Document](p.Documents, func(d part.Document) (*model.Document, error) {
-			ret := new(model.Document)
-			ret.Title = d.Title
-			ret.Document = model.Json(d.Document)
+	return true, nil
+}
 
-			return ret, nil
-		})
-		if err != nil {
-			return nil, err
+// CreatePolicy is the resolver for the createPolicy field.
+func (r *mutationResolver) CreatePolicy(ctx context.Context, policyInput model.NewPolicyInput) (*model.LicensePolicy, error) {
+	rules := make(policy.Rules, 0, len(policyInput.Rules))
+	for _, v := range policyInput.Rules {
+		rule := policy.Rule{
+			Type:           v.Type,
+			Licenses:       v.Licenses,
+			ParentLicenses: v.ParentLicenses,
+		}
+		if v.Message != nil {
+			rule.Message = *v.Message
 		}
 
-		ret.Documents = documents
+		rules = append(rules, rule)
+	}
 
-		return ret, nil
-	})
+	var description string
+	if policyInput.Description != nil {
+		description = *policyInput.Description
+	}
+
+	p, err := r.PolicyController.CreatePolicy(policyInput.Name, description, rules)
 	if err != nil {
-		return nil, errWrapper.Wrapf(err, "error mapping internal profiles to model profiles")
+		return nil, err
 	}
 
-	return ret, nil
+	ret := model.ToLicensePolicy(p)
+	return &ret, nil
 }
 
-// SubParts is the resolver for the sub_parts field.
-func (r *partResolver) SubParts(ctx context.Context, obj *model.Part) ([]*model.SubPart, error) {
-	subParts, err := r.PartController.SubParts(obj.ID)
+// DeletePolicy is the resolver for the deletePolicy field.
+func (r *mutationResolver) DeletePolicy(ctx context.Context, id int64) (*model.LicensePolicy, error) {
+	p, err := r.PolicyController.DeletePolicy(id)
 	if err != nil {
 		return nil, err
 	}
 
-	ret := make([]*model.SubPart, 0)
-	for _, subPart := range subParts {
-		prt, err := r.PartController.GetByID(subPart.ID)
-		if err != nil {
-			return ret, err
-		}
+	ret := model.ToLicensePolicy(p)
+	return &ret, nil
+}
+
+// CreateSourceBundle is the resolver for the createSourceBundle field.
+func (r *mutationResolver) CreateSourceBundle(ctx context.Context, partlistID int64, licenses []string) (*model.SourceBundle, error) {
+	b, err := r.BundleController.CreateBundle(partlistID, licenses)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToSourceBundle(b)
+	return &ret, nil
+}
+
+// ResumeSourceBundle is the resolver for the resumeSourceBundle field.
+func (r *mutationResolver) ResumeSourceBundle(ctx context.Context, id int64) (*model.SourceBundle, error) {
+	b, err := r.BundleController.Start(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToSourceBundle(b)
+	return &ret, nil
+}
+
+// ImportVulnerabilities is the resolver for the importVulnerabilities field.
+func (r *mutationResolver) ImportVulnerabilities(ctx context.Context, file graphql.Upload) (int64, error) {
+	count, err := r.VulnerabilityController.ImportFile(file.File)
+	if err != nil {
+		return count, errWrapper.Wrapf(err, "error importing vulnerabilities")
+	}
+
+	return count, nil
+}
+
+// ImportVulnerabilityDirectory is the resolver for the importVulnerabilityDirectory field.
+func (r *mutationResolver) ImportVulnerabilityDirectory(ctx context.Context, path string) (int64, error) {
+	count, err := r.VulnerabilityController.ImportDirectory(path)
+	if err != nil {
+		return count, errWrapper.Wrapf(err, "error importing vulnerabilities from \"%s\"", path)
+	}
+
+	return count, nil
+}
+
+// MatchVulnerabilities is the resolver for the matchVulnerabilities field.
+func (r *mutationResolver) MatchVulnerabilities(ctx context.Context, partIds []string) (bool, error) {
+	partUUIDs := make([]uuid.UUID, 0, len(partIds))
+	for _, v := range partIds {
+		partUUID, err := uuid.Parse(v)
+		if err != nil {
+			return false, errWrapper.Wrapf(err, "error parsing part_id \"%s\"", v)
+		}
+
+		partUUIDs = append(partUUIDs, partUUID)
+	}
+
+	r.VulnerabilityController.QueueMatch(partUUIDs...)
+
+	return true, nil
+}
+
+// SetVexStatement is the resolver for the setVexStatement field.
+func (r *mutationResolver) SetVexStatement(ctx context.Context, statementInput model.VexStatementInput) (*model.VexStatement, error) {
+	toNullString := func(s *string) sql.NullString {
+		if s == nil || *s == "" {
+			return sql.NullString{}
+		}
+		return sql.NullString{String: *s, Valid: true}
+	}
+
+	statement := vulnerability.Statement{
+		VulnerabilityID: statementInput.VulnerabilityID,
+		Status:          statementInput.Status,
+		Justification:   toNullString(statementInput.Justification),
+		ImpactStatement: toNullString(statementInput.ImpactStatement),
+		ActionStatement: toNullString(statementInput.ActionStatement),
+	}
+	if statementInput.PartID != nil {
+		partUUID, err := uuid.Parse(*statementInput.PartID)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error parsing part_id")
+		}
+		statement.PartID = part.ID(partUUID)
+	}
+	if statementInput.PartlistID != nil {
+		statement.PartListID = sql.NullInt64{Int64: *statementInput.PartlistID, Valid: true}
+	}
+
+	s, err := r.VulnerabilityController.SetStatement(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToVexStatement(s)
+	return &ret, nil
+}
+
+// DeleteVexStatement is the resolver for the deleteVexStatement field.
+func (r *mutationResolver) DeleteVexStatement(ctx context.Context, id int64) (*model.VexStatement, error) {
+	s, err := r.VulnerabilityController.DeleteStatement(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToVexStatement(s)
+	return &ret, nil
+}
+
+// ImportVex is the resolver for the importVex field.
+func (r *mutationResolver) ImportVex(ctx context.Context, file graphql.Upload, partID *string, partlistID *int64) (int64, error) {
+	var scopePartID part.ID
+	if partID != nil {
+		partUUID, err := uuid.Parse(*partID)
+		if err != nil {
+			return 0, errWrapper.Wrapf(err, "error parsing part_id")
+		}
+		scopePartID = part.ID(partUUID)
+	}
+	var scopePartListID sql.NullInt64
+	if partlistID != nil {
+		scopePartListID = sql.NullInt64{Int64: *partlistID, Valid: true}
+	}
+	if partID != nil && partlistID != nil {
+		return 0, errWrapper.New("importVex takes at most one of part_id and partlist_id")
+	}
+
+	count, err := r.VulnerabilityController.ImportVEX(file.File, scopePartID, scopePartListID)
+	if err != nil {
+		return count, errWrapper.Wrapf(err, "error importing VEX document")
+	}
+
+	return count, nil
+}
+
+// AddPartIdentifier is the resolver for the addPartIdentifier field.
+func (r *mutationResolver) AddPartIdentifier(ctx context.Context, partID string, typeArg string, value string) (*model.PartIdentifier, error) {
+	partUUID, err := uuid.Parse(partID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing part_id")
+	}
+
+	i, err := r.PartController.AddIdentifier(part.ID(partUUID), typeArg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToPartIdentifier(i)
+	return &ret, nil
+}
+
+// DeletePartIdentifier is the resolver for the deletePartIdentifier field.
+func (r *mutationResolver) DeletePartIdentifier(ctx context.Context, partID string, typeArg string, value string) (bool, error) {
+	partUUID, err := uuid.Parse(partID)
+	if err != nil {
+		return false, errWrapper.Wrapf(err, "error parsing part_id")
+	}
+
+	if err := r.PartController.DeleteIdentifier(part.ID(partUUID), typeArg, value); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RevertPart is the resolver for the revertPart field.
+func (r *mutationResolver) RevertPart(ctx context.Context, id string, toRevision int64) (*model.Part, error) {
+	partUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing id")
+	}
+
+	if _, err := r.PartController.As(audit.GetActor(ctx)).RevertPart(part.ID(partUUID), toRevision); err != nil {
+		return nil, errWrapper.Wrapf(err, "error reverting part")
+	}
+
+	p, err := r.PartController.GetByID(part.ID(partUUID))
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting reverted part")
+	}
+	r.VulnerabilityController.QueueMatch(partUUID)
+
+	ret := model.ToPart(p)
+
+	return &ret, nil
+}
+
+// RequestReview is the resolver for the requestReview field.
+func (r *mutationResolver) RequestReview(ctx context.Context, id string, comment *string) (*model.Review, error) {
+	partUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing id")
+	}
+
+	rev, err := r.ReviewController.As(audit.GetActor(ctx)).RequestReview(partUUID, comment)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error requesting review of part")
+	}
+
+	ret := model.ToReview(rev)
+
+	return &ret, nil
+}
+
+// ApprovePart is the resolver for the approvePart field.
+func (r *mutationResolver) ApprovePart(ctx context.Context, id string, comment *string) (*model.Review, error) {
+	partUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing id")
+	}
+
+	rev, err := r.ReviewController.As(audit.GetActor(ctx)).Approve(partUUID, comment)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error approving part")
+	}
+
+	ret := model.ToReview(rev)
+
+	return &ret, nil
+}
+
+// RejectPart is the resolver for the rejectPart field.
+func (r *mutationResolver) RejectPart(ctx context.Context, id string, comment string) (*model.Review, error) {
+	partUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing id")
+	}
+
+	rev, err := r.ReviewController.As(audit.GetActor(ctx)).Reject(partUUID, &comment)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error rejecting part")
+	}
+
+	ret := model.ToReview(rev)
+
+	return &ret, nil
+}
+
+// MergeParts is the resolver for the mergeParts field.
+func (r *mutationResolver) MergeParts(ctx context.Context, keep string, remove string, strategy *string) (*model.Part, error) {
+	keepUUID, err := uuid.Parse(keep)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing keep")
+	}
+	removeUUID, err := uuid.Parse(remove)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing remove")
+	}
+
+	var mergeStrategy string
+	if strategy != nil {
+		mergeStrategy = *strategy
+	}
+	if _, err := r.PartController.As(audit.GetActor(ctx)).MergeParts(part.ID(keepUUID), part.ID(removeUUID), mergeStrategy); err != nil {
+		return nil, errWrapper.Wrapf(err, "error merging parts")
+	}
+
+	p, err := r.PartController.GetByID(part.ID(keepUUID))
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting merged part")
+	}
+	r.VulnerabilityController.QueueMatch(keepUUID)
+	if err := r.SimilarityController.Update(keepUUID); err != nil {
+		log.Error().Str(zerolog.CallerFieldName, "mutationResolver.MergeParts").Err(err).Msg("error updating similarity signature")
+	}
+
+	ret := model.ToPart(p)
+
+	return &ret, nil
+}
+
+// SetFamilyScheme is the resolver for the setFamilyScheme field.
+func (r *mutationResolver) SetFamilyScheme(ctx context.Context, name string, scheme string) (*model.VersionFamily, error) {
+	f, err := r.FamilyController.SetScheme(name, scheme)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error setting version scheme")
+	}
+
+	ret := model.ToVersionFamily(f)
+
+	return &ret, nil
+}
+
+// ID is the resolver for the id field.
+func (r *partResolver) ID(ctx context.Context, obj *model.Part) (string, error) {
+	return obj.ID.String(), nil
+}
+
+// FileVerificationCode is the resolver for the file_verification_code field.
+func (r *partResolver) FileVerificationCode(ctx context.Context, obj *model.Part) (*string, error) {
+	if len(obj.FileVerificationCode) == 0 {
+		return nil, nil
+	}
+
+	encoded := hex.EncodeToString(obj.FileVerificationCode)
+	return &encoded, nil
+}
+
+// FileVerificationCodeOne is the resolver for the file_verification_code_one field.
+func (r *partResolver) FileVerificationCodeOne(ctx context.Context, obj *model.Part) (*string, error) {
+	if len(obj.FileVerificationCodeOne) == 0 {
+		return nil, nil
+	}
+
+	encoded := hex.EncodeToString(obj.FileVerificationCodeOne)
+	return &encoded, nil
+}
+
+// SpdxVerificationCode is the resolver for the spdx_verification_code field.
+func (r *partResolver) SpdxVerificationCode(ctx context.Context, obj *model.Part) (*string, error) {
+	if len(obj.FileVerificationCodeOne) <= 5 {
+		return nil, nil
+	}
+
+	encoded := hex.EncodeToString(obj.FileVerificationCodeOne[5:])
+	return &encoded, nil
+}
+
+// Swhid is the resolver for the swhid field.
+func (r *partResolver) Swhid(ctx context.Context, obj *model.Part) (*string, error) {
+	if len(obj.GitTreeSha1) == 0 {
+		return nil, nil
+	}
+
+	ret := gitoid.SWHID(gitoid.TypeDirectory, obj.GitTreeSha1)
+	return &ret, nil
+}
+
+// License is the resolver for the license field.
+func (r *partResolver) License(ctx context.Context, obj *model.Part) (*string, error) {
+	return obj.License, nil
+}
+
+// Comprised is the resolver for the comprised field.
+func (r *partResolver) Comprised(ctx context.Context, obj *model.Part) (*string, error) {
+	if obj.Comprised == nil {
+		return nil, nil
+	}
+
+	comprisedID := obj.Comprised.String()
+	return &comprisedID, nil
+}
+
+// Aliases is the resolver for the aliases field.
+func (r *partResolver) Aliases(ctx context.Context, obj *model.Part) ([]string, error) {
+	aliases, err := r.PartController.GetAliases(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// Profiles is the resolver for the profiles field.
+func (r *partResolver) Profiles(ctx context.Context, obj *model.Part) ([]*model.Profile, error) {
+	profiles, err := r.PartController.GetProfiles(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[part.Profile, *model.Profile](profiles, func(p part.Profile) (*model.Profile, error) {
+		ret := new(model.Profile)
+		ret.Key = p.Key
+
+		documents, err := generics.Map[part.Document, *model.Document](p.Documents, func(d part.Document) (*model.Document, error) {
+			ret := new(model.Document)
+			ret.Title = d.Title
+			ret.Document = model.Json(d.Document)
+
+			return ret, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ret.Documents = documents
+
+		return ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal profiles to model profiles")
+	}
+
+	return ret, nil
+}
+
+// SubParts is the resolver for the sub_parts field.
+func (r *partResolver) SubParts(ctx context.Context, obj *model.Part) ([]*model.SubPart, error) {
+	subParts, err := r.PartController.SubParts(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*model.SubPart, 0)
+	for _, subPart := range subParts {
+		prt, err := r.PartController.GetByID(subPart.ID)
+		if err != nil {
+			return ret, err
+		}
 
 		modelPart := model.ToPart(prt)
 
-		ret = append(ret, &model.SubPart{
-			Path: subPart.Path,
-			Part: &modelPart,
-		})
+		ret = append(ret, &model.SubPart{
+			Path: subPart.Path,
+			Part: &modelPart,
+		})
+	}
+
+	return ret, nil
+}
+
+// Parents is the resolver for the parents field.
+func (r *partResolver) Parents(ctx context.Context, obj *model.Part) ([]*model.ParentPart, error) {
+	parents, err := r.PartController.Parents(obj.ID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting parents")
+	}
+
+	return generics.Map[part.Parent, *model.ParentPart](parents, func(p part.Parent) (*model.ParentPart, error) {
+		modelPart := model.ToPart(&p.Part)
+		return &model.ParentPart{Path: p.Path, Part: &modelPart}, nil
+	})
+}
+
+// Ancestors is the resolver for the ancestors field.
+func (r *partResolver) Ancestors(ctx context.Context, obj *model.Part, maxDepth *int) ([]*model.AncestorPart, error) {
+	var depth int
+	if maxDepth != nil {
+		depth = *maxDepth
+	}
+
+	ancestors, err := r.PartController.Ancestors(obj.ID, depth)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting ancestors")
+	}
+
+	return generics.Map[part.Ancestor, *model.AncestorPart](ancestors, func(a part.Ancestor) (*model.AncestorPart, error) {
+		modelPart := model.ToPart(&a.Part)
+		return &model.AncestorPart{Part: &modelPart, Depth: a.Depth, Path: a.Path}, nil
+	})
+}
+
+// EmbeddedParts is the resolver for the embedded_parts field.
+func (r *partResolver) EmbeddedParts(ctx context.Context, obj *model.Part) ([]*model.EmbeddedPart, error) {
+	embedded, err := r.PartController.GetEmbedded(obj.ID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting embedded parts")
+	}
+
+	return generics.Map[part.Embedded, *model.EmbeddedPart](embedded, func(e part.Embedded) (*model.EmbeddedPart, error) {
+		modelPart := model.ToPart(&e.Part)
+		return &model.EmbeddedPart{Path: e.Path, Part: &modelPart, Linked: e.Linked}, nil
+	})
+}
+
+// EmbeddedIn is the resolver for the embedded_in field.
+func (r *partResolver) EmbeddedIn(ctx context.Context, obj *model.Part) ([]*model.EmbeddedPart, error) {
+	embedded, err := r.PartController.GetEmbeddedIn(obj.ID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting parts embedded in")
+	}
+
+	return generics.Map[part.Embedded, *model.EmbeddedPart](embedded, func(e part.Embedded) (*model.EmbeddedPart, error) {
+		modelPart := model.ToPart(&e.Part)
+		return &model.EmbeddedPart{Path: e.Path, Part: &modelPart, Linked: e.Linked}, nil
+	})
+}
+
+// Partlists is the resolver for the partlists field.
+func (r *partResolver) Partlists(ctx context.Context, obj *model.Part) ([]*model.PartList, error) {
+	partlists, err := r.PartListController.GetByPart(obj.ID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting partlists")
+	}
+
+	return generics.Map[partlist.PartList, *model.PartList](partlists, func(p partlist.PartList) (*model.PartList, error) {
+		ret := model.ToPartList(&p)
+		return &ret, nil
+	})
+}
+
+// Archives is the resolver for the archives field.
+func (r *partResolver) Archives(ctx context.Context, obj *model.Part) ([]*model.Archive, error) {
+	archives, err := r.ArchiveController.GetByPart(obj.ID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting archives")
+	}
+
+	return generics.Map[archive.Archive, *model.Archive](archives, func(a archive.Archive) (*model.Archive, error) {
+		ret := model.ToArchive(&a)
+		return &ret, nil
+	})
+}
+
+// ComprisedBy is the resolver for the comprised_by field.
+func (r *partResolver) ComprisedBy(ctx context.Context, obj *model.Part) (*model.Part, error) {
+	if obj.Comprised == nil {
+		return nil, nil
+	}
+
+	p, err := r.PartController.GetByID(*obj.Comprised)
+	if err == part.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting comprising part")
+	}
+
+	ret := model.ToPart(p)
+	return &ret, nil
+}
+
+// Licenses is the resolver for the licenses field.
+func (r *partResolver) Licenses(ctx context.Context, obj *model.Part) ([]*model.License, error) {
+	if obj.License == nil || *obj.License == "" {
+		return nil, nil
+	}
+
+	licenses, err := r.LicenseController.GetByLicenseExpression(*obj.License)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[license.License, *model.License](licenses, func(l license.License) (*model.License, error) {
+		ret := model.ToLicense(&l)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal licenses to model licenses")
+	}
+
+	return ret, nil
+}
+
+// Vulnerabilities is the resolver for the vulnerabilities field.
+func (r *partResolver) Vulnerabilities(ctx context.Context, obj *model.Part, partlistID *int64, includeResolved *bool) ([]*model.PartVulnerability, error) {
+	vulnerabilities, err := r.VulnerabilityController.GetByPartInScope(obj.ID, partlistID, includeResolved != nil && *includeResolved)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[vulnerability.PartVulnerability, *model.PartVulnerability](vulnerabilities, func(v vulnerability.PartVulnerability) (*model.PartVulnerability, error) {
+		ret := model.ToPartVulnerability(&v)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal vulnerabilities to model vulnerabilities")
+	}
+
+	return ret, nil
+}
+
+// Vex is the resolver for the vex field.
+func (r *partResolver) Vex(ctx context.Context, obj *model.Part) ([]*model.VexStatement, error) {
+	statements, err := r.VulnerabilityController.GetStatementsByPart(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[vulnerability.Statement, *model.VexStatement](statements, func(s vulnerability.Statement) (*model.VexStatement, error) {
+		ret := model.ToVexStatement(&s)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal VEX statements to model VEX statements")
+	}
+
+	return ret, nil
+}
+
+// Identifiers is the resolver for the identifiers field.
+func (r *partResolver) Identifiers(ctx context.Context, obj *model.Part) ([]*model.PartIdentifier, error) {
+	identifiers, err := r.PartController.GetIdentifiers(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[part.Identifier, *model.PartIdentifier](identifiers, func(i part.Identifier) (*model.PartIdentifier, error) {
+		ret := model.ToPartIdentifier(&i)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal identifiers to model identifiers")
+	}
+
+	return ret, nil
+}
+
+// URI is the resolver for the uri field.
+func (r *partResolver) URI(ctx context.Context, obj *model.Part) (string, error) {
+	return r.PartController.URI(part.SchemePartID, obj.ID.String()), nil
+}
+
+// Uris is the resolver for the uris field.
+func (r *partResolver) Uris(ctx context.Context, obj *model.Part) ([]string, error) {
+	return r.PartController.GetURIs(obj.ID, obj.FileVerificationCode)
+}
+
+// History is the resolver for the history field.
+func (r *partResolver) History(ctx context.Context, obj *model.Part) ([]*model.Revision, error) {
+	entries, err := r.PartController.GetHistory(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[audit.Entry, *model.Revision](entries, func(e audit.Entry) (*model.Revision, error) {
+		ret := model.ToRevision(&e)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal history to model revisions")
+	}
+
+	return ret, nil
+}
+
+// Review is the resolver for the review field.
+func (r *partResolver) Review(ctx context.Context, obj *model.Part) (*model.Review, error) {
+	rev, err := r.ReviewController.Get(uuid.UUID(obj.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToReview(rev)
+
+	return &ret, nil
+}
+
+// NewerVersions is the resolver for the newer_versions field.
+func (r *partResolver) NewerVersions(ctx context.Context, obj *model.Part) ([]*model.Part, error) {
+	parts, err := r.FamilyController.NewerVersions(uuid.UUID(obj.ID))
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting newer versions")
+	}
+
+	ret := make([]*model.Part, 0, len(parts))
+	for i := range parts {
+		p := model.ToPart(&parts[i])
+		ret = append(ret, &p)
+	}
+
+	return ret, nil
+}
+
+// Relationships is the resolver for the relationships field.
+func (r *partResolver) Relationships(ctx context.Context, obj *model.Part, direction *string, types []string) ([]*model.PartRelationship, error) {
+	incoming, err := model.IsIncoming(direction)
+	if err != nil {
+		return nil, err
+	}
+
+	relationships, err := r.PartController.Relationships(obj.ID, incoming, types)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting relationships")
+	}
+
+	return generics.Map[part.Relationship, *model.PartRelationship](relationships, func(p part.Relationship) (*model.PartRelationship, error) {
+		ret := model.ToPartRelationship(p)
+		return &ret, nil
+	})
+}
+
+// History is the resolver for the history field.
+func (r *partListResolver) History(ctx context.Context, obj *model.PartList) ([]*model.Revision, error) {
+	entries, err := r.PartListController.GetHistory(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[audit.Entry, *model.Revision](entries, func(e audit.Entry) (*model.Revision, error) {
+		ret := model.ToRevision(&e)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal history to model revisions")
+	}
+
+	return ret, nil
+}
+
+// Part is the resolver for the part field.
+func (r *partRelationshipResolver) Part(ctx context.Context, obj *model.PartRelationship) (*model.Part, error) {
+	p, err := r.PartController.GetByID(obj.PartID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting part %s", obj.PartID.String())
+	}
+
+	ret := model.ToPart(p)
+	return &ret, nil
+}
+
+// Related is the resolver for the related field.
+func (r *partRelationshipResolver) Related(ctx context.Context, obj *model.PartRelationship) (*model.Part, error) {
+	p, err := r.PartController.GetByID(obj.RelatedID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting part %s", obj.RelatedID.String())
+	}
+
+	ret := model.ToPart(p)
+	return &ret, nil
+}
+
+// Part is the resolver for the part field.
+func (r *policyViolationResolver) Part(ctx context.Context, obj *model.PolicyViolation) (*model.Part, error) {
+	partUUID, err := uuid.Parse(obj.PartID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing part_id")
+	}
+
+	partID := part.ID(partUUID)
+	p, err := r.PartController.GetBy(nil, &partID)
+	if err == part.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToPart(p)
+	return &ret, nil
+}
+
+// Archive is the resolver for the archive field.
+func (r *queryResolver) Archive(ctx context.Context, sha256 *string, name *string, sha512 *string, gitoid *string, swhid *string) (*model.Archive, error) {
+	// Fetch by sha256 if given
+	if sha256 != nil && *sha256 != "" {
+		rawSha256, err := hex.DecodeString(*sha256)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error decoding sha256 string \"%s\"", *sha256)
+		}
+		archve, err := r.ArchiveController.GetBySha256(rawSha256)
+		if err != nil {
+			log.Error().Err(err).Str(zerolog.CallerFieldName, "queryResolver.Archive").
+				Str("sha256", *sha256).
+				Msg("error getting archive by sha256")
+			return nil, err
+		}
+
+		ret := model.ToArchive(archve)
+		return &ret, nil
+	}
+
+	// Fetch by name if given
+	if name != nil && *name != "" {
+		archve, err := r.ArchiveController.GetByName(*name)
+		if err != nil {
+			log.Error().Err(err).Str(zerolog.CallerFieldName, "queryResolver.Archive").
+				Str("name", *name).
+				Msg("error getting archive by name")
+			return nil, err
+		}
+
+		ret := model.ToArchive(archve)
+		return &ret, nil
+	}
+
+	// Fetch by content identifier if given
+	var archve *archive.Archive
+	var err error
+	if sha512 != nil && *sha512 != "" {
+		rawSha512, decodeErr := hex.DecodeString(*sha512)
+		if decodeErr != nil {
+			return nil, errWrapper.Wrapf(decodeErr, "error decoding sha512 string \"%s\"", *sha512)
+		}
+		archve, err = r.ArchiveController.GetBySha512(rawSha512)
+	} else if gitoid != nil && *gitoid != "" {
+		archve, err = r.ArchiveController.GetByGitoid(*gitoid)
+	} else if swhid != nil && *swhid != "" {
+		archve, err = r.ArchiveController.GetBySWHID(*swhid)
+	} else {
+		return nil, nil // should this be an error, no arguments found?
+	}
+	if err == archive.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting archive")
+	}
+
+	ret := model.ToArchive(archve)
+	return &ret, nil
+}
+
+// FindArchive is the resolver for the find_archive field.
+func (r *queryResolver) FindArchive(ctx context.Context, query string, method *string, costs *model.SearchCosts) ([]*model.ArchiveDistance, error) {
+	var methodValue archive.SearchMethod
+	if method == nil || *method == "" {
+		methodValue = archive.ParseMethod("levenshtein")
+	} else {
+		methodValue = archive.ParseMethod(*method)
+	}
+
+	if costs == nil {
+		costs = &model.SearchCosts{
+			Insert:      editDistance.OPERATION_INSERT_COST,
+			Delete:      editDistance.OPERATION_DELETE_COST,
+			Substitute:  editDistance.OPERATION_SUBSTITUTE_COST,
+			MaxDistance: &editDistance.DISTANCE_MAX,
+		}
+	}
+	if costs.MaxDistance == nil {
+		costs.MaxDistance = &editDistance.DISTANCE_MAX_NIL
+	}
+
+	log.Debug().Str("query", query).Interface("methodValue", methodValue).Msg("SearchForArchiveAll")
+	distances, err := r.ArchiveController.SearchForArchiveAll(query, methodValue, costs.Insert, costs.Delete, costs.Substitute, *costs.MaxDistance)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Interface("distances", distances).Msg("Found Archive Distances")
+	ret := make([]*model.ArchiveDistance, 0, len(distances))
+	log.Debug().Str("query", query).Interface("methodValue", methodValue).Msg("SearchForArchiveAll")
+	for _, v := range distances {
+		if costs.MaxDistance != nil && *costs.MaxDistance != editDistance.DISTANCE_MAX_NIL && v.Distance > int64(*costs.MaxDistance) {
+			// if max distance in use reject distances above it
+			continue
+		}
+		internalArchive, err := r.ArchiveController.GetBySha256(v.Sha256[:])
+		if err != nil {
+			return nil, err
+		}
+		a := model.ToArchive(internalArchive)
+		a.Name = v.MatchedName
+
+		ret = append(ret, &model.ArchiveDistance{
+			Distance: v.Distance,
+			Archive:  &a,
+		})
+	}
+
+	return ret, nil
+}
+
+// Part is the resolver for the part field.
+func (r *queryResolver) Part(ctx context.Context, id *string, fileVerificationCode *string, sha256 *string, sha1 *string, name *string, purl *string, cpe *string, swid *string, sha512 *string, gitoid *string, swhid *string) (*model.Part, error) {
+	if id != nil && *id != "" {
+		partUUID, err := uuid.Parse(*id)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error parsing part_id \"%s\"", *id)
+		}
+		p, err := r.PartController.GetByID(part.ID(partUUID))
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", partUUID.String())
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+	if fileVerificationCode != nil && *fileVerificationCode != "" {
+		rawFVC, err := hex.DecodeString(*fileVerificationCode)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error decoding file_verification_code: \"%s\"", *fileVerificationCode)
+		}
+		p, err := r.PartController.GetByVerificationCode(rawFVC)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by file_verification_code: \"%s\"", *fileVerificationCode)
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+	if sha256 != nil && *sha256 != "" {
+		rawSha256, err := hex.DecodeString(*sha256)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error decoding sha256: \"%s\"", *sha256)
+		}
+		a, err := r.ArchiveController.GetBySha256(rawSha256)
+		if err != nil {
+			return nil, err
+		}
+		if a.PartID == nil {
+			return nil, nil
+		}
+		p, err := r.PartController.GetByID(*a.PartID)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", a.PartID.String())
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+	if sha1 != nil && *sha1 != "" {
+		rawSha1, err := hex.DecodeString(*sha1)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error decoding sha1: \"%s\"", *sha1)
+		}
+		a, err := r.ArchiveController.GetBySha1(rawSha1)
+		if err != nil {
+			return nil, err
+		}
+		if a.PartID == nil {
+			return nil, nil
+		}
+		p, err := r.PartController.GetByID(*a.PartID)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", a.PartID.String())
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+	if name != nil && *name != "" {
+		a, err := r.ArchiveController.GetByName(*name)
+		if err != nil {
+			return nil, err
+		}
+		if a.PartID == nil {
+			return nil, nil
+		}
+		p, err := r.PartController.GetByID(*a.PartID)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", a.PartID.String())
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+	for _, v := range []struct {
+		idType string
+		value  *string
+	}{{identifier.TypePurl, purl}, {identifier.TypeCPE, cpe}, {identifier.TypeSWID, swid}} {
+		if v.value == nil || *v.value == "" {
+			continue
+		}
+
+		p, err := r.PartController.GetByIdentifier(v.idType, *v.value)
+		if err == part.ErrNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by %s: \"%s\"", v.idType, *v.value)
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+	if sha512 != nil && *sha512 != "" || gitoid != nil && *gitoid != "" {
+		var a *archive.Archive
+		var err error
+		if sha512 != nil && *sha512 != "" {
+			rawSha512, decodeErr := hex.DecodeString(*sha512)
+			if decodeErr != nil {
+				return nil, errWrapper.Wrapf(decodeErr, "error decoding sha512: \"%s\"", *sha512)
+			}
+			a, err = r.ArchiveController.GetBySha512(rawSha512)
+		} else {
+			a, err = r.ArchiveController.GetByGitoid(*gitoid)
+		}
+		if err == archive.ErrNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting archive of part")
+		}
+		if a.PartID == nil {
+			return nil, nil
+		}
+		p, err := r.PartController.GetByID(*a.PartID)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", a.PartID.String())
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+	if swhid != nil && *swhid != "" {
+		p, err := r.PartController.GetBySWHID(*swhid)
+		if err == part.ErrNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, errWrapper.Wrapf(err, "error getting part by swhid: \"%s\"", *swhid)
+		}
+
+		ret := model.ToPart(p)
+		return &ret, nil
+	}
+
+	return nil, nil // Should this be an error, no arguments found?
+}
+
+// File is the resolver for the file field.
+func (r *queryResolver) File(ctx context.Context, sha256 *string, sha1 *string, sha512 *string, gitoid *string, swhid *string) (*model.File, error) {
+	var f *archive.CatalogedFile
+	var err error
+	if sha256 != nil && *sha256 != "" {
+		rawSha256, decodeErr := hex.DecodeString(*sha256)
+		if decodeErr != nil {
+			return nil, errWrapper.Wrapf(decodeErr, "error decoding sha256: \"%s\"", *sha256)
+		}
+		f, err = r.ArchiveController.GetFile(rawSha256)
+	} else if sha1 != nil && *sha1 != "" {
+		rawSha1, decodeErr := hex.DecodeString(*sha1)
+		if decodeErr != nil {
+			return nil, errWrapper.Wrapf(decodeErr, "error decoding sha1: \"%s\"", *sha1)
+		}
+		f, err = r.ArchiveController.GetFileBySha1(rawSha1)
+	} else if sha512 != nil && *sha512 != "" {
+		rawSha512, decodeErr := hex.DecodeString(*sha512)
+		if decodeErr != nil {
+			return nil, errWrapper.Wrapf(decodeErr, "error decoding sha512: \"%s\"", *sha512)
+		}
+		f, err = r.ArchiveController.GetFileBySha512(rawSha512)
+	} else if gitoid != nil && *gitoid != "" {
+		f, err = r.ArchiveController.GetFileByGitoid(*gitoid)
+	} else if swhid != nil && *swhid != "" {
+		f, err = r.ArchiveController.GetFileBySWHID(*swhid)
+	} else {
+		return nil, errWrapper.New("file requires one of sha256, sha1, sha512, gitoid, or swhid")
+	}
+	if err == archive.ErrFileNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting file")
+	}
+
+	ret := model.ToFile(f)
+	return &ret, nil
+}
+
+// Resolve is the resolver for the resolve field.
+func (r *queryResolver) Resolve(ctx context.Context, uri string) (*model.Part, error) {
+	p, err := r.PartController.Resolve(uri)
+	if err == part.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errWrapper.Wrapf(err, "error resolving uri: \"%s\"", uri)
+	}
+
+	ret := model.ToPart(p)
+	return &ret, nil
+}
+
+// FindParts is the resolver for the find_parts field.
+func (r *queryResolver) FindParts(ctx context.Context, purl *string, cpe *string, versions *string) ([]*model.Part, error) {
+	var idType, value, versionRange string
+	if purl != nil && *purl != "" {
+		idType, value = identifier.TypePurl, *purl
+	} else if cpe != nil && *cpe != "" {
+		idType, value = identifier.TypeCPE, *cpe
+	} else {
+		return nil, errWrapper.New("find_parts requires one of purl or cpe")
+	}
+	if versions != nil {
+		versionRange = *versions
+	}
+
+	parts, err := r.PartController.FindByIdentifier(idType, value, versionRange)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[part.Part, *model.Part](parts, func(p part.Part) (*model.Part, error) {
+		ret := model.ToPart(&p)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal parts to model parts")
+	}
+
+	return ret, nil
+}
+
+// Archives is the resolver for the archives field.
+func (r *queryResolver) Archives(ctx context.Context, id *string, vcode *string) ([]*model.Archive, error) {
+	var partID part.ID
+
+	if id == nil || *id == "" {
+		if vcode == nil || *vcode == "" {
+			return nil, errWrapper.New("no identifiers provided")
+		}
+
+		rawVerificationCode, err := hex.DecodeString(*vcode)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error decoding verification_code")
+		}
+
+		fileCollection, err := r.PartController.GetByVerificationCode(rawVerificationCode)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error fetching file_collection")
+		}
+
+		partID = fileCollection.PartID
+	} else {
+		var err error
+		partUUID, err := uuid.Parse(*id)
+		if err != nil {
+			return nil, errWrapper.Wrapf(err, "error parsing part_id: \"%s\"", *id)
+		}
+
+		partID = part.ID(partUUID)
+	}
+
+	archives, err := r.ArchiveController.GetByPart(partID)
+	if err != nil {
+		return nil, errWrapper.Wrap(err, "error fetching archives")
+	}
+
+	ret := make([]*model.Archive, len(archives))
+
+	for i, v := range archives {
+		m := model.ToArchive(&v)
+		ret[i] = &m
+	}
+
+	return ret, nil
+}
+
+// Partlist is the resolver for the partlist field.
+func (r *queryResolver) Partlist(ctx context.Context, id *int64, name *string) (*model.PartList, error) {
+	if id != nil && *id != 0 {
+		pl, err := r.PartListController.GetByID(*id)
+		if err != nil {
+			return nil, err
+		}
+		ret := model.ToPartList(pl)
+		return &ret, nil
+	}
+	if name != nil && *name != "" {
+		pl, err := r.PartListController.GetByName(*name)
+		if err != nil {
+			return nil, err
+		}
+		ret := model.ToPartList(pl)
+		return &ret, nil
+	}
+	return nil, nil
+}
+
+// Partlist is the resolver for the partlist field.
+func (r *queryResolver) PartlistParts(ctx context.Context, id int64) ([]*model.Part, error) {
+	if id != 0 {
+		parts, err := r.PartListController.GetParts(id)
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]*model.Part, len(parts))
+		for i, v := range parts {
+			part := model.ToPart(v)
+			ret[i] = &part
+		}
+		return ret, nil
+	}
+	return nil, nil
+}
+
+// Partlists is the resolver for the partlists field.
+func (r *queryResolver) Partlists(ctx context.Context, parentID int64) ([]*model.PartList, error) {
+	partlists, err := r.PartListController.GetByParentID(parentID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error fetching partlists")
 	}
 
+	ret := make([]*model.PartList, len(partlists))
+
+	for i, v := range partlists {
+		m := model.ToPartList(&v)
+		ret[i] = &m
+	}
 	return ret, nil
 }
 
-// Archive is the resolver for the archive field.
-func (r *queryResolver) Archive(ctx context.Context, sha256 *string, name *string) (*model.Archive, error) {
-	// Fetch by sha256 if given
-	if sha256 != nil && *sha256 != "" {
-		rawSha256, err := hex.DecodeString(*sha256)
+// FileCount is the resolver for the file_count field.
+func (r *queryResolver) FileCount(ctx context.Context, id *string, vcode *string) (int64, error) {
+	if (id == nil || *id == "") && (vcode == nil || *vcode == "") {
+		return 0, errWrapper.New("no identifiers provided")
+	}
+
+	var partID part.ID
+
+	if id != nil && *id != "" {
+		partUUID, err := uuid.Parse(*id)
 		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error decoding sha256 string \"%s\"", *sha256)
+			return 0, errWrapper.Wrapf(err, "error parsing part_id \"%s\"", *id)
 		}
-		archve, err := r.ArchiveController.GetBySha256(rawSha256)
+
+		partID = part.ID(partUUID)
+	} else {
+		fileVerificationCode, err := hex.DecodeString(*vcode)
 		if err != nil {
-			log.Error().Err(err).Str(zerolog.CallerFieldName, "queryResolver.Archive").
-				Str("sha256", *sha256).
-				Msg("error getting archive by sha256")
-			return nil, err
+			return 0, errWrapper.Wrapf(err, "error decoding verification_code")
 		}
 
-		ret := model.ToArchive(archve)
-		return &ret, nil
-	}
-
-	// Fetch by name if given
-	if name != nil && *name != "" {
-		archve, err := r.ArchiveController.GetByName(*name)
+		p, err := r.PartController.GetByVerificationCode(fileVerificationCode)
 		if err != nil {
-			log.Error().Err(err).Str(zerolog.CallerFieldName, "queryResolver.Archive").
-				Str("name", *name).
-				Msg("error getting archive by name")
-			return nil, err
+			return 0, errWrapper.Wrapf(err, "error getting part")
 		}
 
-		ret := model.ToArchive(archve)
+		partID = p.PartID
+	}
+
+	count, err := r.PartController.CountFiles(partID)
+	if err != nil {
+		return count, errWrapper.Wrapf(err, "error counting files")
+	}
+
+	return count, nil
+}
+
+// Comprised is the resolver for the comprised field.
+func (r *queryResolver) Comprised(ctx context.Context, id *string) ([]*model.Part, error) {
+	if id == nil {
+		return nil, nil
+	}
+	comprisedID, err := uuid.Parse(*id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing id")
+	}
+
+	parts, err := r.PartController.GetByComprised(part.ID(comprisedID))
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[part.Part, *model.Part](parts, func(p part.Part) (*model.Part, error) {
+		ret := model.ToPart(&p)
 		return &ret, nil
+	})
+	if err != nil {
+		return ret, nil
 	}
 
-	return nil, nil // should this be an error, no arguments found?
+	return ret, nil
 }
 
-// FindArchive is the resolver for the find_archive field.
-func (r *queryResolver) FindArchive(ctx context.Context, query string, method *string, costs *model.SearchCosts) ([]*model.ArchiveDistance, error) {
-	var methodValue archive.SearchMethod
-	if method == nil || *method == "" {
-		methodValue = archive.ParseMethod("levenshtein")
-	} else {
-		methodValue = archive.ParseMethod(*method)
+// Profile is the resolver for the profile field.
+func (r *queryResolver) Profile(ctx context.Context, id *string, key *string) ([]*model.Document, error) {
+	if id == nil || key == nil {
+		return nil, nil
 	}
 
-	if costs == nil {
-		costs = &model.SearchCosts{
-			Insert:      editDistance.OPERATION_INSERT_COST,
-			Delete:      editDistance.OPERATION_DELETE_COST,
-			Substitute:  editDistance.OPERATION_SUBSTITUTE_COST,
-			MaxDistance: &editDistance.DISTANCE_MAX,
-		}
+	partId, err := uuid.Parse(*id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing id")
 	}
-	if costs.MaxDistance == nil {
-		costs.MaxDistance = &editDistance.DISTANCE_MAX_NIL
+
+	profile, err := r.PartController.GetProfile(part.ID(partId), *key)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Debug().Str("query", query).Interface("methodValue", methodValue).Msg("SearchForArchiveAll")
-	distances, err := r.ArchiveController.SearchForArchiveAll(query, methodValue, costs.Insert, costs.Delete, costs.Substitute, *costs.MaxDistance)
+	ret, err := generics.Map[part.Document, *model.Document](profile.Documents, func(d part.Document) (*model.Document, error) {
+		ret := new(model.Document)
+		ret.Title = d.Title
+		ret.Document = model.Json(d.Document)
+
+		return ret, nil
+	})
+	if err != nil {
+		return ret, err
+	}
+
+	return ret, nil
+}
+
+// Licenses is the resolver for the licenses field.
+func (r *queryResolver) Licenses(ctx context.Context, search *string) ([]*model.License, error) {
+	var searchValue string
+	if search != nil {
+		searchValue = *search
+	}
+
+	licenses, err := r.LicenseController.List(searchValue)
 	if err != nil {
 		return nil, err
 	}
-	log.Debug().Interface("distances", distances).Msg("Found Archive Distances")
-	ret := make([]*model.ArchiveDistance, 0, len(distances))
-	log.Debug().Str("query", query).Interface("methodValue", methodValue).Msg("SearchForArchiveAll")
-	for _, v := range distances {
-		if costs.MaxDistance != nil && *costs.MaxDistance != editDistance.DISTANCE_MAX_NIL && v.Distance > int64(*costs.MaxDistance) {
-			// if max distance in use reject distances above it
-			continue
-		}
-		internalArchive, err := r.ArchiveController.GetBySha256(v.Sha256[:])
-		if err != nil {
-			return nil, err
-		}
-		a := model.ToArchive(internalArchive)
-		a.Name = v.MatchedName
 
-		ret = append(ret, &model.ArchiveDistance{
-			Distance: v.Distance,
-			Archive:  &a,
-		})
+	ret, err := generics.Map[license.License, *model.License](licenses, func(l license.License) (*model.License, error) {
+		ret := model.ToLicense(&l)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal licenses to model licenses")
 	}
 
 	return ret, nil
 }
 
-// Part is the resolver for the part field.
-func (r *queryResolver) Part(ctx context.Context, id *string, fileVerificationCode *string, sha256 *string, sha1 *string, name *string) (*model.Part, error) {
-	if id != nil && *id != "" {
-		partUUID, err := uuid.Parse(*id)
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error parsing part_id \"%s\"", *id)
-		}
-		p, err := r.PartController.GetByID(part.ID(partUUID))
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", partUUID.String())
-		}
+// License is the resolver for the license field.
+func (r *queryResolver) License(ctx context.Context, id string) (*model.License, error) {
+	l, err := r.LicenseController.GetByIdentifier(id)
+	if err == license.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
 
-		ret := model.ToPart(p)
+	ret := model.ToLicense(l)
+	return &ret, nil
+}
+
+// Policies is the resolver for the policies field.
+func (r *queryResolver) Policies(ctx context.Context) ([]*model.LicensePolicy, error) {
+	policies, err := r.PolicyController.List()
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[policy.Policy, *model.LicensePolicy](policies, func(p policy.Policy) (*model.LicensePolicy, error) {
+		ret := model.ToLicensePolicy(&p)
 		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal policies to model policies")
 	}
-	if fileVerificationCode != nil && *fileVerificationCode != "" {
-		rawFVC, err := hex.DecodeString(*fileVerificationCode)
+
+	return ret, nil
+}
+
+// CheckPolicy is the resolver for the check_policy field.
+func (r *queryResolver) CheckPolicy(ctx context.Context, partID *string, partlistID *int64, policy string) (*model.PolicyCheck, error) {
+	p, err := r.PolicyController.GetByName(policy)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting policy \"%s\"", policy)
+	}
+
+	if partID != nil {
+		partUUID, err := uuid.Parse(*partID)
 		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error decoding file_verification_code: \"%s\"", *fileVerificationCode)
+			return nil, errWrapper.Wrapf(err, "error parsing part_id")
 		}
-		p, err := r.PartController.GetByVerificationCode(rawFVC)
+
+		result, err := r.PolicyController.CheckPart(*p, partUUID)
 		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error getting part by file_verification_code: \"%s\"", *fileVerificationCode)
+			return nil, err
 		}
 
-		ret := model.ToPart(p)
+		ret := model.ToPolicyCheck(result)
 		return &ret, nil
 	}
-	if sha256 != nil && *sha256 != "" {
-		rawSha256, err := hex.DecodeString(*sha256)
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error decoding sha256: \"%s\"", *sha256)
-		}
-		a, err := r.ArchiveController.GetBySha256(rawSha256)
+
+	if partlistID != nil {
+		result, err := r.PolicyController.CheckPartList(*p, *partlistID)
 		if err != nil {
 			return nil, err
 		}
-		if a.PartID == nil {
-			return nil, nil
-		}
-		p, err := r.PartController.GetByID(*a.PartID)
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", a.PartID.String())
-		}
 
-		ret := model.ToPart(p)
+		ret := model.ToPolicyCheck(result)
+		return &ret, nil
+	}
+
+	return nil, errWrapper.New("check_policy requires part_id or partlist_id")
+}
+
+// SourceBundle is the resolver for the source_bundle field.
+func (r *queryResolver) SourceBundle(ctx context.Context, id int64) (*model.SourceBundle, error) {
+	b, err := r.BundleController.GetByID(id)
+	if err == bundle.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToSourceBundle(b)
+	return &ret, nil
+}
+
+// Vulnerability is the resolver for the vulnerability field.
+func (r *queryResolver) Vulnerability(ctx context.Context, id string) (*model.Vulnerability, error) {
+	v, err := r.VulnerabilityController.GetByID(id)
+	if err == vulnerability.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToVulnerability(v)
+	return &ret, nil
+}
+
+// VulnerableParts is the resolver for the vulnerable_parts field.
+func (r *queryResolver) VulnerableParts(ctx context.Context, id string, includeResolved *bool) ([]*model.Part, error) {
+	parts, err := r.VulnerabilityController.GetParts(id, includeResolved != nil && *includeResolved)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := generics.Map[part.Part, *model.Part](parts, func(p part.Part) (*model.Part, error) {
+		ret := model.ToPart(&p)
 		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal parts to model parts")
 	}
-	if sha1 != nil && *sha1 != "" {
-		rawSha1, err := hex.DecodeString(*sha1)
+
+	return ret, nil
+}
+
+// VulnerabilityReport is the resolver for the vulnerability_report field.
+func (r *queryResolver) VulnerabilityReport(ctx context.Context, partID *string, partlistID *int64, includeResolved *bool) ([]*model.VulnerabilityFinding, error) {
+	var findings []vulnerability.Finding
+	if partID != nil {
+		partUUID, err := uuid.Parse(*partID)
 		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error decoding sha1: \"%s\"", *sha1)
+			return nil, errWrapper.Wrapf(err, "error parsing part_id")
 		}
-		a, err := r.ArchiveController.GetBySha1(rawSha1)
-		if err != nil {
+
+		if findings, err = r.VulnerabilityController.ReportPart(part.ID(partUUID), includeResolved != nil && *includeResolved); err != nil {
 			return nil, err
 		}
-		if a.PartID == nil {
-			return nil, nil
-		}
-		p, err := r.PartController.GetByID(*a.PartID)
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", a.PartID.String())
+	} else if partlistID != nil {
+		var err error
+		if findings, err = r.VulnerabilityController.ReportPartList(*partlistID, includeResolved != nil && *includeResolved); err != nil {
+			return nil, err
 		}
+	} else {
+		return nil, errWrapper.New("vulnerability_report requires part_id or partlist_id")
+	}
 
-		ret := model.ToPart(p)
+	ret, err := generics.Map[vulnerability.Finding, *model.VulnerabilityFinding](findings, func(f vulnerability.Finding) (*model.VulnerabilityFinding, error) {
+		ret := model.ToVulnerabilityFinding(&f)
 		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal findings to model findings")
 	}
-	if name != nil && *name != "" {
-		a, err := r.ArchiveController.GetByName(*name)
+
+	return ret, nil
+}
+
+// VexStatements is the resolver for the vex_statements field.
+func (r *queryResolver) VexStatements(ctx context.Context, partID *string, partlistID *int64) ([]*model.VexStatement, error) {
+	var statements []vulnerability.Statement
+	if partID != nil {
+		partUUID, err := uuid.Parse(*partID)
 		if err != nil {
-			return nil, err
+			return nil, errWrapper.Wrapf(err, "error parsing part_id")
 		}
-		if a.PartID == nil {
-			return nil, nil
+
+		if statements, err = r.VulnerabilityController.GetStatementsByPart(part.ID(partUUID)); err != nil {
+			return nil, err
 		}
-		p, err := r.PartController.GetByID(*a.PartID)
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error getting part by id: \"%s\"", a.PartID.String())
+	} else if partlistID != nil {
+		var err error
+		if statements, err = r.VulnerabilityController.GetStatementsByPartList(*partlistID); err != nil {
+			return nil, err
 		}
+	} else {
+		return nil, errWrapper.New("vex_statements requires part_id or partlist_id")
+	}
 
-		ret := model.ToPart(p)
+	ret, err := generics.Map[vulnerability.Statement, *model.VexStatement](statements, func(s vulnerability.Statement) (*model.VexStatement, error) {
+		ret := model.ToVexStatement(&s)
 		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error mapping internal VEX statements to model VEX statements")
 	}
 
-	return nil, nil // Should this be an error, no arguments found?
+	return ret, nil
 }
 
-// Archives is the resolver for the archives field.
-func (r *queryResolver) Archives(ctx context.Context, id *string, vcode *string) ([]*model.Archive, error) {
-	var partID part.ID
+// ReviewQueue is the resolver for the review_queue field.
+func (r *queryResolver) ReviewQueue(ctx context.Context, partlistID *int64, state *string) ([]*model.Part, error) {
+	var queueState string
+	if state != nil {
+		queueState = *state
+	}
 
-	if id == nil || *id == "" {
-		if vcode == nil || *vcode == "" {
-			return nil, errWrapper.New("no identifiers provided")
-		}
+	reviews, err := r.ReviewController.Queue(partlistID, queueState)
+	if err != nil {
+		return nil, err
+	}
 
-		rawVerificationCode, err := hex.DecodeString(*vcode)
+	ret, err := generics.Map[review.Review, *model.Part](reviews, func(rev review.Review) (*model.Part, error) {
+		p, err := r.PartController.GetByID(part.ID(rev.PartID))
 		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error decoding verification_code")
+			return nil, err
 		}
 
-		fileCollection, err := r.PartController.GetByVerificationCode(rawVerificationCode)
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error fetching file_collection")
-		}
+		ret := model.ToPart(p)
+		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting parts in review queue")
+	}
 
-		partID = fileCollection.PartID
-	} else {
-		var err error
-		partUUID, err := uuid.Parse(*id)
-		if err != nil {
-			return nil, errWrapper.Wrapf(err, "error parsing part_id: \"%s\"", *id)
-		}
+	return ret, nil
+}
 
-		partID = part.ID(partUUID)
+// PartDiff is the resolver for the part_diff field.
+func (r *queryResolver) PartDiff(ctx context.Context, from string, to string, textDiffs *bool) (*model.PartDiff, error) {
+	fromUUID, err := uuid.Parse(from)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing from")
+	}
+	toUUID, err := uuid.Parse(to)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing to")
 	}
 
-	archives, err := r.ArchiveController.GetByPart(partID)
+	d, err := r.DiffController.Compare(part.ID(fromUUID), part.ID(toUUID), textDiffs != nil && *textDiffs)
 	if err != nil {
-		return nil, errWrapper.Wrap(err, "error fetching archives")
+		return nil, errWrapper.Wrapf(err, "error comparing parts")
 	}
 
-	ret := make([]*model.Archive, len(archives))
+	ret := model.ToPartDiff(d)
 
-	for i, v := range archives {
-		m := model.ToArchive(&v)
-		ret[i] = &m
+	return &ret, nil
+}
+
+// SimilarParts is the resolver for the similar_parts field.
+func (r *queryResolver) SimilarParts(ctx context.Context, id string, minSimilarity *float64, limit *int) ([]*model.SimilarPart, error) {
+	partUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing id")
 	}
 
-	return ret, nil
-}
+	var threshold float64
+	if minSimilarity != nil {
+		threshold = *minSimilarity
+	}
+	var count int
+	if limit != nil {
+		count = *limit
+	}
 
-// Partlist is the resolver for the partlist field.
-func (r *queryResolver) Partlist(ctx context.Context, id *int64, name *string) (*model.PartList, error) {
-	if id != nil && *id != 0 {
-		pl, err := r.PartListController.GetByID(*id)
-		if err != nil {
-			return nil, err
-		}
-		ret := model.ToPartList(pl)
-		return &ret, nil
+	matches, err := r.SimilarityController.Similar(partUUID, threshold, count)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error finding similar parts")
 	}
-	if name != nil && *name != "" {
-		pl, err := r.PartListController.GetByName(*name)
+
+	ret, err := generics.Map[similarity.Match, *model.SimilarPart](matches, func(m similarity.Match) (*model.SimilarPart, error) {
+		p, err := r.PartController.GetByID(part.ID(m.PartID))
 		if err != nil {
 			return nil, err
 		}
-		ret := model.ToPartList(pl)
+
+		similar := model.ToPart(p)
+		ret := model.ToSimilarPart(m, &similar)
 		return &ret, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting similar parts")
 	}
-	return nil, nil
+
+	return ret, nil
 }
 
-// Partlist is the resolver for the partlist field.
-func (r *queryResolver) PartlistParts(ctx context.Context, id int64) ([]*model.Part, error) {
-	if id != 0 {
-		parts, err := r.PartListController.GetParts(id)
-		if err != nil {
-			return nil, err
-		}
-		ret := make([]*model.Part, len(parts))
-		for i, v := range parts {
-			part := model.ToPart(v)
-			ret[i] = &part
-		}
-		return ret, nil
+// Family is the resolver for the family field.
+func (r *queryResolver) Family(ctx context.Context, name string) (*model.VersionFamily, error) {
+	f, err := r.FamilyController.Get(name)
+	if err == family.ErrFamilyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting version family")
 	}
-	return nil, nil
+
+	ret := model.ToVersionFamily(f)
+
+	return &ret, nil
 }
 
-// Partlists is the resolver for the partlists field.
-func (r *queryResolver) Partlists(ctx context.Context, parentID int64) ([]*model.PartList, error) {
-	partlists, err := r.PartListController.GetByParentID(parentID)
-	if err != nil {
-		return nil, errWrapper.Wrapf(err, "error fetching partlists")
+// LatestInFamily is the resolver for the latest_in_family field.
+func (r *queryResolver) LatestInFamily(ctx context.Context, name string) (*model.Part, error) {
+	p, err := r.FamilyController.Latest(name)
+	if err == family.ErrFamilyNotFound || err == nil && p == nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting latest version")
 	}
 
-	ret := make([]*model.PartList, len(partlists))
+	ret := model.ToPart(p)
 
-	for i, v := range partlists {
-		m := model.ToPartList(&v)
-		ret[i] = &m
-	}
-	return ret, nil
+	return &ret, nil
 }
 
-// FileCount is the resolver for the file_count field.
-func (r *queryResolver) FileCount(ctx context.Context, id *string, vcode *string) (int64, error) {
-	if (id == nil || *id == "") && (vcode == nil || *vcode == "") {
-		return 0, errWrapper.New("no identifiers provided")
+// OutdatedParts is the resolver for the outdated_parts field.
+func (r *queryResolver) OutdatedParts(ctx context.Context, partlistID *int64) ([]*model.OutdatedPart, error) {
+	outdated, err := r.FamilyController.Outdated(partlistID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting outdated parts")
 	}
 
-	var partID part.ID
+	partlists := make(map[int64]*model.PartList)
+	ret, err := generics.Map[family.Outdated, *model.OutdatedPart](outdated, func(o family.Outdated) (*model.OutdatedPart, error) {
+		pl, ok := partlists[o.PartListID]
+		if !ok {
+			list, err := r.PartListController.GetByID(o.PartListID)
+			if err != nil {
+				return nil, err
+			}
 
-	if id != nil && *id != "" {
-		partUUID, err := uuid.Parse(*id)
-		if err != nil {
-			return 0, errWrapper.Wrapf(err, "error parsing part_id \"%s\"", *id)
+			tmp := model.ToPartList(list)
+			pl = &tmp
+			partlists[o.PartListID] = pl
 		}
 
-		partID = part.ID(partUUID)
-	} else {
-		fileVerificationCode, err := hex.DecodeString(*vcode)
+		p, err := r.PartController.GetByID(o.PartID)
 		if err != nil {
-			return 0, errWrapper.Wrapf(err, "error decoding verification_code")
+			return nil, err
 		}
-
-		p, err := r.PartController.GetByVerificationCode(fileVerificationCode)
+		latest, err := r.PartController.GetByID(o.Latest)
 		if err != nil {
-			return 0, errWrapper.Wrapf(err, "error getting part")
+			return nil, err
 		}
 
-		partID = p.PartID
+		member, newest := model.ToPart(p), model.ToPart(latest)
+		return &model.OutdatedPart{PartList: pl, Part: &member, FamilyName: o.Family, Latest: &newest}, nil
+	})
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error getting outdated parts")
 	}
 
-	count, err := r.PartController.CountFiles(partID)
+	return ret, nil
+}
+
+// Parts is the resolver for the parts field.
+func (r *queryResolver) Parts(ctx context.Context, typeUnder string) ([]*model.Part, error) {
+	parts, err := r.PartController.GetByTypeUnder(typeUnder)
 	if err != nil {
-		return count, errWrapper.Wrapf(err, "error counting files")
+		return nil, errWrapper.Wrapf(err, "error getting parts by type")
 	}
 
-	return count, nil
+	ret := make([]*model.Part, 0, len(parts))
+	for i := range parts {
+		p := model.ToPart(&parts[i])
+		ret = append(ret, &p)
+	}
+
+	return ret, nil
 }
 
-// Comprised is the resolver for the comprised field.
-func (r *queryResolver) Comprised(ctx context.Context, id *string) ([]*model.Part, error) {
-	if id == nil {
-		return nil, nil
+// PartTypes is the resolver for the part_types field.
+func (r *queryResolver) PartTypes(ctx context.Context) ([]*model.PartType, error) {
+	ret := make([]*model.PartType, 0, len(part.Types))
+	for _, t := range part.Types {
+		partType := model.ToPartType(t)
+		ret = append(ret, &partType)
 	}
-	comprisedID, err := uuid.Parse(*id)
+
+	return ret, nil
+}
+
+// PartGraph is the resolver for the part_graph field.
+func (r *queryResolver) PartGraph(ctx context.Context, id string, types []string, direction *string, maxDepth *int) ([]*model.PartEdge, error) {
+	partUUID, err := uuid.Parse(id)
 	if err != nil {
 		return nil, errWrapper.Wrapf(err, "error parsing id")
 	}
-
-	parts, err := r.PartController.GetByComprised(part.ID(comprisedID))
+	incoming, err := model.IsIncoming(direction)
 	if err != nil {
 		return nil, err
 	}
+	var depth int
+	if maxDepth != nil {
+		depth = *maxDepth
+	}
 
-	ret, err := generics.Map[part.Part, *model.Part](parts, func(p part.Part) (*model.Part, error) {
-		ret := model.ToPart(&p)
-		return &ret, nil
-	})
+	edges, err := r.PartController.Traverse(part.ID(partUUID), types, incoming, depth)
 	if err != nil {
-		return ret, nil
+		return nil, errWrapper.Wrapf(err, "error walking part graph")
 	}
 
-	return ret, nil
+	return generics.Map[part.Edge, *model.PartEdge](edges, func(e part.Edge) (*model.PartEdge, error) {
+		ret := model.ToPartEdge(e)
+		return &ret, nil
+	})
 }
 
-// Profile is the resolver for the profile field.
-func (r *queryResolver) Profile(ctx context.Context, id *string, key *string) ([]*model.Document, error) {
-	if id == nil || key == nil {
-		return nil, nil
+// IntegrityReport is the resolver for the integrity_report field.
+func (r *queryResolver) IntegrityReport(ctx context.Context) (*model.IntegrityReport, error) {
+	report, err := r.PartController.CheckIntegrity()
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error checking integrity")
 	}
 
-	partId, err := uuid.Parse(*id)
+	ret := model.ToIntegrityReport(report)
+	return &ret, nil
+}
+
+// VerificationMismatches is the resolver for the verification_mismatches field.
+func (r *queryResolver) VerificationMismatches(ctx context.Context) ([]*model.CodeMismatch, error) {
+	mismatches, err := r.PartController.VerificationMismatches()
 	if err != nil {
-		return nil, errWrapper.Wrapf(err, "error parsing id")
+		return nil, errWrapper.Wrapf(err, "error getting verification mismatches")
 	}
 
-	profile, err := r.PartController.GetProfile(part.ID(partId), *key)
+	return generics.Map(mismatches, func(m part.CodeMismatch) (*model.CodeMismatch, error) {
+		ret := model.ToCodeMismatch(m)
+		return &ret, nil
+	})
+}
+
+// Events is the resolver for the events field.
+func (r *reviewResolver) Events(ctx context.Context, obj *model.Review) ([]*model.ReviewEvent, error) {
+	events, err := r.ReviewController.GetEvents(obj.PartID)
 	if err != nil {
 		return nil, err
 	}
 
-	ret, err := generics.Map[part.Document, *model.Document](profile.Documents, func(d part.Document) (*model.Document, error) {
-		ret := new(model.Document)
-		ret.Title = d.Title
-		ret.Document = model.Json(d.Document)
-
-		return ret, nil
+	ret, err := generics.Map[review.Event, *model.ReviewEvent](events, func(e review.Event) (*model.ReviewEvent, error) {
+		ret := model.ToReviewEvent(&e)
+		return &ret, nil
 	})
 	if err != nil {
-		return ret, err
+		return nil, errWrapper.Wrapf(err, "error mapping internal review events to model events")
 	}
 
 	return ret, nil
 }
 
+// Aliases is the resolver for the aliases field.
+func (r *vulnerabilityResolver) Aliases(ctx context.Context, obj *model.Vulnerability) ([]string, error) {
+	return r.VulnerabilityController.GetAliases(obj.ID)
+}
+
+// Part is the resolver for the part field.
+func (r *vulnerabilityFindingResolver) Part(ctx context.Context, obj *model.VulnerabilityFinding) (*model.Part, error) {
+	partUUID, err := uuid.Parse(obj.PartID)
+	if err != nil {
+		return nil, errWrapper.Wrapf(err, "error parsing part_id")
+	}
+
+	partID := part.ID(partUUID)
+	p, err := r.PartController.GetBy(nil, &partID)
+	if err == part.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret := model.ToPart(p)
+	return &ret, nil
+}
+
 // Archive returns generated.ArchiveResolver implementation.
 func (r *Resolver) Archive() generated.ArchiveResolver { return &archiveResolver{r} }
 
+// File returns generated.FileResolver implementation.
+func (r *Resolver) File() generated.FileResolver { return &fileResolver{r} }
+
+// License returns generated.LicenseResolver implementation.
+func (r *Resolver) License() generated.LicenseResolver { return &licenseResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Part returns generated.PartResolver implementation.
 func (r *Resolver) Part() generated.PartResolver { return &partResolver{r} }
 
+// PartList returns generated.PartListResolver implementation.
+func (r *Resolver) PartList() generated.PartListResolver { return &partListResolver{r} }
+
+// PartRelationship returns generated.PartRelationshipResolver implementation.
+func (r *Resolver) PartRelationship() generated.PartRelationshipResolver {
+	return &partRelationshipResolver{r}
+}
+
+// PolicyViolation returns generated.PolicyViolationResolver implementation.
+func (r *Resolver) PolicyViolation() generated.PolicyViolationResolver {
+	return &policyViolationResolver{r}
+}
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Review returns generated.ReviewResolver implementation.
+func (r *Resolver) Review() generated.ReviewResolver { return &reviewResolver{r} }
+
+// Vulnerability returns generated.VulnerabilityResolver implementation.
+func (r *Resolver) Vulnerability() generated.VulnerabilityResolver { return &vulnerabilityResolver{r} }
+
+// VulnerabilityFinding returns generated.VulnerabilityFindingResolver implementation.
+func (r *Resolver) VulnerabilityFinding() generated.VulnerabilityFindingResolver {
+	return &vulnerabilityFindingResolver{r}
+}
+
 type archiveResolver struct{ *Resolver }
+type fileResolver struct{ *Resolver }
+type licenseResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type partResolver struct{ *Resolver }
+type partListResolver struct{ *Resolver }
+type partRelationshipResolver struct{ *Resolver }
+type policyViolationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type reviewResolver struct{ *Resolver }
+type vulnerabilityResolver struct{ *Resolver }
+type vulnerabilityFindingResolver struct{ *Resolver }
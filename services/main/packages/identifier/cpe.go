@@ -0,0 +1,171 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package identifier
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CPE is a CPE 2.3 name, with attributes in formatted string binding: lower case, with special characters escaped by a backslash
+type CPE struct {
+	Part      string
+	Vendor    string
+	Product   string
+	Version   string
+	Update    string
+	Edition   string
+	Language  string
+	SWEdition string
+	TargetSW  string
+	TargetHW  string
+	Other     string
+}
+
+// splitEscaped splits s on sep, except where sep is escaped with a backslash
+func splitEscaped(s string, sep byte) []string {
+	ret := make([]string, 0)
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case sep:
+			ret = append(ret, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(ret, s[start:])
+}
+
+// quote escapes an attribute decoded from a CPE 2.2 URI for the formatted string binding
+func quote(s string) string {
+	if s == "" {
+		return "*"
+	}
+	if s == "-" || s == "*" {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '.' || c == '_' || c == '-') {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// ParseCPE parses and normalizes a CPE 2.3 formatted string, or a CPE 2.2 URI which is converted to a formatted string.
+// Missing trailing attributes are taken as *.
+func ParseCPE(s string) (*CPE, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	var attributes []string
+	switch {
+	case strings.HasPrefix(s, "cpe:2.3:"):
+		attributes = splitEscaped(s[len("cpe:2.3:"):], ':')
+	case strings.HasPrefix(s, "cpe:/"):
+		uri := strings.Split(s[len("cpe:/"):], ":")
+		if len(uri) > 7 {
+			return nil, errors.Errorf("CPE URI \"%s\" has too many components", s)
+		}
+		for len(uri) < 7 {
+			uri = append(uri, "")
+		}
+
+		// the edition may pack the extended attributes, ~edition~sw_edition~target_sw~target_hw~other
+		extended := []string{uri[5], "", "", "", ""}
+		if strings.HasPrefix(uri[5], "~") {
+			packed := strings.Split(uri[5][1:], "~")
+			if len(packed) > 5 {
+				return nil, errors.Errorf("CPE URI \"%s\" has too many packed edition components", s)
+			}
+			copy(extended, packed)
+		}
+		// part, vendor, product, version, update, edition, language, sw_edition, target_sw, target_hw, other
+		uri = []string{uri[0], uri[1], uri[2], uri[3], uri[4], extended[0], uri[6], extended[1], extended[2], extended[3], extended[4]}
+
+		attributes = make([]string, 0, len(uri))
+		for _, v := range uri {
+			decoded, err := url.PathUnescape(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error decoding CPE URI \"%s\"", s)
+			}
+			attributes = append(attributes, quote(decoded))
+		}
+	default:
+		return nil, errors.Errorf("CPE \"%s\" does not start with cpe:2.3: or cpe:/", s)
+	}
+
+	if len(attributes) > 11 {
+		return nil, errors.Errorf("CPE \"%s\" has too many components", s)
+	}
+	if len(attributes) < 3 {
+		return nil, errors.Errorf("CPE \"%s\" needs at least a part, vendor, and product", s)
+	}
+	for len(attributes) < 11 {
+		attributes = append(attributes, "*")
+	}
+	for i := range attributes {
+		if attributes[i] == "" {
+			attributes[i] = "*"
+		}
+	}
+
+	cpe := &CPE{
+		Part:      attributes[0],
+		Vendor:    attributes[1],
+		Product:   attributes[2],
+		Version:   attributes[3],
+		Update:    attributes[4],
+		Edition:   attributes[5],
+		Language:  attributes[6],
+		SWEdition: attributes[7],
+		TargetSW:  attributes[8],
+		TargetHW:  attributes[9],
+		Other:     attributes[10],
+	}
+	if cpe.Part != "a" && cpe.Part != "o" && cpe.Part != "h" && cpe.Part != "*" && cpe.Part != "-" {
+		return nil, errors.Errorf("CPE \"%s\" has invalid part \"%s\", expected a, o, or h", s, cpe.Part)
+	}
+	if cpe.Product == "*" || cpe.Product == "-" {
+		return nil, errors.Errorf("CPE \"%s\" has no product", s)
+	}
+
+	return cpe, nil
+}
+
+// Base returns the CPE's part, vendor, and product, which every version of the product shares
+func (cpe CPE) Base() string {
+	return "cpe:2.3:" + cpe.Part + ":" + cpe.Vendor + ":" + cpe.Product
+}
+
+// VersionString returns the version without escapes, or an empty string if it is any (*) or not applicable (-)
+func (cpe CPE) VersionString() string {
+	if cpe.Version == "*" || cpe.Version == "-" {
+		return ""
+	}
+
+	return strings.ReplaceAll(cpe.Version, "\\", "")
+}
+
+// String returns the CPE 2.3 formatted string
+func (cpe CPE) String() string {
+	return strings.Join([]string{"cpe", "2.3", cpe.Part, cpe.Vendor, cpe.Product, cpe.Version, cpe.Update, cpe.Edition,
+		cpe.Language, cpe.SWEdition, cpe.TargetSW, cpe.TargetHW, cpe.Other}, ":")
+}
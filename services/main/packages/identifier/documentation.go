@@ -0,0 +1,2 @@
+// identifier validates and normalizes external identifiers of software parts: package URLs (purl), CPE 2.3 names, and SWID tag ids
+package identifier
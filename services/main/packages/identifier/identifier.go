@@ -0,0 +1,101 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package identifier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Types of identifiers
+const (
+	TypePurl = "purl"
+	TypeCPE  = "cpe"
+	TypeSWID = "swid"
+)
+
+// Types lists every identifier type
+var Types = []string{TypePurl, TypeCPE, TypeSWID}
+
+// Identifier is a normalized external identifier.
+// Name is the identifier without its version, so that every version of a package shares it, and Version is empty if the identifier has none.
+type Identifier struct {
+	Type    string
+	Value   string
+	Name    string
+	Version string
+}
+
+// Parse validates and normalizes an identifier of the given type
+func Parse(idType string, value string) (*Identifier, error) {
+	switch strings.ToLower(idType) {
+	case TypePurl:
+		purl, err := ParsePurl(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Identifier{Type: TypePurl, Value: purl.String(), Name: purl.Base(), Version: purl.Version}, nil
+	case TypeCPE:
+		cpe, err := ParseCPE(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Identifier{Type: TypeCPE, Value: cpe.String(), Name: cpe.Base(), Version: cpe.VersionString()}, nil
+	case TypeSWID:
+		tagID, err := ParseSWID(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Identifier{Type: TypeSWID, Value: tagID, Name: tagID}, nil
+	default:
+		return nil, errors.Errorf("unknown identifier type \"%s\", expected one of %s", idType, strings.Join(Types, ", "))
+	}
+}
+
+// ParseSWID validates a SWID tag id, returning it trimmed, and in lower case if it is a GUID
+func ParseSWID(tagID string) (string, error) {
+	tagID = strings.TrimSpace(tagID)
+	if tagID == "" {
+		return "", errors.New("SWID tag id is empty")
+	}
+	for _, r := range tagID {
+		if r < 0x20 || r == 0x7f {
+			return "", errors.Errorf("SWID tag id \"%s\" has control characters", tagID)
+		}
+	}
+
+	if id, err := uuid.Parse(tagID); err == nil {
+		return id.String(), nil
+	}
+
+	return tagID, nil
+}
+
+// escape percent-encodes everything but unreserved characters and colons
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte("-._~:", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
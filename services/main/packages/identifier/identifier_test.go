@@ -0,0 +1,121 @@
+package identifier
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		idType    string
+		value     string
+		want      string
+		wantName  string
+		wantVer   string
+		wantError bool
+	}{
+		{
+			name:     "purl",
+			idType:   TypePurl,
+			value:    "pkg:npm/%40angular/animation@12.3.1",
+			want:     "pkg:npm/%40angular/animation@12.3.1",
+			wantName: "pkg:npm/%40angular/animation",
+			wantVer:  "12.3.1",
+		},
+		{
+			name:     "purl qualifiers are sorted and subpath cleaned",
+			idType:   TypePurl,
+			value:    "PKG:Maven/org.apache.commons/io@1.3.4?type=jar&Classifier=sources&empty=#/src/./main/",
+			want:     "pkg:maven/org.apache.commons/io@1.3.4?classifier=sources&type=jar#src/main",
+			wantName: "pkg:maven/org.apache.commons/io",
+			wantVer:  "1.3.4",
+		},
+		{
+			name:     "purl type rules",
+			idType:   TypePurl,
+			value:    "pkg:pypi/Django_Rest@3.0",
+			want:     "pkg:pypi/django-rest@3.0",
+			wantName: "pkg:pypi/django-rest",
+			wantVer:  "3.0",
+		},
+		{
+			name:     "purl unencoded npm scope",
+			idType:   TypePurl,
+			value:    "pkg:npm/@babel/core",
+			want:     "pkg:npm/%40babel/core",
+			wantName: "pkg:npm/%40babel/core",
+		},
+		{
+			name:     "purl version escaping",
+			idType:   TypePurl,
+			value:    "pkg:deb/debian/curl@7.50.3-1+deb9u1?arch=i386",
+			want:     "pkg:deb/debian/curl@7.50.3-1%2Bdeb9u1?arch=i386",
+			wantName: "pkg:deb/debian/curl",
+			wantVer:  "7.50.3-1+deb9u1",
+		},
+		{name: "purl without scheme", idType: TypePurl, value: "npm/foo@1.0", wantError: true},
+		{name: "purl without name", idType: TypePurl, value: "pkg:npm/", wantError: true},
+		{
+			name:     "cpe 2.3",
+			idType:   TypeCPE,
+			value:    "cpe:2.3:a:OpenSSL:OpenSSL:1.1.1k:*:*:*:*:*:*:*",
+			want:     "cpe:2.3:a:openssl:openssl:1.1.1k:*:*:*:*:*:*:*",
+			wantName: "cpe:2.3:a:openssl:openssl",
+			wantVer:  "1.1.1k",
+		},
+		{
+			name:     "cpe 2.3 short",
+			idType:   TypeCPE,
+			value:    "cpe:2.3:a:gnu:zlib",
+			want:     "cpe:2.3:a:gnu:zlib:*:*:*:*:*:*:*:*",
+			wantName: "cpe:2.3:a:gnu:zlib",
+		},
+		{
+			name:     "cpe 2.3 escaped colon",
+			idType:   TypeCPE,
+			value:    "cpe:2.3:a:foo\\:bar:baz:1.0\\:2:*:*:*:*:*:*:*",
+			want:     "cpe:2.3:a:foo\\:bar:baz:1.0\\:2:*:*:*:*:*:*:*",
+			wantName: "cpe:2.3:a:foo\\:bar:baz",
+			wantVer:  "1.0:2",
+		},
+		{
+			name:     "cpe 2.2 uri",
+			idType:   TypeCPE,
+			value:    "cpe:/a:microsoft:internet_explorer:8.0.6001:beta",
+			want:     "cpe:2.3:a:microsoft:internet_explorer:8.0.6001:beta:*:*:*:*:*:*",
+			wantName: "cpe:2.3:a:microsoft:internet_explorer",
+			wantVer:  "8.0.6001",
+		},
+		{
+			name:     "cpe 2.2 uri packed edition",
+			idType:   TypeCPE,
+			value:    "cpe:/a:hp:insight_diagnostics:7.4.0.1570::~~online~win2003~x64~",
+			want:     "cpe:2.3:a:hp:insight_diagnostics:7.4.0.1570:*:*:*:online:win2003:x64:*",
+			wantName: "cpe:2.3:a:hp:insight_diagnostics",
+			wantVer:  "7.4.0.1570",
+		},
+		{name: "cpe invalid part", idType: TypeCPE, value: "cpe:2.3:x:foo:bar", wantError: true},
+		{name: "cpe without product", idType: TypeCPE, value: "cpe:2.3:a:foo", wantError: true},
+		{
+			name:     "swid guid",
+			idType:   TypeSWID,
+			value:    " 2DF9BA8E-6C4C-4E3E-8C3A-7DA4F8B0E0C9 ",
+			want:     "2df9ba8e-6c4c-4e3e-8c3a-7da4f8b0e0c9",
+			wantName: "2df9ba8e-6c4c-4e3e-8c3a-7da4f8b0e0c9",
+		},
+		{name: "swid empty", idType: TypeSWID, value: "  ", wantError: true},
+		{name: "unknown type", idType: "spdx", value: "x", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.idType, tt.value)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Parse() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err != nil {
+				return
+			}
+			if got.Value != tt.want || got.Name != tt.wantName || got.Version != tt.wantVer {
+				t.Errorf("Parse() = %s %s %s, want %s %s %s", got.Value, got.Name, got.Version, tt.want, tt.wantName, tt.wantVer)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package identifier
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Purl is a package URL, pkg:type/namespace/name@version?qualifiers#subpath
+type Purl struct {
+	Type       string
+	Namespace  string
+	Name       string
+	Version    string
+	Qualifiers map[string]string
+	Subpath    string
+}
+
+// NewPurl returns a purl of the given type, namespace, name, and version, normalized as if parsed
+func NewPurl(purlType string, namespace string, name string, version string) Purl {
+	purl := Purl{
+		Type:      strings.ToLower(purlType),
+		Namespace: strings.Trim(namespace, "/"),
+		Name:      name,
+		Version:   version,
+	}
+	purl.normalize()
+
+	return purl
+}
+
+// normalize applies the case and character rules of the purl types that have them
+func (purl *Purl) normalize() {
+	switch purl.Type {
+	case "github", "bitbucket", "composer":
+		purl.Namespace = strings.ToLower(purl.Namespace)
+		purl.Name = strings.ToLower(purl.Name)
+	case "pypi":
+		purl.Name = strings.ReplaceAll(strings.ToLower(purl.Name), "_", "-")
+	case "generic":
+		purl.Name = strings.ToLower(purl.Name)
+	}
+}
+
+func unescapeSegments(path string) ([]string, error) {
+	ret := make([]string, 0)
+	for _, v := range strings.Split(path, "/") {
+		if v == "" {
+			continue
+		}
+
+		unescaped, err := url.PathUnescape(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error unescaping \"%s\"", v)
+		}
+		ret = append(ret, unescaped)
+	}
+
+	return ret, nil
+}
+
+// ParsePurl parses and normalizes a package URL
+func ParsePurl(s string) (*Purl, error) {
+	purl := new(Purl)
+	remainder := strings.TrimSpace(s)
+
+	if i := strings.LastIndexByte(remainder, '#'); i >= 0 {
+		segments, err := unescapeSegments(remainder[i+1:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing purl subpath")
+		}
+
+		clean := make([]string, 0, len(segments))
+		for _, v := range segments {
+			if v != "." && v != ".." {
+				clean = append(clean, v)
+			}
+		}
+		purl.Subpath = strings.Join(clean, "/")
+		remainder = remainder[:i]
+	}
+
+	if i := strings.LastIndexByte(remainder, '?'); i >= 0 {
+		purl.Qualifiers = make(map[string]string)
+		for _, v := range strings.Split(remainder[i+1:], "&") {
+			key, value, _ := strings.Cut(v, "=")
+			unescaped, err := url.PathUnescape(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing purl qualifier \"%s\"", key)
+			}
+			if key != "" && unescaped != "" {
+				purl.Qualifiers[strings.ToLower(key)] = unescaped
+			}
+		}
+		remainder = remainder[:i]
+	}
+
+	if !strings.HasPrefix(strings.ToLower(remainder), "pkg:") {
+		return nil, errors.Errorf("purl \"%s\" does not start with pkg:", s)
+	}
+	remainder = strings.TrimLeft(remainder[len("pkg:"):], "/")
+
+	purlType, remainder, ok := strings.Cut(remainder, "/")
+	if !ok || purlType == "" {
+		return nil, errors.Errorf("purl \"%s\" has no type", s)
+	}
+	for _, c := range purlType {
+		if !(('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '.' || c == '+' || c == '-') {
+			return nil, errors.Errorf("purl \"%s\" has an invalid type", s)
+		}
+	}
+	purl.Type = strings.ToLower(purlType)
+
+	// a version follows the last @, unless it is part of the namespace such as an unencoded npm scope
+	if i := strings.LastIndexByte(remainder, '@'); i >= 0 && i > strings.LastIndexByte(remainder, '/') {
+		version, err := url.PathUnescape(remainder[i+1:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing purl version")
+		}
+		purl.Version = version
+		remainder = remainder[:i]
+	}
+
+	segments, err := unescapeSegments(remainder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing purl name")
+	}
+	if len(segments) == 0 {
+		return nil, errors.Errorf("purl \"%s\" has no name", s)
+	}
+	purl.Name = segments[len(segments)-1]
+	purl.Namespace = strings.Join(segments[:len(segments)-1], "/")
+
+	purl.normalize()
+
+	return purl, nil
+}
+
+// Base returns the purl without version, qualifiers, or subpath, which every version of the package shares
+func (purl Purl) Base() string {
+	var b strings.Builder
+	b.WriteString("pkg:" + purl.Type + "/")
+	if purl.Namespace != "" {
+		for _, v := range strings.Split(purl.Namespace, "/") {
+			b.WriteString(escape(v) + "/")
+		}
+	}
+	b.WriteString(escape(purl.Name))
+
+	return b.String()
+}
+
+// String returns the canonical form of the purl
+func (purl Purl) String() string {
+	ret := purl.Base()
+	if purl.Version != "" {
+		ret += "@" + escape(purl.Version)
+	}
+
+	if len(purl.Qualifiers) > 0 {
+		keys := make([]string, 0, len(purl.Qualifiers))
+		for k := range purl.Qualifiers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		qualifiers := make([]string, 0, len(keys))
+		for _, k := range keys {
+			qualifiers = append(qualifiers, k+"="+escape(purl.Qualifiers[k]))
+		}
+		ret += "?" + strings.Join(qualifiers, "&")
+	}
+
+	if purl.Subpath != "" {
+		segments := strings.Split(purl.Subpath, "/")
+		for i := range segments {
+			segments[i] = escape(segments[i])
+		}
+		ret += "#" + strings.Join(segments, "/")
+	}
+
+	return ret
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"wrs/tk/packages/core/audit"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Roles of identities, admin has every role, and curators and uploaders are viewers too
+const (
+	RoleViewer   = "viewer"   // may read the catalog
+	RoleCurator  = "curator"  // may change curated data, such as licenses, aliases, documents, and partlists
+	RoleUploader = "uploader" // may add archives and parts
+	RoleAdmin    = "admin"    // may delete parts, import from server paths, and use the playground
+)
+
+// Roles lists every role
+var Roles = []string{RoleViewer, RoleCurator, RoleUploader, RoleAdmin}
+
+// Identity is who a request is made by
+type Identity struct {
+	Name   string   // recorded as the actor of changes made by the request
+	Roles  []string // roles granted, unknown roles are ignored
+	Method string   // how the identity was authenticated, token, jwt, or anonymous
+}
+
+// HasRole returns whether the identity was granted the role, or a role including it
+func (identity *Identity) HasRole(role string) bool {
+	if identity == nil {
+		return false
+	}
+
+	for _, v := range identity.Roles {
+		if v == role || v == RoleAdmin || role == RoleViewer && (v == RoleCurator || v == RoleUploader) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authenticator authenticates the credential of a request, a bearer token, or the password of basic authentication.
+// A nil identity and error are returned if the credential is not of a kind the authenticator handles.
+type Authenticator interface {
+	Authenticate(credential string) (*Identity, error)
+}
+
+type identityKey int
+
+// IdentityKey guarentees uniqueness for use as a context value key.
+const IdentityKey identityKey = iota
+
+// GetIdentity returns the identity a request was made by, nil if it was not authenticated
+func GetIdentity(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(IdentityKey).(*Identity)
+	return identity
+}
+
+// credential returns the bearer token or basic authentication password of a request
+func credential(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if scheme, value, ok := strings.Cut(authorization, " "); ok {
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			return strings.TrimSpace(value)
+		case "basic":
+			if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value)); err == nil {
+				if _, password, ok := strings.Cut(string(b), ":"); ok {
+					return password
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// Authenticate creates a handler attaching the identity of the request's credential to its context, and attributing the changes it makes to that identity.
+// Requests without a credential are anonymous, with anonymousRoles. Requests with a credential no authenticator accepts are unauthorized.
+func Authenticate(anonymousRoles []string, authenticators ...Authenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := &Identity{Name: audit.Anonymous, Roles: anonymousRoles, Method: "anonymous"}
+			if c := credential(r); c != "" {
+				identity = nil
+				for _, authenticator := range authenticators {
+					id, err := authenticator.Authenticate(c)
+					if err != nil {
+						log.Info().Err(err).Str("path", r.URL.Path).Msg("authentication failed")
+						unauthorized(w, "invalid credentials")
+						return
+					} else if id != nil {
+						identity = id
+						break
+					}
+				}
+				if identity == nil {
+					unauthorized(w, "unsupported credentials")
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), IdentityKey, identity)
+			ctx = audit.ContextWithActor(ctx, identity.Name)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole creates a handler only passing on requests by identities with the role
+func RequireRole(role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := GetIdentity(r.Context())
+			if identity.HasRole(role) {
+				next.ServeHTTP(w, r)
+			} else if identity == nil || identity.Method == "anonymous" {
+				unauthorized(w, fmt.Sprintf("%s role required", role))
+			} else {
+				http.Error(w, fmt.Sprintf("%s role required", role), 403)
+			}
+		})
+	}
+}
+
+// unauthorized responds with a 401, offering basic authentication so browsers can prompt for a token
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Add("WWW-Authenticate", `Bearer realm="tk"`)
+	w.Header().Add("WWW-Authenticate", `Basic realm="tk"`)
+	http.Error(w, message, 401)
+}
@@ -0,0 +1,352 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // hashes of RS256, PS256, and ES256
+	_ "crypto/sha512" // hashes of RS384, RS512, PS384, PS512, ES384, and ES512
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidJWT = fmt.Errorf("invalid jwt")
+
+// leeway is the clock skew allowed when checking the times of a JWT
+const leeway = time.Minute
+
+// JWTAuthenticator authenticates OIDC/JWT bearer tokens signed by a key of a JWKS file, with an RS, PS, or ES algorithm
+type JWTAuthenticator struct {
+	Keys       map[string]crypto.PublicKey // keys by key id
+	Issuer     string                      // required iss claim, if set
+	Audience   string                      // required in the aud claim, if set
+	NameClaim  string                      // claim naming the user, sub if empty
+	RolesClaim string                      // claim listing roles, a dot separated path into nested claims, roles if empty
+	Now        func() time.Time            // clock, time.Now if nil
+}
+
+// NewJWTAuthenticator returns an authenticator of JWTs signed by the keys of the JWKS file at jwksPath
+func NewJWTAuthenticator(jwksPath string, issuer string, audience string, nameClaim string, rolesClaim string) (*JWTAuthenticator, error) {
+	b, err := os.ReadFile(jwksPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading jwks %s", jwksPath)
+	}
+
+	keys, err := ParseJWKS(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing jwks %s", jwksPath)
+	}
+
+	return &JWTAuthenticator{
+		Keys:       keys,
+		Issuer:     issuer,
+		Audience:   audience,
+		NameClaim:  nameClaim,
+		RolesClaim: rolesClaim,
+	}, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS returns the RSA and EC signing keys of a JWKS, by key id
+func ParseJWKS(b []byte) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &jwks); err != nil {
+		return nil, err
+	}
+
+	bigInt := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil || len(b) == 0 {
+			return nil, errors.Errorf("invalid key parameter %s", s)
+		}
+
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	ret := make(map[string]crypto.PublicKey)
+	for _, key := range jwks.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		switch key.Kty {
+		case "RSA":
+			n, err := bigInt(key.N)
+			if err != nil {
+				return nil, errors.Wrapf(err, "key %s", key.Kid)
+			}
+			e, err := bigInt(key.E)
+			if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31 {
+				return nil, errors.Errorf("key %s has an invalid exponent", key.Kid)
+			}
+
+			ret[key.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch key.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, errors.Errorf("key %s has unsupported curve %s", key.Kid, key.Crv)
+			}
+			x, err := bigInt(key.X)
+			if err != nil {
+				return nil, errors.Wrapf(err, "key %s", key.Kid)
+			}
+			y, err := bigInt(key.Y)
+			if err != nil {
+				return nil, errors.Wrapf(err, "key %s", key.Kid)
+			}
+			if !curve.IsOnCurve(x, y) {
+				return nil, errors.Errorf("key %s is not on curve %s", key.Kid, key.Crv)
+			}
+
+			ret[key.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("no rsa or ec signing keys")
+	}
+
+	return ret, nil
+}
+
+// verifySignature verifies the signature of a JWT's header and payload
+func verifySignature(alg string, key crypto.PublicKey, signed []byte, signature []byte) error {
+	if len(alg) != 5 {
+		return errors.Errorf("unsupported alg %s", alg)
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return errors.Errorf("unsupported alg %s", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.Errorf("alg %s needs an rsa key", alg)
+		}
+		if alg[:2] == "RS" {
+			return rsa.VerifyPKCS1v15(k, hash, digest, signature)
+		}
+
+		return rsa.VerifyPSS(k, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.Errorf("alg %s needs an ec key", alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	default:
+		return errors.Errorf("unsupported alg %s", alg)
+	}
+}
+
+// numericDate returns the time of a NumericDate claim
+func numericDate(claims map[string]any, claim string) (*time.Time, error) {
+	v, ok := claims[claim]
+	if !ok {
+		return nil, nil
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, errors.Errorf("claim %s is not a number", claim)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, errors.Wrapf(err, "claim %s", claim)
+	}
+
+	t := time.Unix(int64(f), 0)
+	return &t, nil
+}
+
+// claimStrings returns a claim that is a string, a space separated string, or an array of strings, such as aud or roles
+func claimStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		ret := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+
+		return ret
+	}
+
+	return nil
+}
+
+// Authenticate returns the identity of a JWT, or an error if its signature, times, issuer, or audience are invalid
+func (a *JWTAuthenticator) Authenticate(credential string) (*Identity, error) {
+	parts := strings.Split(credential, ".")
+	if len(parts) != 3 {
+		return nil, nil
+	}
+
+	decode := func(s string, v any) error {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(string(b)))
+		decoder.UseNumber()
+		return decoder.Decode(v)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decode(parts[0], &header); err != nil {
+		return nil, errors.Wrapf(ErrInvalidJWT, "header: %s", err.Error())
+	}
+
+	key, ok := a.Keys[header.Kid]
+	if !ok && header.Kid == "" && len(a.Keys) == 1 {
+		for _, v := range a.Keys {
+			key, ok = v, true
+		}
+	}
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidJWT, "unknown key %s", header.Kid)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidJWT, "signature: %s", err.Error())
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, errors.Wrapf(ErrInvalidJWT, "%s", err.Error())
+	}
+
+	claims := make(map[string]any)
+	if err := decode(parts[1], &claims); err != nil {
+		return nil, errors.Wrapf(ErrInvalidJWT, "claims: %s", err.Error())
+	}
+
+	now := time.Now()
+	if a.Now != nil {
+		now = a.Now()
+	}
+	exp, err := numericDate(claims, "exp")
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidJWT, "%s", err.Error())
+	} else if exp == nil {
+		return nil, errors.Wrapf(ErrInvalidJWT, "no exp")
+	} else if now.After(exp.Add(leeway)) {
+		return nil, errors.Wrapf(ErrInvalidJWT, "expired at %s", exp.String())
+	}
+	if nbf, err := numericDate(claims, "nbf"); err != nil {
+		return nil, errors.Wrapf(ErrInvalidJWT, "%s", err.Error())
+	} else if nbf != nil && now.Before(nbf.Add(-leeway)) {
+		return nil, errors.Wrapf(ErrInvalidJWT, "not valid before %s", nbf.String())
+	}
+	if a.Issuer != "" && claims["iss"] != a.Issuer {
+		return nil, errors.Wrapf(ErrInvalidJWT, "issuer %v", claims["iss"])
+	}
+	if a.Audience != "" {
+		found := false
+		for _, v := range claimStrings(claims["aud"]) {
+			found = found || v == a.Audience
+		}
+		if !found {
+			return nil, errors.Wrapf(ErrInvalidJWT, "audience %v", claims["aud"])
+		}
+	}
+
+	nameClaim := a.NameClaim
+	if nameClaim == "" {
+		nameClaim = "sub"
+	}
+	name, _ := claims[nameClaim].(string)
+	if name == "" {
+		return nil, errors.Wrapf(ErrInvalidJWT, "no %s", nameClaim)
+	}
+
+	rolesClaim := a.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	var roles any = claims
+	for _, v := range strings.Split(rolesClaim, ".") {
+		if m, ok := roles.(map[string]any); ok {
+			roles = m[v]
+		} else {
+			roles = nil
+		}
+	}
+
+	identity := Identity{Name: name, Roles: make([]string, 0), Method: "jwt"}
+	for _, role := range claimStrings(roles) {
+		if isRole(role) {
+			identity.Roles = append(identity.Roles, role)
+		}
+	}
+
+	return &identity, nil
+}
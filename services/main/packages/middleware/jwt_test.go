@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": "%s", "e": "%s"},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "%s", "y": "%s"},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"}
+	]}`, b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()), b64(ecKey.X.FillBytes(make([]byte, 32))), b64(ecKey.Y.FillBytes(make([]byte, 32))))
+	keys, err := ParseJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatalf("ParseJWKS() error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("ParseJWKS() = %d keys, want 2", len(keys))
+	}
+
+	now := time.Unix(1700000000, 0)
+	sign := func(alg string, kid string, claims map[string]any) string {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signed := b64(header) + "." + b64(payload)
+		digest := sha256.Sum256([]byte(signed))
+
+		var signature []byte
+		switch alg {
+		case "RS256":
+			signature, _ = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		case "PS256":
+			signature, _ = rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		case "ES256":
+			r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+
+		return signed + "." + b64(signature)
+	}
+	claims := func(extra map[string]any) map[string]any {
+		ret := map[string]any{"sub": "alice", "iss": "https://idp", "aud": []string{"tk"}, "exp": now.Add(time.Hour).Unix(),
+			"realm_access": map[string]any{"roles": []string{"curator", "offline_access"}}}
+		for k, v := range extra {
+			ret[k] = v
+		}
+
+		return ret
+	}
+
+	authenticator := JWTAuthenticator{Keys: keys, Issuer: "https://idp", Audience: "tk", RolesClaim: "realm_access.roles", Now: func() time.Time { return now }}
+	tests := []struct {
+		name      string
+		token     string
+		want      *Identity
+		wantError bool
+	}{
+		{name: "rs256", token: sign("RS256", "rsa", claims(nil)), want: &Identity{Name: "alice", Roles: []string{RoleCurator}, Method: "jwt"}},
+		{name: "ps256", token: sign("PS256", "rsa", claims(nil)), want: &Identity{Name: "alice", Roles: []string{RoleCurator}, Method: "jwt"}},
+		{name: "es256", token: sign("ES256", "ec", claims(nil)), want: &Identity{Name: "alice", Roles: []string{RoleCurator}, Method: "jwt"}},
+		{name: "api token", token: TokenPrefix + "abc"},
+		{name: "wrong key type", token: sign("ES256", "rsa", claims(nil)), wantError: true},
+		{name: "unknown key", token: sign("RS256", "other", claims(nil)), wantError: true},
+		{name: "alg none", token: sign("none", "rsa", claims(nil)), wantError: true},
+		{name: "expired", token: sign("RS256", "rsa", claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})), wantError: true},
+		{name: "no exp", token: sign("RS256", "rsa", claims(map[string]any{"exp": nil})), wantError: true},
+		{name: "not yet valid", token: sign("RS256", "rsa", claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})), wantError: true},
+		{name: "wrong issuer", token: sign("RS256", "rsa", claims(map[string]any{"iss": "https://other"})), wantError: true},
+		{name: "wrong audience", token: sign("RS256", "rsa", claims(map[string]any{"aud": "other"})), wantError: true},
+		{name: "no subject", token: sign("RS256", "rsa", claims(map[string]any{"sub": ""})), wantError: true},
+		{name: "tampered", token: sign("RS256", "rsa", claims(nil)) + "A", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authenticator.Authenticate(tt.token)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Authenticate() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentityHasRole(t *testing.T) {
+	tests := []struct {
+		roles []string
+		role  string
+		want  bool
+	}{
+		{roles: []string{RoleAdmin}, role: RoleUploader, want: true},
+		{roles: []string{RoleCurator}, role: RoleViewer, want: true},
+		{roles: []string{RoleUploader}, role: RoleViewer, want: true},
+		{roles: []string{RoleCurator}, role: RoleUploader, want: false},
+		{roles: []string{RoleViewer}, role: RoleCurator, want: false},
+		{roles: nil, role: RoleViewer, want: false},
+	}
+	for _, tt := range tests {
+		if got := (&Identity{Roles: tt.roles}).HasRole(tt.role); got != tt.want {
+			t.Errorf("Identity{Roles: %v}.HasRole(%s) = %v, want %v", tt.roles, tt.role, got, tt.want)
+		}
+	}
+}
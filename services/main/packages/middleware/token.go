@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// TokenPrefix starts every API token, telling them apart from JWTs
+const TokenPrefix = "tk_"
+
+var ErrTokenNotFound = fmt.Errorf("api token not found")
+
+// TokenAuthenticator authenticates static API tokens, which are only stored as their sha256
+type TokenAuthenticator struct {
+	DB *sqlx.DB
+}
+
+// Authenticate returns the identity of an API token, named token:<name>, or an error if it is unknown, revoked, or expired
+func (a TokenAuthenticator) Authenticate(credential string) (*Identity, error) {
+	if !strings.HasPrefix(credential, TokenPrefix) {
+		return nil, nil
+	}
+
+	sum := sha256.Sum256([]byte(credential))
+	var name string
+	var roles []byte
+	if err := a.DB.QueryRow(`UPDATE api_token SET last_used=NOW()
+	WHERE token_sha256=$1 AND NOT revoked AND (expires IS NULL OR expires > NOW())
+	RETURNING name, roles`, sum[:]).Scan(&name, &roles); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTokenNotFound
+		}
+
+		return nil, errors.Wrapf(err, "error selecting api token")
+	}
+
+	identity := Identity{Name: "token:" + name, Method: "token"}
+	if err := json.Unmarshal(roles, &identity.Roles); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshalling roles of api token %s", name)
+	}
+
+	return &identity, nil
+}
+
+// CreateToken creates an API token with roles, expiring after expires unless it is nil, and returns it.
+// The token can't be retrieved again, only its sha256 is kept.
+func (a TokenAuthenticator) CreateToken(name string, roles []string, expires *time.Time) (string, error) {
+	if name == "" {
+		return "", errors.New("api token name required")
+	}
+	for _, role := range roles {
+		if !isRole(role) {
+			return "", errors.Errorf("unknown role %s", role)
+		}
+	}
+	b, err := json.Marshal(roles)
+	if err != nil {
+		return "", errors.Wrapf(err, "error marshalling roles")
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", errors.Wrapf(err, "error generating api token")
+	}
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+	sum := sha256.Sum256([]byte(token))
+
+	if _, err := a.DB.Exec("INSERT INTO api_token (name, token_sha256, roles, expires) VALUES ($1, $2, $3, $4)",
+		name, sum[:], b, expires); err != nil {
+		return "", errors.Wrapf(err, "error inserting api token %s", name)
+	}
+
+	return token, nil
+}
+
+// RevokeToken revokes the API token with a name
+func (a TokenAuthenticator) RevokeToken(name string) error {
+	res, err := a.DB.Exec("UPDATE api_token SET revoked=TRUE WHERE name=$1 AND NOT revoked", name)
+	if err != nil {
+		return errors.Wrapf(err, "error revoking api token %s", name)
+	}
+	if count, _ := res.RowsAffected(); count < 1 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
+
+func isRole(role string) bool {
+	for _, v := range Roles {
+		if v == role {
+			return true
+		}
+	}
+
+	return false
+}
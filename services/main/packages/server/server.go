@@ -15,23 +15,31 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
-	"wrs/tk/packages/blob"
-	"wrs/tk/packages/blob/bucket"
 	mainConfig "wrs/tk/packages/config"
 	archive_core "wrs/tk/packages/core/archive"
+	"wrs/tk/packages/core/bundle"
+	"wrs/tk/packages/core/diff"
+	"wrs/tk/packages/core/family"
+	"wrs/tk/packages/core/offline"
 	"wrs/tk/packages/core/part"
 	"wrs/tk/packages/core/partlist"
+	"wrs/tk/packages/core/policy"
+	"wrs/tk/packages/core/review"
+	"wrs/tk/packages/core/similarity"
+	"wrs/tk/packages/core/vulnerability"
 	"wrs/tk/packages/web_services/archive_web"
+	"wrs/tk/packages/web_services/bundle_web"
+	"wrs/tk/packages/web_services/offline_web"
+	"wrs/tk/packages/web_services/part_web"
+	"wrs/tk/packages/web_services/partlist_web"
+	"wrs/tk/packages/web_services/vex_web"
 
 	// "wrs/tk/packages/core/group"
 	"wrs/tk/packages/core/license"
-	"wrs/tk/packages/database"
 	"wrs/tk/packages/middleware"
 
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
-	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
@@ -72,43 +80,9 @@ func NewServer(host string, port int, db *sqlx.DB, frontdoorHost string, threads
 		server.logger = logger
 	}
 
-	if config.Blob.Bucket == "" {
-		return nil, errors.New("no bucket found")
-	}
 	// Initialize requirements for Handler
-	// initialize blob storage
-	var fileStorage blob.Storage
-	var archiveStorage blob.Storage
-	log.Info().Str("config.Blob.Bucket", config.Blob.Bucket).Msg("setting up s3 blob storage")
-
-	// connect to blob database
-	secrets := "/var/run/secrets"
-	info, err := database.NewEncryptedDBInfo(filepath.Join(secrets, "blob"), filepath.Join(secrets, "key"))
-	if err != nil {
-		return nil, errors.Wrapf(err, "error reading blob database info")
-	}
-	blobDB, err := info.Connect()
-	if err != nil {
-		return nil, errors.Wrapf(err, "error connecting to blob database")
-	}
-
-	// if credentials required create credentials object
-	// a nil cred object can still be a valid use of credentials, as it would then rely on IAM roles
-	var cred *credentials.Credentials
-	if config.Blob.ID != "" {
-		log.Info().Str("ObjectStorage.ID", config.Blob.ID).Msg("Using ObjectStorage Credentials")
-		cred = credentials.NewStaticCredentials(config.Blob.ID, config.Blob.Secret, config.Blob.Token)
-	}
-
-	// create blob storage
-	fileStorage, err = bucket.CreateBlobBucket(blobDB, "blob", config.Blob.Bucket, config.Blob.Region, config.Blob.Endpoint, cred)
+	archiveController, err := NewArchiveController(db, config, threads)
 	if err != nil {
-		err = errors.Wrapf(err, "error opening blob bucket")
-		return nil, err
-	}
-	archiveStorage, err = bucket.CreateBlobBucket(blobDB, "archive", config.Blob.Bucket, config.Blob.Region, config.Blob.Endpoint, cred)
-	if err != nil {
-		err = errors.Wrapf(err, "error opening blob bucket")
 		return nil, err
 	}
 
@@ -118,33 +92,78 @@ func NewServer(host string, port int, db *sqlx.DB, frontdoorHost string, threads
 	server.Server.Handler = router
 
 	// Create new controllers
-	archiveController := archive_core.NewArchiveController(db, fileStorage, archiveStorage, int(threads), config.Blob.Bucket, cred, config.Blob.Endpoint, config.Blob.Region)
-	partController := part.PartController{DB: db}
+	partController := part.PartController{DB: db, Instance: config.InstanceID(), EnforceVerificationCodes: config.Verification.Enforce}
+	verifier := part.NewVerifier(partController)
 	partlistController := partlist.PartListController{DB: db}
 	licenseController := license.LicenseController{
-		DB:                db,
-		PartController:    partController,
-		ArchiveController: archiveController,
+		DB:                 db,
+		PartController:     partController,
+		PartListController: partlistController,
+		ArchiveController:  archiveController,
 	}
+	policyController := policy.PolicyController{DB: db}
+	bundleDirectory := config.Bundle.Directory
+	if bundleDirectory == "" {
+		bundleDirectory = filepath.Join(config.Server.UploadDirectory, "bundles")
+	}
+	bundleController := bundle.NewBundleController(db, archiveController, &licenseController, bundleDirectory, config.Bundle.Copyleft)
+	vulnerabilityController := vulnerability.NewVulnerabilityController(db)
+	offlineController := offline.NewOfflineController(db, archiveController, partController)
+	reviewController := review.ReviewController{DB: db}
+	diffController := diff.DiffController{DB: db, ArchiveController: archiveController}
+	similarityController := similarity.SimilarityController{DB: db}
+	familyController := family.FamilyController{DB: db, PartController: &partController}
 	// groupController := group.GroupController{DB: db}
 
+	authenticators := []middleware.Authenticator{middleware.TokenAuthenticator{DB: db}}
+	if config.Auth.JWKS != "" {
+		jwtAuthenticator, err := middleware.NewJWTAuthenticator(config.Auth.JWKS, config.Auth.Issuer, config.Auth.Audience, config.Auth.NameClaim, config.Auth.RolesClaim)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+
+	router.Use(middleware.Authenticate(config.Auth.AnonymousRoles, authenticators...))
 	router.Use(middleware.ContextWithValue(archive_core.ArchiveKey, archiveController))
 	router.Use(middleware.ContextWithValue(part.PartKey, &partController))
 	router.Use(middleware.ContextWithValue(partlist.PartListKey, &partlistController))
 	router.Use(middleware.ContextWithValue(license.LicenseKey, &licenseController))
+	router.Use(middleware.ContextWithValue(policy.PolicyKey, &policyController))
+	router.Use(middleware.ContextWithValue(bundle.BundleKey, bundleController))
+	router.Use(middleware.ContextWithValue(vulnerability.VulnerabilityKey, vulnerabilityController))
+	router.Use(middleware.ContextWithValue(offline.OfflineKey, offlineController))
 	// router.Use(middleware.ContextWithValue(group.GroupKey, &groupController))
 
 	//
 	graphqlHandler := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &graphql.Resolver{
-		ArchiveController:  archiveController,
-		PartController:     &partController,
-		PartListController: &partlistController,
-		LicenseController:  &licenseController,
-	}}))
-	router.Handle("/playground", playground.Handler("GraphQL playground", "/api/graphql"))
+		ArchiveController:       archiveController,
+		PartController:          &partController,
+		Verifier:                verifier,
+		PartListController:      &partlistController,
+		LicenseController:       &licenseController,
+		PolicyController:        &policyController,
+		BundleController:        bundleController,
+		VulnerabilityController: vulnerabilityController,
+		ReviewController:        &reviewController,
+		DiffController:          &diffController,
+		SimilarityController:    &similarityController,
+		FamilyController:        &familyController,
+	}, Directives: generated.DirectiveRoot{HasRole: graphql.HasRole}}))
+	router.With(middleware.RequireRole(middleware.RoleAdmin)).Handle("/playground", playground.Handler("GraphQL playground", "/api/graphql"))
 	router.Handle("/api/graphql", graphqlHandler)
-	router.Get("/api/archive/{archiveSha256:[a-fA-F0-9]+}", archive_web.HandleArchiveDownload)               // if archive has a name, which it probably does, redirects
-	router.Get("/api/archive/{archiveSha256:[a-fA-F0-9]+}/{archiveName}", archive_web.HandleArchiveDownload) // serves archive with the given name
+	viewer := router.With(middleware.RequireRole(middleware.RoleViewer))
+	viewer.Get("/api/archive/{archiveSha256:[a-fA-F0-9]+}", archive_web.HandleArchiveDownload)               // if archive has a name, which it probably does, redirects
+	viewer.Get("/api/archive/{archiveSha256:[a-fA-F0-9]+}/{archiveName}", archive_web.HandleArchiveDownload) // serves archive with the given name
+	viewer.Get("/api/partlist/{partlistID:[0-9]+}/obligations", partlist_web.HandleObligationReport)         // serves the license obligation report as json, csv, or html
+	viewer.Get("/api/partlist/{partlistID:[0-9]+}/source", bundle_web.HandleBundleStream)                    // streams a corresponding-source bundle as it is built
+	viewer.Get("/api/source_bundle/{bundleID:[0-9]+}", bundle_web.HandleBundleDownload)                      // serves a complete corresponding-source bundle
+	viewer.Get("/api/part/{partID}/vex", vex_web.HandlePartVEX)                                              // serves VEX statements of a part as OpenVEX or CycloneDX
+	viewer.Get("/api/partlist/{partlistID:[0-9]+}/vex", vex_web.HandlePartListVEX)                           // serves VEX statements of a partlist as OpenVEX or CycloneDX
+	viewer.Get("/resolve", part_web.HandleResolve)                                                           // serves the part referenced by a partid://, fvcid://, or aliasid:// uri
+	admin := router.With(middleware.RequireRole(middleware.RoleAdmin))
+	admin.Get("/api/offline/export", offline_web.HandleExport)  // streams an offline bundle of parts or a partlist
+	admin.Post("/api/offline/import", offline_web.HandleImport) // merges an offline bundle, and serves the import report as json
 
 	return &server, nil
 }
@@ -0,0 +1,73 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package server
+
+import (
+	"path/filepath"
+	"wrs/tk/packages/blob/bucket"
+	mainConfig "wrs/tk/packages/config"
+	archive_core "wrs/tk/packages/core/archive"
+	"wrs/tk/packages/core/archive/sync"
+	"wrs/tk/packages/database"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// NewArchiveController connects to blob storage as configured, and returns an archive controller storing files and archives in it
+func NewArchiveController(db *sqlx.DB, config *mainConfig.MainConfig, threads int) (*archive_core.ArchiveController, error) {
+	if threads == 0 {
+		threads = 1
+	}
+	if config.Blob.Bucket == "" {
+		return nil, errors.New("no bucket found")
+	}
+	log.Info().Str("config.Blob.Bucket", config.Blob.Bucket).Msg("setting up s3 blob storage")
+
+	// connect to blob database
+	secrets := "/var/run/secrets"
+	info, err := database.NewEncryptedDBInfo(filepath.Join(secrets, "blob"), filepath.Join(secrets, "key"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading blob database info")
+	}
+	blobDB, err := info.Connect()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error connecting to blob database")
+	}
+
+	// if credentials required create credentials object
+	// a nil cred object can still be a valid use of credentials, as it would then rely on IAM roles
+	var cred *credentials.Credentials
+	if config.Blob.ID != "" {
+		log.Info().Str("ObjectStorage.ID", config.Blob.ID).Msg("Using ObjectStorage Credentials")
+		cred = credentials.NewStaticCredentials(config.Blob.ID, config.Blob.Secret, config.Blob.Token)
+	}
+
+	// create blob storage
+	fileStorage, err := bucket.CreateBlobBucket(blobDB, "blob", config.Blob.Bucket, config.Blob.Region, config.Blob.Endpoint, cred)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening blob bucket")
+	}
+	archiveStorage, err := bucket.CreateBlobBucket(blobDB, "archive", config.Blob.Bucket, config.Blob.Region, config.Blob.Endpoint, cred)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening blob bucket")
+	}
+
+	ret := archive_core.NewArchiveController(db, fileStorage, archiveStorage, threads, config.Blob.Bucket, cred, config.Blob.Endpoint, config.Blob.Region)
+	ret.SyncOptions = sync.Options{
+		LinkEmbedded:     config.Archive.LinkEmbedded,
+		EmbeddedMinFiles: config.Archive.EmbeddedMinFiles,
+	}
+
+	return ret, nil
+}
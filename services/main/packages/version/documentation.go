@@ -0,0 +1,3 @@
+// version compares version strings of any scheme, or by the rules of an ecosystem's scheme, semver, Debian, RPM, or PEP 440,
+// and evaluates version ranges in the vers notation used by package URLs
+package version
@@ -0,0 +1,181 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package version
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Comparators of a range constraint
+const (
+	Equal          = "="
+	NotEqual       = "!="
+	Less           = "<"
+	LessOrEqual    = "<="
+	Greater        = ">"
+	GreaterOrEqual = ">="
+)
+
+// Constraint is a comparator and a version, such as >=1.0.0
+type Constraint struct {
+	Comparator string
+	Version    string
+}
+
+// Range is a list of constraints in the vers notation, such as vers:npm/>=1.0.0|<2.0.0
+type Range struct {
+	Scheme      string
+	Any         bool
+	Constraints []Constraint
+}
+
+// ParseRange parses a vers range, e.g. vers:npm/>=1.0.0|<2.0.0|!=1.5.0.
+// The vers: prefix and scheme may be left out, e.g. >=1.0.0|<2.0.0, and a bare version is an = constraint.
+func ParseRange(s string) (*Range, error) {
+	ret := new(Range)
+
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToLower(s), "vers:") {
+		s = s[len("vers:"):]
+		i := strings.IndexByte(s, '/')
+		if i < 0 {
+			return nil, errors.Errorf("vers range \"%s\" has no scheme", s)
+		}
+		ret.Scheme, s = strings.ToLower(s[:i]), s[i+1:]
+	}
+
+	if strings.TrimSpace(s) == "*" {
+		ret.Any = true
+		return ret, nil
+	}
+
+	ret.Constraints = make([]Constraint, 0)
+	for _, v := range strings.Split(s, "|") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		constraint := Constraint{Comparator: Equal}
+		for _, comparator := range []string{GreaterOrEqual, LessOrEqual, NotEqual, Greater, Less, Equal} {
+			if strings.HasPrefix(v, comparator) {
+				constraint.Comparator = comparator
+				v = strings.TrimSpace(v[len(comparator):])
+				break
+			}
+		}
+
+		unescaped, err := url.PathUnescape(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error unescaping version \"%s\"", v)
+		}
+		if unescaped == "" {
+			return nil, errors.Errorf("range constraint \"%s\" has no version", constraint.Comparator)
+		}
+		constraint.Version = unescaped
+
+		ret.Constraints = append(ret.Constraints, constraint)
+	}
+	if len(ret.Constraints) == 0 {
+		return nil, errors.New("range has no constraints")
+	}
+
+	return ret, nil
+}
+
+// String returns the range in vers notation
+func (r Range) String() string {
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "generic"
+	}
+	if r.Any {
+		return "vers:" + scheme + "/*"
+	}
+
+	constraints := make([]string, 0, len(r.Constraints))
+	for _, v := range r.Constraints {
+		comparator := v.Comparator
+		if comparator == Equal {
+			comparator = ""
+		}
+		constraints = append(constraints, comparator+url.PathEscape(v.Version))
+	}
+
+	return "vers:" + scheme + "/" + strings.Join(constraints, "|")
+}
+
+// compare compares versions by the version scheme of the range
+func (r Range) compare(a string, b string) int {
+	return CompareScheme(r.Scheme, a, b)
+}
+
+// Contains returns true if the version satisfies the range.
+// = and != constraints are checked first, then the remaining constraints are sorted by version and paired into intervals,
+// each opened by a > or >= and closed by the following < or <=. An interval without an opening starts from the lowest version,
+// and one without a closing has no upper bound.
+func (r Range) Contains(v string) bool {
+	if r.Any {
+		return true
+	}
+
+	bounds := make([]Constraint, 0, len(r.Constraints))
+	for _, c := range r.Constraints {
+		switch c.Comparator {
+		case Equal:
+			if r.compare(v, c.Version) == 0 {
+				return true
+			}
+		case NotEqual:
+			if r.compare(v, c.Version) == 0 {
+				return false
+			}
+		default:
+			bounds = append(bounds, c)
+		}
+	}
+	if len(bounds) == 0 {
+		// only = and != constraints, so != alone means anything else
+		for _, c := range r.Constraints {
+			if c.Comparator == Equal {
+				return false
+			}
+		}
+		return true
+	}
+
+	sort.SliceStable(bounds, func(i, j int) bool {
+		return r.compare(bounds[i].Version, bounds[j].Version) < 0
+	})
+
+	var lower *Constraint
+	for i := range bounds {
+		c := bounds[i]
+		switch c.Comparator {
+		case Greater, GreaterOrEqual:
+			lower = &bounds[i]
+		case Less, LessOrEqual:
+			aboveLower := lower == nil || r.compare(v, lower.Version) > 0 || (lower.Comparator == GreaterOrEqual && r.compare(v, lower.Version) == 0)
+			belowUpper := r.compare(v, c.Version) < 0 || (c.Comparator == LessOrEqual && r.compare(v, c.Version) == 0)
+			if aboveLower && belowUpper {
+				return true
+			}
+			lower = nil
+		}
+	}
+
+	// an unclosed lower bound has no upper bound
+	return lower != nil && (r.compare(v, lower.Version) > 0 || (lower.Comparator == GreaterOrEqual && r.compare(v, lower.Version) == 0))
+}
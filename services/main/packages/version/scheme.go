@@ -0,0 +1,517 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package version
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Version schemes, named as in the vers notation
+const (
+	SchemeGeneric = "generic" // Compare, for versions of no particular ecosystem
+	SchemeSemver  = "semver"  // semantic versioning 2.0.0
+	SchemeDebian  = "deb"     // Debian [epoch:]upstream[-revision]
+	SchemeRPM     = "rpm"     // RPM [epoch:]version[-release]
+	SchemePEP440  = "pypi"    // Python PEP 440
+)
+
+// Schemes lists every version scheme
+var Schemes = []string{SchemeGeneric, SchemeSemver, SchemeDebian, SchemeRPM, SchemePEP440}
+
+// SchemeOf returns the version scheme of a vers scheme or purl type, such as semver for npm, or generic if it has no scheme of its own
+func SchemeOf(ecosystem string) string {
+	switch strings.ToLower(ecosystem) {
+	case SchemeSemver, "npm", "cargo", "golang", "composer", "hex", "swift":
+		return SchemeSemver
+	case SchemeDebian, "debian":
+		return SchemeDebian
+	case SchemeRPM:
+		return SchemeRPM
+	case SchemePEP440, "pep440", "python":
+		return SchemePEP440
+	default:
+		return SchemeGeneric
+	}
+}
+
+// CompareScheme compares two versions of a version scheme, or of the scheme of a vers scheme or purl type, returning -1, 0, or 1.
+// Versions that are not valid in the scheme are compared by Compare.
+func CompareScheme(scheme string, a string, b string) int {
+	switch SchemeOf(scheme) {
+	case SchemeSemver:
+		return compareSemver(a, b)
+	case SchemeDebian:
+		return compareDebian(a, b)
+	case SchemeRPM:
+		return compareRPM(a, b)
+	case SchemePEP440:
+		return comparePEP440(a, b)
+	default:
+		return Compare(a, b)
+	}
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareUint(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseSemver splits a semantic version into its major, minor, and patch versions, and its pre-release identifiers, dropping build metadata.
+// Missing minor and patch versions are 0, as in version ranges.
+func parseSemver(v string) ([3]uint64, []string, bool) {
+	var core [3]uint64
+
+	v = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(v), "v"), "V")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	var pre []string
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		pre = strings.Split(v[i+1:], ".")
+		v = v[:i]
+	}
+
+	fields := strings.Split(v, ".")
+	if len(fields) > 3 {
+		return core, nil, false
+	}
+	for i, field := range fields {
+		if !isDigits(field) {
+			return core, nil, false
+		}
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return core, nil, false
+		}
+		core[i] = n
+	}
+
+	return core, pre, true
+}
+
+// compareSemver compares semantic versions by precedence, a version without pre-release identifiers following those with them
+func compareSemver(a string, b string) int {
+	ac, ap, aok := parseSemver(a)
+	bc, bp, bok := parseSemver(b)
+	if !aok || !bok {
+		return Compare(a, b)
+	}
+
+	for i := range ac {
+		if c := compareUint(ac[i], bc[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case ap == nil && bp == nil:
+		return 0
+	case ap == nil:
+		return 1
+	case bp == nil:
+		return -1
+	}
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		x, y := ap[i], bp[i]
+		switch {
+		case isDigits(x) && isDigits(y):
+			xn, _ := strconv.ParseUint(x, 10, 64)
+			yn, _ := strconv.ParseUint(y, 10, 64)
+			if c := compareUint(xn, yn); c != 0 {
+				return c
+			}
+		case isDigits(x): // numeric identifiers have lower precedence
+			return -1
+		case isDigits(y):
+			return 1
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return sign(len(ap) - len(bp))
+}
+
+// splitEpoch splits a leading epoch: from a Debian or RPM version, 0 if there is none
+func splitEpoch(v string) (uint64, string) {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexByte(v, ':'); i >= 0 {
+		if epoch, err := strconv.ParseUint(v[:i], 10, 64); err == nil {
+			return epoch, v[i+1:]
+		}
+	}
+
+	return 0, v
+}
+
+// splitRelease splits the Debian revision or RPM release, after the last -, from a version
+func splitRelease(v string) (string, string, bool) {
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:], true
+	}
+
+	return v, "", false
+}
+
+// compareDebian compares Debian versions by epoch, then upstream version, then revision, as dpkg does
+func compareDebian(a string, b string) int {
+	ae, av := splitEpoch(a)
+	be, bv := splitEpoch(b)
+	if c := compareUint(ae, be); c != 0 {
+		return c
+	}
+
+	au, ar, _ := splitRelease(av)
+	bu, br, _ := splitRelease(bv)
+	if c := debianCompare(au, bu); c != 0 {
+		return c
+	}
+
+	return debianCompare(ar, br)
+}
+
+// debianOrder is the order of a character of a Debian version, ~ sorting before anything, even the end of the version, and letters before other characters
+func debianOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+
+	c := s[i]
+	switch {
+	case c >= '0' && c <= '9':
+		return 0
+	case c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigitAt(s string, i int) bool {
+	return i < len(s) && s[i] >= '0' && s[i] <= '9'
+}
+
+// debianCompare compares Debian upstream versions or revisions, alternating between runs of non-digits and runs of digits
+func debianCompare(a string, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigitAt(a, i)) || (j < len(b) && !isDigitAt(b, j)) {
+			if ac, bc := debianOrder(a, i), debianOrder(b, j); ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+
+		for isDigitAt(a, i) && a[i] == '0' {
+			i++
+		}
+		for isDigitAt(b, j) && b[j] == '0' {
+			j++
+		}
+
+		firstDiff := 0
+		for isDigitAt(a, i) && isDigitAt(b, j) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if isDigitAt(a, i) {
+			return 1
+		}
+		if isDigitAt(b, j) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+
+	return 0
+}
+
+// compareRPM compares RPM versions by epoch, then version, then release if both have one, as rpm does
+func compareRPM(a string, b string) int {
+	ae, av := splitEpoch(a)
+	be, bv := splitEpoch(b)
+	if c := compareUint(ae, be); c != 0 {
+		return c
+	}
+
+	av, ar, ahas := splitRelease(av)
+	bv, br, bhas := splitRelease(bv)
+	if c := rpmCompare(av, bv); c != 0 || !ahas || !bhas {
+		return c
+	}
+
+	return rpmCompare(ar, br)
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// rpmCompare compares RPM versions or releases segment by segment, as rpmvercmp does.
+// ~ sorts before anything, even the end of the version, and ^ after the end of the version but before anything else.
+func rpmCompare(a string, b string) int {
+	if a == b {
+		return 0
+	}
+
+	for len(a) > 0 || len(b) > 0 {
+		a = strings.TrimLeftFunc(a, func(r rune) bool { return r > unicode.MaxASCII || !isAlnum(byte(r)) && r != '~' && r != '^' })
+		b = strings.TrimLeftFunc(b, func(r rune) bool { return r > unicode.MaxASCII || !isAlnum(byte(r)) && r != '~' && r != '^' })
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		numeric := isDigitAt(a, 0)
+		segment := func(s string) (string, string) {
+			i := 0
+			for i < len(s) && (numeric && isDigitAt(s, i) || !numeric && isAlnum(s[i]) && !isDigitAt(s, i)) {
+				i++
+			}
+			return s[:i], s[i:]
+		}
+		var x, y string
+		x, a = segment(a)
+		y, b = segment(b)
+
+		if y == "" { // segments of different types, numeric segments being newer
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+
+		if numeric {
+			x, y = strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+			if c := sign(len(x) - len(y)); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+var pep440Pattern = regexp.MustCompile(`(?i)^v?(?:(?P<epoch>[0-9]+)!)?(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(?P<pre_l>alpha|a|beta|b|preview|pre|rc|c)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?:-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+	`(?:[-_.]?(?P<dev_l>dev)[-_.]?(?P<dev_n>[0-9]+)?)?` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+// pep440Key is the sort key of a PEP 440 version, missing segments being math.MinInt64 or math.MaxInt64 where they sort before or after any value
+type pep440Key struct {
+	epoch    int64
+	release  []int64
+	prePhase int64 // a, b, rc as 0, 1, 2
+	pre      int64
+	post     int64
+	dev      int64
+	local    []string
+}
+
+func parsePEP440(v string) (*pep440Key, bool) {
+	match := pep440Pattern.FindStringSubmatch(strings.TrimSpace(v))
+	if match == nil {
+		return nil, false
+	}
+	group := func(name string) string {
+		return match[pep440Pattern.SubexpIndex(name)]
+	}
+	number := func(s string) int64 {
+		n, _ := strconv.ParseInt(s, 10, 64)
+		return n
+	}
+
+	ret := &pep440Key{epoch: number(group("epoch"))}
+	for _, field := range strings.Split(group("release"), ".") {
+		ret.release = append(ret.release, number(field))
+	}
+	for len(ret.release) > 1 && ret.release[len(ret.release)-1] == 0 { // 1.0 == 1.0.0
+		ret.release = ret.release[:len(ret.release)-1]
+	}
+
+	hasPost := group("post_n1") != "" || group("post_l") != ""
+	hasDev := group("dev_l") != ""
+	switch strings.ToLower(group("pre_l")) {
+	case "a", "alpha":
+		ret.prePhase, ret.pre = 0, number(group("pre_n"))
+	case "b", "beta":
+		ret.prePhase, ret.pre = 1, number(group("pre_n"))
+	case "rc", "c", "pre", "preview":
+		ret.prePhase, ret.pre = 2, number(group("pre_n"))
+	default:
+		if hasDev && !hasPost { // 1.0.dev1 < 1.0a1
+			ret.prePhase = math.MinInt64
+		} else {
+			ret.prePhase = math.MaxInt64
+		}
+	}
+
+	ret.post = math.MinInt64
+	if hasPost {
+		ret.post = number(group("post_n1") + group("post_n2"))
+	}
+	ret.dev = math.MaxInt64
+	if hasDev {
+		ret.dev = number(group("dev_n"))
+	}
+	if local := group("local"); local != "" {
+		ret.local = strings.FieldsFunc(strings.ToLower(local), func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	}
+
+	return ret, true
+}
+
+func compareInt(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePEP440 compares Python versions as PEP 440 orders them
+func comparePEP440(a string, b string) int {
+	ak, aok := parsePEP440(a)
+	bk, bok := parsePEP440(b)
+	if !aok || !bok {
+		return Compare(a, b)
+	}
+
+	if c := compareInt(ak.epoch, bk.epoch); c != 0 {
+		return c
+	}
+	for i := 0; i < len(ak.release) || i < len(bk.release); i++ {
+		var x, y int64
+		if i < len(ak.release) {
+			x = ak.release[i]
+		}
+		if i < len(bk.release) {
+			y = bk.release[i]
+		}
+		if c := compareInt(x, y); c != 0 {
+			return c
+		}
+	}
+	for _, v := range [][2]int64{{ak.prePhase, bk.prePhase}, {ak.pre, bk.pre}, {ak.post, bk.post}, {ak.dev, bk.dev}} {
+		if c := compareInt(v[0], v[1]); c != 0 {
+			return c
+		}
+	}
+
+	// a local version follows the same public version, its segments compared numerically if numeric, numeric segments following others
+	for i := 0; i < len(ak.local) && i < len(bk.local); i++ {
+		x, y := ak.local[i], bk.local[i]
+		switch {
+		case isDigits(x) && isDigits(y):
+			xn, _ := strconv.ParseInt(x, 10, 64)
+			yn, _ := strconv.ParseInt(y, 10, 64)
+			if c := compareInt(xn, yn); c != 0 {
+				return c
+			}
+		case isDigits(x):
+			return 1
+		case isDigits(y):
+			return -1
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return sign(len(ak.local) - len(bk.local))
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Wind River Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//       http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software  distributed
+// under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES
+// OR CONDITIONS OF ANY KIND, either express or implied.
+
+package version
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// versionTokens splits a version into runs of digits and runs of letters, dropping separators and semver build metadata
+func versionTokens(version string) []string {
+	version = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(version), "v"), "V")
+	if i := strings.IndexByte(version, '+'); i > 0 {
+		version = version[:i]
+	}
+
+	ret := make([]string, 0)
+	var current []rune
+	var currentIsDigit bool
+	for _, r := range version {
+		isDigit := unicode.IsDigit(r)
+		if !isDigit && !unicode.IsLetter(r) { // separator
+			if len(current) > 0 {
+				ret = append(ret, string(current))
+				current = nil
+			}
+			continue
+		}
+
+		if len(current) > 0 && isDigit != currentIsDigit {
+			ret = append(ret, string(current))
+			current = nil
+		}
+		current = append(current, r)
+		currentIsDigit = isDigit
+	}
+	if len(current) > 0 {
+		ret = append(ret, string(current))
+	}
+
+	return ret
+}
+
+func isNumeric(token string) bool {
+	return token != "" && unicode.IsDigit(rune(token[0]))
+}
+
+// Compare compares two versions, returning -1, 0, or 1.
+// Numeric parts are compared numerically and other parts case-insensitively.
+// When one version runs out of parts, a remaining letter part marks a pre-release (1.0rc1 < 1.0), and a remaining number a later version (1.0 < 1.0.1).
+func Compare(a string, b string) int {
+	at, bt := versionTokens(a), versionTokens(b)
+
+	for i := 0; i < len(at) || i < len(bt); i++ {
+		if i >= len(at) {
+			if isNumeric(bt[i]) {
+				return -1
+			}
+			return 1
+		}
+		if i >= len(bt) {
+			if isNumeric(at[i]) {
+				return 1
+			}
+			return -1
+		}
+
+		x, y := at[i], bt[i]
+		switch {
+		case isNumeric(x) && isNumeric(y):
+			xn, _ := strconv.ParseUint(strings.TrimLeft(x, "0")+"0", 10, 64)
+			yn, _ := strconv.ParseUint(strings.TrimLeft(y, "0")+"0", 10, 64)
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case isNumeric(x): // numbers sort after letters, 1.0.1 > 1.0.rc1
+			return 1
+		case isNumeric(y):
+			return -1
+		default:
+			if c := strings.Compare(strings.ToLower(x), strings.ToLower(y)); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return 0
+}
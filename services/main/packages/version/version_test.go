@@ -0,0 +1,110 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "v1.2.3", b: "1.2.3", want: 0},
+		{a: "1.2.3+build5", b: "1.2.3", want: 0},
+		{a: "1.2", b: "1.10", want: -1},
+		{a: "1.0", b: "1.0.1", want: -1},
+		{a: "1.0.0-rc1", b: "1.0.0", want: -1},
+		{a: "1.0.0-rc2", b: "1.0.0-rc10", want: -1},
+		{a: "1.1.1t", b: "1.1.1k", want: 1},
+		{a: "2.0.0-alpha", b: "2.0.0-beta", want: -1},
+		{a: "1.0.1", b: "1.0.rc1", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("Compare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareScheme(t *testing.T) {
+	tests := []struct {
+		scheme string
+		a      string
+		b      string
+		want   int
+	}{
+		{scheme: SchemeSemver, a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{scheme: SchemeSemver, a: "1.0.0-alpha.beta", b: "1.0.0-beta", want: -1},
+		{scheme: SchemeSemver, a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{scheme: SchemeSemver, a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{scheme: SchemeSemver, a: "1.0.0+build.1", b: "1.0.0+build.2", want: 0},
+		{scheme: "npm", a: "2.0", b: "1.10.0", want: 1},
+		{scheme: SchemeDebian, a: "1.0~rc1-1", b: "1.0-1", want: -1},
+		{scheme: SchemeDebian, a: "1:0.9-1", b: "2.0-1", want: 1},
+		{scheme: SchemeDebian, a: "1.2.3-1ubuntu1", b: "1.2.3-1", want: 1},
+		{scheme: SchemeDebian, a: "1.0+dfsg-2", b: "1.0-2", want: 1},
+		{scheme: SchemeRPM, a: "1.0~rc1-1", b: "1.0-1", want: -1},
+		{scheme: SchemeRPM, a: "1.0^git1-1", b: "1.0-1", want: 1},
+		{scheme: SchemeRPM, a: "1.0^git1", b: "1.0.1", want: -1},
+		{scheme: SchemeRPM, a: "2.0-1.el8", b: "2.0-1.el9", want: -1},
+		{scheme: SchemeRPM, a: "1.0a", b: "1.0.1", want: -1},
+		{scheme: SchemeRPM, a: "1:1.0-1", b: "2.0-1", want: 1},
+		{scheme: SchemeRPM, a: "1.0-2", b: "1.0", want: 0},
+		{scheme: SchemePEP440, a: "1.0.dev1", b: "1.0a1", want: -1},
+		{scheme: SchemePEP440, a: "1.0a1", b: "1.0b1", want: -1},
+		{scheme: SchemePEP440, a: "1.0rc1", b: "1.0", want: -1},
+		{scheme: SchemePEP440, a: "1.0", b: "1.0.post1", want: -1},
+		{scheme: SchemePEP440, a: "1.0.post1.dev1", b: "1.0.post1", want: -1},
+		{scheme: SchemePEP440, a: "1.0", b: "1.0.0", want: 0},
+		{scheme: SchemePEP440, a: "1.0+local.2", b: "1.0+local.10", want: -1},
+		{scheme: SchemePEP440, a: "1!0.1", b: "2.0", want: 1},
+		{scheme: SchemeGeneric, a: "1.2", b: "1.10", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme+"_"+tt.a+"_"+tt.b, func(t *testing.T) {
+			if got := CompareScheme(tt.scheme, tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareScheme() = %v, want %v", got, tt.want)
+			}
+			if got := CompareScheme(tt.scheme, tt.b, tt.a); got != -tt.want {
+				t.Errorf("CompareScheme() reversed = %v, want %v", got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestRangeContains(t *testing.T) {
+	tests := []struct {
+		name    string
+		vers    string
+		version string
+		want    bool
+	}{
+		{name: "within", vers: "vers:npm/>=1.0.0|<2.0.0", version: "1.4.2", want: true},
+		{name: "at upper bound", vers: "vers:npm/>=1.0.0|<2.0.0", version: "2.0.0", want: false},
+		{name: "at inclusive upper bound", vers: "vers:npm/>=1.0.0|<=2.0.0", version: "2.0.0", want: true},
+		{name: "below", vers: "vers:npm/>=1.0.0|<2.0.0", version: "0.9", want: false},
+		{name: "excluded", vers: "vers:npm/>=1.0.0|!=1.5.0|<2.0.0", version: "1.5.0", want: false},
+		{name: "exact", vers: "vers:pypi/1.2.3|2.0.0", version: "2.0.0", want: true},
+		{name: "not exact", vers: "vers:pypi/1.2.3|2.0.0", version: "1.3", want: false},
+		{name: "no upper bound", vers: ">=3.1", version: "10.0", want: true},
+		{name: "no lower bound", vers: "<1.1.1t", version: "1.1.1k", want: true},
+		{name: "two intervals, between", vers: "vers:generic/>=1.0|<1.2|>=2.0|<2.4", version: "1.5", want: false},
+		{name: "two intervals, second", vers: "vers:generic/>=1.0|<1.2|>=2.0|<2.4", version: "2.2", want: true},
+		{name: "any", vers: "vers:deb/*", version: "anything", want: true},
+		{name: "not equal alone", vers: "!=1.0", version: "1.1", want: true},
+		{name: "debian tilde", vers: "vers:deb/<1.0", version: "1.0~rc1", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRange(tt.vers)
+			if err != nil {
+				t.Fatalf("ParseRange() error = %v", err)
+			}
+			if got := r.Contains(tt.version); got != tt.want {
+				t.Errorf("Range.Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package bundle_web
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"wrs/tk/packages/core/bundle"
+	"wrs/tk/packages/core/partlist"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleBundleDownload serves the tarball of a complete source bundle.
+// Range requests are supported, so large downloads can be resumed.
+// The function depends on a bundle controller from the request context
+func HandleBundleDownload(w http.ResponseWriter, r *http.Request) {
+	bundleIDString := chi.URLParam(r, "bundleID")
+	bundleID, err := strconv.ParseInt(bundleIDString, 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing bundle id", 400)
+		log.Error().Err(err).Str("bundle_id", bundleIDString).Msg("error parsing bundle id")
+		return
+	}
+
+	bundleController, err := bundle.GetBundleController(r.Context())
+	if err != nil {
+		http.Error(w, "error getting bundle controller", 500)
+		log.Error().Err(err).Msg("error getting bundle controller")
+		return
+	}
+
+	b, err := bundleController.GetByID(bundleID)
+	if err == bundle.ErrNotFound {
+		http.Error(w, "source bundle not found", 404)
+		return
+	} else if err != nil {
+		http.Error(w, "error selecting source bundle", 500)
+		log.Error().Err(err).Int64("bundle_id", bundleID).Msg("error selecting source bundle")
+		return
+	}
+	if b.Status != bundle.StatusComplete {
+		http.Error(w, fmt.Sprintf("source bundle is %s", b.Status), 409)
+		return
+	}
+
+	f, err := bundleController.Open(*b)
+	if err != nil {
+		http.Error(w, "error opening source bundle", 500)
+		log.Error().Err(err).Int64("bundle_id", bundleID).Msg("error opening source bundle")
+		return
+	}
+	defer f.Close()
+
+	name := filepath.Base(b.StoragePath.String)
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	http.ServeContent(w, r, name, b.UpdateDate, f)
+}
+
+// HandleBundleStream builds a source bundle of a part list and streams it as it is written, without storing it.
+// Copyleft licenses may be given with repeated license query parameters, otherwise the configured licenses are used.
+// The function depends on a bundle controller from the request context
+func HandleBundleStream(w http.ResponseWriter, r *http.Request) {
+	partlistIDString := chi.URLParam(r, "partlistID")
+	partlistID, err := strconv.ParseInt(partlistIDString, 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing partlist id", 400)
+		log.Error().Err(err).Str("partlist_id", partlistIDString).Msg("error parsing partlist id")
+		return
+	}
+
+	bundleController, err := bundle.GetBundleController(r.Context())
+	if err != nil {
+		http.Error(w, "error getting bundle controller", 500)
+		log.Error().Err(err).Msg("error getting bundle controller")
+		return
+	}
+
+	list, err := bundleController.PartListController.GetByID(partlistID)
+	if err == partlist.ErrNotFound {
+		http.Error(w, "partlist not found", 404)
+		return
+	} else if err != nil {
+		http.Error(w, "error selecting partlist", 500)
+		log.Error().Err(err).Int64("partlist_id", partlistID).Msg("error selecting partlist")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"source-bundle-partlist-%d.tar\"", list.ID))
+
+	// the status has already been sent once streaming starts, so errors can only be logged and the tarball left truncated
+	if err := bundleController.Stream(w, partlistID, r.URL.Query()["license"]); err != nil {
+		log.Error().Err(err).Int64("partlist_id", partlistID).Msg("error streaming source bundle")
+	}
+}
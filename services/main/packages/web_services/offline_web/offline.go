@@ -0,0 +1,150 @@
+package offline_web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"wrs/tk/packages/core/audit"
+	"wrs/tk/packages/core/offline"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/partlist"
+	"wrs/tk/packages/core/vulnerability"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// maxMemory is how much of a multipart upload is kept in memory, the rest is spooled to disk
+const maxMemory = 32 << 20
+
+// writeTracker records whether anything was written, after which errors can no longer be served
+type writeTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writeTracker) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// HandleExport streams an offline bundle of the parts given by repeated part query parameters, and/or the partlist given by the partlist query parameter.
+// An incremental bundle is built if since gives a previous export, and blobs=false leaves out file and archive contents.
+// The function depends on an offline controller from the request context
+func HandleExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := offline.ExportOptions{Blobs: query.Get("blobs") != "false"}
+	for _, v := range query["part"] {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "error parsing part id", 400)
+			return
+		}
+
+		options.PartIDs = append(options.PartIDs, part.ID(id))
+	}
+	if v := query.Get("partlist"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "error parsing partlist id", 400)
+			return
+		}
+
+		options.PartListID = &id
+	}
+	if v := query.Get("since"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "error parsing since export id", 400)
+			return
+		}
+
+		options.SinceID = &id
+	}
+	if len(options.PartIDs) == 0 && options.PartListID == nil {
+		http.Error(w, "part or partlist required", 400)
+		return
+	}
+
+	offlineController, err := offline.GetOfflineController(r.Context())
+	if err != nil {
+		http.Error(w, "error getting offline controller", 500)
+		log.Error().Err(err).Msg("error getting offline controller")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"offline-bundle.tar\"")
+
+	// records are collected before anything is written, so most errors can still be served
+	tracker := writeTracker{ResponseWriter: w}
+	export, err := offlineController.Export(&tracker, options)
+	if err == nil {
+		log.Info().Int64("export_id", export.ID).Msg("offline export complete")
+		return
+	}
+
+	log.Error().Err(err).Interface("options", options).Msg("error exporting offline bundle")
+	if tracker.written {
+		return
+	}
+	w.Header().Del("Content-Disposition")
+	if errors.Is(err, offline.ErrNotFound) || errors.Is(err, part.ErrNotFound) || errors.Is(err, partlist.ErrNotFound) || errors.Is(err, offline.ErrIncomplete) {
+		http.Error(w, err.Error(), 404)
+	} else {
+		http.Error(w, "error exporting offline bundle", 500)
+	}
+}
+
+// HandleImport merges the offline bundle given as the request body, or as the file of a multipart form, and serves the import report as json.
+// Changes are attributed to the actor of the request context, and the parts the report lists are queued to be matched to vulnerabilities.
+// The function depends on an offline and a vulnerability controller from the request context
+func HandleImport(w http.ResponseWriter, r *http.Request) {
+	offlineController, err := offline.GetOfflineController(r.Context())
+	if err != nil {
+		http.Error(w, "error getting offline controller", 500)
+		log.Error().Err(err).Msg("error getting offline controller")
+		return
+	}
+	vulnerabilityController, err := vulnerability.GetVulnerabilityController(r.Context())
+	if err != nil {
+		http.Error(w, "error getting vulnerability controller", 500)
+		log.Error().Err(err).Msg("error getting vulnerability controller")
+		return
+	}
+
+	var body io.Reader = r.Body
+	if err := r.ParseMultipartForm(maxMemory); err == nil {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "error reading file", 400)
+			return
+		}
+		defer f.Close()
+
+		body = f
+	} else if err != http.ErrNotMultipart {
+		http.Error(w, "error parsing multipart form", 400)
+		return
+	}
+
+	report, err := offlineController.Import(body, audit.GetActor(r.Context()))
+	if errors.Is(err, offline.ErrFormat) {
+		http.Error(w, err.Error(), 400)
+		return
+	} else if err != nil {
+		http.Error(w, "error importing offline bundle", 500)
+		log.Error().Err(err).Msg("error importing offline bundle")
+		return
+	}
+	if len(report.PartIDs) > 0 {
+		vulnerabilityController.QueueMatch(report.PartIDs...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Error().Err(err).Msg("error encoding offline import report")
+	}
+}
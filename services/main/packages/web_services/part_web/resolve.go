@@ -0,0 +1,86 @@
+package part_web
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"wrs/tk/packages/core/part"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// resolvedPart is the part as served by HandleResolve
+type resolvedPart struct {
+	ID                   string   `json:"id"`
+	URIs                 []string `json:"uris"`
+	Type                 *string  `json:"type"`
+	Name                 *string  `json:"name"`
+	Version              *string  `json:"version"`
+	Label                *string  `json:"label"`
+	FileVerificationCode *string  `json:"file_verification_code"`
+	License              *string  `json:"license"`
+}
+
+// HandleResolve serves the part referenced by the partid://, fvcid://, or aliasid:// URI given by the uri query parameter as json.
+// The function depends on a part controller from the request context
+func HandleResolve(w http.ResponseWriter, r *http.Request) {
+	uri := r.URL.Query().Get("uri")
+	if _, err := part.ParseURI(uri); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	partController, err := part.GetPartontroller(r.Context())
+	if err != nil {
+		http.Error(w, "error getting part controller", 500)
+		log.Error().Err(err).Msg("error getting part controller")
+		return
+	}
+
+	p, err := partController.Resolve(uri)
+	if errors.Is(err, part.ErrNotFound) {
+		http.Error(w, "part not found", 404)
+		return
+	} else if errors.Is(err, part.ErrForeignInstance) {
+		http.Error(w, err.Error(), 404)
+		return
+	} else if err != nil {
+		http.Error(w, "error resolving uri", 500)
+		log.Error().Err(err).Str("uri", uri).Msg("error resolving uri")
+		return
+	}
+
+	uris, err := partController.GetURIs(p.PartID, p.FileVerificationCode)
+	if err != nil {
+		http.Error(w, "error listing part uris", 500)
+		log.Error().Err(err).Str("part_id", p.PartID.String()).Msg("error listing part uris")
+		return
+	}
+
+	ret := resolvedPart{ID: p.PartID.String(), URIs: uris}
+	if p.Type.Valid {
+		ret.Type = &p.Type.String
+	}
+	if p.Name.Valid {
+		ret.Name = &p.Name.String
+	}
+	if p.Version.Valid {
+		ret.Version = &p.Version.String
+	}
+	if p.Label.Valid {
+		ret.Label = &p.Label.String
+	}
+	if len(p.FileVerificationCode) > 0 {
+		fvc := hex.EncodeToString(p.FileVerificationCode)
+		ret.FileVerificationCode = &fvc
+	}
+	if p.License.Valid {
+		ret.License = &p.License.String
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		log.Error().Err(err).Str("uri", uri).Msg("error writing resolved part")
+	}
+}
@@ -0,0 +1,85 @@
+package partlist_web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"wrs/tk/packages/core/license"
+	"wrs/tk/packages/core/partlist"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// HandleObligationReport serves the license obligation report of a part list and its nested part lists.
+// The format query parameter picks json (default), csv, or html.
+// The function depends on a license controller from the request context to build the report
+func HandleObligationReport(w http.ResponseWriter, r *http.Request) {
+	partlistIDString := chi.URLParam(r, "partlistID")
+	partlistID, err := strconv.ParseInt(partlistIDString, 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing partlist id", 400)
+		log.Error().Err(err).Str("partlist_id", partlistIDString).Msg("error parsing partlist id")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	var contentType string
+	switch format {
+	case "json":
+		contentType = "application/json"
+	case "csv":
+		contentType = "text/csv"
+	case "html":
+		contentType = "text/html; charset=utf-8"
+	default:
+		http.Error(w, "format must be json, csv, or html", 400)
+		return
+	}
+
+	licenseController, err := license.GetLicenseController(r)
+	if err != nil {
+		http.Error(w, "error getting license controller", 500)
+		log.Error().Err(err).Msg("error getting license controller")
+		return
+	}
+
+	report, err := licenseController.ObligationReport(partlistID)
+	if err == partlist.ErrNotFound {
+		http.Error(w, "partlist not found", 404)
+		return
+	} else if err != nil {
+		http.Error(w, "error building obligation report", 500)
+		log.Error().Err(err).Int64("partlist_id", partlistID).Msg("error building obligation report")
+		return
+	}
+
+	// write to a buffer first so a failure can still be reported with an error status
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		err = report.WriteJSON(&buf)
+	case "csv":
+		err = report.WriteCSV(&buf)
+	case "html":
+		err = report.WriteHTML(&buf)
+	}
+	if err != nil {
+		http.Error(w, "error writing obligation report", 500)
+		log.Error().Err(err).Str("format", format).Msg("error writing obligation report")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if format != "html" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"obligations-%d.%s\"", partlistID, format))
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Error().Err(err).Msg("error serving obligation report")
+	}
+}
@@ -0,0 +1,110 @@
+package vex_web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"wrs/tk/packages/core/part"
+	"wrs/tk/packages/core/vulnerability"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// HandlePartVEX serves the VEX statements on a part and its sub-parts.
+// The format query parameter picks openvex (default) or cyclonedx, and author sets the OpenVEX author.
+// The function depends on a vulnerability controller from the request context
+func HandlePartVEX(w http.ResponseWriter, r *http.Request) {
+	partIDString := chi.URLParam(r, "partID")
+	partUUID, err := uuid.Parse(partIDString)
+	if err != nil {
+		http.Error(w, "error parsing part id", 400)
+		log.Error().Err(err).Str("part_id", partIDString).Msg("error parsing part id")
+		return
+	}
+
+	vulnerabilityController, err := vulnerability.GetVulnerabilityController(r.Context())
+	if err != nil {
+		http.Error(w, "error getting vulnerability controller", 500)
+		log.Error().Err(err).Msg("error getting vulnerability controller")
+		return
+	}
+
+	statements, err := vulnerabilityController.ExportStatements(part.ID(partUUID))
+	if err != nil {
+		http.Error(w, "error selecting VEX statements", 500)
+		log.Error().Err(err).Str("part_id", partIDString).Msg("error selecting VEX statements")
+		return
+	}
+
+	writeVEX(w, r, vulnerabilityController, statements, "vex-"+partIDString)
+}
+
+// HandlePartListVEX serves the VEX statements on a part list, the part lists beneath it, and the parts they list.
+// The format query parameter picks openvex (default) or cyclonedx, and author sets the OpenVEX author.
+// The function depends on a vulnerability controller from the request context
+func HandlePartListVEX(w http.ResponseWriter, r *http.Request) {
+	partlistIDString := chi.URLParam(r, "partlistID")
+	partlistID, err := strconv.ParseInt(partlistIDString, 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing partlist id", 400)
+		log.Error().Err(err).Str("partlist_id", partlistIDString).Msg("error parsing partlist id")
+		return
+	}
+
+	vulnerabilityController, err := vulnerability.GetVulnerabilityController(r.Context())
+	if err != nil {
+		http.Error(w, "error getting vulnerability controller", 500)
+		log.Error().Err(err).Msg("error getting vulnerability controller")
+		return
+	}
+
+	statements, err := vulnerabilityController.ExportPartListStatements(partlistID)
+	if err != nil {
+		http.Error(w, "error selecting VEX statements", 500)
+		log.Error().Err(err).Int64("partlist_id", partlistID).Msg("error selecting VEX statements")
+		return
+	}
+
+	writeVEX(w, r, vulnerabilityController, statements, fmt.Sprintf("vex-partlist-%d", partlistID))
+}
+
+func writeVEX(w http.ResponseWriter, r *http.Request, vulnerabilityController *vulnerability.VulnerabilityController, statements []vulnerability.Statement, filename string) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = vulnerability.FormatOpenVEX
+	}
+	author := r.URL.Query().Get("author")
+	if author == "" {
+		author = "Software Parts Catalog"
+	}
+
+	// write to a buffer first so a failure can still be reported with an error status
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case vulnerability.FormatOpenVEX:
+		err = vulnerability.WriteOpenVEX(&buf, author, statements)
+	case vulnerability.FormatCycloneDX:
+		var names map[string]string
+		if names, err = vulnerabilityController.ProductNames(statements); err == nil {
+			err = vulnerability.WriteCycloneDX(&buf, names, statements)
+		}
+	default:
+		http.Error(w, "format must be openvex or cyclonedx", 400)
+		return
+	}
+	if err != nil {
+		http.Error(w, "error writing VEX document", 500)
+		log.Error().Err(err).Str("format", format).Msg("error writing VEX document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s.json\"", filename, format))
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Error().Err(err).Msg("error serving VEX document")
+	}
+}
@@ -1,4 +1,7 @@
 #!/usr/bin/bash
+# Uploads an archive to the catalog
+# Usage: upload.sh NAME FILEPATH
+# Set TOKEN to an API token with the uploader role, created with the token create command, sent as a bearer token
 NAME=$1
 FILEPATH=$2
 
@@ -9,6 +12,7 @@ OPERATIONS="${OPERATIONS_1}${NAME}${OPERATIONS_2}"
 echo "OPERATIONS=${OPERATIONS}"
 
 curl http://localhost/api/graphql \
+    -H "Authorization: Bearer ${TOKEN}" \
     -F operations="${OPERATIONS}" \
     -F map='{ "0": ["variables.file"] }' \
     -F 0=@${FILEPATH}
@@ -0,0 +1,36 @@
+#!/usr/bin/bash
+# Checks a license policy against a part, or a partlist with -l, and exits non-zero if any violations are found.
+# Usage: check_policy.sh POLICY PART_ID
+#        check_policy.sh -l POLICY PARTLIST_ID
+# Set HOST to check against another catalog, defaults to http://localhost
+# Set TOKEN to an API token with the viewer role, created with the token create command, sent as a bearer token
+HOST=${HOST:-http://localhost}
+
+if [ "$1" == "-l" ]; then
+    POLICY=$2
+    TARGET='"partlist_id": '$3
+else
+    POLICY=$1
+    TARGET='"part_id": "'$2'"'
+fi
+
+QUERY='query check($policy: String!, $part_id: UUID, $partlist_id: Int64) {\n  check_policy(policy: $policy, part_id: $part_id, partlist_id: $partlist_id) {\n    passed\n    violations {\n      rule {\n        type\n        message\n      }\n      part_id\n      license\n      path\n      parent_id\n    }\n  }\n}'
+OPERATIONS='{"query":"'${QUERY}'","variables":{"policy": "'${POLICY}'", '${TARGET}'},"operationName":"check"}'
+
+RESPONSE=$(curl -s ${HOST}/api/graphql \
+    -H 'Content-Type: application/json' \
+    -H "Authorization: Bearer ${TOKEN}" \
+    -d "${OPERATIONS}")
+if [ $? -ne 0 ]; then
+    echo "unable to reach ${HOST}" >&2
+    exit 2
+fi
+
+echo "${RESPONSE}" | jq '.'
+
+if [ "$(echo "${RESPONSE}" | jq -r '.errors | length')" != "0" ]; then
+    exit 2
+fi
+if [ "$(echo "${RESPONSE}" | jq -r '.data.check_policy.passed')" != "true" ]; then
+    exit 1
+fi